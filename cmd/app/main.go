@@ -1,43 +1,206 @@
 package main
 
 import (
+	"chat-app-api/internal/config"
 	"chat-app-api/internal/database"
+	"chat-app-api/internal/health"
+	"chat-app-api/internal/logging"
+	"chat-app-api/internal/metrics"
+	"chat-app-api/internal/realtime"
+	"chat-app-api/internal/repositories"
 	"chat-app-api/internal/routes"
-	"github.com/gin-contrib/cors"
-	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv"
-	"log"
+	"chat-app-api/internal/services"
+	"chat-app-api/internal/tracing"
+	"chat-app-api/internal/utils"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(os.Args[0]+" migrate", os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "role" {
+		os.Exit(runRole(os.Args[0]+" role", os.Args[2:]))
+	}
 
-	// Load environment variables from .env file
-	if err := godotenv.Load(); err != nil {
-		log.Fatalf("Error loading .env file")
+	// Load configuration from defaults, an optional .env file, environment variables and flags
+	cfg, err := config.Load(os.Args[0], os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+		os.Exit(1)
 	}
 
-	db, err := database.Connect()
+	logger, err := logging.New(cfg.Log, os.Stderr)
 	if err != nil {
-		log.Fatalf("Could not connect to the database: %v", err)
+		fmt.Fprintf(os.Stderr, "Invalid logging configuration: %v\n", err)
+		os.Exit(1)
+	}
+	// Libraries still writing through the standard log package end up in the structured log too
+	slog.SetDefault(logger)
+
+	if err := utils.InitJWT(cfg.JWT); err != nil {
+		fatal(logger, "invalid JWT configuration", err)
 	}
 
-	router := gin.Default()
+	if err := utils.ConfigurePasswordHashing(cfg.Password); err != nil {
+		fatal(logger, "invalid password hashing configuration", err)
+	}
 
-	// CORS configuration
-	router.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"http://localhost:5173"},                   // Allow specific origin
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE"},            // Allow specific HTTP methods
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"}, // Allow specific headers
-		ExposeHeaders:    []string{"Content-Length"},
-		AllowCredentials: true, // Allow credentials (cookies, authorization headers)
-		MaxAge:           12 * time.Hour,
-	}))
+	shutdownTracing, err := tracing.Init(context.Background(), cfg.Tracing)
+	if err != nil {
+		fatal(logger, "could not set up tracing", err)
+	}
 
-	api := router.Group("/api")
-	routes.SetupRoutes(api, db)
+	db, err := database.Connect(cfg.Database)
+	if err != nil {
+		fatal(logger, "could not connect to the database", err)
+	}
 
-	if err := router.Run(":8080"); err != nil {
-		log.Fatalf("Server failed to start: %v", err)
+	// Serving with an outdated schema would fail at the first query, so refuse to start instead
+	migrator, err := database.NewMigrator(db)
+	if err != nil {
+		fatal(logger, "could not load migrations", err)
+	}
+	if cfg.Database.AutoMigrate {
+		applied, err := migrator.Up(context.Background())
+		for _, migration := range applied {
+			logger.Info("applied migration", "version", migration.Version, "name", migration.Name)
+		}
+		if err != nil {
+			fatal(logger, "could not migrate the database", err)
+		}
+	}
+	if err := migrator.CheckSchema(context.Background()); err != nil {
+		fatal(logger, "refusing to start", err)
+	}
+
+	// Several instances share WebSocket events through the database; a single one keeps them in process
+	var broker realtime.Broker = realtime.NewLocalBroker()
+	var listener health.Listener
+	listenCtx, stopListening := context.WithCancel(context.Background())
+	listenerDone := make(chan struct{})
+	if cfg.Realtime.Broker == "postgres" {
+		if db.Dialector.Name() != database.Postgres {
+			fatal(logger, "invalid realtime configuration", errors.New("REALTIME_BROKER=postgres needs a PostgreSQL database"))
+		}
+		postgresBroker := realtime.NewPostgresBroker(db, logger)
+		broker, listener = postgresBroker, postgresBroker
+		go func() {
+			postgresBroker.Run(listenCtx)
+			close(listenerDone)
+		}()
+	} else {
+		close(listenerDone)
+	}
+	hub := realtime.NewHub(broker, logger)
+
+	// Probes for the orchestrator and build information
+	checker := health.NewChecker(db, migrator, listener)
+
+	if err := metrics.RegisterHub(hub); err != nil {
+		fatal(logger, "could not register WebSocket metrics", err)
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		if err := metrics.RegisterDB(sqlDB, db.Dialector.Name()); err != nil {
+			fatal(logger, "could not register database metrics", err)
+		}
+	}
+
+	repos := repositories.NewSet(db)
+	router, err := routes.NewRouter(cfg, repos, hub, checker, logger)
+	if err != nil {
+		fatal(logger, "could not set up routes", err)
 	}
+
+	server := &http.Server{
+		Addr:              cfg.Server.Addr(),
+		Handler:           router,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+
+	// Stop on SIGINT (Ctrl+C) or SIGTERM (container stop / deploy)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Deleted accounts are purged in the background once their grace period is over
+	purger := services.NewAccountPurger(repos.Users, cfg.Account, logger)
+	purgerDone := make(chan struct{})
+	go func() {
+		purger.Run(ctx)
+		close(purgerDone)
+	}()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		logger.Info("listening", "addr", server.Addr)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		fatal(logger, "server failed to start", err)
+	case <-ctx.Done():
+	}
+	stop()
+
+	// Fail readiness first so the load balancer stops sending new traffic before the listener closes
+	checker.StartShutdown()
+	if cfg.Server.ReadinessDelay > 0 {
+		logger.Info("readiness failing, waiting for traffic to drain", "delay", cfg.Server.ReadinessDelay)
+		time.Sleep(cfg.Server.ReadinessDelay)
+	}
+
+	logger.Info("shutting down", "timeout", cfg.Server.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	// Stop accepting connections and let in-flight HTTP requests finish
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Error("error shutting down HTTP server", "error", err)
+	}
+
+	// WebSockets are hijacked connections the HTTP server no longer tracks: drain message saves
+	// and send every client a "going away" close frame so it reconnects to another instance
+	if err := hub.Shutdown(shutdownCtx); err != nil {
+		logger.Error("error closing WebSocket connections", "error", err)
+	}
+
+	// Let a purge in progress finish and the realtime listener give back its connection before the pool is closed
+	<-purgerDone
+	stopListening()
+	<-listenerDone
+
+	if sqlDB, err := db.DB(); err == nil {
+		if err := sqlDB.Close(); err != nil {
+			logger.Error("error closing database pool", "error", err)
+		}
+	}
+
+	// Flush the spans of the last requests
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		logger.Error("error flushing traces", "error", err)
+	}
+
+	logger.Info("shutdown complete")
+}
+
+// fatal logs the error and exits; deferred functions do not run
+func fatal(logger *slog.Logger, msg string, err error) {
+	logger.Error(msg, "error", err)
+	os.Exit(1)
 }
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"chat-app-api/internal/config"
+	"chat-app-api/internal/database"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+const migrateUsage = `usage:
+  %[1]s [config flags] up          apply every pending migration
+  %[1]s [config flags] down [N]    revert the last N migrations (default 1)
+  %[1]s [config flags] status      list migrations and when they were applied
+  %[1]s create [-dir DIR] NAME     write a new pair of up/down SQL files per database
+`
+
+// runMigrate implements the migrate subcommand and returns the process exit code
+func runMigrate(name string, args []string) int {
+	if len(args) > 0 && args[0] == "create" {
+		return createMigration(name+" create", args[1:])
+	}
+
+	cfg, args, err := config.LoadArgs(name, args)
+	if errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintf(os.Stderr, migrateUsage, name)
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+		return 1
+	}
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, migrateUsage, name)
+		return 2
+	}
+
+	db, err := database.Connect(cfg.Database)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not connect to the database: %v\n", err)
+		return 1
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+
+	migrator, err := database.NewMigrator(db)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not load migrations: %v\n", err)
+		return 1
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, migration := range applied {
+			fmt.Printf("applied %d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if len(applied) == 0 {
+			fmt.Println("schema is up to date")
+		}
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				fmt.Fprintf(os.Stderr, "invalid number of migrations %q\n", args[1])
+				return 2
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		for _, migration := range reverted {
+			fmt.Printf("reverted %d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, "VERSION\tNAME\tAPPLIED AT")
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(writer, "%d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+		writer.Flush()
+
+	default:
+		fmt.Fprintf(os.Stderr, migrateUsage, name)
+		return 2
+	}
+	return 0
+}
+
+func createMigration(name string, args []string) int {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	dir := flags.String("dir", database.MigrationsDir, "directory of the migration files")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintf(os.Stderr, "usage: %s [-dir DIR] NAME\n", name)
+		return 2
+	}
+
+	paths, err := database.CreateMigration(*dir, flags.Arg(0))
+	for _, path := range paths {
+		fmt.Printf("created %s\n", path)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
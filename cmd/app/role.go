@@ -0,0 +1,64 @@
+package main
+
+import (
+	"chat-app-api/internal/config"
+	"chat-app-api/internal/database"
+	"chat-app-api/internal/models"
+	"chat-app-api/internal/repositories"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+)
+
+const roleUsage = `usage:
+  %[1]s [config flags] USERNAME admin|user   set the role of a user, e.g. to create the first admin
+`
+
+// runRole implements the role subcommand and returns the process exit code
+func runRole(name string, args []string) int {
+	cfg, args, err := config.LoadArgs(name, args)
+	if errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintf(os.Stderr, roleUsage, name)
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+		return 1
+	}
+	if len(args) != 2 || (args[1] != models.RoleAdmin && args[1] != models.RoleUser) {
+		fmt.Fprintf(os.Stderr, roleUsage, name)
+		return 2
+	}
+	username, role := args[0], args[1]
+
+	db, err := database.Connect(cfg.Database)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not connect to the database: %v\n", err)
+		return 1
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+
+	ctx := context.Background()
+	users := repositories.NewSet(db).Users
+	user, err := users.FindByUsername(ctx, username)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not find user %q: %v\n", username, err)
+		return 1
+	}
+	if err := users.UpdateRole(ctx, user.ID, role); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	// Tokens carry the role, so the ones already issued must not keep the old one
+	if err := users.RevokeTokens(ctx, user.ID); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	fmt.Printf("%s is now %s\n", username, role)
+	return 0
+}
@@ -0,0 +1,126 @@
+package apidocs
+
+import (
+	"chat-app-api/internal/apierror"
+	"chat-app-api/internal/buildinfo"
+	"chat-app-api/internal/dto"
+	"chat-app-api/internal/services"
+	"chat-app-api/internal/utils"
+)
+
+// WebSocketPath is the route the AsyncAPI channel describes
+const WebSocketPath = "/api/messages/ws"
+
+// AsyncAPI builds the AsyncAPI 2.6 document of the events exchanged on the chat WebSocket.
+// In AsyncAPI 2, publish is what the client sends and subscribe is what it receives.
+func AsyncAPI() Schema {
+	registry := newSchemaRegistry("#/components/schemas/")
+
+	messages := Schema{
+		"SendMessage": Schema{
+			"name":    "sendMessage",
+			"title":   "Send a message",
+			"summary": "Sends a chat message to another user. The sender is the user who opened the connection.",
+			"payload": registry.schemaOf(dto.SendMessageEvent{}),
+		},
+		"ChatMessage": Schema{
+			"name":  "chatMessage",
+			"title": "Chat message",
+			"summary": "A saved message. It reaches every connection of the recipient with is_self false, " +
+				"and every connection of the sender, including the one it was sent on, with is_self true.",
+			"payload": registry.schemaOf(services.RealTimeMessageResponse{}),
+		},
+		"Error": Schema{
+			"name":  "error",
+			"title": "Rejected event",
+			"summary": "Sent only to the connection whose event was rejected, with type \"" + dto.ErrorEventType + "\" " +
+				"and the same code, message and details as an HTTP error response. The connection stays open. " +
+				"A message refused by the content filter has code \"" + string(apierror.CodeMessageRejected) + "\" " +
+				"and the filter stage, action and reason as details.",
+			"payload": registry.schemaOf(dto.ErrorEvent{}),
+		},
+		"Throttled": Schema{
+			"name":  "throttled",
+			"title": "Throttled",
+			"summary": "Sent only to a connection sending messages faster than its rate limit, with type \"" + dto.ThrottledEventType + "\", " +
+				"when its first message is dropped. Messages sent before retry_after_ms has passed are dropped too; " +
+				"a connection that keeps sending is closed with 1008 (policy violation).",
+			"payload": registry.schemaOf(dto.ThrottledEvent{}),
+		},
+		"MessageRemoved": Schema{
+			"name":  "messageRemoved",
+			"title": "Message removed",
+			"summary": "Sent to both participants, with type \"" + services.MessageRemovedEventType + "\", " +
+				"when a moderator removes a reported message for everyone.",
+			"payload": registry.schemaOf(services.MessageRemovedEvent{}),
+		},
+		"Warning": Schema{
+			"name":  "warning",
+			"title": "Moderator warning",
+			"summary": "Sent to a user, with type \"" + services.WarningEventType + "\", " +
+				"when a moderator warns them about a report against them.",
+			"payload": registry.schemaOf(services.WarningEvent{}),
+		},
+		"ReportResolved": Schema{
+			"name":  "reportResolved",
+			"title": "Report resolved",
+			"summary": "Sent to the reporter, with type \"" + services.ReportResolvedEventType + "\", " +
+				"when their report is actioned or dismissed.",
+			"payload": registry.schemaOf(services.ReportResolvedEvent{}),
+		},
+	}
+
+	return Schema{
+		"asyncapi": "2.6.0",
+		"info": Schema{
+			"title":   "Chat App WebSocket API",
+			"version": buildinfo.Get().Commit,
+			"description": "Every WebSocket frame is a JSON text message. The server closes connections with 1001 " +
+				"(going away) when it shuts down, clients should reconnect; connections that cannot keep up with " +
+				"their outbound messages, that keep sending while throttled, and frames larger than 64 KiB are closed.",
+		},
+		"defaultContentType": "application/json",
+		"channels": Schema{
+			WebSocketPath: Schema{
+				"description": "Opened with an authenticated GET request, see /openapi.json.",
+				"bindings": Schema{
+					"ws": Schema{
+						"method": "GET",
+						"query": Schema{
+							"type": "object",
+							"properties": Schema{
+								"access_token": Schema{
+									"type":        "string",
+									"description": "Access token for clients that cannot send the Authorization header",
+								},
+							},
+						},
+					},
+				},
+				"publish": Schema{
+					"operationId": "sendMessage",
+					"message":     Schema{"$ref": "#/components/messages/SendMessage"},
+				},
+				"subscribe": Schema{
+					"operationId": "receiveEvents",
+					"message": Schema{"oneOf": []Schema{
+						{"$ref": "#/components/messages/ChatMessage"},
+						{"$ref": "#/components/messages/Error"},
+						{"$ref": "#/components/messages/Throttled"},
+						{"$ref": "#/components/messages/MessageRemoved"},
+						{"$ref": "#/components/messages/Warning"},
+						{"$ref": "#/components/messages/ReportResolved"},
+					}},
+				},
+			},
+		},
+		"components": Schema{
+			"messages": messages,
+			"schemas":  registry.components,
+			"securitySchemes": Schema{
+				bearerAuth: Schema{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+				cookieAuth: Schema{"type": "httpApiKey", "in": "cookie", "name": utils.AccessTokenCookie},
+			},
+		},
+	}
+}
@@ -0,0 +1,389 @@
+package apidocs
+
+import (
+	"chat-app-api/internal/apierror"
+	"chat-app-api/internal/buildinfo"
+	"chat-app-api/internal/dto"
+	"chat-app-api/internal/models"
+	"chat-app-api/internal/repositories"
+	"chat-app-api/internal/services"
+	"chat-app-api/internal/utils"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Operation documents one route. Path uses Gin syntax so it can be compared with the router.
+type Operation struct {
+	Method      string
+	Path        string
+	Tag         string
+	Summary     string
+	Description string
+	Security    []string // names of the accepted security schemes, none for public routes
+	Params      any      // struct whose uri and form fields are the path and query parameters
+	Body        any      // JSON request body
+	Responses   []Response
+}
+
+// Response is one documented status; Body is a Go value or a Schema, nil for an empty body
+type Response struct {
+	Status      int
+	Description string
+	ContentType string // defaults to application/json
+	Body        any
+}
+
+const (
+	bearerAuth = "bearerAuth"
+	cookieAuth = "cookieAuth"
+)
+
+// userAuth is accepted by every route behind AuthMiddleware; the admin routes also need the admin role
+var userAuth = []string{bearerAuth, cookieAuth}
+
+var messageBody = Schema{
+	"type":       "object",
+	"properties": Schema{"message": Schema{"type": "string"}},
+}
+
+// rateLimited is the response of a rate limited route once the client used up its requests
+var rateLimited = Response{
+	Status:      http.StatusTooManyRequests,
+	Description: "Rate limited; Retry-After tells when to try again and the RateLimit headers show the policy",
+	Body:        apierror.Response{},
+}
+
+var readinessBody = Schema{
+	"type": "object",
+	"properties": Schema{
+		"status": Schema{"type": "string", "enum": []string{"ok", "unavailable"}},
+		"checks": Schema{"type": "object", "additionalProperties": Schema{"type": "string"}},
+	},
+}
+
+// loginResult is the body of a successful password or single sign-on login
+type loginResult struct {
+	Data    services.LoginResponse `json:"data"`
+	Message string                 `json:"message"`
+}
+
+// Operations lists every route the server registers
+func Operations() []Operation {
+	return []Operation{
+		{
+			Method: http.MethodGet, Path: "/healthz", Tag: "operations", Summary: "Liveness probe",
+			Responses: []Response{{Status: http.StatusOK, Description: "The process is up", Body: Schema{
+				"type": "object", "properties": Schema{"status": Schema{"type": "string", "enum": []string{"ok"}}},
+			}}},
+		},
+		{
+			Method: http.MethodGet, Path: "/readyz", Tag: "operations", Summary: "Readiness probe",
+			Description: "Fails while the database is unreachable, a migration is pending or the server is shutting down.",
+			Responses: []Response{
+				{Status: http.StatusOK, Description: "Ready to take traffic", Body: readinessBody},
+				{Status: http.StatusServiceUnavailable, Description: "Not ready; checks lists the failures", Body: readinessBody},
+			},
+		},
+		{
+			Method: http.MethodGet, Path: "/version", Tag: "operations", Summary: "Build information",
+			Responses: []Response{{Status: http.StatusOK, Description: "Commit and build time", Body: buildinfo.Info{}}},
+		},
+		{
+			Method: http.MethodGet, Path: "/metrics", Tag: "operations", Summary: "Prometheus metrics",
+			Responses: []Response{{Status: http.StatusOK, Description: "Prometheus text exposition format", ContentType: "text/plain", Body: Schema{"type": "string"}}},
+		},
+		{
+			Method: http.MethodGet, Path: "/openapi.json", Tag: "documentation", Summary: "This OpenAPI document",
+			Responses: []Response{{Status: http.StatusOK, Description: "OpenAPI 3 document", Body: Schema{"type": "object"}}},
+		},
+		{
+			Method: http.MethodGet, Path: "/asyncapi.json", Tag: "documentation", Summary: "AsyncAPI document of the WebSocket protocol",
+			Responses: []Response{{Status: http.StatusOK, Description: "AsyncAPI 2 document", Body: Schema{"type": "object"}}},
+		},
+		{
+			Method: http.MethodGet, Path: "/docs", Tag: "documentation", Summary: "Swagger UI",
+			Responses: []Response{{Status: http.StatusOK, Description: "HTML page", ContentType: "text/html", Body: Schema{"type": "string"}}},
+		},
+		{
+			Method: http.MethodGet, Path: "/.well-known/jwks.json", Tag: "auth", Summary: "Public keys verifying access tokens",
+			Description: "Empty when access tokens are signed with a shared secret. Refresh tokens are never signed with these keys; " +
+				"verifiers should still require the token_type claim to be \"access\".",
+			Responses: []Response{{Status: http.StatusOK, Description: "JSON Web Key Set", Body: utils.JWKS{}}},
+		},
+
+		{
+			Method: http.MethodPost, Path: "/api/auth/login", Tag: "auth", Summary: "Log in with a username or email and a password",
+			Description: "Send X-Auth-Mode: cookie to receive the tokens as HttpOnly cookies instead of in the body. " +
+				"Repeated failures lock the account or client IP, and logins per client IP are rate limited; " +
+				"the 429 response carries Retry-After.",
+			Body: dto.LoginRequest{},
+			Responses: []Response{
+				{Status: http.StatusOK, Description: "Logged in", Body: loginResult{}},
+				{Status: http.StatusTooManyRequests, Description: "Account or client IP temporarily locked, or too many logins", Body: apierror.Response{}},
+			},
+		},
+		{
+			Method: http.MethodPost, Path: "/api/auth/renew", Tag: "auth", Summary: "Issue a new access token",
+			Description: "Cookie clients send no body; their refresh token is read from the refresh_token cookie and the CSRF header is required.",
+			Body:        dto.RenewTokenRequest{},
+			Responses: []Response{{Status: http.StatusOK, Description: "New access token, or a message in cookie mode", Body: Schema{
+				"type": "object",
+				"properties": Schema{
+					"access_token": Schema{"type": "string"},
+					"message":      Schema{"type": "string"},
+				},
+			}}},
+		},
+		{
+			Method: http.MethodPost, Path: "/api/auth/logout", Tag: "auth", Summary: "Clear the authentication cookies",
+			Responses: []Response{{Status: http.StatusOK, Description: "Logged out", Body: messageBody}},
+		},
+		{
+			Method: http.MethodGet, Path: "/api/auth/oidc/login", Tag: "auth", Summary: "Start single sign-on",
+			Description: "Only registered when OIDC_ISSUER_URL is set. Redirects the browser to the identity provider.",
+			Responses:   []Response{{Status: http.StatusFound, Description: "Redirect to the identity provider"}},
+		},
+		{
+			Method: http.MethodGet, Path: "/api/auth/oidc/callback", Tag: "auth", Summary: "Complete single sign-on",
+			Description: "Only registered when OIDC_ISSUER_URL is set. The identity provider redirects the browser here.",
+			Params:      dto.OIDCCallbackQuery{},
+			Responses:   []Response{{Status: http.StatusOK, Description: "Logged in", Body: loginResult{}}},
+		},
+
+		{
+			Method: http.MethodPost, Path: "/api/users/signup", Tag: "users", Summary: "Create an account",
+			Description: "Signups are rate limited per client IP.",
+			Body:        dto.CreateUserRequest{},
+			Responses: []Response{
+				{Status: http.StatusCreated, Description: "Account created", Body: services.Account{}},
+				rateLimited,
+			},
+		},
+		{
+			Method: http.MethodGet, Path: "/api/users/search", Tag: "users", Summary: "Search users by name", Security: userAuth,
+			Description: "Searches are rate limited per user.",
+			Params:      dto.SearchUsersQuery{},
+			Responses: []Response{
+				{Status: http.StatusOK, Description: "Matching users", Body: []services.SearchResponse{}},
+				rateLimited,
+			},
+		},
+		{
+			Method: http.MethodGet, Path: "/api/users/:id", Tag: "users", Summary: "Get a user", Security: userAuth,
+			Params:    dto.UserIDParam{},
+			Responses: []Response{{Status: http.StatusOK, Description: "The user's public profile", Body: services.UserProfile{}}},
+		},
+		{
+			Method: http.MethodPut, Path: "/api/users/:id", Tag: "users", Summary: "Update your profile", Security: userAuth,
+			Params: dto.UserIDParam{}, Body: dto.UpdateUserRequest{},
+			Responses: []Response{{Status: http.StatusOK, Description: "The updated account", Body: services.Account{}}},
+		},
+		{
+			Method: http.MethodDelete, Path: "/api/users/:id", Tag: "users", Summary: "Delete your account", Security: userAuth,
+			Params:    dto.UserIDParam{},
+			Responses: []Response{{Status: http.StatusNoContent, Description: "Deleted"}},
+		},
+
+		{
+			Method: http.MethodPost, Path: "/api/reports", Tag: "reports", Summary: "Report a message or an account", Security: userAuth,
+			Description: "target_type message needs the message_id of a message you received, user needs the user_id of another account. " +
+				"Admins review open reports in the moderation queue; you get a report_resolved WebSocket event when yours is resolved.",
+			Body:      dto.CreateReportRequest{},
+			Responses: []Response{{Status: http.StatusCreated, Description: "The open report", Body: models.Report{}}},
+		},
+
+		{
+			Method: http.MethodGet, Path: "/api/messages/ws", Tag: "messages", Summary: "Open the chat WebSocket", Security: userAuth,
+			Description: "Browsers that cannot set headers pass the token in the access_token query parameter. " +
+				"The events exchanged on the connection are described in /asyncapi.json. New connections are rate limited per user.",
+			Responses: []Response{
+				{Status: http.StatusSwitchingProtocols, Description: "Switched to the WebSocket protocol"},
+				rateLimited,
+			},
+		},
+		{
+			Method: http.MethodGet, Path: "/api/messages/friends", Tag: "messages", Summary: "Conversations with their last message", Security: userAuth,
+			Responses: []Response{{Status: http.StatusOK, Description: "One entry per conversation", Body: []repositories.FriendsList{}}},
+		},
+		{
+			Method: http.MethodGet, Path: "/api/messages/friend/chats", Tag: "messages", Summary: "Messages exchanged with a user", Security: userAuth,
+			Params:    dto.ConversationQuery{},
+			Responses: []Response{{Status: http.StatusOK, Description: "The conversation", Body: []services.MessageResponse{}}},
+		},
+
+		{
+			Method: http.MethodGet, Path: "/api/admin/users", Tag: "admin", Summary: "List and search users", Security: userAuth,
+			Description: "search matches the username, email, first or last name; status selects active, suspended or deleted accounts.",
+			Params:      dto.ListUsersQuery{},
+			Responses:   []Response{{Status: http.StatusOK, Description: "One page of users and the number of matches", Body: services.UserPage{}}},
+		},
+		{
+			Method: http.MethodPut, Path: "/api/admin/users/:id/role", Tag: "admin", Summary: "Change the role of a user", Security: userAuth,
+			Description: "The user's tokens are revoked so the new role applies at once. Admins cannot change their own role.",
+			Params:      dto.UserIDParam{}, Body: dto.ChangeRoleRequest{},
+			Responses: []Response{{Status: http.StatusOK, Description: "The updated user", Body: models.User{}}},
+		},
+		{
+			Method: http.MethodPost, Path: "/api/admin/users/:id/suspend", Tag: "admin", Summary: "Suspend an account", Security: userAuth,
+			Description: "The user cannot log in until the given time; their tokens are revoked and their WebSocket connections closed.",
+			Params:      dto.UserIDParam{}, Body: dto.SuspendUserRequest{},
+			Responses: []Response{{Status: http.StatusOK, Description: "The suspended user", Body: models.User{}}},
+		},
+		{
+			Method: http.MethodPost, Path: "/api/admin/users/:id/unsuspend", Tag: "admin", Summary: "Lift a suspension", Security: userAuth,
+			Params:    dto.UserIDParam{},
+			Responses: []Response{{Status: http.StatusOK, Description: "The user", Body: models.User{}}},
+		},
+		{
+			Method: http.MethodPost, Path: "/api/admin/users/:id/restore", Tag: "admin", Summary: "Restore a deleted account", Security: userAuth,
+			Description: "Only possible during the deletion grace period, before the account is purged.",
+			Params:      dto.UserIDParam{},
+			Responses:   []Response{{Status: http.StatusOK, Description: "The restored user", Body: models.User{}}},
+		},
+		{
+			Method: http.MethodPost, Path: "/api/admin/users/:id/logout", Tag: "admin", Summary: "Log a user out everywhere", Security: userAuth,
+			Description: "Revokes every access and refresh token issued to the user and closes their WebSocket connections.",
+			Params:      dto.UserIDParam{},
+			Responses:   []Response{{Status: http.StatusOK, Description: "Sessions revoked", Body: messageBody}},
+		},
+		{
+			Method: http.MethodPost, Path: "/api/admin/users/:id/password", Tag: "admin", Summary: "Reset a user's password", Security: userAuth,
+			Description: "The new password must satisfy the password policy. The user is logged out everywhere.",
+			Params:      dto.UserIDParam{}, Body: dto.ResetPasswordRequest{},
+			Responses: []Response{{Status: http.StatusOK, Description: "Password reset", Body: messageBody}},
+		},
+		{
+			Method: http.MethodPost, Path: "/api/admin/users/:id/unlock", Tag: "admin", Summary: "Clear a login lockout", Security: userAuth,
+			Params:    dto.UserIDParam{},
+			Responses: []Response{{Status: http.StatusOK, Description: "Account unlocked", Body: messageBody}},
+		},
+		{
+			Method: http.MethodGet, Path: "/api/admin/stats", Tag: "admin", Summary: "System statistics", Security: userAuth,
+			Responses: []Response{{Status: http.StatusOK, Description: "Users, messages and open connections", Body: services.SystemStats{}}},
+		},
+		{
+			Method: http.MethodGet, Path: "/api/admin/audit-events", Tag: "admin", Summary: "Query the audit log", Security: userAuth,
+			Description: "Logins, token renewals, password and email changes, deletions and admin actions, newest first. " +
+				"since and until are RFC 3339 times.",
+			Params:    dto.ListAuditEventsQuery{},
+			Responses: []Response{{Status: http.StatusOK, Description: "One page of events and the number of matches", Body: services.AuditPage{}}},
+		},
+		{
+			Method: http.MethodGet, Path: "/api/admin/reports", Tag: "admin", Summary: "The moderation queue", Security: userAuth,
+			Description: "Reports oldest first; status open lists the ones waiting for a decision.",
+			Params:      dto.ListReportsQuery{},
+			Responses:   []Response{{Status: http.StatusOK, Description: "One page of reports and the number of matches", Body: services.ReportPage{}}},
+		},
+		{
+			Method: http.MethodGet, Path: "/api/admin/reports/:id", Tag: "admin", Summary: "Get a report", Security: userAuth,
+			Params:    dto.ReportIDParam{},
+			Responses: []Response{{Status: http.StatusOK, Description: "The report", Body: models.Report{}}},
+		},
+		{
+			Method: http.MethodPost, Path: "/api/admin/reports/:id/resolve", Tag: "admin", Summary: "Action or dismiss a report", Security: userAuth,
+			Description: "An actioned report takes one or more actions: remove_message deletes a reported message for both participants, " +
+				"warn notifies its author and suspend suspends the author until suspend_until. The reporter is notified either way.",
+			Params: dto.ReportIDParam{}, Body: dto.ResolveReportRequest{},
+			Responses: []Response{{Status: http.StatusOK, Description: "The resolved report", Body: models.Report{}}},
+		},
+	}
+}
+
+// ginParam matches the :name and *name segments of a Gin route
+var ginParam = regexp.MustCompile(`[:*]([A-Za-z0-9_]+)`)
+
+// OpenAPIPath converts a Gin route to an OpenAPI path template
+func OpenAPIPath(path string) string {
+	return ginParam.ReplaceAllString(path, "{$1}")
+}
+
+// OpenAPI builds the OpenAPI 3.0 document from Operations and the request and response types
+func OpenAPI() Schema {
+	registry := newSchemaRegistry("#/components/schemas/")
+	errorResponse := Schema{
+		"description": "Error, see the code for the reason",
+		"content":     Schema{"application/json": Schema{"schema": registry.schemaOf(apierror.Response{})}},
+	}
+
+	paths := Schema{}
+	for _, op := range Operations() {
+		path := OpenAPIPath(op.Path)
+		item, ok := paths[path].(Schema)
+		if !ok {
+			item = Schema{}
+			paths[path] = item
+		}
+
+		operation := Schema{
+			"tags":        []string{op.Tag},
+			"summary":     op.Summary,
+			"operationId": operationID(op),
+		}
+		if op.Description != "" {
+			operation["description"] = op.Description
+		}
+		if params := registry.parameters(op.Params); len(params) > 0 {
+			operation["parameters"] = params
+		}
+		if op.Body != nil {
+			operation["requestBody"] = Schema{"content": Schema{"application/json": Schema{"schema": registry.schemaOf(op.Body)}}}
+		}
+		if len(op.Security) > 0 {
+			security := make([]Schema, 0, len(op.Security))
+			for _, name := range op.Security {
+				security = append(security, Schema{name: []string{}})
+			}
+			operation["security"] = security
+		}
+
+		responses := Schema{"default": errorResponse}
+		for _, response := range op.Responses {
+			described := Schema{"description": response.Description}
+			if response.Body != nil {
+				contentType := response.ContentType
+				if contentType == "" {
+					contentType = "application/json"
+				}
+				described["content"] = Schema{contentType: Schema{"schema": registry.schemaOf(response.Body)}}
+			}
+			responses[strconv.Itoa(response.Status)] = described
+		}
+		operation["responses"] = responses
+
+		item[strings.ToLower(op.Method)] = operation
+	}
+
+	return Schema{
+		"openapi": "3.0.3",
+		"info": Schema{
+			"title":       "Chat App API",
+			"version":     buildinfo.Get().Commit,
+			"description": "Errors share one envelope: code, message, details for invalid fields and request_id.",
+		},
+		"paths": paths,
+		"components": Schema{
+			"schemas": registry.components,
+			"securitySchemes": Schema{
+				bearerAuth: Schema{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+				cookieAuth: Schema{
+					"type": "apiKey", "in": "cookie", "name": utils.AccessTokenCookie,
+					"description": "Unsafe methods also need the " + utils.CSRFTokenHeader + " header matching the " + utils.CSRFTokenCookie + " cookie",
+				},
+			},
+		},
+	}
+}
+
+// operationID is the method followed by the path segments, e.g. getApiUsersId
+func operationID(op Operation) string {
+	var id strings.Builder
+	id.WriteString(strings.ToLower(op.Method))
+	for _, segment := range strings.FieldsFunc(op.Path, func(r rune) bool {
+		return !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9')
+	}) {
+		id.WriteString(strings.ToUpper(segment[:1]) + segment[1:])
+	}
+	return id.String()
+}
@@ -0,0 +1,220 @@
+package apidocs
+
+import (
+	"chat-app-api/internal/apierror"
+	"chat-app-api/internal/buildinfo"
+	"chat-app-api/internal/services"
+	"chat-app-api/internal/validation"
+	"gorm.io/gorm"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema is a JSON Schema object as used by OpenAPI 3.0 and AsyncAPI 2
+type Schema map[string]any
+
+// componentNames overrides the schema name of types whose Go name is ambiguous
+var componentNames = map[reflect.Type]string{
+	reflect.TypeOf(apierror.Response{}): "Error",
+	reflect.TypeOf(loginResult{}):       "LoginResult",
+	reflect.TypeOf(buildinfo.Info{}):    "BuildInfo",
+}
+
+type structField struct {
+	Type reflect.Type
+	Name string
+}
+
+// fieldValues describes fields declared as any by the value they actually hold
+var fieldValues = map[structField]any{
+	{reflect.TypeOf(apierror.Response{}), "Details"}: []services.FieldError{},
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	deletedAtType = reflect.TypeOf(gorm.DeletedAt{}) // marshalled as a time, or null when unset
+)
+
+// schemaRegistry turns Go types into schemas, collecting named structs as reusable components
+type schemaRegistry struct {
+	refPrefix  string
+	components map[string]Schema
+}
+
+func newSchemaRegistry(refPrefix string) *schemaRegistry {
+	return &schemaRegistry{refPrefix: refPrefix, components: map[string]Schema{}}
+}
+
+// schemaOf accepts a Go value, whose type is described, or a ready made Schema
+func (r *schemaRegistry) schemaOf(v any) Schema {
+	if schema, ok := v.(Schema); ok {
+		return schema
+	}
+	return r.schema(reflect.TypeOf(v))
+}
+
+func (r *schemaRegistry) schema(t reflect.Type) Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return Schema{"type": "string", "format": "date-time"}
+	case t == deletedAtType:
+		return Schema{"type": "string", "format": "date-time", "nullable": true}
+	case t.Kind() == reflect.Struct && t.Name() != "":
+		name := componentName(t)
+		if _, ok := r.components[name]; !ok {
+			r.components[name] = nil // placeholder for recursive types
+			r.components[name] = r.structSchema(t)
+		}
+		return Schema{"$ref": r.refPrefix + name}
+	case t.Kind() == reflect.Struct:
+		return r.structSchema(t)
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return Schema{"type": "string"}
+	case reflect.Bool:
+		return Schema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return Schema{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Schema{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return Schema{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return Schema{"type": "array", "items": r.schema(t.Elem())}
+	case reflect.Map:
+		return Schema{"type": "object", "additionalProperties": r.schema(t.Elem())}
+	}
+	return Schema{}
+}
+
+func (r *schemaRegistry) structSchema(t reflect.Type) Schema {
+	properties := Schema{}
+	var required []string
+	r.addFields(t, properties, &required)
+
+	schema := Schema{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// addFields describes the JSON fields of t, flattening embedded structs like encoding/json does
+func (r *schemaRegistry) addFields(t reflect.Type, properties Schema, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if field.Anonymous && tag == "" && field.Type.Kind() == reflect.Struct {
+			r.addFields(field.Type, properties, required)
+			continue
+		}
+
+		name := strings.SplitN(tag, ",", 2)[0]
+		if !field.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		schema := r.schema(field.Type)
+		if value, ok := fieldValues[structField{t, field.Name}]; ok {
+			schema = r.schemaOf(value)
+		}
+		if applyRules(schema, field.Tag.Get("binding")) {
+			*required = append(*required, name)
+		}
+		properties[name] = schema
+	}
+}
+
+// parameters lists the fields of a struct bound from the path or the query string
+func (r *schemaRegistry) parameters(v any) []Schema {
+	if v == nil {
+		return nil
+	}
+
+	t := reflect.TypeOf(v)
+	var params []Schema
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		in, name := "query", field.Tag.Get("form")
+		if uri := field.Tag.Get("uri"); uri != "" {
+			in, name = "path", uri
+		}
+		if name == "" || name == "-" {
+			continue
+		}
+
+		schema := r.schema(field.Type)
+		required := applyRules(schema, field.Tag.Get("binding"))
+		params = append(params, Schema{
+			"name":     name,
+			"in":       in,
+			"required": required || in == "path",
+			"schema":   schema,
+		})
+	}
+	return params
+}
+
+// applyRules copies the validator rules that have a JSON Schema equivalent and reports whether
+// the field is required
+func applyRules(schema Schema, binding string) bool {
+	required := false
+	isString := schema["type"] == "string"
+	isArray := schema["type"] == "array"
+	rules := strings.Split(binding, ",")
+	for i, rule := range rules {
+		name, param, _ := strings.Cut(rule, "=")
+		n, err := strconv.Atoi(param)
+		switch {
+		case name == "dive":
+			// The remaining rules apply to the elements
+			if items, ok := schema["items"].(Schema); ok {
+				applyRules(items, strings.Join(rules[i+1:], ","))
+			}
+			return required
+		case name == "required":
+			required = true
+		case name == "min" && err == nil && isString:
+			schema["minLength"] = n
+		case name == "min" && err == nil && isArray:
+			schema["minItems"] = n
+		case name == "min" && err == nil:
+			schema["minimum"] = n
+		case name == "max" && err == nil && isString:
+			schema["maxLength"] = n
+		case name == "max" && err == nil && isArray:
+			schema["maxItems"] = n
+		case name == "max" && err == nil:
+			schema["maximum"] = n
+		case name == "email":
+			schema["format"] = "email"
+		case name == "url":
+			schema["format"] = "uri"
+		case name == "oneof":
+			schema["enum"] = strings.Fields(param)
+		case name == "username":
+			schema["pattern"] = validation.UsernamePattern.String()
+		case name == "content":
+			schema["description"] = "Must not be blank; at most MESSAGE_MAX_LENGTH characters"
+		}
+	}
+	return required
+}
+
+func componentName(t reflect.Type) string {
+	if name, ok := componentNames[t]; ok {
+		return name
+	}
+	return t.Name()
+}
@@ -0,0 +1,147 @@
+package apierror
+
+import (
+	"chat-app-api/internal/services"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Code is the stable, machine-readable error identifier clients switch on
+type Code string
+
+const (
+	CodeBadRequest         Code = "bad_request"
+	CodeValidation         Code = "validation_failed"
+	CodeUnauthorized       Code = "unauthorized"
+	CodeForbidden          Code = "forbidden"
+	CodeAccountSuspended   Code = "account_suspended"
+	CodeNotFound           Code = "not_found"
+	CodeConflict           Code = "conflict"
+	CodeMessageRejected    Code = "message_rejected"
+	CodeTooManyRequests    Code = "too_many_requests"
+	CodeInternal           Code = "internal_error"
+	CodeBadGateway         Code = "bad_gateway"
+	CodeServiceUnavailable Code = "service_unavailable"
+)
+
+// Response is the body of every error response and WebSocket error event
+type Response struct {
+	Code      Code   `json:"code"`
+	Message   string `json:"message"`
+	Details   any    `json:"details,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// Error is an error with the status and envelope it is rendered as. Err is the cause,
+// which is logged but never sent to the client.
+type Error struct {
+	Status  int
+	Code    Code
+	Message string
+	Details any
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Response renders the error for the client
+func (e *Error) Response(requestID string) Response {
+	return Response{Code: e.Code, Message: e.Message, Details: e.Details, RequestID: requestID}
+}
+
+func New(status int, code Code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+func BadRequest(message string) *Error {
+	return New(http.StatusBadRequest, CodeBadRequest, message)
+}
+
+func Unauthorized(message string) *Error {
+	return New(http.StatusUnauthorized, CodeUnauthorized, message)
+}
+
+func Forbidden(message string) *Error {
+	return New(http.StatusForbidden, CodeForbidden, message)
+}
+
+func NotFound(message string) *Error {
+	return New(http.StatusNotFound, CodeNotFound, message)
+}
+
+// Internal hides the cause behind a generic message
+func Internal(err error) *Error {
+	return &Error{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "Internal server error", Err: err}
+}
+
+// fieldErrors is implemented by errors that know which input fields were invalid
+type fieldErrors interface {
+	FieldErrors() []services.FieldError
+}
+
+// From maps any error returned by a handler or service to its API error. Errors of unknown kinds
+// become internal errors so their messages, which may contain SQL, never reach the client.
+func From(err error) *Error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	switch {
+	case errors.Is(err, services.ErrValidation):
+		result := &Error{Status: http.StatusBadRequest, Code: CodeValidation, Message: "Validation failed", Err: err}
+		var invalid fieldErrors
+		if errors.As(err, &invalid) {
+			result.Details = invalid.FieldErrors()
+		}
+		return result
+	case errors.Is(err, services.ErrNotFound):
+		return &Error{Status: http.StatusNotFound, Code: CodeNotFound, Message: domainMessage(err), Err: err}
+	case errors.Is(err, services.ErrConflict):
+		return &Error{Status: http.StatusConflict, Code: CodeConflict, Message: domainMessage(err), Err: err}
+	case errors.Is(err, services.ErrForbidden):
+		return &Error{Status: http.StatusForbidden, Code: CodeForbidden, Message: domainMessage(err), Err: err}
+	case errors.Is(err, services.ErrMessageRejected):
+		result := &Error{Status: http.StatusUnprocessableEntity, Code: CodeMessageRejected, Message: "Message rejected by the content filter", Err: err}
+		var rejected *services.MessageRejectedError
+		if errors.As(err, &rejected) {
+			result.Details = rejected.Decision
+		}
+		return result
+	case errors.Is(err, services.ErrAccountLocked):
+		return &Error{Status: http.StatusTooManyRequests, Code: CodeTooManyRequests, Message: "Too many failed login attempts, try again later", Err: err}
+	case errors.Is(err, services.ErrAccountSuspended):
+		result := &Error{Status: http.StatusForbidden, Code: CodeAccountSuspended, Message: "Account is suspended", Err: err}
+		var suspended *services.AccountSuspendedError
+		if errors.As(err, &suspended) {
+			result.Details = map[string]time.Time{"suspended_until": suspended.Until}
+		}
+		return result
+	case errors.Is(err, services.ErrSessionRevoked):
+		return &Error{Status: http.StatusUnauthorized, Code: CodeUnauthorized, Message: "Session expired or revoked, please log in again", Err: err}
+	case errors.Is(err, services.ErrInvalidCredentials):
+		return &Error{Status: http.StatusUnauthorized, Code: CodeUnauthorized, Message: "Invalid username or password", Err: err}
+	case errors.Is(err, services.ErrOIDCExchangeFailed), errors.Is(err, services.ErrOIDCInvalidIDToken), errors.Is(err, services.ErrOIDCIdentityMissing):
+		return &Error{Status: http.StatusUnauthorized, Code: CodeUnauthorized, Message: "Single sign-on failed", Err: err}
+	}
+	return Internal(err)
+}
+
+// domainMessage returns the client-safe message of a services.Error
+func domainMessage(err error) string {
+	var domainErr *services.Error
+	if errors.As(err, &domainErr) {
+		return domainErr.Message
+	}
+	return err.Error()
+}
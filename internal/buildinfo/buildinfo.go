@@ -0,0 +1,44 @@
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Set at build time with:
+//
+//	go build -ldflags "-X chat-app-api/internal/buildinfo.Commit=$(git rev-parse HEAD) -X chat-app-api/internal/buildinfo.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	Commit    = ""
+	BuildTime = ""
+)
+
+type Info struct {
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the build information, falling back to the VCS data the Go toolchain embeds
+func Get() Info {
+	info := Info{Commit: Commit, BuildTime: BuildTime, GoVersion: runtime.Version()}
+
+	if buildInfo, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range buildInfo.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = setting.Value
+			case setting.Key == "vcs.time" && info.BuildTime == "":
+				info.BuildTime = setting.Value
+			}
+		}
+	}
+
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	if info.BuildTime == "" {
+		info.BuildTime = "unknown"
+	}
+	return info
+}
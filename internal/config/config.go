@@ -0,0 +1,342 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Config is the complete application configuration
+type Config struct {
+	Server    ServerConfig
+	Database  DatabaseConfig
+	CORS      CORSConfig
+	JWT       JWTConfig
+	Cookie    CookieConfig
+	Login     LoginConfig
+	Password  PasswordConfig
+	Account   AccountConfig
+	Audit     AuditConfig
+	OIDC      OIDCConfig
+	Message   MessageConfig
+	RateLimit RateLimitConfig
+	Realtime  RealtimeConfig
+	Tracing   TracingConfig
+	Log       LogConfig
+}
+
+type ServerConfig struct {
+	Host              string
+	Port              int
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	ShutdownTimeout   time.Duration // deadline for draining requests, sockets and the database pool
+	ReadinessDelay    time.Duration // time /readyz fails before the server stops accepting connections
+}
+
+// Addr is the address the HTTP server listens on
+func (c ServerConfig) Addr() string {
+	return fmt.Sprintf("%s:%d", c.Host, c.Port)
+}
+
+type DatabaseConfig struct {
+	DSN         string
+	AutoMigrate bool // apply pending migrations at startup instead of refusing to start
+}
+
+type CORSConfig struct {
+	AllowedOrigins []string
+}
+
+// JWTConfig selects between asymmetric signing of access tokens (when SigningKeyFile is set) and
+// an HS256 secret. Refresh tokens are always signed with RefreshTokenSecret, which is never published.
+type JWTConfig struct {
+	AccessTokenSecret    string
+	RefreshTokenSecret   string
+	AccessTokenTTL       time.Duration
+	RefreshTokenTTL      time.Duration
+	Issuer               string
+	SigningKeyFile       string
+	VerificationKeyFiles []string
+}
+
+// CookieConfig controls the optional HttpOnly cookie authentication mode
+type CookieConfig struct {
+	Enabled  bool
+	Secure   bool
+	SameSite http.SameSite
+	Domain   string
+}
+
+// LoginConfig controls how failed login attempts are throttled
+type LoginConfig struct {
+	MaxAccountFailures int           // failures allowed per account before it is locked
+	MaxIPFailures      int           // failures allowed per client IP before it is locked
+	BaseLockout        time.Duration // lockout applied on the first lock, doubled on every further failure
+	MaxLockout         time.Duration // upper bound for a single lockout
+	FailureWindow      time.Duration // failures older than this are forgotten
+}
+
+// PasswordConfig holds the password policy and the hashing parameters for new hashes
+type PasswordConfig struct {
+	MinLength           int
+	MaxLength           int
+	CommonPasswordsFile string // one password per line, compared case-insensitively
+	HashAlgorithm       string // argon2id or bcrypt
+	BcryptCost          int
+	Argon2Memory        uint32 // KiB
+	Argon2Iterations    uint32
+	Argon2Parallelism   uint8
+}
+
+// AccountConfig controls how long deleted accounts are kept before they are purged
+type AccountConfig struct {
+	DeletionGracePeriod time.Duration // time a deleted account can still be restored
+	PurgeInterval       time.Duration // how often accounts past the grace period are purged
+}
+
+// AuditConfig controls where audit events are written besides the database
+type AuditConfig struct {
+	File string // JSON-lines file every event is appended to, disabled when empty
+}
+
+// OIDCConfig describes a standards-compliant OpenID Connect provider
+type OIDCConfig struct {
+	ProviderName string
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// Enabled reports whether single sign-on is configured
+func (c OIDCConfig) Enabled() bool {
+	return c.IssuerURL != ""
+}
+
+// MessageConfig bounds message content and configures the message filter. Each filter
+// action is mask, reject or flag; flagged messages are delivered and reported to moderators.
+type MessageConfig struct {
+	MaxLength           int      // longest message content in characters
+	MaxRepeatedChars    int      // longest run of one character, 0 disables the check
+	RepeatedCharsAction string   // mask shortens the run to the limit
+	BlockedWords        []string // matched as whole words, case-insensitively
+	BlocklistFile       string   // one word or /regular expression/ per line
+	BlocklistAction     string
+	DeniedDomains       []string // links to these domains or their subdomains
+	DeniedDomainsAction string
+}
+
+// RatePolicy allows Requests per Period on average, in bursts of up to Requests
+type RatePolicy struct {
+	Requests int
+	Period   time.Duration
+}
+
+func (p RatePolicy) String() string {
+	return fmt.Sprintf("%d/%s", p.Requests, p.Period)
+}
+
+// RateLimitConfig sets the rates allowed per user, or per client IP before login, and where
+// the token buckets are kept. Instances behind a load balancer share them through Redis.
+type RateLimitConfig struct {
+	Enabled           bool
+	Store             string // memory or redis
+	RedisAddr         string // host:port
+	RedisPassword     string
+	Login             RatePolicy
+	Signup            RatePolicy
+	Search            RatePolicy
+	WebSocket         RatePolicy // new WebSocket connections
+	WebSocketMessages RatePolicy // messages sent on one connection
+	MaxThrottled      int        // messages dropped after a throttle event before the connection is closed
+}
+
+// RealtimeConfig selects how WebSocket events reach users connected to other instances
+type RealtimeConfig struct {
+	Broker string // local for a single instance, or postgres for LISTEN/NOTIFY on the shared database
+}
+
+// TracingConfig selects where OpenTelemetry spans are exported
+type TracingConfig struct {
+	Exporter     string // none, stdout or otlp
+	OTLPEndpoint string // host:port of an OTLP/HTTP collector
+	OTLPInsecure bool
+	ServiceName  string
+	SampleRatio  float64 // fraction of new traces that are recorded
+}
+
+type LogConfig struct {
+	Level  string // debug, info, warn or error
+	Format string // json or text
+}
+
+// Default returns the configuration used for every setting that is not provided
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:              8080,
+			ReadTimeout:       15 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       60 * time.Second,
+			ShutdownTimeout:   20 * time.Second,
+		},
+		CORS: CORSConfig{AllowedOrigins: []string{"http://localhost:5173"}},
+		JWT: JWTConfig{
+			AccessTokenTTL:  15 * time.Minute,
+			RefreshTokenTTL: 7 * 24 * time.Hour,
+		},
+		Cookie: CookieConfig{Secure: true, SameSite: http.SameSiteLaxMode},
+		Login: LoginConfig{
+			MaxAccountFailures: 5,
+			MaxIPFailures:      20,
+			BaseLockout:        time.Minute,
+			MaxLockout:         time.Hour,
+			FailureWindow:      15 * time.Minute,
+		},
+		// Argon2id parameters follow the OWASP recommendation
+		Password: PasswordConfig{
+			MinLength:         8,
+			MaxLength:         128,
+			HashAlgorithm:     "argon2id",
+			BcryptCost:        10,
+			Argon2Memory:      19 * 1024,
+			Argon2Iterations:  2,
+			Argon2Parallelism: 1,
+		},
+		Account: AccountConfig{
+			DeletionGracePeriod: 30 * 24 * time.Hour,
+			PurgeInterval:       time.Hour,
+		},
+		OIDC: OIDCConfig{
+			ProviderName: "oidc",
+			Scopes:       []string{"openid", "profile", "email"},
+		},
+		Message: MessageConfig{
+			MaxLength:           4000,
+			MaxRepeatedChars:    20,
+			RepeatedCharsAction: "mask",
+			BlocklistAction:     "mask",
+			DeniedDomainsAction: "reject",
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:           true,
+			Store:             "memory",
+			RedisAddr:         "localhost:6379",
+			Login:             RatePolicy{Requests: 10, Period: time.Minute},
+			Signup:            RatePolicy{Requests: 5, Period: time.Hour},
+			Search:            RatePolicy{Requests: 60, Period: time.Minute},
+			WebSocket:         RatePolicy{Requests: 30, Period: time.Minute},
+			WebSocketMessages: RatePolicy{Requests: 30, Period: 10 * time.Second},
+			MaxThrottled:      10,
+		},
+		Realtime: RealtimeConfig{
+			Broker: "local",
+		},
+		Tracing: TracingConfig{
+			Exporter:     "none",
+			OTLPEndpoint: "localhost:4318",
+			ServiceName:  "chat-app-api",
+			SampleRatio:  1,
+		},
+		Log: LogConfig{Level: "info", Format: "json"},
+	}
+}
+
+// Validate reports every missing or invalid setting at once
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Server.Port > 0 && c.Server.Port < 65536, "PORT must be between 1 and 65535")
+	check(c.Server.ReadTimeout > 0 && c.Server.ReadHeaderTimeout > 0 && c.Server.WriteTimeout > 0 && c.Server.IdleTimeout > 0,
+		"SERVER_READ_TIMEOUT, SERVER_READ_HEADER_TIMEOUT, SERVER_WRITE_TIMEOUT and SERVER_IDLE_TIMEOUT must be positive")
+	check(c.Server.ShutdownTimeout > 0, "SHUTDOWN_TIMEOUT must be positive")
+	check(c.Server.ReadinessDelay >= 0, "SHUTDOWN_READINESS_DELAY must not be negative")
+	check(c.Database.DSN != "", "DATABASE_DSN is required")
+
+	if c.JWT.SigningKeyFile == "" {
+		check(c.JWT.AccessTokenSecret != "", "ACCESS_TOKEN_SECRET is required unless JWT_SIGNING_KEY_FILE is set")
+	}
+	check(c.JWT.RefreshTokenSecret != "", "REFRESH_TOKEN_SECRET is required")
+	check(c.JWT.AccessTokenTTL > 0, "ACCESS_TOKEN_EXPIRATION must be positive")
+	check(c.JWT.RefreshTokenTTL > 0, "REFRESH_TOKEN_EXPIRATION must be positive")
+
+	check(c.Cookie.SameSite != http.SameSiteNoneMode || c.Cookie.Secure, "COOKIE_SAMESITE=none requires COOKIE_SECURE=true")
+
+	check(c.Login.MaxAccountFailures > 0, "LOGIN_MAX_ACCOUNT_FAILURES must be positive")
+	check(c.Login.MaxIPFailures > 0, "LOGIN_MAX_IP_FAILURES must be positive")
+	check(c.Login.BaseLockout > 0 && c.Login.BaseLockout <= c.Login.MaxLockout, "LOGIN_BASE_LOCKOUT must be positive and not exceed LOGIN_MAX_LOCKOUT")
+	check(c.Login.FailureWindow > 0, "LOGIN_FAILURE_WINDOW must be positive")
+
+	check(c.Password.MinLength > 0, "PASSWORD_MIN_LENGTH must be positive")
+	check(c.Password.MaxLength >= c.Password.MinLength, "PASSWORD_MAX_LENGTH must not be less than PASSWORD_MIN_LENGTH")
+	switch c.Password.HashAlgorithm {
+	case "argon2id":
+		check(c.Password.Argon2Memory > 0 && c.Password.Argon2Iterations > 0 && c.Password.Argon2Parallelism > 0,
+			"ARGON2_MEMORY_KIB, ARGON2_ITERATIONS and ARGON2_PARALLELISM must be positive")
+	case "bcrypt":
+		check(c.Password.BcryptCost >= 4 && c.Password.BcryptCost <= 31, "BCRYPT_COST must be between 4 and 31")
+	default:
+		check(false, "PASSWORD_HASH_ALGORITHM must be argon2id or bcrypt, got %q", c.Password.HashAlgorithm)
+	}
+
+	check(c.Account.DeletionGracePeriod >= 0, "ACCOUNT_DELETION_GRACE_PERIOD must not be negative")
+	check(c.Account.PurgeInterval > 0, "ACCOUNT_PURGE_INTERVAL must be positive")
+
+	if c.OIDC.Enabled() {
+		check(c.OIDC.ClientID != "", "OIDC_CLIENT_ID is required when OIDC_ISSUER_URL is set")
+		check(c.OIDC.RedirectURL != "", "OIDC_REDIRECT_URL is required when OIDC_ISSUER_URL is set")
+	}
+
+	check(c.Message.MaxLength > 0, "MESSAGE_MAX_LENGTH must be positive")
+	check(c.Message.MaxRepeatedChars >= 0, "MESSAGE_MAX_REPEATED_CHARS must not be negative")
+	checkFilterAction := func(key, action string) {
+		check(action == "mask" || action == "reject" || action == "flag", "%s must be mask, reject or flag, got %q", key, action)
+	}
+	checkFilterAction("MESSAGE_REPEATED_CHARS_ACTION", c.Message.RepeatedCharsAction)
+	checkFilterAction("MESSAGE_BLOCKLIST_ACTION", c.Message.BlocklistAction)
+	checkFilterAction("MESSAGE_DENIED_DOMAINS_ACTION", c.Message.DeniedDomainsAction)
+
+	if c.RateLimit.Enabled {
+		switch c.RateLimit.Store {
+		case "memory":
+		case "redis":
+			check(c.RateLimit.RedisAddr != "", "RATE_LIMIT_REDIS_ADDR is required when RATE_LIMIT_STORE=redis")
+		default:
+			check(false, "RATE_LIMIT_STORE must be memory or redis, got %q", c.RateLimit.Store)
+		}
+		check(c.RateLimit.MaxThrottled >= 0, "RATE_LIMIT_WEBSOCKET_MAX_THROTTLED must not be negative")
+	}
+
+	check(c.Realtime.Broker == "local" || c.Realtime.Broker == "postgres",
+		"REALTIME_BROKER must be local or postgres, got %q", c.Realtime.Broker)
+
+	switch c.Tracing.Exporter {
+	case "none", "stdout":
+	case "otlp":
+		check(c.Tracing.OTLPEndpoint != "", "OTLP_ENDPOINT is required when TRACING_EXPORTER=otlp")
+	default:
+		check(false, "TRACING_EXPORTER must be none, stdout or otlp, got %q", c.Tracing.Exporter)
+	}
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "TRACING_SAMPLE_RATIO must be between 0 and 1")
+
+	switch strings.ToLower(c.Log.Level) {
+	case "debug", "info", "warn", "error":
+	default:
+		check(false, "LOG_LEVEL must be debug, info, warn or error, got %q", c.Log.Level)
+	}
+	check(c.Log.Format == "json" || c.Log.Format == "text", "LOG_FORMAT must be json or text, got %q", c.Log.Format)
+
+	return errors.Join(errs...)
+}
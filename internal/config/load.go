@@ -0,0 +1,309 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// defaultConfigFile is read when present; it is never required
+const defaultConfigFile = ".env"
+
+// setting binds one configuration key to a field of Config
+type setting struct {
+	key   string // environment variable and config file key
+	usage string
+	set   func(string) error
+}
+
+// flagName turns DATABASE_DSN into database-dsn
+func (s setting) flagName() string {
+	return strings.ToLower(strings.ReplaceAll(s.key, "_", "-"))
+}
+
+func settings(c *Config) []setting {
+	return []setting{
+		stringSetting("HOST", &c.Server.Host, "interface the HTTP server binds to"),
+		intSetting("PORT", &c.Server.Port, "port the HTTP server listens on"),
+		durationSetting("SERVER_READ_TIMEOUT", &c.Server.ReadTimeout, "maximum time to read a request including its body"),
+		durationSetting("SERVER_READ_HEADER_TIMEOUT", &c.Server.ReadHeaderTimeout, "maximum time to read request headers"),
+		durationSetting("SERVER_WRITE_TIMEOUT", &c.Server.WriteTimeout, "maximum time to write a response"),
+		durationSetting("SERVER_IDLE_TIMEOUT", &c.Server.IdleTimeout, "how long idle keep-alive connections are kept open"),
+		durationSetting("SHUTDOWN_TIMEOUT", &c.Server.ShutdownTimeout, "deadline for a graceful shutdown"),
+		durationSetting("SHUTDOWN_READINESS_DELAY", &c.Server.ReadinessDelay, "how long /readyz fails before the server stops accepting connections"),
+
+		stringSetting("DATABASE_DSN", &c.Database.DSN, "PostgreSQL connection string or URL, or sqlite://file for a local SQLite database"),
+		boolSetting("DATABASE_AUTO_MIGRATE", &c.Database.AutoMigrate, "apply pending migrations at startup"),
+
+		listSetting("CORS_ALLOWED_ORIGINS", &c.CORS.AllowedOrigins, "comma separated browser origins allowed to call the API"),
+
+		stringSetting("ACCESS_TOKEN_SECRET", &c.JWT.AccessTokenSecret, "HS256 secret for access tokens when no signing key file is set"),
+		stringSetting("REFRESH_TOKEN_SECRET", &c.JWT.RefreshTokenSecret, "HS256 secret for refresh tokens"),
+		durationSetting("ACCESS_TOKEN_EXPIRATION", &c.JWT.AccessTokenTTL, "lifetime of access tokens"),
+		durationSetting("REFRESH_TOKEN_EXPIRATION", &c.JWT.RefreshTokenTTL, "lifetime of refresh tokens"),
+		stringSetting("JWT_ISSUER", &c.JWT.Issuer, "iss claim of issued tokens"),
+		stringSetting("JWT_SIGNING_KEY_FILE", &c.JWT.SigningKeyFile, "PEM RSA or Ed25519 private key used to sign access tokens"),
+		listSetting("JWT_VERIFICATION_KEY_FILES", &c.JWT.VerificationKeyFiles, "comma separated PEM keys still accepted for verification"),
+
+		boolSetting("COOKIE_AUTH_ENABLED", &c.Cookie.Enabled, "allow browser clients to authenticate with HttpOnly cookies"),
+		boolSetting("COOKIE_SECURE", &c.Cookie.Secure, "only send auth cookies over HTTPS"),
+		sameSiteSetting("COOKIE_SAMESITE", &c.Cookie.SameSite, "SameSite mode of auth cookies: lax, strict or none"),
+		stringSetting("COOKIE_DOMAIN", &c.Cookie.Domain, "domain of auth cookies"),
+
+		intSetting("LOGIN_MAX_ACCOUNT_FAILURES", &c.Login.MaxAccountFailures, "failed logins before an account is locked"),
+		intSetting("LOGIN_MAX_IP_FAILURES", &c.Login.MaxIPFailures, "failed logins before a client IP is locked"),
+		durationSetting("LOGIN_BASE_LOCKOUT", &c.Login.BaseLockout, "first lockout, doubled on every further failure"),
+		durationSetting("LOGIN_MAX_LOCKOUT", &c.Login.MaxLockout, "longest single lockout"),
+		durationSetting("LOGIN_FAILURE_WINDOW", &c.Login.FailureWindow, "how long failed logins are remembered"),
+
+		intSetting("PASSWORD_MIN_LENGTH", &c.Password.MinLength, "minimum password length"),
+		intSetting("PASSWORD_MAX_LENGTH", &c.Password.MaxLength, "maximum password length"),
+		stringSetting("PASSWORD_BLOCKLIST_FILE", &c.Password.CommonPasswordsFile, "file of common or breached passwords, one per line"),
+		stringSetting("PASSWORD_HASH_ALGORITHM", &c.Password.HashAlgorithm, "algorithm for new password hashes: argon2id or bcrypt"),
+		intSetting("BCRYPT_COST", &c.Password.BcryptCost, "bcrypt cost"),
+		uint32Setting("ARGON2_MEMORY_KIB", &c.Password.Argon2Memory, "argon2id memory in KiB"),
+		uint32Setting("ARGON2_ITERATIONS", &c.Password.Argon2Iterations, "argon2id iterations"),
+		uint8Setting("ARGON2_PARALLELISM", &c.Password.Argon2Parallelism, "argon2id parallelism"),
+
+		durationSetting("ACCOUNT_DELETION_GRACE_PERIOD", &c.Account.DeletionGracePeriod, "time a deleted account can be restored before it is purged"),
+		durationSetting("ACCOUNT_PURGE_INTERVAL", &c.Account.PurgeInterval, "how often deleted accounts past the grace period are purged"),
+
+		stringSetting("AUDIT_LOG_FILE", &c.Audit.File, "file audit events are appended to as JSON lines, for shipping to a SIEM"),
+
+		stringSetting("OIDC_PROVIDER_NAME", &c.OIDC.ProviderName, "name external identities are stored under"),
+		stringSetting("OIDC_ISSUER_URL", &c.OIDC.IssuerURL, "issuer URL of the OpenID Connect provider, enables SSO"),
+		stringSetting("OIDC_CLIENT_ID", &c.OIDC.ClientID, "OIDC client ID"),
+		stringSetting("OIDC_CLIENT_SECRET", &c.OIDC.ClientSecret, "OIDC client secret"),
+		stringSetting("OIDC_REDIRECT_URL", &c.OIDC.RedirectURL, "callback URL registered with the provider"),
+		listSetting("OIDC_SCOPES", &c.OIDC.Scopes, "comma separated scopes to request"),
+
+		intSetting("MESSAGE_MAX_LENGTH", &c.Message.MaxLength, "longest message content in characters"),
+		intSetting("MESSAGE_MAX_REPEATED_CHARS", &c.Message.MaxRepeatedChars, "longest run of one character in a message, 0 disables the check"),
+		stringSetting("MESSAGE_REPEATED_CHARS_ACTION", &c.Message.RepeatedCharsAction, "what to do with longer runs: mask, reject or flag"),
+		listSetting("MESSAGE_BLOCKED_WORDS", &c.Message.BlockedWords, "comma separated words filtered from messages"),
+		stringSetting("MESSAGE_BLOCKLIST_FILE", &c.Message.BlocklistFile, "file of blocked words or /regular expressions/, one per line"),
+		stringSetting("MESSAGE_BLOCKLIST_ACTION", &c.Message.BlocklistAction, "what to do with blocked words: mask, reject or flag"),
+		listSetting("MESSAGE_DENIED_DOMAINS", &c.Message.DeniedDomains, "comma separated domains links in messages must not point to"),
+		stringSetting("MESSAGE_DENIED_DOMAINS_ACTION", &c.Message.DeniedDomainsAction, "what to do with links to denied domains: mask, reject or flag"),
+
+		boolSetting("RATE_LIMIT_ENABLED", &c.RateLimit.Enabled, "limit request and WebSocket message rates"),
+		stringSetting("RATE_LIMIT_STORE", &c.RateLimit.Store, "where rate limit buckets are kept: memory or redis"),
+		stringSetting("RATE_LIMIT_REDIS_ADDR", &c.RateLimit.RedisAddr, "host:port of the Redis server shared by all instances"),
+		stringSetting("RATE_LIMIT_REDIS_PASSWORD", &c.RateLimit.RedisPassword, "Redis password"),
+		rateSetting("RATE_LIMIT_LOGIN", &c.RateLimit.Login, "logins per client IP, as requests/period such as 10/1m"),
+		rateSetting("RATE_LIMIT_SIGNUP", &c.RateLimit.Signup, "signups per client IP"),
+		rateSetting("RATE_LIMIT_SEARCH", &c.RateLimit.Search, "user searches per user"),
+		rateSetting("RATE_LIMIT_WEBSOCKET", &c.RateLimit.WebSocket, "new WebSocket connections per user"),
+		rateSetting("RATE_LIMIT_WEBSOCKET_MESSAGES", &c.RateLimit.WebSocketMessages, "messages per WebSocket connection"),
+		intSetting("RATE_LIMIT_WEBSOCKET_MAX_THROTTLED", &c.RateLimit.MaxThrottled, "messages dropped after a throttle event before the connection is closed"),
+
+		stringSetting("REALTIME_BROKER", &c.Realtime.Broker, "how events reach other instances: local (single instance) or postgres (LISTEN/NOTIFY)"),
+
+		stringSetting("TRACING_EXPORTER", &c.Tracing.Exporter, "where spans are exported: none, stdout or otlp"),
+		stringSetting("OTLP_ENDPOINT", &c.Tracing.OTLPEndpoint, "host:port of the OTLP/HTTP trace collector"),
+		boolSetting("OTLP_INSECURE", &c.Tracing.OTLPInsecure, "send traces to the collector over plain HTTP"),
+		stringSetting("SERVICE_NAME", &c.Tracing.ServiceName, "service name attached to every span"),
+		float64Setting("TRACING_SAMPLE_RATIO", &c.Tracing.SampleRatio, "fraction of new traces that are recorded, between 0 and 1"),
+
+		stringSetting("LOG_LEVEL", &c.Log.Level, "minimum level written: debug, info, warn or error"),
+		stringSetting("LOG_FORMAT", &c.Log.Format, "log output format: json or text"),
+	}
+}
+
+// Load builds the configuration from, in increasing priority: defaults, the config file,
+// environment variables and command line flags. All problems are reported together.
+func Load(name string, args []string) (*Config, error) {
+	cfg, _, err := LoadArgs(name, args)
+	return cfg, err
+}
+
+// LoadArgs is Load for commands that take positional arguments after the flags, which it returns
+func LoadArgs(name string, args []string) (*Config, []string, error) {
+	cfg := Default()
+	all := settings(cfg)
+
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	configFile := flags.String("config", os.Getenv("CONFIG_FILE"), "optional KEY=VALUE file, defaults to .env when present")
+	flagValues := make(map[string]*string, len(all))
+	for _, s := range all {
+		flagValues[s.key] = flags.String(s.flagName(), "", fmt.Sprintf("%s (env %s)", s.usage, s.key))
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	fileValues, err := readConfigFile(*configFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	explicitFlags := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) { explicitFlags[f.Name] = true })
+
+	var errs []error
+	for _, s := range all {
+		value, ok := fileValues[s.key]
+		if envValue, set := os.LookupEnv(s.key); set {
+			value, ok = envValue, true
+		}
+		if explicitFlags[s.flagName()] {
+			value, ok = *flagValues[s.key], true
+		}
+		if !ok {
+			continue
+		}
+
+		if err := s.set(strings.TrimSpace(value)); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.key, err))
+		}
+	}
+	if err := cfg.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return nil, nil, errors.Join(errs...)
+	}
+	return cfg, flags.Args(), nil
+}
+
+// readConfigFile reads a .env style file; the default file is optional, an explicit one is not
+func readConfigFile(path string) (map[string]string, error) {
+	if path == "" {
+		if _, err := os.Stat(defaultConfigFile); err != nil {
+			return nil, nil
+		}
+		path = defaultConfigFile
+	}
+
+	values, err := godotenv.Read(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+	return values, nil
+}
+
+func stringSetting(key string, target *string, usage string) setting {
+	return setting{key: key, usage: usage, set: func(value string) error {
+		*target = value
+		return nil
+	}}
+}
+
+func intSetting(key string, target *int, usage string) setting {
+	return setting{key: key, usage: usage, set: func(value string) error {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		*target = parsed
+		return nil
+	}}
+}
+
+func uint32Setting(key string, target *uint32, usage string) setting {
+	return setting{key: key, usage: usage, set: func(value string) error {
+		parsed, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer %q", value)
+		}
+		*target = uint32(parsed)
+		return nil
+	}}
+}
+
+func uint8Setting(key string, target *uint8, usage string) setting {
+	return setting{key: key, usage: usage, set: func(value string) error {
+		parsed, err := strconv.ParseUint(value, 10, 8)
+		if err != nil {
+			return fmt.Errorf("invalid value %q, must be between 0 and 255", value)
+		}
+		*target = uint8(parsed)
+		return nil
+	}}
+}
+
+func float64Setting(key string, target *float64, usage string) setting {
+	return setting{key: key, usage: usage, set: func(value string) error {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		*target = parsed
+		return nil
+	}}
+}
+
+func boolSetting(key string, target *bool, usage string) setting {
+	return setting{key: key, usage: usage, set: func(value string) error {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		*target = parsed
+		return nil
+	}}
+}
+
+func durationSetting(key string, target *time.Duration, usage string) setting {
+	return setting{key: key, usage: usage, set: func(value string) error {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", value)
+		}
+		*target = parsed
+		return nil
+	}}
+}
+
+func listSetting(key string, target *[]string, usage string) setting {
+	return setting{key: key, usage: usage, set: func(value string) error {
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		*target = items
+		return nil
+	}}
+}
+
+// rateSetting reads requests/period, such as 10/1m
+func rateSetting(key string, target *RatePolicy, usage string) setting {
+	return setting{key: key, usage: usage, set: func(value string) error {
+		requests, period, ok := strings.Cut(value, "/")
+		parsedRequests, err := strconv.Atoi(requests)
+		if !ok || err != nil || parsedRequests < 1 {
+			return fmt.Errorf("invalid rate %q, must be a positive number of requests per period such as 10/1m", value)
+		}
+		parsedPeriod, err := time.ParseDuration(period)
+		if err != nil || parsedPeriod < time.Millisecond {
+			return fmt.Errorf("invalid rate %q, the period must be at least 1ms, such as 1m", value)
+		}
+		*target = RatePolicy{Requests: parsedRequests, Period: parsedPeriod}
+		return nil
+	}}
+}
+
+func sameSiteSetting(key string, target *http.SameSite, usage string) setting {
+	return setting{key: key, usage: usage, set: func(value string) error {
+		switch strings.ToLower(value) {
+		case "lax":
+			*target = http.SameSiteLaxMode
+		case "strict":
+			*target = http.SameSiteStrictMode
+		case "none":
+			*target = http.SameSiteNoneMode
+		default:
+			return fmt.Errorf("invalid SameSite mode %q, must be lax, strict or none", value)
+		}
+		return nil
+	}}
+}
@@ -0,0 +1,192 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// isolateEnv unsets every configuration variable until the test ends, so the environment the
+// tests run in does not leak into them
+func isolateEnv(t *testing.T) {
+	t.Helper()
+	keys := []string{"CONFIG_FILE"}
+	for _, s := range settings(Default()) {
+		keys = append(keys, s.key)
+	}
+	for _, key := range keys {
+		t.Setenv(key, "")
+		os.Unsetenv(key)
+	}
+}
+
+// writeConfigFile writes a KEY=VALUE file to the test's directory
+func writeConfigFile(t *testing.T, lines ...string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "test.env")
+	if err := os.WriteFile(file, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+// required are the settings without a default
+var required = []string{"DATABASE_DSN=sqlite://chat.db", "ACCESS_TOKEN_SECRET=access", "REFRESH_TOKEN_SECRET=refresh"}
+
+func TestLoadPrecedence(t *testing.T) {
+	isolateEnv(t)
+	file := writeConfigFile(t, append(required, "PORT=1000", "HOST=file", "LOG_LEVEL=debug", "COOKIE_DOMAIN=file.example.com")...)
+	t.Setenv("PORT", "2000")
+	t.Setenv("HOST", "env")
+	t.Setenv("COOKIE_DOMAIN", "env.example.com")
+
+	cfg, err := Load("test", []string{"-config", file, "-port", "3000", "-cookie-domain="})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, test := range map[string]struct{ got, want any }{
+		"flag over env and file": {cfg.Server.Port, 3000},
+		"env over file":          {cfg.Server.Host, "env"},
+		"file over default":      {cfg.Log.Level, "debug"},
+		"default":                {cfg.Server.ShutdownTimeout, 20 * time.Second},
+		"explicitly empty flag":  {cfg.Cookie.Domain, ""},
+		"required from the file": {cfg.Database.DSN, "sqlite://chat.db"},
+		"default rate":           {cfg.RateLimit.Login, RatePolicy{Requests: 10, Period: time.Minute}},
+	} {
+		if test.got != test.want {
+			t.Errorf("%s: got %v, want %v", name, test.got, test.want)
+		}
+	}
+
+	// The file can also be named by CONFIG_FILE
+	t.Setenv("CONFIG_FILE", file)
+	if cfg, err := Load("test", nil); err != nil || cfg.Log.Level != "debug" {
+		t.Errorf("CONFIG_FILE was not read: %v", err)
+	}
+}
+
+func TestLoadReadsTheOriginalEnvironmentNames(t *testing.T) {
+	isolateEnv(t)
+	// The variables deployments set before the configuration was centralized
+	t.Setenv("DATABASE_DSN", "host=localhost dbname=chat_app")
+	t.Setenv("ACCESS_TOKEN_SECRET", "access")
+	t.Setenv("REFRESH_TOKEN_SECRET", "refresh")
+	t.Setenv("ACCESS_TOKEN_EXPIRATION", "5m")
+	t.Setenv("REFRESH_TOKEN_EXPIRATION", "24h")
+
+	cfg, err := Load("test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := JWTConfig{AccessTokenSecret: "access", RefreshTokenSecret: "refresh", AccessTokenTTL: 5 * time.Minute, RefreshTokenTTL: 24 * time.Hour}
+	if cfg.Database.DSN != "host=localhost dbname=chat_app" || !reflect.DeepEqual(cfg.JWT, want) {
+		t.Errorf("got database %+v and JWT %+v, want the values of the environment", cfg.Database, cfg.JWT)
+	}
+}
+
+func TestLoadRejectsInvalidValues(t *testing.T) {
+	for name, test := range map[string]struct {
+		key, value string
+		want       string
+	}{
+		"rate without a period":    {"RATE_LIMIT_LOGIN", "10", "must be a positive number of requests per period"},
+		"rate of zero requests":    {"RATE_LIMIT_LOGIN", "0/1m", "must be a positive number of requests per period"},
+		"rate of text":             {"RATE_LIMIT_SIGNUP", "ten/1m", "must be a positive number of requests per period"},
+		"rate with a zero period":  {"RATE_LIMIT_SEARCH", "10/0s", "the period must be at least 1ms"},
+		"rate with a bad period":   {"RATE_LIMIT_WEBSOCKET", "10/minute", "the period must be at least 1ms"},
+		"duration without a unit":  {"SHUTDOWN_TIMEOUT", "20", "invalid duration"},
+		"duration of text":         {"ACCESS_TOKEN_EXPIRATION", "soon", "invalid duration"},
+		"integer of text":          {"PORT", "http", "invalid integer"},
+		"boolean of text":          {"COOKIE_AUTH_ENABLED", "sometimes", "invalid boolean"},
+		"unknown SameSite mode":    {"COOKIE_SAMESITE", "loose", "must be lax, strict or none"},
+		"parallelism out of range": {"ARGON2_PARALLELISM", "256", "must be between 0 and 255"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			isolateEnv(t)
+			file := writeConfigFile(t, required...)
+			t.Setenv(test.key, test.value)
+
+			_, err := Load("test", []string{"-config", file})
+			if err == nil || !strings.Contains(err.Error(), test.key+": ") || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("got %v, want %s rejected with %q", err, test.key, test.want)
+			}
+		})
+	}
+
+	t.Run("valid rate", func(t *testing.T) {
+		isolateEnv(t)
+		t.Setenv("RATE_LIMIT_LOGIN", " 5/30s ")
+		cfg, err := Load("test", []string{"-config", writeConfigFile(t, required...)})
+		if err != nil || cfg.RateLimit.Login != (RatePolicy{Requests: 5, Period: 30 * time.Second}) {
+			t.Errorf("got %+v (%v), want 5 requests per 30s", cfg, err)
+		}
+	})
+}
+
+func TestLoadRequiresAnExplicitConfigFile(t *testing.T) {
+	isolateEnv(t)
+	if _, err := Load("test", []string{"-config", filepath.Join(t.TempDir(), "missing.env")}); err == nil {
+		t.Error("got no error for a missing config file")
+	}
+}
+
+func TestLoadReportsEveryProblem(t *testing.T) {
+	isolateEnv(t)
+	t.Setenv("PORT", "http")
+	t.Setenv("RATE_LIMIT_LOGIN", "10")
+	t.Setenv("COOKIE_SAMESITE", "none")
+	t.Setenv("COOKIE_SECURE", "false")
+
+	_, err := Load("test", nil)
+	if err == nil {
+		t.Fatal("got no error")
+	}
+	for _, want := range []string{
+		"PORT: invalid integer",
+		"RATE_LIMIT_LOGIN: invalid rate",
+		"DATABASE_DSN is required",
+		"ACCESS_TOKEN_SECRET is required",
+		"REFRESH_TOKEN_SECRET is required",
+		"COOKIE_SAMESITE=none requires COOKIE_SECURE=true",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("the error does not report %q:\n%v", want, err)
+		}
+	}
+}
+
+func TestValidateJoinsEveryError(t *testing.T) {
+	cfg := Default()
+	cfg.Server.Port = 0
+	cfg.Password.HashAlgorithm = "md5"
+
+	err := cfg.Validate()
+	var joined interface{ Unwrap() []error }
+	if !errors.As(err, &joined) {
+		t.Fatalf("got %v, want the errors joined", err)
+	}
+	// PORT, DATABASE_DSN, both secrets and PASSWORD_HASH_ALGORITHM
+	if errs := joined.Unwrap(); len(errs) != 5 {
+		t.Errorf("got %d errors, want 5:\n%v", len(errs), err)
+	}
+	if !strings.Contains(err.Error(), `PASSWORD_HASH_ALGORITHM must be argon2id or bcrypt, got "md5"`) {
+		t.Errorf("got %v, want the unknown hash algorithm reported", err)
+	}
+
+	cfg = Default()
+	cfg.Database.DSN = "sqlite://chat.db"
+	cfg.JWT.SigningKeyFile = "signing.pem"
+	cfg.JWT.RefreshTokenSecret = "refresh"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("a signing key file should replace the access token secret, got %v", err)
+	}
+	cfg.JWT.RefreshTokenSecret = ""
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "REFRESH_TOKEN_SECRET is required") {
+		t.Errorf("got %v, want the refresh token secret required with a signing key file too", err)
+	}
+}
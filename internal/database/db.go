@@ -1,34 +1,88 @@
 package database
 
 import (
-	"chat-app-api/internal/models"
+	"chat-app-api/internal/config"
+	"chat-app-api/internal/metrics"
+	"chat-app-api/internal/tracing"
 	"fmt"
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"log"
-	"os"
+	"strings"
+	"time"
 )
 
-func Connect() (*gorm.DB, error) {
-	dsn := os.Getenv("DATABASE_DSN")
-	if dsn == "" {
-		log.Fatalf("DATABASE_DSN environment variable not set")
+// Names of the supported drivers, as reported by gorm.Dialector.Name
+const (
+	Postgres = "postgres"
+	SQLite   = "sqlite"
+)
+
+// sqlitePragmas are set on every SQLite connection: SQLite ignores foreign keys unless told
+// otherwise, and waits for a lock instead of failing straight away with SQLITE_BUSY
+var sqlitePragmas = []string{"foreign_keys(1)", "busy_timeout(5000)"}
+
+func Connect(cfg config.DatabaseConfig) (*gorm.DB, error) {
+	dialector, err := dialectorFor(cfg.DSN)
+	if err != nil {
+		return nil, err
 	}
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(dialector, &gorm.Config{
+		// Report constraint violations as gorm.ErrDuplicatedKey and gorm.ErrForeignKeyViolated
+		TranslateError: true,
+		// Store timestamps in UTC so SQLite, which compares them as text, orders them correctly
+		NowFunc: func() time.Time { return time.Now().UTC() },
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	// Auto migrate models
-	if err := db.AutoMigrate(&models.User{}); err != nil {
-		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	if db.Dialector.Name() == SQLite {
+		// SQLite has a single writer, and every connection to :memory: would open its own empty database
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, err
+		}
+		sqlDB.SetMaxOpenConns(1)
+	}
+
+	if err := db.Use(metrics.GormPlugin{}); err != nil {
+		return nil, fmt.Errorf("failed to register metrics plugin: %w", err)
 	}
 
-	// Auto migrate models
-	if err := db.AutoMigrate(&models.Message{}); err != nil {
-		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	if err := db.Use(tracing.GormPlugin{}); err != nil {
+		return nil, fmt.Errorf("failed to register tracing plugin: %w", err)
 	}
 
 	return db, nil
 }
+
+// dialectorFor picks the driver from the DSN scheme. postgres:// and postgresql:// URLs and
+// key=value connection strings select PostgreSQL; sqlite:// selects SQLite, followed by the
+// path of the database file or :memory:
+func dialectorFor(dsn string) (gorm.Dialector, error) {
+	scheme, rest, hasScheme := strings.Cut(dsn, "://")
+	if !hasScheme {
+		return postgres.Open(dsn), nil
+	}
+
+	switch strings.ToLower(scheme) {
+	case "postgres", "postgresql":
+		return postgres.Open(dsn), nil
+	case "sqlite", "sqlite3":
+		if rest == "" {
+			return nil, fmt.Errorf("sqlite DSN %q has no database path", dsn)
+		}
+		separator := "?"
+		if strings.Contains(rest, "?") {
+			separator = "&"
+		}
+		for _, pragma := range sqlitePragmas {
+			rest += separator + "_pragma=" + pragma
+			separator = "&"
+		}
+		return sqlite.Open(rest), nil
+	}
+	return nil, fmt.Errorf("unsupported database scheme %q, use postgres:// or sqlite://", scheme)
+}
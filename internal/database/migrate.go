@@ -0,0 +1,343 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed migrations/*/*.sql
+var migrationFiles embed.FS
+
+// MigrationsDir is where `migrate create` writes new files, relative to the repository root.
+// Every dialect has its own subdirectory holding the same versions.
+const MigrationsDir = "internal/database/migrations"
+
+// migrationLockKey is the pg_advisory_lock key held while migrating, so replicas starting together take turns
+const migrationLockKey int64 = 0x63686174617070 // "chatapp"
+
+// dialect holds the statements the migrator runs that differ between databases
+type dialect struct {
+	// lock and unlock serialise migrators; empty where the database has no session lock
+	lock, unlock string
+	// tableExists reports whether schema_migrations exists
+	tableExists string
+	// timestamp is the column type the driver reads back as time.Time
+	timestamp string
+}
+
+var dialects = map[string]dialect{
+	Postgres: {
+		lock:        `SELECT pg_advisory_lock($1)`,
+		unlock:      `SELECT pg_advisory_unlock($1)`,
+		tableExists: `SELECT to_regclass('schema_migrations') IS NOT NULL`,
+		timestamp:   "TIMESTAMPTZ",
+	},
+	// SQLite is meant for local runs and tests with a single process migrating
+	SQLite: {
+		tableExists: `SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations')`,
+		timestamp:   "DATETIME",
+	},
+}
+
+var migrationFileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+var ErrSchemaBehind = errors.New("database schema is behind, run the migrate up command")
+
+// Migration is one versioned schema change with the SQL to apply and to revert it
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Migrator applies the embedded migrations and records them in the schema_migrations table
+type Migrator struct {
+	db         *sql.DB
+	dialect    dialect
+	migrations []Migration
+}
+
+// NewMigrator loads the migrations written for the database the connection uses
+func NewMigrator(db *gorm.DB) (*Migrator, error) {
+	name := db.Dialector.Name()
+	dialect, ok := dialects[name]
+	if !ok {
+		return nil, fmt.Errorf("no migrations for database %s", name)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	migrations, err := loadMigrations(migrationFiles, "migrations/"+name)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: sqlDB, dialect: dialect, migrations: migrations}, nil
+}
+
+func loadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := migrationFileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("unexpected file %s in migrations", entry.Name())
+		}
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+
+		content, err := fs.ReadFile(fsys, dir+"/"+entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Up applies every pending migration in order and returns the ones applied
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		done, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := done[migration.Version]; ok {
+				continue
+			}
+			if err := m.apply(ctx, conn, migration, migration.Up,
+				`INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)`,
+				migration.Version, migration.Name, time.Now().UTC()); err != nil {
+				return err
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the given number of most recently applied migrations and returns the ones reverted
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var reverted []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		done, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := done[migration.Version]; !ok {
+				continue
+			}
+			if err := m.apply(ctx, conn, migration, migration.Down,
+				`DELETE FROM schema_migrations WHERE version = $1`, migration.Version); err != nil {
+				return err
+			}
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status lists every known migration with the time it was applied, if it was
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	done, err := m.appliedVersions(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := MigrationStatus{Migration: migration}
+		if appliedAt, ok := done[migration.Version]; ok {
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// CheckSchema returns ErrSchemaBehind when a migration known to this build has not been applied
+func (m *Migrator) CheckSchema(ctx context.Context) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	var pending []string
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			pending = append(pending, fmt.Sprintf("%d_%s", status.Version, status.Name))
+		}
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: pending %s", ErrSchemaBehind, strings.Join(pending, ", "))
+	}
+	return nil
+}
+
+// apply runs the migration SQL and the bookkeeping statement in one transaction
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, migration Migration, script string, record string, args ...any) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return fmt.Errorf("migration %d_%s failed: %w", migration.Version, migration.Name, err)
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return fmt.Errorf("failed to record migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+	return tx.Commit()
+}
+
+// withLock runs fn on a dedicated connection holding the migration advisory lock
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if m.dialect.lock != "" {
+		if _, err := conn.ExecContext(ctx, m.dialect.lock, migrationLockKey); err != nil {
+			return fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+		defer conn.ExecContext(context.Background(), m.dialect.unlock, migrationLockKey)
+	}
+
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    BIGINT PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at `+m.dialect.timestamp+` NOT NULL
+	)`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	return fn(conn)
+}
+
+// appliedVersions returns when each applied migration ran; a missing table means none has
+func (m *Migrator) appliedVersions(ctx context.Context, conn *sql.Conn) (map[int64]time.Time, error) {
+	var exists bool
+	if err := conn.QueryRowContext(ctx, m.dialect.tableExists).Scan(&exists); err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]time.Time)
+	if !exists {
+		return applied, nil
+	}
+
+	rows, err := conn.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version int64
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+// CreateMigration writes an up/down pair to fill in for every dialect, named after the current
+// UTC time, and returns their paths
+func CreateMigration(dir, name string) ([]string, error) {
+	name = strings.ToLower(strings.Join(strings.Fields(name), "_"))
+	if !regexp.MustCompile(`^\w+$`).MatchString(name) {
+		return nil, fmt.Errorf("invalid migration name %q, use letters, digits and underscores", name)
+	}
+
+	dialectNames := make([]string, 0, len(dialects))
+	for dialectName := range dialects {
+		dialectNames = append(dialectNames, dialectName)
+	}
+	sort.Strings(dialectNames)
+
+	version := time.Now().UTC().Format("20060102150405")
+	var paths []string
+	for _, dialectName := range dialectNames {
+		base := filepath.Join(dir, dialectName, version+"_"+name)
+		for _, direction := range []string{"up", "down"} {
+			path := base + "." + direction + ".sql"
+			file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+			if err != nil {
+				return paths, err
+			}
+			_, err = fmt.Fprintf(file, "-- %s %s (%s)\n", name, direction, dialectName)
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return paths, err
+			}
+			paths = append(paths, path)
+		}
+	}
+	return paths, nil
+}
@@ -0,0 +1,131 @@
+package database
+
+import (
+	"chat-app-api/internal/config"
+	"chat-app-api/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// TestDialectsShareMigrations keeps the per-database migration directories in step, so a
+// schema change cannot land for one database and be forgotten for the other
+func TestDialectsShareMigrations(t *testing.T) {
+	versions := make(map[string][]string)
+	for name := range dialects {
+		migrations, err := loadMigrations(migrationFiles, "migrations/"+name)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		for _, migration := range migrations {
+			versions[name] = append(versions[name], fmt.Sprintf("%d_%s", migration.Version, migration.Name))
+		}
+	}
+
+	if !slices.Equal(versions[Postgres], versions[SQLite]) {
+		t.Errorf("postgres migrations %v differ from sqlite migrations %v", versions[Postgres], versions[SQLite])
+	}
+}
+
+// connectForTest opens the database and closes it when the test ends
+func connectForTest(t *testing.T, dsn string) *gorm.DB {
+	t.Helper()
+	db, err := Connect(config.DatabaseConfig{DSN: dsn})
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// TestSQLiteMigrationsRoundTrip applies every migration, reverts them all and applies them again,
+// so each down script undoes its up script well enough for the next up to run
+func TestSQLiteMigrationsRoundTrip(t *testing.T) {
+	db := connectForTest(t, "sqlite://:memory:")
+	testMigrationsRoundTrip(t, db,
+		`SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT IN ('schema_migrations', 'sqlite_sequence')`)
+}
+
+// testMigrationsRoundTrip migrates the empty database up, all the way down and up again.
+// leftoverTables lists the tables besides the bookkeeping ones.
+func testMigrationsRoundTrip(t *testing.T, db *gorm.DB, leftoverTables string) {
+	t.Helper()
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	ctx := context.Background()
+	total := len(migrator.migrations)
+
+	if err := migrator.CheckSchema(ctx); !errors.Is(err, ErrSchemaBehind) {
+		t.Fatalf("got %v before migrating, want ErrSchemaBehind", err)
+	}
+	up := func(when string) {
+		t.Helper()
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			t.Fatalf("up %s: %v", when, err)
+		}
+		if len(applied) != total {
+			t.Fatalf("up %s applied %d migrations, want %d", when, len(applied), total)
+		}
+		if err := migrator.CheckSchema(ctx); err != nil {
+			t.Fatalf("after up %s: %v", when, err)
+		}
+		assertAuditEventsAreAppendOnly(t, db)
+	}
+
+	up("on an empty database")
+
+	reverted, err := migrator.Down(ctx, total)
+	if err != nil {
+		t.Fatalf("down: %v", err)
+	}
+	if len(reverted) != total {
+		t.Fatalf("reverted %d migrations, want %d", len(reverted), total)
+	}
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range statuses {
+		if status.AppliedAt != nil {
+			t.Errorf("migration %d_%s is still applied after reverting everything", status.Version, status.Name)
+		}
+	}
+	// Only the bookkeeping is left
+	var tables []string
+	if err := db.Raw(leftoverTables).Scan(&tables).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(tables) != 0 {
+		t.Errorf("tables %v are left after reverting every migration", tables)
+	}
+
+	up("after reverting everything")
+}
+
+// assertAuditEventsAreAppendOnly checks that the triggers reject changes to stored audit events
+func assertAuditEventsAreAppendOnly(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	event := &models.AuditEvent{Action: "auth.login", Outcome: models.OutcomeSuccess}
+	if err := db.Create(event).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.Model(event).Update("outcome", models.OutcomeFailure).Error; err == nil || !strings.Contains(err.Error(), "append-only") {
+		t.Errorf("got %v updating an audit event, want it rejected as append-only", err)
+	}
+	if err := db.Delete(event).Error; err == nil || !strings.Contains(err.Error(), "append-only") {
+		t.Errorf("got %v deleting an audit event, want it rejected as append-only", err)
+	}
+}
@@ -0,0 +1,38 @@
+-- Baseline schema. IF NOT EXISTS lets databases created by the former AutoMigrate adopt it.
+CREATE TABLE IF NOT EXISTS users (
+    id                BIGSERIAL PRIMARY KEY,
+    username          TEXT NOT NULL,
+    first_name        TEXT,
+    last_name         TEXT,
+    email             TEXT NOT NULL,
+    profile_image_url TEXT,
+    password          TEXT NOT NULL,
+    created_at        TIMESTAMPTZ,
+    updated_at        TIMESTAMPTZ,
+    CONSTRAINT uni_users_username UNIQUE (username),
+    CONSTRAINT uni_users_email UNIQUE (email)
+);
+
+CREATE TABLE IF NOT EXISTS messages (
+    id          BIGSERIAL PRIMARY KEY,
+    content     TEXT NOT NULL,
+    sender_id   BIGINT NOT NULL,
+    receiver_id BIGINT NOT NULL,
+    created_at  TIMESTAMPTZ,
+    updated_at  TIMESTAMPTZ,
+    CONSTRAINT fk_messages_sender FOREIGN KEY (sender_id) REFERENCES users (id),
+    CONSTRAINT fk_messages_receiver FOREIGN KEY (receiver_id) REFERENCES users (id)
+);
+
+CREATE TABLE IF NOT EXISTS user_identities (
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    BIGINT NOT NULL,
+    provider   TEXT NOT NULL,
+    subject    TEXT NOT NULL,
+    email      TEXT,
+    created_at TIMESTAMPTZ,
+    CONSTRAINT fk_user_identities_user FOREIGN KEY (user_id) REFERENCES users (id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_identities_provider_subject ON user_identities (provider, subject);
@@ -0,0 +1,3 @@
+-- Conversations are read by participant pair, newest first
+CREATE INDEX IF NOT EXISTS idx_messages_sender_receiver_created ON messages (sender_id, receiver_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_messages_receiver_sender_created ON messages (receiver_id, sender_id, created_at DESC);
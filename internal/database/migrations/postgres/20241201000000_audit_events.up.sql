@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS audit_events (
+    id         BIGSERIAL PRIMARY KEY,
+    action     TEXT NOT NULL,
+    outcome    TEXT NOT NULL,
+    actor_id   BIGINT,
+    target_id  BIGINT,
+    ip         TEXT,
+    user_agent TEXT,
+    request_id TEXT,
+    details    TEXT,
+    created_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_action ON audit_events (action);
+CREATE INDEX IF NOT EXISTS idx_audit_events_actor_id ON audit_events (actor_id);
+CREATE INDEX IF NOT EXISTS idx_audit_events_target_id ON audit_events (target_id);
+CREATE INDEX IF NOT EXISTS idx_audit_events_created_at ON audit_events (created_at);
+
+-- The audit log is append-only: rows cannot be changed or removed, except by TRUNCATE
+CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_events_append_only
+    BEFORE UPDATE OR DELETE ON audit_events
+    FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
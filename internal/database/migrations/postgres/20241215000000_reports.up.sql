@@ -0,0 +1,27 @@
+-- Reports outlive the messages they are about, whose content they keep, but not the accounts involved
+CREATE TABLE IF NOT EXISTS reports (
+    id               BIGSERIAL PRIMARY KEY,
+    reporter_id      BIGINT NOT NULL,
+    target_type      TEXT NOT NULL,
+    message_id       BIGINT,
+    reported_user_id BIGINT NOT NULL,
+    message_content  TEXT,
+    reason           TEXT NOT NULL,
+    comment          TEXT,
+    status           TEXT NOT NULL,
+    actions          TEXT,
+    moderator_note   TEXT,
+    resolved_by_id   BIGINT,
+    resolved_at      TIMESTAMPTZ,
+    created_at       TIMESTAMPTZ,
+    updated_at       TIMESTAMPTZ,
+    CONSTRAINT fk_reports_reporter FOREIGN KEY (reporter_id) REFERENCES users (id),
+    CONSTRAINT fk_reports_message FOREIGN KEY (message_id) REFERENCES messages (id) ON DELETE SET NULL,
+    CONSTRAINT fk_reports_reported_user FOREIGN KEY (reported_user_id) REFERENCES users (id),
+    CONSTRAINT fk_reports_resolved_by FOREIGN KEY (resolved_by_id) REFERENCES users (id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_reports_reporter_id ON reports (reporter_id);
+CREATE INDEX IF NOT EXISTS idx_reports_message_id ON reports (message_id);
+CREATE INDEX IF NOT EXISTS idx_reports_reported_user_id ON reports (reported_user_id);
+CREATE INDEX IF NOT EXISTS idx_reports_status ON reports (status);
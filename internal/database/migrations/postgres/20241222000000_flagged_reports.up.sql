@@ -0,0 +1,2 @@
+-- Messages flagged by the message filter are reported without a reporting user
+ALTER TABLE reports ALTER COLUMN reporter_id DROP NOT NULL;
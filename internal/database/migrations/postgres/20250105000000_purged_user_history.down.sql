@@ -0,0 +1,17 @@
+DELETE FROM messages WHERE sender_id IS NULL OR receiver_id IS NULL;
+DELETE FROM reports WHERE reported_user_id IS NULL;
+
+ALTER TABLE messages
+    ALTER COLUMN sender_id SET NOT NULL,
+    ALTER COLUMN receiver_id SET NOT NULL,
+    DROP CONSTRAINT fk_messages_sender,
+    DROP CONSTRAINT fk_messages_receiver,
+    ADD CONSTRAINT fk_messages_sender FOREIGN KEY (sender_id) REFERENCES users (id),
+    ADD CONSTRAINT fk_messages_receiver FOREIGN KEY (receiver_id) REFERENCES users (id);
+
+ALTER TABLE reports
+    ALTER COLUMN reported_user_id SET NOT NULL,
+    DROP CONSTRAINT fk_reports_reporter,
+    DROP CONSTRAINT fk_reports_reported_user,
+    ADD CONSTRAINT fk_reports_reporter FOREIGN KEY (reporter_id) REFERENCES users (id),
+    ADD CONSTRAINT fk_reports_reported_user FOREIGN KEY (reported_user_id) REFERENCES users (id);
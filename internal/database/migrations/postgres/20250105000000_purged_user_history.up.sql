@@ -0,0 +1,15 @@
+-- Purging an account keeps the conversations and reports it took part in, with the account left out
+ALTER TABLE messages
+    ALTER COLUMN sender_id DROP NOT NULL,
+    ALTER COLUMN receiver_id DROP NOT NULL,
+    DROP CONSTRAINT fk_messages_sender,
+    DROP CONSTRAINT fk_messages_receiver,
+    ADD CONSTRAINT fk_messages_sender FOREIGN KEY (sender_id) REFERENCES users (id) ON DELETE SET NULL,
+    ADD CONSTRAINT fk_messages_receiver FOREIGN KEY (receiver_id) REFERENCES users (id) ON DELETE SET NULL;
+
+ALTER TABLE reports
+    ALTER COLUMN reported_user_id DROP NOT NULL,
+    DROP CONSTRAINT fk_reports_reporter,
+    DROP CONSTRAINT fk_reports_reported_user,
+    ADD CONSTRAINT fk_reports_reporter FOREIGN KEY (reporter_id) REFERENCES users (id) ON DELETE SET NULL,
+    ADD CONSTRAINT fk_reports_reported_user FOREIGN KEY (reported_user_id) REFERENCES users (id) ON DELETE SET NULL;
@@ -0,0 +1,38 @@
+-- Baseline schema, kept equivalent to the PostgreSQL one
+CREATE TABLE IF NOT EXISTS users (
+    id                INTEGER PRIMARY KEY AUTOINCREMENT,
+    username          TEXT NOT NULL,
+    first_name        TEXT,
+    last_name         TEXT,
+    email             TEXT NOT NULL,
+    profile_image_url TEXT,
+    password          TEXT NOT NULL,
+    created_at        DATETIME,
+    updated_at        DATETIME,
+    CONSTRAINT uni_users_username UNIQUE (username),
+    CONSTRAINT uni_users_email UNIQUE (email)
+);
+
+CREATE TABLE IF NOT EXISTS messages (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    content     TEXT NOT NULL,
+    sender_id   INTEGER NOT NULL,
+    receiver_id INTEGER NOT NULL,
+    created_at  DATETIME,
+    updated_at  DATETIME,
+    CONSTRAINT fk_messages_sender FOREIGN KEY (sender_id) REFERENCES users (id),
+    CONSTRAINT fk_messages_receiver FOREIGN KEY (receiver_id) REFERENCES users (id)
+);
+
+CREATE TABLE IF NOT EXISTS user_identities (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id    INTEGER NOT NULL,
+    provider   TEXT NOT NULL,
+    subject    TEXT NOT NULL,
+    email      TEXT,
+    created_at DATETIME,
+    CONSTRAINT fk_user_identities_user FOREIGN KEY (user_id) REFERENCES users (id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_identities_provider_subject ON user_identities (provider, subject);
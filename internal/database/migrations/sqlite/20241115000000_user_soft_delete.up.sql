@@ -0,0 +1,4 @@
+-- Deleted users keep their row until they are purged, so their messages still have a sender
+ALTER TABLE users ADD COLUMN deleted_at DATETIME;
+
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
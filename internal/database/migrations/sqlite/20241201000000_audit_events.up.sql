@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS audit_events (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    action     TEXT NOT NULL,
+    outcome    TEXT NOT NULL,
+    actor_id   INTEGER,
+    target_id  INTEGER,
+    ip         TEXT,
+    user_agent TEXT,
+    request_id TEXT,
+    details    TEXT,
+    created_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_action ON audit_events (action);
+CREATE INDEX IF NOT EXISTS idx_audit_events_actor_id ON audit_events (actor_id);
+CREATE INDEX IF NOT EXISTS idx_audit_events_target_id ON audit_events (target_id);
+CREATE INDEX IF NOT EXISTS idx_audit_events_created_at ON audit_events (created_at);
+
+-- The audit log is append-only: rows cannot be changed or removed
+CREATE TRIGGER IF NOT EXISTS audit_events_no_update BEFORE UPDATE ON audit_events
+BEGIN
+    SELECT RAISE(ABORT, 'audit_events is append-only');
+END;
+
+CREATE TRIGGER IF NOT EXISTS audit_events_no_delete BEFORE DELETE ON audit_events
+BEGIN
+    SELECT RAISE(ABORT, 'audit_events is append-only');
+END;
@@ -0,0 +1,32 @@
+DELETE FROM reports WHERE reporter_id IS NULL;
+
+CREATE TABLE reports_new (
+    id               INTEGER PRIMARY KEY AUTOINCREMENT,
+    reporter_id      INTEGER NOT NULL,
+    target_type      TEXT NOT NULL,
+    message_id       INTEGER,
+    reported_user_id INTEGER NOT NULL,
+    message_content  TEXT,
+    reason           TEXT NOT NULL,
+    comment          TEXT,
+    status           TEXT NOT NULL,
+    actions          TEXT,
+    moderator_note   TEXT,
+    resolved_by_id   INTEGER,
+    resolved_at      DATETIME,
+    created_at       DATETIME,
+    updated_at       DATETIME,
+    CONSTRAINT fk_reports_reporter FOREIGN KEY (reporter_id) REFERENCES users (id),
+    CONSTRAINT fk_reports_message FOREIGN KEY (message_id) REFERENCES messages (id) ON DELETE SET NULL,
+    CONSTRAINT fk_reports_reported_user FOREIGN KEY (reported_user_id) REFERENCES users (id),
+    CONSTRAINT fk_reports_resolved_by FOREIGN KEY (resolved_by_id) REFERENCES users (id) ON DELETE SET NULL
+);
+
+INSERT INTO reports_new SELECT * FROM reports;
+DROP TABLE reports;
+ALTER TABLE reports_new RENAME TO reports;
+
+CREATE INDEX IF NOT EXISTS idx_reports_reporter_id ON reports (reporter_id);
+CREATE INDEX IF NOT EXISTS idx_reports_message_id ON reports (message_id);
+CREATE INDEX IF NOT EXISTS idx_reports_reported_user_id ON reports (reported_user_id);
+CREATE INDEX IF NOT EXISTS idx_reports_status ON reports (status);
@@ -0,0 +1,58 @@
+DELETE FROM messages WHERE sender_id IS NULL OR receiver_id IS NULL;
+DELETE FROM reports WHERE reported_user_id IS NULL;
+
+CREATE TABLE reports_new (
+    id               INTEGER PRIMARY KEY AUTOINCREMENT,
+    reporter_id      INTEGER,
+    target_type      TEXT NOT NULL,
+    message_id       INTEGER,
+    reported_user_id INTEGER NOT NULL,
+    message_content  TEXT,
+    reason           TEXT NOT NULL,
+    comment          TEXT,
+    status           TEXT NOT NULL,
+    actions          TEXT,
+    moderator_note   TEXT,
+    resolved_by_id   INTEGER,
+    resolved_at      DATETIME,
+    created_at       DATETIME,
+    updated_at       DATETIME,
+    CONSTRAINT fk_reports_reporter FOREIGN KEY (reporter_id) REFERENCES users (id),
+    CONSTRAINT fk_reports_message FOREIGN KEY (message_id) REFERENCES messages (id) ON DELETE SET NULL,
+    CONSTRAINT fk_reports_reported_user FOREIGN KEY (reported_user_id) REFERENCES users (id),
+    CONSTRAINT fk_reports_resolved_by FOREIGN KEY (resolved_by_id) REFERENCES users (id) ON DELETE SET NULL
+);
+
+INSERT INTO reports_new SELECT * FROM reports;
+DROP TABLE reports;
+ALTER TABLE reports_new RENAME TO reports;
+
+CREATE INDEX IF NOT EXISTS idx_reports_reporter_id ON reports (reporter_id);
+CREATE INDEX IF NOT EXISTS idx_reports_message_id ON reports (message_id);
+CREATE INDEX IF NOT EXISTS idx_reports_reported_user_id ON reports (reported_user_id);
+CREATE INDEX IF NOT EXISTS idx_reports_status ON reports (status);
+
+CREATE TABLE messages_new (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    content     TEXT NOT NULL,
+    sender_id   INTEGER NOT NULL,
+    receiver_id INTEGER NOT NULL,
+    created_at  DATETIME,
+    updated_at  DATETIME,
+    CONSTRAINT fk_messages_sender FOREIGN KEY (sender_id) REFERENCES users (id),
+    CONSTRAINT fk_messages_receiver FOREIGN KEY (receiver_id) REFERENCES users (id)
+);
+
+-- Dropping messages sets the message_id of reports to NULL, so it is put back afterwards
+CREATE TEMP TABLE reported_messages AS SELECT id, message_id FROM reports WHERE message_id IS NOT NULL;
+
+INSERT INTO messages_new SELECT * FROM messages;
+DROP TABLE messages;
+ALTER TABLE messages_new RENAME TO messages;
+
+UPDATE reports SET message_id = (SELECT message_id FROM reported_messages WHERE reported_messages.id = reports.id)
+WHERE id IN (SELECT id FROM reported_messages);
+DROP TABLE reported_messages;
+
+CREATE INDEX IF NOT EXISTS idx_messages_sender_receiver_created ON messages (sender_id, receiver_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_messages_receiver_sender_created ON messages (receiver_id, sender_id, created_at DESC);
@@ -0,0 +1,144 @@
+package database
+
+import (
+	"chat-app-api/internal/logging"
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// postgresTestSchema connects to the PostgreSQL database in TEST_DATABASE_DSN with a schema of
+// its own first on the search path, so migrating up and down does not disturb the tables other
+// packages test against at the same time. The schema is dropped when the test ends.
+func postgresTestSchema(t *testing.T) (db *gorm.DB, admin *gorm.DB) {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+	admin = connectForTest(t, dsn)
+	if admin.Dialector.Name() != Postgres {
+		t.Skip("TEST_DATABASE_DSN is not a PostgreSQL database")
+	}
+
+	schema := "migrate_test_" + logging.NewID()[:12]
+	if err := admin.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.Exec("DROP SCHEMA " + schema + " CASCADE") })
+
+	return connectForTest(t, withSearchPath(dsn, schema)), admin
+}
+
+// withSearchPath adds the search_path run-time parameter to a URL or key=value connection string
+func withSearchPath(dsn, schema string) string {
+	if !strings.Contains(dsn, "://") {
+		return dsn + " search_path=" + schema
+	}
+	separator := "?"
+	if strings.Contains(dsn, "?") {
+		separator = "&"
+	}
+	return dsn + separator + "search_path=" + schema
+}
+
+func TestPostgresMigrationsRoundTrip(t *testing.T) {
+	db, _ := postgresTestSchema(t)
+	testMigrationsRoundTrip(t, db,
+		`SELECT table_name FROM information_schema.tables WHERE table_schema = current_schema() AND table_name <> 'schema_migrations'`)
+}
+
+// TestPostgresMigratorsTakeTurns starts a migrator while another session holds the advisory lock,
+// as when replicas start together, and then lets two migrate at once
+func TestPostgresMigratorsTakeTurns(t *testing.T) {
+	db, admin := postgresTestSchema(t)
+	ctx := context.Background()
+
+	first, err := NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := len(first.migrations)
+
+	// The lock is held by a session, so keep one connection for it
+	sqlDB, err := admin.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	holder, err := sqlDB.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer holder.Close()
+	if _, err := holder.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockKey); err != nil {
+		t.Fatal(err)
+	}
+
+	type result struct {
+		applied []Migration
+		err     error
+	}
+	results := make(chan result, 2)
+	for _, migrator := range []*Migrator{first, second} {
+		go func() {
+			applied, err := migrator.Up(ctx)
+			results <- result{applied, err}
+		}()
+	}
+
+	// Both wait for the lock instead of migrating. Other packages migrating their test database
+	// at the same time may be waiting too.
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		var waiting int
+		if err := admin.Raw(`SELECT count(*) FROM pg_locks WHERE locktype = 'advisory' AND NOT granted`).Scan(&waiting).Error; err != nil {
+			t.Fatal(err)
+		}
+		if waiting >= 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d migrators are waiting for the lock, want 2", waiting)
+		}
+	}
+	select {
+	case r := <-results:
+		t.Fatalf("a migrator finished while the lock was held: %v", r.err)
+	default:
+	}
+
+	if _, err := holder.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, migrationLockKey); err != nil {
+		t.Fatal(err)
+	}
+
+	// One applies everything and the other, running after it, finds nothing left to do
+	applied := 0
+	for range 2 {
+		select {
+		case r := <-results:
+			if r.err != nil {
+				t.Fatalf("up: %v", r.err)
+			}
+			if n := len(r.applied); n != 0 && n != total {
+				t.Errorf("a migrator applied %d migrations, want all %d or none", n, total)
+			}
+			applied += len(r.applied)
+		case <-time.After(30 * time.Second):
+			t.Fatal("timed out waiting for the migrators")
+		}
+	}
+	if applied != total {
+		t.Errorf("applied %d migrations between them, want each of the %d once", applied, total)
+	}
+	if err := first.CheckSchema(ctx); err != nil {
+		t.Error(err)
+	}
+}
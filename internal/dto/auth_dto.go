@@ -0,0 +1,32 @@
+package dto
+
+// LoginRequest accepts the username or email in Identifier; Username and Email are kept for older clients
+type LoginRequest struct {
+	Identifier string `json:"identifier" binding:"required_without_all=Username Email,max=254"`
+	Username   string `json:"username" binding:"max=254"`
+	Email      string `json:"email" binding:"max=254"`
+	Password   string `json:"password" binding:"required,max=1024"`
+}
+
+// LoginIdentifier is the first of Identifier, Username and Email that is set
+func (r LoginRequest) LoginIdentifier() string {
+	switch {
+	case r.Identifier != "":
+		return r.Identifier
+	case r.Username != "":
+		return r.Username
+	}
+	return r.Email
+}
+
+// RenewTokenRequest is empty for cookie clients, whose refresh token comes from a cookie
+type RenewTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"max=4096"`
+}
+
+// OIDCCallbackQuery is the provider's redirect back to the API
+type OIDCCallbackQuery struct {
+	Code  string `form:"code" binding:"required_without=Error,max=2048"`
+	State string `form:"state" binding:"required_without=Error,max=512"`
+	Error string `form:"error" binding:"max=256"`
+}
@@ -0,0 +1,43 @@
+package dto
+
+import (
+	"chat-app-api/internal/apierror"
+	"chat-app-api/internal/models"
+)
+
+// Types of the events the WebSocket handler sends to a single connection
+const (
+	ErrorEventType     = "error"     // an event of the connection was rejected
+	ThrottledEventType = "throttled" // the connection sends messages faster than its rate limit
+)
+
+// SendMessageEvent is what clients send over the WebSocket; the sender is the authenticated user
+type SendMessageEvent struct {
+	ReceiverID uint   `json:"receiver_id" binding:"required,min=1"`
+	Content    string `json:"content" binding:"required,content"`
+}
+
+func (e SendMessageEvent) ToMessage(senderID uint) models.Message {
+	return models.Message{
+		Content:    e.Content,
+		SenderID:   senderID,
+		ReceiverID: e.ReceiverID,
+	}
+}
+
+// ConversationQuery selects the conversation with one other user
+type ConversationQuery struct {
+	UserID uint `form:"user_id" binding:"required,min=1"`
+}
+
+// ErrorEvent carries the same fields as an HTTP error response
+type ErrorEvent struct {
+	Type string `json:"type"`
+	apierror.Response
+}
+
+// ThrottledEvent tells a connection that its messages are being dropped until retry_after_ms has passed
+type ThrottledEvent struct {
+	Type         string `json:"type"`
+	RetryAfterMs int64  `json:"retry_after_ms"`
+}
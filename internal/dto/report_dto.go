@@ -0,0 +1,70 @@
+package dto
+
+import (
+	"chat-app-api/internal/models"
+	"chat-app-api/internal/repositories"
+	"time"
+)
+
+// CreateReportRequest reports a received message by message_id or an account by user_id
+type CreateReportRequest struct {
+	TargetType string `json:"target_type" binding:"required,oneof=message user"`
+	MessageID  uint   `json:"message_id"`
+	UserID     uint   `json:"user_id"`
+	Reason     string `json:"reason" binding:"required,oneof=spam harassment hate_speech violence sexual_content impersonation other"`
+	Comment    string `json:"comment" binding:"max=1000"`
+}
+
+func (r CreateReportRequest) ToReport(reporterID uint) models.Report {
+	report := models.Report{
+		ReporterID:     &reporterID,
+		TargetType:     r.TargetType,
+		ReportedUserID: r.UserID,
+		Reason:         r.Reason,
+		Comment:        r.Comment,
+	}
+	if r.MessageID != 0 {
+		report.MessageID = &r.MessageID
+	}
+	return report
+}
+
+// ReportIDParam is the :id path parameter of the moderation routes
+type ReportIDParam struct {
+	ID uint `uri:"id" binding:"required,min=1"`
+}
+
+// ListReportsQuery filters the moderation queue
+type ListReportsQuery struct {
+	Status         string `form:"status" binding:"omitempty,oneof=open actioned dismissed"`
+	TargetType     string `form:"target_type" binding:"omitempty,oneof=message user"`
+	Reason         string `form:"reason" binding:"omitempty,oneof=spam harassment hate_speech violence sexual_content impersonation other flagged"`
+	ReporterID     uint   `form:"reporter_id"`
+	ReportedUserID uint   `form:"reported_user_id"`
+	Limit          int    `form:"limit" binding:"omitempty,min=1,max=100"`
+	Offset         int    `form:"offset" binding:"min=0"`
+}
+
+func (q ListReportsQuery) ToFilter() repositories.ReportFilter {
+	filter := repositories.ReportFilter{
+		Status:         q.Status,
+		TargetType:     q.TargetType,
+		Reason:         q.Reason,
+		ReporterID:     q.ReporterID,
+		ReportedUserID: q.ReportedUserID,
+		Limit:          q.Limit,
+		Offset:         q.Offset,
+	}
+	if filter.Limit == 0 {
+		filter.Limit = DefaultUserPageSize
+	}
+	return filter
+}
+
+// ResolveReportRequest actions or dismisses a report; suspend_until is required by the suspend action
+type ResolveReportRequest struct {
+	Status       string    `json:"status" binding:"required,oneof=actioned dismissed"`
+	Actions      []string  `json:"actions" binding:"max=3,dive,oneof=remove_message warn suspend"`
+	SuspendUntil time.Time `json:"suspend_until"`
+	Note         string    `json:"note" binding:"max=1000"`
+}
@@ -0,0 +1,130 @@
+package dto
+
+import (
+	"chat-app-api/internal/models"
+	"chat-app-api/internal/repositories"
+	"time"
+)
+
+// Password length is enforced by the password policy, which knows the configured limits
+type CreateUserRequest struct {
+	Username        string `json:"username" binding:"required,username"`
+	FirstName       string `json:"first_name" binding:"max=64"`
+	LastName        string `json:"last_name" binding:"max=64"`
+	Email           string `json:"email" binding:"required,email,max=254"`
+	ProfileImageUrl string `json:"profile_image" binding:"omitempty,url,max=2048"`
+	Password        string `json:"password" binding:"required"`
+}
+
+func (r CreateUserRequest) ToUser() *models.User {
+	return &models.User{
+		Username:        r.Username,
+		FirstName:       r.FirstName,
+		LastName:        r.LastName,
+		Email:           r.Email,
+		ProfileImageUrl: r.ProfileImageUrl,
+		Password:        r.Password,
+	}
+}
+
+// UpdateUserRequest replaces the profile; an empty password keeps the current one
+type UpdateUserRequest struct {
+	Username        string `json:"username" binding:"required,username"`
+	FirstName       string `json:"first_name" binding:"max=64"`
+	LastName        string `json:"last_name" binding:"max=64"`
+	Email           string `json:"email" binding:"required,email,max=254"`
+	ProfileImageUrl string `json:"profile_image" binding:"omitempty,url,max=2048"`
+	Password        string `json:"password"`
+}
+
+func (r UpdateUserRequest) ToUser(id uint) *models.User {
+	return &models.User{
+		ID:              id,
+		Username:        r.Username,
+		FirstName:       r.FirstName,
+		LastName:        r.LastName,
+		Email:           r.Email,
+		ProfileImageUrl: r.ProfileImageUrl,
+		Password:        r.Password,
+	}
+}
+
+// UserIDParam is the :id path parameter of the user routes
+type UserIDParam struct {
+	ID uint `uri:"id" binding:"required,min=1"`
+}
+
+type SearchUsersQuery struct {
+	Search string `form:"search" binding:"max=64"`
+}
+
+// ListUsersQuery filters the admin user listing
+type ListUsersQuery struct {
+	Search string `form:"search" binding:"max=64"`
+	Role   string `form:"role" binding:"omitempty,oneof=user admin"`
+	Status string `form:"status" binding:"omitempty,oneof=active suspended deleted"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=100"`
+	Offset int    `form:"offset" binding:"min=0"`
+}
+
+// DefaultUserPageSize is the page size when the listing query has no limit
+const DefaultUserPageSize = 50
+
+func (q ListUsersQuery) ToFilter() repositories.UserFilter {
+	filter := repositories.UserFilter{Search: q.Search, Role: q.Role, Limit: q.Limit, Offset: q.Offset}
+	if filter.Limit == 0 {
+		filter.Limit = DefaultUserPageSize
+	}
+	switch q.Status {
+	case "deleted":
+		filter.Deleted = true
+	case "active", "suspended":
+		suspended := q.Status == "suspended"
+		filter.Suspended = &suspended
+	}
+	return filter
+}
+
+// ListAuditEventsQuery filters the audit log; since and until are RFC 3339 times
+type ListAuditEventsQuery struct {
+	Action   string    `form:"action" binding:"max=64"`
+	Outcome  string    `form:"outcome" binding:"omitempty,oneof=success failure"`
+	ActorID  uint      `form:"actor_id"`
+	TargetID uint      `form:"target_id"`
+	IP       string    `form:"ip" binding:"omitempty,ip"`
+	Since    time.Time `form:"since" time_format:"2006-01-02T15:04:05Z07:00"`
+	Until    time.Time `form:"until" time_format:"2006-01-02T15:04:05Z07:00"`
+	Limit    int       `form:"limit" binding:"omitempty,min=1,max=100"`
+	Offset   int       `form:"offset" binding:"min=0"`
+}
+
+func (q ListAuditEventsQuery) ToFilter() repositories.AuditFilter {
+	filter := repositories.AuditFilter{
+		Action:   q.Action,
+		Outcome:  q.Outcome,
+		ActorID:  q.ActorID,
+		TargetID: q.TargetID,
+		IP:       q.IP,
+		Since:    q.Since,
+		Until:    q.Until,
+		Limit:    q.Limit,
+		Offset:   q.Offset,
+	}
+	if filter.Limit == 0 {
+		filter.Limit = DefaultUserPageSize
+	}
+	return filter
+}
+
+type ChangeRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=user admin"`
+}
+
+type SuspendUserRequest struct {
+	Until time.Time `json:"until" binding:"required"`
+}
+
+// ResetPasswordRequest carries the new password; its strength is checked by the password policy
+type ResetPasswordRequest struct {
+	Password string `json:"password" binding:"required"`
+}
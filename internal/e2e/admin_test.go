@@ -0,0 +1,180 @@
+package e2e
+
+import (
+	"chat-app-api/internal/apierror"
+	"chat-app-api/internal/dto"
+	"chat-app-api/internal/models"
+	"chat-app-api/internal/repositories"
+	"chat-app-api/internal/services"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// admin signs up an account and grants it the admin role, like the role subcommand does
+func (s *testServer) admin(username string) *testUser {
+	s.t.Helper()
+
+	user := s.signup(username)
+	if err := s.repos.Users.UpdateRole(context.Background(), user.ID, models.RoleAdmin); err != nil {
+		s.t.Fatal(err)
+	}
+	if err := s.repos.Users.RevokeTokens(context.Background(), user.ID); err != nil {
+		s.t.Fatal(err)
+	}
+	return s.login(username)
+}
+
+func TestAdminRoutesRequireTheAdminRole(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.signup("alice")
+	admin := s.admin("admin")
+
+	if status := s.request(http.MethodGet, "/api/admin/stats", alice.AccessToken, nil, nil); status != http.StatusForbidden {
+		t.Errorf("stats as a user: status %d, want %d", status, http.StatusForbidden)
+	}
+	if status := s.request(http.MethodGet, "/api/admin/stats", admin.AccessToken, nil, nil); status != http.StatusOK {
+		t.Errorf("stats as an admin: status %d, want %d", status, http.StatusOK)
+	}
+}
+
+func TestSuspensionClosesConnectionsAndBlocksLogin(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.signup("alice")
+	admin := s.admin("admin")
+	conn := alice.connect()
+
+	path := fmt.Sprintf("/api/admin/users/%d/suspend", alice.ID)
+	request := dto.SuspendUserRequest{Until: time.Now().Add(time.Hour)}
+	if status := s.request(http.MethodPost, path, admin.AccessToken, request, nil); status != http.StatusOK {
+		t.Fatalf("suspend: status %d", status)
+	}
+
+	if closeErr := conn.expectClose(); closeErr.Code != websocket.ClosePolicyViolation {
+		t.Errorf("got close code %d, want %d", closeErr.Code, websocket.ClosePolicyViolation)
+	}
+
+	// The token issued before the suspension is revoked, and a new one cannot be obtained
+	_, resp, err := websocket.DefaultDialer.Dial(alice.websocketURL(), http.Header{"Authorization": {"Bearer " + alice.AccessToken}})
+	if err == nil || resp == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("reconnect with a revoked token: %v, want status %d", err, http.StatusUnauthorized)
+	}
+	var apiErr apierror.Response
+	status := s.request(http.MethodPost, "/api/auth/login", "", dto.LoginRequest{Identifier: "alice", Password: testPassword}, &apiErr)
+	if status != http.StatusForbidden || apiErr.Code != apierror.CodeAccountSuspended {
+		t.Errorf("login while suspended: status %d, code %q", status, apiErr.Code)
+	}
+}
+
+func TestDeletedAccountIsATombstoneUntilRestored(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.signup("alice")
+	bob := s.signup("bob")
+	admin := s.admin("admin")
+
+	aliceConn := alice.connect()
+	bobConn := bob.connect()
+	aliceConn.send(bob, "hi bob")
+	aliceConn.expectMessage()
+	bobConn.expectMessage()
+
+	if status := s.request(http.MethodDelete, fmt.Sprintf("/api/users/%d", bob.ID), bob.AccessToken, nil, nil); status != http.StatusNoContent {
+		t.Fatalf("delete: status %d", status)
+	}
+	if closeErr := bobConn.expectClose(); closeErr.Code != websocket.ClosePolicyViolation {
+		t.Errorf("got close code %d, want %d", closeErr.Code, websocket.ClosePolicyViolation)
+	}
+	if status := s.request(http.MethodGet, "/api/messages/friends", bob.AccessToken, nil, nil); status != http.StatusUnauthorized {
+		t.Errorf("request with the token of a deleted user: status %d, want %d", status, http.StatusUnauthorized)
+	}
+
+	var friends []repositories.FriendsList
+	if status := s.request(http.MethodGet, "/api/messages/friends", alice.AccessToken, nil, &friends); status != http.StatusOK {
+		t.Fatalf("friends: status %d", status)
+	}
+	if len(friends) != 1 || !friends[0].Profile.Deleted || friends[0].Profile.FirstName != models.DeletedUserName || friends[0].Profile.Username != "" {
+		t.Errorf("got friends %+v, want the tombstone of bob", friends)
+	}
+	if messages := alice.conversation(bob); len(messages) != 1 {
+		t.Errorf("got %d messages with the deleted user, want 1", len(messages))
+	}
+
+	if status := s.request(http.MethodPost, fmt.Sprintf("/api/admin/users/%d/restore", bob.ID), admin.AccessToken, nil, nil); status != http.StatusOK {
+		t.Fatalf("restore: status %d", status)
+	}
+	s.login("bob")
+}
+
+func TestAuditLogRecordsLoginsAndAdminActions(t *testing.T) {
+	s := newTestServer(t)
+	// Events of earlier tests stay on SQLite, so the queries only look at this test's
+	since := url.QueryEscape(time.Now().UTC().Format(time.RFC3339Nano))
+	alice := s.signup("alice")
+	admin := s.admin("admin")
+
+	status := s.request(http.MethodPost, "/api/auth/login", "", dto.LoginRequest{Identifier: "alice", Password: "wrong password"}, nil)
+	if status != http.StatusUnauthorized {
+		t.Fatalf("login with a wrong password: status %d", status)
+	}
+	if status := s.request(http.MethodPost, fmt.Sprintf("/api/admin/users/%d/logout", alice.ID), admin.AccessToken, nil, nil); status != http.StatusOK {
+		t.Fatalf("force logout: status %d", status)
+	}
+
+	var page services.AuditPage
+	query := "/api/admin/audit-events?action=auth.login&outcome=failure&since=" + since
+	if status := s.request(http.MethodGet, query, admin.AccessToken, nil, &page); status != http.StatusOK {
+		t.Fatalf("audit events: status %d", status)
+	}
+	if page.Total != 1 || page.Events[0].IP != "127.0.0.1" || page.Events[0].UserAgent == "" || page.Events[0].TargetID == nil || *page.Events[0].TargetID != alice.ID {
+		t.Errorf("got failed logins %+v, want one for alice with the client IP and user agent", page)
+	}
+
+	query = fmt.Sprintf("/api/admin/audit-events?action=%s&target_id=%d&since=%s", services.AuditForcedLogout, alice.ID, since)
+	if status := s.request(http.MethodGet, query, admin.AccessToken, nil, &page); status != http.StatusOK {
+		t.Fatalf("audit events: status %d", status)
+	}
+	if page.Total != 1 || page.Events[0].ActorID == nil || *page.Events[0].ActorID != admin.ID {
+		t.Errorf("got forced logouts %+v, want one by the admin", page)
+	}
+
+	alice = s.login("alice")
+	if status := s.request(http.MethodGet, "/api/admin/audit-events", alice.AccessToken, nil, nil); status != http.StatusForbidden {
+		t.Errorf("audit events as a user: status %d, want %d", status, http.StatusForbidden)
+	}
+	if status := s.request(http.MethodGet, "/api/admin/audit-events?outcome=maybe", admin.AccessToken, nil, nil); status != http.StatusBadRequest {
+		t.Errorf("invalid outcome: status %d, want %d", status, http.StatusBadRequest)
+	}
+}
+
+func TestOnlyAdminsSeeTheFullUser(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.signup("alice")
+	bob := s.signup("bob")
+	admin := s.admin("admin")
+
+	var profile map[string]any
+	if status := s.request(http.MethodGet, fmt.Sprintf("/api/users/%d", alice.ID), bob.AccessToken, nil, &profile); status != http.StatusOK {
+		t.Fatalf("profile: status %d", status)
+	}
+	if profile["username"] != "alice" {
+		t.Errorf("got profile %v, want alice's", profile)
+	}
+	for _, field := range []string{"email", "role", "suspended_until", "deleted_at"} {
+		if _, ok := profile[field]; ok {
+			t.Errorf("the public profile includes %s: %v", field, profile)
+		}
+	}
+
+	var page services.UserPage
+	if status := s.request(http.MethodGet, "/api/admin/users?search=alice", admin.AccessToken, nil, &page); status != http.StatusOK {
+		t.Fatalf("admin listing: status %d", status)
+	}
+	if len(page.Users) != 1 || page.Users[0].Email != "alice@example.com" || page.Users[0].Role != models.RoleUser {
+		t.Errorf("got %+v, want alice's email and role", page.Users)
+	}
+}
@@ -0,0 +1,69 @@
+package e2e
+
+import (
+	"chat-app-api/internal/dto"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestDeliveryAcrossInstances(t *testing.T) {
+	nodes := newTestCluster(t, 2)
+	alice := nodes[0].signup("alice")
+	bob := nodes[1].signup("bob")
+	aliceConn := alice.connect()
+	bobConn := bob.connect()
+
+	// Bob has a second tab on alice's instance
+	bobOnFirst := *bob
+	bobOnFirst.server = nodes[0]
+	bobTab := bobOnFirst.connect()
+
+	aliceConn.send(bob, "hello from the first instance")
+	for _, conn := range []*testConn{bobConn, bobTab} {
+		if message := conn.expectMessage(); message.Message != "hello from the first instance" || message.IsSelf {
+			t.Errorf("%s got %+v, want alice's message", conn.user.Username, message)
+		}
+	}
+	if echo := aliceConn.expectMessage(); !echo.IsSelf {
+		t.Errorf("alice got %+v, want the echo", echo)
+	}
+
+	// Events larger than one notification arrive whole
+	long := strings.Repeat("héllo ✓ ", 500)
+	bobConn.send(alice, long)
+	if message := aliceConn.expectMessage(); message.Message != long {
+		t.Errorf("alice got a message of %d characters, want %d", len([]rune(message.Message)), len([]rune(long)))
+	}
+	for _, conn := range []*testConn{bobConn, bobTab} {
+		if echo := conn.expectMessage(); !echo.IsSelf || echo.Message != long {
+			t.Errorf("%s did not get the echo of the long message", conn.user.Username)
+		}
+	}
+
+	// Every event is delivered once, however many instances relay it
+	aliceConn.expectNoEvent(100 * time.Millisecond)
+	bobConn.expectNoEvent(0)
+	bobTab.expectNoEvent(0)
+}
+
+func TestSessionRevokedAcrossInstances(t *testing.T) {
+	nodes := newTestCluster(t, 2)
+	admin := nodes[0].admin("admin")
+	alice := nodes[1].signup("alice")
+	conn := alice.connect()
+
+	path := fmt.Sprintf("/api/admin/users/%d/suspend", alice.ID)
+	request := dto.SuspendUserRequest{Until: time.Now().Add(time.Hour)}
+	if status := nodes[0].request(http.MethodPost, path, admin.AccessToken, request, nil); status != http.StatusOK {
+		t.Fatalf("suspend: status %d", status)
+	}
+
+	if closeErr := conn.expectClose(); closeErr.Code != websocket.ClosePolicyViolation {
+		t.Errorf("got close code %d, want %d", closeErr.Code, websocket.ClosePolicyViolation)
+	}
+}
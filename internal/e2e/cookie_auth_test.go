@@ -0,0 +1,223 @@
+package e2e
+
+import (
+	"bytes"
+	"chat-app-api/internal/config"
+	"chat-app-api/internal/dto"
+	"chat-app-api/internal/services"
+	"chat-app-api/internal/utils"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+)
+
+// newCookieTestServer is a test server that lets browser clients authenticate with cookies
+func newCookieTestServer(t *testing.T) *testServer {
+	t.Helper()
+	return newTestServerWithConfig(t, func(cfg *config.Config) { cfg.Cookie.Enabled = true })
+}
+
+// cookieLogin logs in in cookie mode and returns the cookies the server set, by name
+func (s *testServer) cookieLogin(username string) map[string]*http.Cookie {
+	s.t.Helper()
+
+	header := http.Header{utils.AuthModeHeader: {utils.AuthModeCookie}}
+	resp := s.cookieRequest(http.MethodPost, "/api/auth/login", header, nil, dto.LoginRequest{Identifier: username, Password: testPassword})
+	defer resp.Body.Close()
+
+	var login struct {
+		Data services.LoginResponse `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&login); err != nil || resp.StatusCode != http.StatusOK {
+		s.t.Fatalf("cookie login %s: status %d (%v)", username, resp.StatusCode, err)
+	}
+	if login.Data.AccessToken != "" || login.Data.RefreshToken != "" {
+		s.t.Errorf("cookie login returned the tokens in the body: %+v", login.Data)
+	}
+
+	cookies := cookiesByName(resp)
+	for _, name := range []string{utils.AccessTokenCookie, utils.RefreshTokenCookie, utils.CSRFTokenCookie} {
+		if cookies[name] == nil || cookies[name].Value == "" {
+			s.t.Fatalf("cookie login did not set %s, got %v", name, resp.Cookies())
+		}
+	}
+	return cookies
+}
+
+// cookieRequest sends a JSON request with the given headers and cookies, as a browser would
+func (s *testServer) cookieRequest(method, path string, header http.Header, cookies []*http.Cookie, body any) *http.Response {
+	s.t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			s.t.Fatal(err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequest(method, s.server.URL+path, reader)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	for _, cookie := range cookies {
+		req.AddCookie(&http.Cookie{Name: cookie.Name, Value: cookie.Value})
+	}
+
+	resp, err := s.server.Client().Do(req)
+	if err != nil {
+		s.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	return resp
+}
+
+func cookiesByName(resp *http.Response) map[string]*http.Cookie {
+	cookies := map[string]*http.Cookie{}
+	for _, cookie := range resp.Cookies() {
+		cookies[cookie.Name] = cookie
+	}
+	return cookies
+}
+
+func TestCookieAuthRequiresCSRFTokenOnUnsafeMethods(t *testing.T) {
+	s := newCookieTestServer(t)
+	alice := s.signup("alice")
+	cookies := s.cookieLogin("alice")
+	sent := []*http.Cookie{cookies[utils.AccessTokenCookie], cookies[utils.CSRFTokenCookie]}
+
+	path := fmt.Sprintf("/api/users/%d", alice.ID)
+	update := dto.UpdateUserRequest{Username: "alice", Email: "alice@example.com", FirstName: "Alice"}
+
+	tests := map[string]struct {
+		method string
+		header http.Header
+		want   int
+	}{
+		"safe method without the header": {http.MethodGet, nil, http.StatusOK},
+		"without the header":             {http.MethodPut, nil, http.StatusForbidden},
+		"with a mismatched header":       {http.MethodPut, http.Header{utils.CSRFTokenHeader: {"not the token"}}, http.StatusForbidden},
+		"with the matching header":       {http.MethodPut, http.Header{utils.CSRFTokenHeader: {cookies[utils.CSRFTokenCookie].Value}}, http.StatusOK},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var body any
+			if test.method == http.MethodPut {
+				body = update
+			}
+			resp := s.cookieRequest(test.method, path, test.header, sent, body)
+			resp.Body.Close()
+			if resp.StatusCode != test.want {
+				t.Errorf("status %d, want %d", resp.StatusCode, test.want)
+			}
+		})
+	}
+}
+
+func TestBearerRequestsDoNotNeedCSRFToken(t *testing.T) {
+	s := newCookieTestServer(t)
+	alice := s.signup("alice")
+	cookies := s.cookieLogin("alice")
+
+	path := fmt.Sprintf("/api/users/%d", alice.ID)
+	update := dto.UpdateUserRequest{Username: "alice", Email: "alice@example.com", FirstName: "Alice"}
+	header := http.Header{"Authorization": {"Bearer " + alice.AccessToken}}
+
+	// The Authorization header wins over cookies the browser happens to send along
+	for name, sent := range map[string][]*http.Cookie{
+		"without cookies":   nil,
+		"with auth cookies": {cookies[utils.AccessTokenCookie], cookies[utils.CSRFTokenCookie]},
+	} {
+		resp := s.cookieRequest(http.MethodPut, path, header, sent, update)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("bearer update %s: status %d, want %d", name, resp.StatusCode, http.StatusOK)
+		}
+	}
+
+	// Bearer clients renew with the refresh token in the body and no CSRF header
+	var login struct {
+		Data services.LoginResponse `json:"data"`
+	}
+	if status := s.request(http.MethodPost, "/api/auth/login", "", dto.LoginRequest{Identifier: "alice", Password: testPassword}, &login); status != http.StatusOK {
+		t.Fatalf("login: status %d", status)
+	}
+	var renewed struct {
+		AccessToken string `json:"access_token"`
+	}
+	status := s.request(http.MethodPost, "/api/auth/renew", "", dto.RenewTokenRequest{RefreshToken: login.Data.RefreshToken}, &renewed)
+	if status != http.StatusOK || renewed.AccessToken == "" {
+		t.Errorf("renew with the refresh token in the body: status %d, token %q", status, renewed.AccessToken)
+	}
+}
+
+func TestCookieRenewReadsTheRefreshCookie(t *testing.T) {
+	s := newCookieTestServer(t)
+	alice := s.signup("alice")
+	cookies := s.cookieLogin("alice")
+	refresh := []*http.Cookie{cookies[utils.RefreshTokenCookie], cookies[utils.CSRFTokenCookie]}
+
+	resp := s.cookieRequest(http.MethodPost, "/api/auth/renew", nil, refresh, nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("renew without the CSRF header: status %d, want %d", resp.StatusCode, http.StatusForbidden)
+	}
+
+	header := http.Header{utils.CSRFTokenHeader: {cookies[utils.CSRFTokenCookie].Value}}
+	resp = s.cookieRequest(http.MethodPost, "/api/auth/renew", header, refresh, nil)
+	defer resp.Body.Close()
+	var body map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("renew: status %d (%v)", resp.StatusCode, err)
+	}
+	if _, ok := body["access_token"]; ok {
+		t.Errorf("renew in cookie mode returned the token in the body: %v", body)
+	}
+
+	// The renewal sets a new access token and CSRF token, but leaves the refresh token alone
+	renewed := cookiesByName(resp)
+	if renewed[utils.RefreshTokenCookie] != nil {
+		t.Errorf("renew replaced the refresh cookie")
+	}
+	if renewed[utils.AccessTokenCookie] == nil || renewed[utils.CSRFTokenCookie] == nil {
+		t.Fatalf("renew did not set the access and CSRF cookies, got %v", resp.Cookies())
+	}
+	profile := s.cookieRequest(http.MethodGet, fmt.Sprintf("/api/users/%d", alice.ID), nil, []*http.Cookie{renewed[utils.AccessTokenCookie]}, nil)
+	profile.Body.Close()
+	if profile.StatusCode != http.StatusOK {
+		t.Errorf("request with the renewed cookie: status %d, want %d", profile.StatusCode, http.StatusOK)
+	}
+}
+
+func TestLogoutClearsTheAuthCookies(t *testing.T) {
+	s := newCookieTestServer(t)
+	s.signup("alice")
+	cookies := s.cookieLogin("alice")
+	sent := []*http.Cookie{cookies[utils.AccessTokenCookie], cookies[utils.RefreshTokenCookie], cookies[utils.CSRFTokenCookie]}
+
+	header := http.Header{utils.CSRFTokenHeader: {cookies[utils.CSRFTokenCookie].Value}}
+	resp := s.cookieRequest(http.MethodPost, "/api/auth/logout", header, sent, nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("logout: status %d", resp.StatusCode)
+	}
+
+	cleared := cookiesByName(resp)
+	for name, path := range map[string]string{
+		utils.AccessTokenCookie:  "/",
+		utils.RefreshTokenCookie: utils.RefreshTokenCookiePath,
+		utils.CSRFTokenCookie:    "/",
+	} {
+		// The path must match the one the cookie was set with, or the browser keeps it
+		if cookie := cleared[name]; cookie == nil || cookie.Value != "" || cookie.MaxAge >= 0 || cookie.Path != path {
+			t.Errorf("logout did not clear %s on %s, got %+v", name, path, cookie)
+		}
+	}
+}
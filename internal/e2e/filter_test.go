@@ -0,0 +1,65 @@
+package e2e
+
+import (
+	"chat-app-api/internal/apierror"
+	"chat-app-api/internal/config"
+	"chat-app-api/internal/models"
+	"chat-app-api/internal/services"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestMessageFilter(t *testing.T) {
+	s := newTestServerWithConfig(t, func(cfg *config.Config) {
+		cfg.Message.BlockedWords = []string{"darn"}
+		cfg.Message.DeniedDomains = []string{"scam.example"}
+		cfg.Message.MaxRepeatedChars = 5
+		cfg.Message.RepeatedCharsAction = services.FilterFlag
+	})
+	alice := s.signup("alice")
+	bob := s.signup("bob")
+	admin := s.admin("admin")
+	aliceConn := alice.connect()
+	bobConn := bob.connect()
+
+	// Rejected messages only reach the sender, as an error naming the stage
+	aliceConn.send(bob, "free coins at https://scam.example")
+	errorEvent := aliceConn.expectError()
+	if errorEvent.Code != apierror.CodeMessageRejected {
+		t.Fatalf("got error %+v, want code %s", errorEvent, apierror.CodeMessageRejected)
+	}
+	var decision services.FilterDecision
+	details, _ := json.Marshal(errorEvent.Details)
+	if err := json.Unmarshal(details, &decision); err != nil || decision.Stage != "denied_domains" || decision.Action != services.FilterReject {
+		t.Errorf("got details %s, want the denied domains decision", details)
+	}
+	bobConn.expectNoEvent(100 * time.Millisecond)
+
+	aliceConn.send(bob, "darn it")
+	if message := bobConn.expectMessage(); message.Message != "**** it" {
+		t.Errorf("bob got %q, want the blocked word masked", message.Message)
+	}
+	if echo := aliceConn.expectMessage(); echo.Message != "**** it" {
+		t.Errorf("alice's echo is %q, want the masked message", echo.Message)
+	}
+
+	// Flagged messages are delivered and land in the moderation queue
+	aliceConn.send(bob, "hellooooooooo")
+	flagged := bobConn.expectMessage()
+	aliceConn.expectMessage()
+
+	var queue services.ReportPage
+	if status := s.request(http.MethodGet, "/api/admin/reports?reason=flagged", admin.AccessToken, nil, &queue); status != http.StatusOK {
+		t.Fatalf("queue: status %d", status)
+	}
+	if queue.Total != 1 {
+		t.Fatalf("got queue %+v, want the flagged message", queue)
+	}
+	report := queue.Reports[0]
+	if report.ReporterID != nil || report.MessageID == nil || *report.MessageID != flagged.ID || report.ReportedUserID != alice.ID ||
+		report.Reason != models.ReportReasonFlagged || report.MessageContent != "hellooooooooo" {
+		t.Errorf("got report %+v", report)
+	}
+}
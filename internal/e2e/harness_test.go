@@ -0,0 +1,488 @@
+package e2e
+
+import (
+	"bytes"
+	"chat-app-api/internal/config"
+	"chat-app-api/internal/database"
+	"chat-app-api/internal/dto"
+	"chat-app-api/internal/health"
+	"chat-app-api/internal/realtime"
+	"chat-app-api/internal/repositories"
+	"chat-app-api/internal/routes"
+	"chat-app-api/internal/services"
+	"chat-app-api/internal/utils"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"gorm.io/gorm"
+)
+
+// eventTimeout bounds every wait for an event; generous so the race detector does not cause flakes
+const eventTimeout = 5 * time.Second
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+
+	// The cheapest bcrypt cost keeps signups and logins fast
+	passwordConfig := config.Default().Password
+	passwordConfig.HashAlgorithm = utils.PasswordAlgorithmBcrypt
+	passwordConfig.BcryptCost = 4
+	if err := utils.ConfigurePasswordHashing(passwordConfig); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	os.Exit(m.Run())
+}
+
+// testServer is the full application behind an httptest server
+type testServer struct {
+	t      *testing.T
+	server *httptest.Server
+	hub    *realtime.Hub
+	repos  repositories.Set
+}
+
+// newTestServer boots the router on the in-memory repositories, or on the database in
+// TEST_DATABASE_DSN when it is set; that database is migrated and emptied first
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	return newTestServerWithConfig(t, func(*config.Config) {})
+}
+
+// newTestServerWithConfig is newTestServer with settings changed from their defaults
+func newTestServerWithConfig(t *testing.T, configure func(cfg *config.Config)) *testServer {
+	t.Helper()
+
+	cfg := testConfig(t, configure)
+	repos, db := testRepositories(t)
+	return startTestServer(t, cfg, repos, db, realtime.NewLocalBroker())
+}
+
+// newTestCluster starts instances sharing one database, as replicas behind a load balancer do.
+// They share an in-process broker, or each listens with its own broker on PostgreSQL.
+func newTestCluster(t *testing.T, size int) []*testServer {
+	t.Helper()
+
+	cfg := testConfig(t, func(*config.Config) {})
+	repos, db := testRepositories(t)
+	localBroker := realtime.NewLocalBroker()
+
+	var servers []*testServer
+	for i := 0; i < size; i++ {
+		var broker realtime.Broker = localBroker
+		if db != nil && db.Dialector.Name() == database.Postgres {
+			broker = startPostgresBroker(t, db)
+		}
+		servers = append(servers, startTestServer(t, cfg, repos, db, broker))
+	}
+	return servers
+}
+
+// startPostgresBroker returns once the broker listens, so no event published by the test is missed
+func startPostgresBroker(t *testing.T, db *gorm.DB) *realtime.PostgresBroker {
+	t.Helper()
+
+	broker := realtime.NewPostgresBroker(db, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		broker.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	deadline := time.Now().Add(eventTimeout)
+	for !broker.Listening() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the realtime listener")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return broker
+}
+
+func testConfig(t *testing.T, configure func(cfg *config.Config)) *config.Config {
+	t.Helper()
+
+	// Every test user signs up from the same IP; the rate limit tests turn the limits back on
+	cfg := config.Default()
+	cfg.RateLimit.Enabled = false
+	configure(cfg)
+	cfg.JWT.AccessTokenSecret = "e2e-access-secret"
+	cfg.JWT.RefreshTokenSecret = "e2e-refresh-secret"
+	if err := utils.InitJWT(cfg.JWT); err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func startTestServer(t *testing.T, cfg *config.Config, repos repositories.Set, db *gorm.DB, broker realtime.Broker) *testServer {
+	t.Helper()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	hub := realtime.NewHub(broker, logger)
+
+	router, err := routes.NewRouter(cfg, repos, hub, health.NewChecker(db, nil, nil), logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &testServer{t: t, server: httptest.NewServer(router), hub: hub, repos: repos}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), eventTimeout)
+		defer cancel()
+		_ = hub.Shutdown(ctx)
+		s.server.Close()
+	})
+	return s
+}
+
+func testRepositories(t *testing.T) (repositories.Set, *gorm.DB) {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		return repositories.NewMemorySet(), nil
+	}
+
+	db, err := database.Connect(config.DatabaseConfig{DSN: dsn})
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	migrator, err := database.NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if err := emptyTables(db); err != nil {
+		t.Fatalf("empty tables: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+	return repositories.NewSet(db), db
+}
+
+// emptyTables removes the rows left by earlier tests and resets the ID sequences.
+// On SQLite the append-only trigger keeps the audit events, so tests filter them by time.
+func emptyTables(db *gorm.DB) error {
+	if db.Dialector.Name() != database.SQLite {
+		return db.Exec("TRUNCATE reports, messages, user_identities, users, audit_events RESTART IDENTITY CASCADE").Error
+	}
+	for _, table := range []string{"reports", "messages", "user_identities", "users"} {
+		if err := db.Exec("DELETE FROM " + table).Error; err != nil {
+			return err
+		}
+	}
+	return db.Exec("DELETE FROM sqlite_sequence WHERE name <> 'audit_events'").Error
+}
+
+// request sends a JSON request and decodes the JSON response into out when it is not nil
+func (s *testServer) request(method, path, accessToken string, body, out any) int {
+	s.t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			s.t.Fatal(err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequest(method, s.server.URL+path, reader)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+
+	resp, err := s.server.Client().Do(req)
+	if err != nil {
+		s.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			s.t.Fatalf("%s %s: decode response: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+// testUser is a signed up and logged in account
+type testUser struct {
+	server      *testServer
+	ID          uint
+	Username    string
+	AccessToken string
+}
+
+const testPassword = "correct horse battery staple"
+
+// signup creates an account through the API and logs in with it
+func (s *testServer) signup(username string) *testUser {
+	s.t.Helper()
+
+	status := s.request(http.MethodPost, "/api/users/signup", "", dto.CreateUserRequest{
+		Username: username,
+		Email:    username + "@example.com",
+		Password: testPassword,
+	}, nil)
+	if status != http.StatusCreated {
+		s.t.Fatalf("signup %s: status %d", username, status)
+	}
+	return s.login(username)
+}
+
+// login logs in with the password every test account is created with
+func (s *testServer) login(username string) *testUser {
+	s.t.Helper()
+
+	var login struct {
+		Data services.LoginResponse `json:"data"`
+	}
+	status := s.request(http.MethodPost, "/api/auth/login", "", dto.LoginRequest{Identifier: username, Password: testPassword}, &login)
+	if status != http.StatusOK {
+		s.t.Fatalf("login %s: status %d", username, status)
+	}
+
+	return &testUser{server: s, ID: login.Data.User.ID, Username: username, AccessToken: login.Data.AccessToken}
+}
+
+// connect opens a WebSocket for the user and waits until the hub tracks it
+func (u *testUser) connect() *testConn {
+	s := u.server
+	s.t.Helper()
+
+	before := s.hub.Stats().Connections
+	conn, resp, err := websocket.DefaultDialer.Dial(u.websocketURL(), http.Header{"Authorization": {"Bearer " + u.AccessToken}})
+	if err != nil {
+		s.t.Fatalf("connect %s: %v", u.Username, err)
+	}
+	resp.Body.Close()
+
+	c := &testConn{t: s.t, user: u, conn: conn, events: make(chan json.RawMessage, 1024)}
+	go c.readPump()
+	s.t.Cleanup(c.close)
+
+	// The handshake completes before the hub registers the connection; messages sent in between would be missed
+	s.waitFor(func() bool { return s.hub.Stats().Connections > before }, "the connection of "+u.Username+" to be registered")
+	return c
+}
+
+func (u *testUser) websocketURL() string {
+	return "ws" + strings.TrimPrefix(u.server.server.URL, "http") + "/api/messages/ws"
+}
+
+// conversation fetches the stored messages between the user and another one
+func (u *testUser) conversation(with *testUser) []services.MessageResponse {
+	u.server.t.Helper()
+
+	var messages []services.MessageResponse
+	path := fmt.Sprintf("/api/messages/friend/chats?user_id=%d", with.ID)
+	if status := u.server.request(http.MethodGet, path, u.AccessToken, nil, &messages); status != http.StatusOK {
+		u.server.t.Fatalf("conversation: status %d", status)
+	}
+	return messages
+}
+
+func (s *testServer) waitFor(condition func() bool, what string) {
+	s.t.Helper()
+	deadline := time.Now().Add(eventTimeout)
+	for !condition() {
+		if time.Now().After(deadline) {
+			s.t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// testConn is one WebSocket connection; received events are queued until the test asserts on them
+type testConn struct {
+	t      *testing.T
+	user   *testUser
+	conn   *websocket.Conn
+	events chan json.RawMessage
+
+	writeMu   sync.Mutex
+	closeOnce sync.Once
+	closeErr  error // why the read loop stopped, valid once events is closed
+}
+
+func (c *testConn) readPump() {
+	defer close(c.events)
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			c.closeErr = err
+			return
+		}
+		c.events <- data
+	}
+}
+
+// send writes a chat message event; it is safe to call from several goroutines
+func (c *testConn) send(to *testUser, content string) {
+	c.sendRaw(dto.SendMessageEvent{ReceiverID: to.ID, Content: content})
+}
+
+func (c *testConn) sendRaw(event any) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	var err error
+	if text, ok := event.(string); ok {
+		err = c.conn.WriteMessage(websocket.TextMessage, []byte(text))
+	} else {
+		err = c.conn.WriteJSON(event)
+	}
+	if err != nil {
+		c.t.Errorf("send as %s: %v", c.user.Username, err)
+	}
+}
+
+// receive waits for the next event and decodes it as a chat message. It reports problems as an
+// error instead of failing the test so it can be used from goroutines other than the test's.
+func (c *testConn) receive() (services.RealTimeMessageResponse, error) {
+	var message services.RealTimeMessageResponse
+	event, err := c.nextEvent()
+	if err != nil {
+		return message, err
+	}
+
+	var errorEvent dto.ErrorEvent
+	if json.Unmarshal(event, &errorEvent) == nil && errorEvent.Type == dto.ErrorEventType {
+		return message, fmt.Errorf("%s got an error event, want a message: %s", c.user.Username, event)
+	}
+	if err := json.Unmarshal(event, &message); err != nil {
+		return message, fmt.Errorf("decode message %s: %w", event, err)
+	}
+	return message, nil
+}
+
+func (c *testConn) nextEvent() (json.RawMessage, error) {
+	select {
+	case event, ok := <-c.events:
+		if !ok {
+			return nil, fmt.Errorf("connection of %s closed while waiting for an event: %v", c.user.Username, c.closeErr)
+		}
+		return event, nil
+	case <-time.After(eventTimeout):
+		return nil, fmt.Errorf("timed out waiting for an event on the connection of %s", c.user.Username)
+	}
+}
+
+// next waits for the next event, failing the test on timeout or a closed connection
+func (c *testConn) next() json.RawMessage {
+	c.t.Helper()
+	event, err := c.nextEvent()
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return event
+}
+
+// expectMessage waits for the next event and requires it to be a chat message
+func (c *testConn) expectMessage() services.RealTimeMessageResponse {
+	c.t.Helper()
+	message, err := c.receive()
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return message
+}
+
+// expectError waits for the next event and requires it to be an error event
+func (c *testConn) expectError() dto.ErrorEvent {
+	c.t.Helper()
+	event := c.next()
+
+	var errorEvent dto.ErrorEvent
+	if err := json.Unmarshal(event, &errorEvent); err != nil || errorEvent.Type != dto.ErrorEventType {
+		c.t.Fatalf("%s got %s, want an error event", c.user.Username, event)
+	}
+	return errorEvent
+}
+
+// expectEvent waits for the next event, requires it to have the given type and decodes it into v
+func (c *testConn) expectEvent(eventType string, v any) {
+	c.t.Helper()
+	event := c.next()
+
+	var typed struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(event, &typed); err != nil || typed.Type != eventType {
+		c.t.Fatalf("%s got %s, want a %s event", c.user.Username, event, eventType)
+	}
+	if err := json.Unmarshal(event, v); err != nil {
+		c.t.Fatalf("decode %s: %v", event, err)
+	}
+}
+
+// expectNoEvent fails if an event arrives within the given time
+func (c *testConn) expectNoEvent(wait time.Duration) {
+	c.t.Helper()
+	select {
+	case event, ok := <-c.events:
+		if ok {
+			c.t.Fatalf("%s got unexpected event %s", c.user.Username, event)
+		}
+	case <-time.After(wait):
+	}
+}
+
+// expectClose waits for the server to close the connection and returns the close frame
+func (c *testConn) expectClose() *websocket.CloseError {
+	c.t.Helper()
+	deadline := time.After(eventTimeout)
+	for {
+		select {
+		case _, ok := <-c.events:
+			if !ok {
+				closeErr, isClose := c.closeErr.(*websocket.CloseError)
+				if !isClose {
+					c.t.Fatalf("connection of %s failed without a close frame: %v", c.user.Username, c.closeErr)
+				}
+				return closeErr
+			}
+		case <-deadline:
+			c.t.Fatalf("timed out waiting for the connection of %s to close", c.user.Username)
+		}
+	}
+}
+
+// close ends the connection with a normal close frame
+func (c *testConn) close() {
+	c.closeOnce.Do(func() {
+		c.writeMu.Lock()
+		_ = c.conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+		c.writeMu.Unlock()
+		_ = c.conn.Close()
+	})
+}
@@ -0,0 +1,92 @@
+package e2e
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// metric scrapes /metrics and returns the value of the sample, or zero when it is not exposed yet
+func (s *testServer) metric(sample string) float64 {
+	s.t.Helper()
+
+	resp, err := s.server.Client().Get(s.server.URL + "/metrics")
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		s.t.Fatalf("scrape: status %d", resp.StatusCode)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if value, ok := strings.CutPrefix(scanner.Text(), sample+" "); ok {
+			v, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				s.t.Fatalf("parse %s: %v", scanner.Text(), err)
+			}
+			return v
+		}
+	}
+	return 0
+}
+
+// expectMetric waits for the sample to grow by want over before. The registry is shared by every
+// test server, so the counters are compared rather than read; a request is counted once its
+// response is written, which may be just after the client has it.
+func (s *testServer) expectMetric(sample string, before, want float64) {
+	s.t.Helper()
+
+	deadline := time.Now().Add(eventTimeout)
+	for {
+		got := s.metric(sample) - before
+		if got == want {
+			return
+		}
+		if got > want || time.Now().After(deadline) {
+			s.t.Errorf("%s grew by %v, want %v", sample, got, want)
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestMetricsCountRequestsAndMessages(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.signup("alice")
+	bob := s.signup("bob")
+
+	const profileRequests = `chat_http_requests_total{method="GET",route="/api/users/:id",status="200"}`
+	before := s.metric(profileRequests)
+	if status := s.request(http.MethodGet, fmt.Sprintf("/api/users/%d", bob.ID), alice.AccessToken, nil, nil); status != http.StatusOK {
+		t.Fatalf("profile: status %d", status)
+	}
+	s.expectMetric(profileRequests, before, 1)
+
+	const (
+		sent      = `chat_messages_total{result="sent"}`
+		delivered = `chat_messages_total{result="delivered"}`
+		failed    = `chat_messages_total{result="failed"}`
+	)
+	sentBefore, deliveredBefore, failedBefore := s.metric(sent), s.metric(delivered), s.metric(failed)
+
+	aliceConn := alice.connect()
+	bobConn := bob.connect()
+
+	// A message that cannot be saved is not counted as sent
+	aliceConn.sendRaw(map[string]any{"receiver_id": 4242, "content": "hello?"})
+	aliceConn.expectError()
+	s.expectMetric(failed, failedBefore, 1)
+
+	aliceConn.send(bob, "hello bob")
+	bobConn.expectMessage()
+	// The echo to the sender is queued after the delivery is counted
+	aliceConn.expectMessage()
+	s.expectMetric(sent, sentBefore, 1)
+	s.expectMetric(delivered, deliveredBefore, 1)
+}
@@ -0,0 +1,112 @@
+package e2e
+
+import (
+	"bytes"
+	"chat-app-api/internal/apierror"
+	"chat-app-api/internal/config"
+	"chat-app-api/internal/dto"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestRequestRateLimits(t *testing.T) {
+	s := newTestServerWithConfig(t, func(cfg *config.Config) {
+		cfg.RateLimit.Enabled = true
+		cfg.RateLimit.Login = config.RatePolicy{Requests: 2, Period: time.Minute}
+		cfg.RateLimit.Search = config.RatePolicy{Requests: 1, Period: time.Minute}
+	})
+	// Signing up logs in, which takes both logins allowed for the client IP
+	alice := s.signup("alice")
+	bob := s.signup("bob")
+
+	resp := s.rawLogin("alice")
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("third login: status %d, want 429", resp.StatusCode)
+	}
+	var body apierror.Response
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.Code != apierror.CodeTooManyRequests {
+		t.Errorf("got body %+v, want code %s", body, apierror.CodeTooManyRequests)
+	}
+	for header, want := range map[string]string{
+		"RateLimit-Limit":     "2",
+		"RateLimit-Remaining": "0",
+		"RateLimit-Reset":     "60",
+		"RateLimit-Policy":    "2;w=60",
+		"Retry-After":         "30",
+	} {
+		if got := resp.Header.Get(header); got != want {
+			t.Errorf("%s is %q, want %q", header, got, want)
+		}
+	}
+
+	// Searches are limited per user
+	if status := s.request(http.MethodGet, "/api/users/search?search=b", alice.AccessToken, nil, nil); status != http.StatusOK {
+		t.Fatalf("first search: status %d", status)
+	}
+	if status := s.request(http.MethodGet, "/api/users/search?search=b", alice.AccessToken, nil, nil); status != http.StatusTooManyRequests {
+		t.Errorf("second search: status %d, want 429", status)
+	}
+	if status := s.request(http.MethodGet, "/api/users/search?search=a", bob.AccessToken, nil, nil); status != http.StatusOK {
+		t.Errorf("bob's search: status %d, want a limit of their own", status)
+	}
+}
+
+func TestWebSocketRateLimits(t *testing.T) {
+	s := newTestServerWithConfig(t, func(cfg *config.Config) {
+		cfg.RateLimit.Enabled = true
+		cfg.RateLimit.WebSocket = config.RatePolicy{Requests: 1, Period: time.Minute}
+		cfg.RateLimit.WebSocketMessages = config.RatePolicy{Requests: 2, Period: time.Minute}
+		cfg.RateLimit.MaxThrottled = 2
+	})
+	alice := s.signup("alice")
+	bob := s.signup("bob")
+	aliceConn := alice.connect()
+
+	// The handshake is refused once the user opened too many connections
+	_, resp, err := websocket.DefaultDialer.Dial(alice.websocketURL(), http.Header{"Authorization": {"Bearer " + alice.AccessToken}})
+	if err == nil || resp == nil || resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("second connection: got %v, want a 429 handshake", err)
+	}
+	resp.Body.Close()
+
+	// Messages over the limit are dropped; the first drop is announced, the last closes the connection
+	for i := 0; i < 2; i++ {
+		aliceConn.send(bob, "hi")
+		aliceConn.expectMessage()
+	}
+	aliceConn.send(bob, "dropped")
+	var throttled dto.ThrottledEvent
+	aliceConn.expectEvent(dto.ThrottledEventType, &throttled)
+	if throttled.RetryAfterMs <= 0 || throttled.RetryAfterMs > 30000 {
+		t.Errorf("got retry_after_ms %d, want up to 30s", throttled.RetryAfterMs)
+	}
+	for i := 0; i < 2; i++ {
+		aliceConn.send(bob, "dropped")
+	}
+	aliceConn.expectNoEvent(100 * time.Millisecond)
+
+	aliceConn.send(bob, "one too many")
+	if closeErr := aliceConn.expectClose(); closeErr.Code != websocket.ClosePolicyViolation {
+		t.Errorf("got close %v, want policy violation", closeErr)
+	}
+	if messages := alice.conversation(bob); len(messages) != 2 {
+		t.Errorf("%d messages were saved, want the 2 within the limit", len(messages))
+	}
+}
+
+// rawLogin attempts a login and returns the response so its headers can be checked
+func (s *testServer) rawLogin(username string) *http.Response {
+	s.t.Helper()
+
+	body, _ := json.Marshal(dto.LoginRequest{Identifier: username, Password: testPassword})
+	resp, err := s.server.Client().Post(s.server.URL+"/api/auth/login", "application/json", bytes.NewReader(body))
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	s.t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
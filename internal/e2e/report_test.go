@@ -0,0 +1,113 @@
+package e2e
+
+import (
+	"chat-app-api/internal/apierror"
+	"chat-app-api/internal/dto"
+	"chat-app-api/internal/models"
+	"chat-app-api/internal/services"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestReportedMessageIsRemovedForEveryone(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.signup("alice")
+	bob := s.signup("bob")
+	admin := s.admin("admin")
+
+	aliceConn := alice.connect()
+	bobConn := bob.connect()
+	aliceConn.send(bob, "you are awful")
+	aliceConn.expectMessage()
+	message := bobConn.expectMessage()
+
+	var report models.Report
+	status := s.request(http.MethodPost, "/api/reports", bob.AccessToken, dto.CreateReportRequest{
+		TargetType: models.ReportTargetMessage, MessageID: message.ID, Reason: models.ReportReasonHarassment,
+	}, &report)
+	if status != http.StatusCreated {
+		t.Fatalf("report: status %d", status)
+	}
+	status = s.request(http.MethodPost, "/api/reports", bob.AccessToken, dto.CreateReportRequest{
+		TargetType: models.ReportTargetMessage, MessageID: message.ID, Reason: models.ReportReasonSpam,
+	}, nil)
+	if status != http.StatusConflict {
+		t.Errorf("second report of the message: status %d, want %d", status, http.StatusConflict)
+	}
+
+	var queue services.ReportPage
+	if status := s.request(http.MethodGet, "/api/admin/reports?status=open", admin.AccessToken, nil, &queue); status != http.StatusOK {
+		t.Fatalf("queue: status %d", status)
+	}
+	if queue.Total != 1 || queue.Reports[0].ID != report.ID || queue.Reports[0].MessageContent != "you are awful" {
+		t.Fatalf("got queue %+v, want the report of bob", queue)
+	}
+	if status := s.request(http.MethodGet, "/api/admin/reports", bob.AccessToken, nil, nil); status != http.StatusForbidden {
+		t.Errorf("queue as a user: status %d, want %d", status, http.StatusForbidden)
+	}
+
+	var resolved models.Report
+	status = s.request(http.MethodPost, fmt.Sprintf("/api/admin/reports/%d/resolve", report.ID), admin.AccessToken, dto.ResolveReportRequest{
+		Status: models.ReportStatusActioned, Actions: []string{models.ModerationRemoveMessage, models.ModerationWarn}, Note: "keep it civil",
+	}, &resolved)
+	if status != http.StatusOK {
+		t.Fatalf("resolve: status %d", status)
+	}
+	if resolved.Status != models.ReportStatusActioned || resolved.MessageID != nil {
+		t.Errorf("got report %+v, want it actioned with the message removed", resolved)
+	}
+
+	var removed services.MessageRemovedEvent
+	aliceConn.expectEvent(services.MessageRemovedEventType, &removed)
+	if removed.MessageID != message.ID {
+		t.Errorf("alice was told message %d was removed, want %d", removed.MessageID, message.ID)
+	}
+	var warning services.WarningEvent
+	aliceConn.expectEvent(services.WarningEventType, &warning)
+	if warning.ReportID != report.ID || warning.Note != "keep it civil" {
+		t.Errorf("alice got warning %+v", warning)
+	}
+	bobConn.expectEvent(services.MessageRemovedEventType, &removed)
+	var outcome services.ReportResolvedEvent
+	bobConn.expectEvent(services.ReportResolvedEventType, &outcome)
+	if outcome.ReportID != report.ID || outcome.Status != models.ReportStatusActioned {
+		t.Errorf("bob got %+v, want his report actioned", outcome)
+	}
+	if messages := bob.conversation(alice); len(messages) != 0 {
+		t.Errorf("got %d messages after the removal, want none", len(messages))
+	}
+}
+
+func TestReportedAccountCanBeSuspended(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.signup("alice")
+	bob := s.signup("bob")
+	admin := s.admin("admin")
+
+	var report models.Report
+	status := s.request(http.MethodPost, "/api/reports", bob.AccessToken, dto.CreateReportRequest{
+		TargetType: models.ReportTargetUser, UserID: alice.ID, Reason: models.ReportReasonImpersonation, Comment: "pretends to be me",
+	}, &report)
+	if status != http.StatusCreated {
+		t.Fatalf("report: status %d", status)
+	}
+
+	path := fmt.Sprintf("/api/admin/reports/%d/resolve", report.ID)
+	invalid := dto.ResolveReportRequest{Status: models.ReportStatusActioned, Actions: []string{models.ModerationRemoveMessage}}
+	if status := s.request(http.MethodPost, path, admin.AccessToken, invalid, nil); status != http.StatusBadRequest {
+		t.Errorf("remove_message on an account report: status %d, want %d", status, http.StatusBadRequest)
+	}
+
+	suspend := dto.ResolveReportRequest{Status: models.ReportStatusActioned, Actions: []string{models.ModerationSuspend},
+		SuspendUntil: time.Now().Add(time.Hour)}
+	if status := s.request(http.MethodPost, path, admin.AccessToken, suspend, nil); status != http.StatusOK {
+		t.Fatalf("resolve: status %d", status)
+	}
+	var apiErr apierror.Response
+	status = s.request(http.MethodPost, "/api/auth/login", "", dto.LoginRequest{Identifier: "alice", Password: testPassword}, &apiErr)
+	if status != http.StatusForbidden || apiErr.Code != apierror.CodeAccountSuspended {
+		t.Errorf("login while suspended: status %d, code %q", status, apiErr.Code)
+	}
+}
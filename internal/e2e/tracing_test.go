@@ -0,0 +1,127 @@
+package e2e
+
+import (
+	"chat-app-api/internal/config"
+	"chat-app-api/internal/database"
+	"chat-app-api/internal/realtime"
+	"chat-app-api/internal/repositories"
+	"context"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newTracedTestServer records every span and runs on a SQLite database, so queries are traced too
+func newTracedTestServer(t *testing.T) (*testServer, *tracetest.SpanRecorder) {
+	t.Helper()
+
+	recorder := tracetest.NewSpanRecorder()
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+
+	db, err := database.Connect(config.DatabaseConfig{DSN: "sqlite://" + filepath.Join(t.TempDir(), "chat.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+	migrator, err := database.NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := testConfig(t, func(*config.Config) {})
+	return startTestServer(t, cfg, repositories.NewSet(db), db, realtime.NewLocalBroker()), recorder
+}
+
+// waitForSpan returns the first ended span matching, waiting for it to end
+func waitForSpan(t *testing.T, recorder *tracetest.SpanRecorder, description string, match func(sdktrace.ReadOnlySpan) bool) sdktrace.ReadOnlySpan {
+	t.Helper()
+	for deadline := time.Now().Add(eventTimeout); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		for _, span := range recorder.Ended() {
+			if match(span) {
+				return span
+			}
+		}
+	}
+	t.Fatalf("timed out waiting for %s", description)
+	return nil
+}
+
+func TestRequestTraceReachesTheDatabase(t *testing.T) {
+	s, recorder := newTracedTestServer(t)
+	alice := s.signup("alice")
+
+	// The caller's trace is continued, and the queries of the request are children of its span
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	header := http.Header{"Authorization": {"Bearer " + alice.AccessToken}, "Traceparent": {traceparent}}
+	resp := s.cookieRequest(http.MethodGet, fmt.Sprintf("/api/users/%d", alice.ID), header, nil, nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("profile: status %d", resp.StatusCode)
+	}
+
+	server := waitForSpan(t, recorder, "the request span", func(span sdktrace.ReadOnlySpan) bool {
+		return span.SpanKind() == trace.SpanKindServer && span.SpanContext().TraceID().String() == "4bf92f3577b34da6a3ce929d0e0e4736"
+	})
+	if parent := server.Parent(); !parent.IsRemote() || parent.SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("the request span has parent %v, want the caller's span", parent)
+	}
+	waitForSpan(t, recorder, "a query under the request span", func(span sdktrace.ReadOnlySpan) bool {
+		return strings.HasPrefix(span.Name(), "db.query users") && span.Parent().SpanID() == server.SpanContext().SpanID()
+	})
+}
+
+func TestWebSocketMessagesGetTheirOwnTraces(t *testing.T) {
+	s, recorder := newTracedTestServer(t)
+	alice := s.signup("alice")
+	bob := s.signup("bob")
+	aliceConn := alice.connect()
+	bobConn := bob.connect()
+
+	aliceConn.send(bob, "hello bob")
+	bobConn.expectMessage()
+	aliceConn.expectMessage()
+
+	// Every message is the root of a new trace, so a long-lived connection is not one endless trace
+	message := waitForSpan(t, recorder, "the message span", func(span sdktrace.ReadOnlySpan) bool {
+		return span.Name() == "websocket message"
+	})
+	if message.Parent().IsValid() {
+		t.Errorf("the message span has parent %v, want a new root", message.Parent())
+	}
+	waitForSpan(t, recorder, "the insert under the message span", func(span sdktrace.ReadOnlySpan) bool {
+		return span.Name() == "db.create messages" && span.Parent().SpanID() == message.SpanContext().SpanID()
+	})
+
+	// It links to the span of the upgrade request, which ends with the connection
+	aliceConn.close()
+	upgrade := waitForSpan(t, recorder, "the span of alice's upgrade request", func(span sdktrace.ReadOnlySpan) bool {
+		return span.SpanKind() == trace.SpanKindServer && strings.Contains(span.Name(), "/api/messages/ws") &&
+			span.EndTime().After(message.EndTime())
+	})
+	links := message.Links()
+	if len(links) != 1 || links[0].SpanContext.SpanID() != upgrade.SpanContext().SpanID() {
+		t.Errorf("the message span links %v, want the upgrade request %v", links, upgrade.SpanContext())
+	}
+}
@@ -0,0 +1,223 @@
+package e2e
+
+import (
+	"chat-app-api/internal/apierror"
+	"chat-app-api/internal/dto"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestSendAndReceive(t *testing.T) {
+	s := newTestServer(t)
+	alice, bob := s.signup("alice"), s.signup("bob")
+	aliceConn, bobConn := alice.connect(), bob.connect()
+
+	aliceConn.send(bob, "hello bob")
+
+	received := bobConn.expectMessage()
+	if received.Message != "hello bob" || received.IsSelf || received.Sender.ID != alice.ID || received.ReceiverID != bob.ID {
+		t.Errorf("bob received %+v", received)
+	}
+
+	// The sender's connections get the saved message too, marked as their own
+	echo := aliceConn.expectMessage()
+	if echo.ID != received.ID || !echo.IsSelf {
+		t.Errorf("alice received %+v, want message %d marked as her own", echo, received.ID)
+	}
+
+	bobConn.send(alice, "hi alice")
+	if reply := aliceConn.expectMessage(); reply.Message != "hi alice" || reply.Sender.ID != bob.ID {
+		t.Errorf("alice received %+v", reply)
+	}
+	bobConn.expectMessage()
+
+	history := alice.conversation(bob)
+	if len(history) != 2 {
+		t.Fatalf("conversation has %d messages, want 2", len(history))
+	}
+}
+
+func TestRejectedEventsKeepTheConnectionOpen(t *testing.T) {
+	s := newTestServer(t)
+	alice, bob := s.signup("alice"), s.signup("bob")
+	aliceConn, bobConn := alice.connect(), bob.connect()
+
+	tests := []struct {
+		name  string
+		event any
+		code  apierror.Code
+		field string
+	}{
+		{"malformed JSON", "{not json", apierror.CodeBadRequest, ""},
+		{"wrong type", `{"receiver_id": "bob", "content": "hi"}`, apierror.CodeValidation, "receiver_id"},
+		{"blank content", dto.SendMessageEvent{ReceiverID: bob.ID, Content: "   "}, apierror.CodeValidation, "content"},
+		{"missing receiver", dto.SendMessageEvent{Content: "hi"}, apierror.CodeValidation, "receiver_id"},
+		{"unknown receiver", dto.SendMessageEvent{ReceiverID: 4242, Content: "hi"}, apierror.CodeValidation, "receiver_id"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			aliceConn.sendRaw(test.event)
+
+			errorEvent := aliceConn.expectError()
+			if errorEvent.Code != test.code {
+				t.Errorf("got code %s, want %s", errorEvent.Code, test.code)
+			}
+			if test.field != "" && !strings.Contains(fmt.Sprint(errorEvent.Details), test.field) {
+				t.Errorf("details %v do not mention %s", errorEvent.Details, test.field)
+			}
+		})
+	}
+
+	bobConn.expectNoEvent(100 * time.Millisecond)
+
+	aliceConn.send(bob, "still connected")
+	if received := bobConn.expectMessage(); received.Message != "still connected" {
+		t.Errorf("bob received %+v", received)
+	}
+}
+
+func TestMultipleTabs(t *testing.T) {
+	s := newTestServer(t)
+	alice, bob := s.signup("alice"), s.signup("bob")
+	aliceTabs := []*testConn{alice.connect(), alice.connect()}
+	bobTabs := []*testConn{bob.connect(), bob.connect(), bob.connect()}
+
+	aliceTabs[0].send(bob, "to every tab")
+
+	for i, tab := range bobTabs {
+		if received := tab.expectMessage(); received.Message != "to every tab" || received.IsSelf {
+			t.Errorf("bob's tab %d received %+v", i, received)
+		}
+	}
+	for i, tab := range aliceTabs {
+		if echo := tab.expectMessage(); echo.Message != "to every tab" || !echo.IsSelf {
+			t.Errorf("alice's tab %d received %+v", i, echo)
+		}
+	}
+
+	// Closing one tab leaves the others connected
+	bobTabs[0].close()
+	s.waitFor(func() bool { return s.hub.Stats().Connections == 4 }, "the closed tab to be unregistered")
+
+	aliceTabs[1].send(bob, "after closing a tab")
+	for _, tab := range bobTabs[1:] {
+		if received := tab.expectMessage(); received.Message != "after closing a tab" {
+			t.Errorf("bob received %+v", received)
+		}
+	}
+}
+
+func TestReconnect(t *testing.T) {
+	s := newTestServer(t)
+	alice, bob := s.signup("alice"), s.signup("bob")
+	aliceConn := alice.connect()
+
+	bobConn := bob.connect()
+	bobConn.close()
+	s.waitFor(func() bool { return s.hub.Stats().Connections == 1 }, "bob's connection to be unregistered")
+
+	// Messages sent while the recipient is offline are stored, not delivered
+	aliceConn.send(bob, "while you were away")
+	aliceConn.expectMessage()
+
+	bobConn = bob.connect()
+	bobConn.expectNoEvent(100 * time.Millisecond)
+	if history := bob.conversation(alice); len(history) != 1 || history[0].Message != "while you were away" {
+		t.Errorf("bob's history is %+v", history)
+	}
+
+	aliceConn.send(bob, "welcome back")
+	if received := bobConn.expectMessage(); received.Message != "welcome back" {
+		t.Errorf("bob received %+v", received)
+	}
+}
+
+func TestConcurrentSenders(t *testing.T) {
+	const senders, messagesEach = 8, 20
+
+	s := newTestServer(t)
+	receiver := s.signup("receiver")
+	receiverConn := receiver.connect()
+
+	conns := make([]*testConn, senders)
+	for i := range conns {
+		conns[i] = s.signup(fmt.Sprintf("sender%d", i)).connect()
+	}
+
+	// Each sender waits for its echo before sending again, like a client confirming delivery;
+	// an unpaced burst would overflow the receiver's send queue and get it dropped as a slow consumer
+	var wg sync.WaitGroup
+	for i, conn := range conns {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < messagesEach; j++ {
+				conn.send(receiver, fmt.Sprintf("%d/%d", i, j))
+				if echo, err := conn.receive(); err != nil || !echo.IsSelf {
+					t.Errorf("%s received %+v (%v), want its own message", conn.user.Username, echo, err)
+					return
+				}
+			}
+		}()
+	}
+
+	// Every message arrives exactly once, and each sender's messages arrive in the order sent
+	next := make(map[uint]int, senders)
+	for n := 0; n < senders*messagesEach; n++ {
+		received := receiverConn.expectMessage()
+		var sender, seq int
+		if _, err := fmt.Sscanf(received.Message, "%d/%d", &sender, &seq); err != nil {
+			t.Fatalf("unexpected message %q", received.Message)
+		}
+		if seq != next[received.Sender.ID] {
+			t.Fatalf("message %q from user %d arrived out of order, want sequence %d", received.Message, received.Sender.ID, next[received.Sender.ID])
+		}
+		next[received.Sender.ID]++
+	}
+	wg.Wait()
+	receiverConn.expectNoEvent(100 * time.Millisecond)
+}
+
+func TestHandshakeRequiresAuthentication(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.signup("alice")
+
+	for name, header := range map[string]http.Header{
+		"no token":      nil,
+		"invalid token": {"Authorization": {"Bearer not-a-token"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			conn, resp, err := websocket.DefaultDialer.Dial(alice.websocketURL(), header)
+			if err == nil {
+				conn.Close()
+				t.Fatal("handshake succeeded")
+			}
+			if resp == nil || resp.StatusCode != http.StatusUnauthorized {
+				t.Fatalf("got response %v, want 401", resp)
+			}
+		})
+	}
+}
+
+func TestShutdownSendsGoingAway(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.signup("alice")
+	conn := alice.connect()
+
+	ctx, cancel := context.WithTimeout(context.Background(), eventTimeout)
+	defer cancel()
+	if err := s.hub.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if closeErr := conn.expectClose(); closeErr.Code != websocket.CloseGoingAway {
+		t.Errorf("got close code %d, want %d", closeErr.Code, websocket.CloseGoingAway)
+	}
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"chat-app-api/internal/apierror"
+	"chat-app-api/internal/dto"
+	"chat-app-api/internal/services"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type AdminHandler struct {
+	adminService services.AdminService
+	authService  services.AuthService
+}
+
+func NewAdminHandler(adminService services.AdminService, authService services.AuthService) *AdminHandler {
+	return &AdminHandler{adminService: adminService, authService: authService}
+}
+
+func (h *AdminHandler) ListUsers(c *gin.Context) {
+	var query dto.ListUsersQuery
+	if !bindQuery(c, &query) {
+		return
+	}
+
+	page, err := h.adminService.ListUsers(c.Request.Context(), query.ToFilter())
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+func (h *AdminHandler) ChangeRole(c *gin.Context) {
+	actorID, err := getCurrentUserID(c)
+	if err != nil {
+		_ = c.Error(apierror.Unauthorized("User not logged in"))
+		return
+	}
+	var params dto.UserIDParam
+	if !bindURI(c, &params) {
+		return
+	}
+	var request dto.ChangeRoleRequest
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	user, err := h.adminService.ChangeRole(c.Request.Context(), actorID, params.ID, request.Role)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+func (h *AdminHandler) SuspendUser(c *gin.Context) {
+	actorID, err := getCurrentUserID(c)
+	if err != nil {
+		_ = c.Error(apierror.Unauthorized("User not logged in"))
+		return
+	}
+	var params dto.UserIDParam
+	if !bindURI(c, &params) {
+		return
+	}
+	var request dto.SuspendUserRequest
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	user, err := h.adminService.SuspendUser(c.Request.Context(), actorID, params.ID, request.Until)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+func (h *AdminHandler) UnsuspendUser(c *gin.Context) {
+	var params dto.UserIDParam
+	if !bindURI(c, &params) {
+		return
+	}
+
+	user, err := h.adminService.UnsuspendUser(c.Request.Context(), params.ID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+func (h *AdminHandler) RestoreUser(c *gin.Context) {
+	var params dto.UserIDParam
+	if !bindURI(c, &params) {
+		return
+	}
+
+	user, err := h.adminService.RestoreUser(c.Request.Context(), params.ID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+func (h *AdminHandler) ForceLogout(c *gin.Context) {
+	var params dto.UserIDParam
+	if !bindURI(c, &params) {
+		return
+	}
+
+	if err := h.adminService.ForceLogout(c.Request.Context(), params.ID); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "User logged out everywhere"})
+}
+
+func (h *AdminHandler) ResetPassword(c *gin.Context) {
+	var params dto.UserIDParam
+	if !bindURI(c, &params) {
+		return
+	}
+	var request dto.ResetPasswordRequest
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	if err := h.adminService.ResetPassword(c.Request.Context(), params.ID, request.Password); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset"})
+}
+
+func (h *AdminHandler) UnlockUser(c *gin.Context) {
+	var params dto.UserIDParam
+	if !bindURI(c, &params) {
+		return
+	}
+
+	if err := h.authService.UnlockAccount(c.Request.Context(), params.ID); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Account unlocked"})
+}
+
+func (h *AdminHandler) ListAuditEvents(c *gin.Context) {
+	var query dto.ListAuditEventsQuery
+	if !bindQuery(c, &query) {
+		return
+	}
+
+	page, err := h.adminService.ListAuditEvents(c.Request.Context(), query.ToFilter())
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+func (h *AdminHandler) Stats(c *gin.Context) {
+	stats, err := h.adminService.Stats(c.Request.Context())
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"chat-app-api/internal/config"
+	"chat-app-api/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// wantsCookies reports whether the client asked for cookie authentication and the server allows it
+func wantsCookies(c *gin.Context, cookieConfig config.CookieConfig) bool {
+	return cookieConfig.Enabled && c.GetHeader(utils.AuthModeHeader) == utils.AuthModeCookie
+}
+
+// setAuthCookies stores the tokens in HttpOnly cookies and issues a fresh double-submit CSRF token
+func setAuthCookies(c *gin.Context, cookieConfig config.CookieConfig, accessToken, refreshToken string) error {
+	csrfToken, err := utils.GenerateCSRFToken()
+	if err != nil {
+		return err
+	}
+
+	c.SetSameSite(cookieConfig.SameSite)
+	c.SetCookie(utils.AccessTokenCookie, accessToken, int(utils.AccessTokenTTL().Seconds()), "/", cookieConfig.Domain, cookieConfig.Secure, true)
+	if refreshToken != "" {
+		c.SetCookie(utils.RefreshTokenCookie, refreshToken, int(utils.RefreshTokenTTL().Seconds()), utils.RefreshTokenCookiePath, cookieConfig.Domain, cookieConfig.Secure, true)
+	}
+
+	// The CSRF cookie must be readable by the frontend so it can echo it in the X-CSRF-Token header
+	c.SetCookie(utils.CSRFTokenCookie, csrfToken, int(utils.RefreshTokenTTL().Seconds()), "/", cookieConfig.Domain, cookieConfig.Secure, false)
+	return nil
+}
+
+func clearAuthCookies(c *gin.Context, cookieConfig config.CookieConfig) {
+	c.SetSameSite(cookieConfig.SameSite)
+	c.SetCookie(utils.AccessTokenCookie, "", -1, "/", cookieConfig.Domain, cookieConfig.Secure, true)
+	c.SetCookie(utils.RefreshTokenCookie, "", -1, utils.RefreshTokenCookiePath, cookieConfig.Domain, cookieConfig.Secure, true)
+	c.SetCookie(utils.CSRFTokenCookie, "", -1, "/", cookieConfig.Domain, cookieConfig.Secure, false)
+}
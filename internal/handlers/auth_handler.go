@@ -1,34 +1,54 @@
 package handlers
 
 import (
+	"chat-app-api/internal/apierror"
+	"chat-app-api/internal/config"
+	"chat-app-api/internal/dto"
 	"chat-app-api/internal/services"
+	"chat-app-api/internal/utils"
+	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
+	"io"
+	"math"
+	"strconv"
 )
 
 type AuthHandler struct {
-	authService services.AuthService
+	authService  services.AuthService
+	cookieConfig config.CookieConfig
 }
 
-func NewAuthHandler(authService services.AuthService) *AuthHandler {
-	return &AuthHandler{authService: authService}
+func NewAuthHandler(authService services.AuthService, cookieConfig config.CookieConfig) *AuthHandler {
+	return &AuthHandler{authService: authService, cookieConfig: cookieConfig}
 }
 
 func (h *AuthHandler) Login(ctx *gin.Context) {
-	var loginRequest struct {
-		Username string `json:"username"`
-		Password string `json:"password"`
-	}
-	if err := ctx.BindJSON(&loginRequest); err != nil {
-		ctx.JSON(400, gin.H{"error": "Invalid request"})
+	var loginRequest dto.LoginRequest
+	if !bindJSON(ctx, &loginRequest) {
 		return
 	}
 
-	response, err := h.authService.Login(loginRequest.Username, loginRequest.Password)
+	response, err := h.authService.Login(ctx.Request.Context(), loginRequest.LoginIdentifier(), loginRequest.Password, ctx.ClientIP())
 	if err != nil {
-		ctx.JSON(401, gin.H{"error": "Unauthorized"})
+		var lockedErr *services.AccountLockedError
+		if errors.As(err, &lockedErr) {
+			ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(lockedErr.RetryAfter.Seconds()))))
+		}
+		_ = ctx.Error(err)
 		return
 	}
 
+	// In cookie mode the tokens never reach JavaScript
+	if wantsCookies(ctx, h.cookieConfig) {
+		if err := setAuthCookies(ctx, h.cookieConfig, response.AccessToken, response.RefreshToken); err != nil {
+			_ = ctx.Error(apierror.Internal(fmt.Errorf("failed to set auth cookies: %w", err)))
+			return
+		}
+		response.AccessToken = ""
+		response.RefreshToken = ""
+	}
+
 	ctx.JSON(200, gin.H{
 		"data":    response,
 		"message": "Login successful",
@@ -36,17 +56,33 @@ func (h *AuthHandler) Login(ctx *gin.Context) {
 }
 
 func (h *AuthHandler) RenewAccessToken(ctx *gin.Context) {
-	var renewRequest struct {
-		RefreshToken string `json:"refresh_token"`
-	}
-	if err := ctx.BindJSON(&renewRequest); err != nil {
-		ctx.JSON(400, gin.H{"error": "Invalid request"})
+	var renewRequest dto.RenewTokenRequest
+	if err := ctx.ShouldBindJSON(&renewRequest); err != nil && !errors.Is(err, io.EOF) {
+		_ = ctx.Error(invalidInput(err, "Invalid request body"))
 		return
 	}
 
-	newAccessToken, err := h.authService.RenewAccessToken(renewRequest.RefreshToken)
+	// Cookie clients send no body, their refresh token comes from the HttpOnly cookie
+	fromCookie := false
+	if renewRequest.RefreshToken == "" && h.cookieConfig.Enabled {
+		if cookie, err := ctx.Cookie(utils.RefreshTokenCookie); err == nil {
+			renewRequest.RefreshToken = cookie
+			fromCookie = true
+		}
+	}
+
+	newAccessToken, err := h.authService.RenewAccessToken(ctx.Request.Context(), renewRequest.RefreshToken)
 	if err != nil {
-		ctx.JSON(401, gin.H{"error": "Unauthorized"})
+		_ = ctx.Error(err)
+		return
+	}
+
+	if fromCookie {
+		if err := setAuthCookies(ctx, h.cookieConfig, newAccessToken, ""); err != nil {
+			_ = ctx.Error(apierror.Internal(fmt.Errorf("failed to set auth cookies: %w", err)))
+			return
+		}
+		ctx.JSON(200, gin.H{"message": "Access token renewed"})
 		return
 	}
 
@@ -54,3 +90,9 @@ func (h *AuthHandler) RenewAccessToken(ctx *gin.Context) {
 		"access_token": newAccessToken,
 	})
 }
+
+// Logout clears the authentication cookies; bearer clients simply drop their tokens
+func (h *AuthHandler) Logout(ctx *gin.Context) {
+	clearAuthCookies(ctx, h.cookieConfig)
+	ctx.JSON(200, gin.H{"message": "Logout successful"})
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"chat-app-api/internal/apierror"
+	"chat-app-api/internal/services"
+	"chat-app-api/internal/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bindJSON decodes and validates the request body; on failure the error is left for ErrorMiddleware
+func bindJSON(c *gin.Context, v any) bool {
+	if err := c.ShouldBindJSON(v); err != nil {
+		_ = c.Error(invalidInput(err, "Invalid request body"))
+		return false
+	}
+	return true
+}
+
+func bindURI(c *gin.Context, v any) bool {
+	if err := c.ShouldBindUri(v); err != nil {
+		_ = c.Error(invalidInput(err, "Invalid path parameter"))
+		return false
+	}
+	return true
+}
+
+func bindQuery(c *gin.Context, v any) bool {
+	if err := c.ShouldBindQuery(v); err != nil {
+		_ = c.Error(invalidInput(err, "Invalid query parameter"))
+		return false
+	}
+	return true
+}
+
+// invalidInput turns a binding error into a services.ValidationError listing the invalid fields,
+// or into a bad request with the given message when the input could not be decoded at all
+func invalidInput(err error, message string) error {
+	fields := validation.Fields(err)
+	if len(fields) == 0 {
+		return apierror.BadRequest(message)
+	}
+	invalid := make([]services.FieldError, 0, len(fields))
+	for _, field := range fields {
+		invalid = append(invalid, services.FieldError{Field: field.Field, Message: field.Message})
+	}
+	return services.NewValidationError(invalid...)
+}
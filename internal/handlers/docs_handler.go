@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"chat-app-api/internal/apidocs"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// DocsHandler serves the API documents, rendered once since they only change with the binary
+type DocsHandler struct {
+	openAPI  []byte
+	asyncAPI []byte
+}
+
+func NewDocsHandler() (*DocsHandler, error) {
+	openAPI, err := json.Marshal(apidocs.OpenAPI())
+	if err != nil {
+		return nil, err
+	}
+	asyncAPI, err := json.Marshal(apidocs.AsyncAPI())
+	if err != nil {
+		return nil, err
+	}
+	return &DocsHandler{openAPI: openAPI, asyncAPI: asyncAPI}, nil
+}
+
+func (h *DocsHandler) OpenAPI(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json", h.openAPI)
+}
+
+func (h *DocsHandler) AsyncAPI(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json", h.asyncAPI)
+}
+
+func (h *DocsHandler) SwaggerUI(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(apidocs.SwaggerUI))
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"chat-app-api/internal/buildinfo"
+	"chat-app-api/internal/health"
+	"context"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+type HealthHandler struct {
+	checker *health.Checker
+}
+
+func NewHealthHandler(checker *health.Checker) *HealthHandler {
+	return &HealthHandler{checker: checker}
+}
+
+// Liveness only tells that the process is up and serving requests
+func (h *HealthHandler) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readiness reports whether the instance can take traffic, with the result of each check
+func (h *HealthHandler) Readiness(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+	defer cancel()
+
+	status := http.StatusOK
+	checks := gin.H{}
+	for name, err := range h.checker.Ready(ctx) {
+		if err != nil {
+			status = http.StatusServiceUnavailable
+			checks[name] = err.Error()
+		} else {
+			checks[name] = "ok"
+		}
+	}
+
+	result := "ok"
+	if status != http.StatusOK {
+		result = "unavailable"
+	}
+	c.JSON(status, gin.H{"status": result, "checks": checks})
+}
+
+func (h *HealthHandler) Version(c *gin.Context) {
+	c.JSON(http.StatusOK, buildinfo.Get())
+}
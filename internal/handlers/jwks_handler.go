@@ -0,0 +1,15 @@
+package handlers
+
+import (
+	"chat-app-api/internal/utils"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// GetJWKS publishes the public keys used to verify access tokens. Refresh tokens are signed with
+// a secret instead, so nothing verified with these keys is a refresh token; verifiers should still
+// require token_type "access".
+func GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, utils.PublicJWKS())
+}
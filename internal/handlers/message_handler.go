@@ -1,159 +1,233 @@
 package handlers
 
 import (
-	"fmt"
-	"log"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"slices"
 	"strconv"
-	"sync"
+	"time"
 
+	"chat-app-api/internal/apierror"
+	"chat-app-api/internal/dto"
+	"chat-app-api/internal/logging"
+	"chat-app-api/internal/metrics"
 	"chat-app-api/internal/models"
+	"chat-app-api/internal/ratelimit"
+	"chat-app-api/internal/realtime"
 	"chat-app-api/internal/services"
+	"chat-app-api/internal/tracing"
+	"chat-app-api/internal/validation"
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// WebSocket Upgrader configuration
-var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool {
-		return true
-	},
-}
-
-// Mutex for concurrent map access
-var mu sync.Mutex
-
-// Map to track connected clients by user ID
-var clients = make(map[uint]*websocket.Conn)
-
 // MessageHandler handles real-time chat through WebSocket
 type MessageHandler struct {
 	messageService services.MessageService
+	hub            *realtime.Hub
+	messageLimit   ratelimit.Policy // per connection, a zero Limit disables it
+	maxThrottled   int
+	upgrader       websocket.Upgrader
+	logger         *slog.Logger
+}
+
+// NewMessageHandler creates a new instance of MessageHandler. A connection sending faster than
+// messageLimit gets a throttled event; once it has sent maxThrottled more messages that were
+// dropped, it is closed.
+func NewMessageHandler(messageService services.MessageService, hub *realtime.Hub, allowedOrigins []string,
+	messageLimit ratelimit.Policy, maxThrottled int, logger *slog.Logger) *MessageHandler {
+	return &MessageHandler{
+		messageService: messageService,
+		hub:            hub,
+		messageLimit:   messageLimit,
+		maxThrottled:   maxThrottled,
+		logger:         logger,
+		// WebSocket Upgrader configuration
+		upgrader: websocket.Upgrader{
+			CheckOrigin: checkOrigin(allowedOrigins),
+		},
+	}
 }
 
-// NewMessageHandler creates a new instance of MessageHandler
-func NewMessageHandler(messageService services.MessageService) *MessageHandler {
-	return &MessageHandler{messageService: messageService}
+// checkOrigin only accepts handshakes from the configured frontends, since browsers attach
+// authentication cookies to cross-site WebSocket requests
+func checkOrigin(allowedOrigins []string) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		if u, err := url.Parse(origin); err == nil && u.Host == r.Host {
+			return true
+		}
+		return slices.Contains(allowedOrigins, origin)
+	}
 }
 
 // HandleConnections handles incoming WebSocket connections
 func (h *MessageHandler) HandleConnections(c *gin.Context) {
-	ws, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	// The sender is the authenticated user, never a client supplied ID
+	senderID, err := getCurrentUserID(c)
 	if err != nil {
-		log.Printf("Error upgrading connection: %v", err)
+		_ = c.Error(apierror.Unauthorized("User not logged in"))
 		return
 	}
-	defer func(ws *websocket.Conn) {
-		err := ws.Close()
-		if err != nil {
-			log.Printf("Error closing connection: %v", err)
-		}
-	}(ws)
 
-	// Extract the user ID from query parameters
-	senderID, err := getUserIDFromQuery(c)
+	ws, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
-		log.Printf("Invalid user ID: %v", err)
+		h.logger.WarnContext(c.Request.Context(), "error upgrading connection", "error", err)
 		return
 	}
 
-	// Add the WebSocket connection to the map
-	mu.Lock()
-	clients[senderID] = ws
-	mu.Unlock()
+	// Track the connection so messages and shutdown notices can reach it
+	client, err := h.hub.Register(senderID, ws)
+	if err != nil {
+		_ = ws.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"), time.Now().Add(time.Second))
+		_ = ws.Close()
+		return
+	}
+	defer h.hub.Unregister(client)
+
+	logger := client.Logger()
+	logger.InfoContext(c.Request.Context(), "websocket connected")
+	defer logger.Info("websocket disconnected")
+
+	// The upgrade request's span lasts as long as the connection; every event gets its own trace linked to it
+	connCtx := c.Request.Context()
+	connLink := trace.LinkFromContext(connCtx)
+
+	bucket := ratelimit.NewBucket(h.messageLimit)
+	throttled, dropped := false, 0
 
 	// Listen for incoming messages
 	for {
-		// Define a structure for receiving only content, receiver_id, and sender_id
-		var incomingMessage struct {
-			Content    string `json:"content"`
-			ReceiverID uint   `json:"receiver_id"`
-		}
+		var event dto.SendMessageEvent
 
 		// Read the message from the WebSocket connection
-		err := ws.ReadJSON(&incomingMessage)
+		data, err := client.ReadMessage()
 		if err != nil {
-			log.Printf("Error reading JSON message: %v", err)
-			mu.Lock()
-			delete(clients, senderID)
-			mu.Unlock()
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				logger.Warn("error reading websocket message", "error", err)
+			}
 			break
 		}
 
-		// Create a Message model with the extracted information
-		message := models.Message{
-			Content:    incomingMessage.Content,
-			SenderID:   senderID, // Use the sender's ID from the WebSocket connection query
-			ReceiverID: incomingMessage.ReceiverID,
+		// Events over the connection's rate are dropped; the first drop tells the client how long to wait
+		if h.messageLimit.Limit > 0 {
+			result := bucket.Take(time.Now())
+			if !result.Allowed {
+				metrics.RateLimited.WithLabelValues(h.messageLimit.Name).Inc()
+				if !throttled {
+					throttled, dropped = true, 0
+					h.hub.SendToClient(client, dto.ThrottledEvent{Type: dto.ThrottledEventType, RetryAfterMs: result.RetryAfter.Milliseconds()})
+					continue
+				}
+				if dropped++; dropped > h.maxThrottled {
+					logger.Warn("closing websocket that kept sending while throttled", "dropped", dropped)
+					h.hub.Disconnect(client, websocket.ClosePolicyViolation, "rate limit exceeded")
+					break
+				}
+				continue
+			}
+			throttled = false
+		}
+
+		// A malformed event is rejected without dropping the connection
+		if err := json.Unmarshal(data, &event); err != nil {
+			h.sendError(connCtx, client, invalidInput(err, "Invalid message payload"))
+			continue
+		}
+		if err := validation.Struct(&event); err != nil {
+			h.sendError(connCtx, client, invalidInput(err, "Invalid message payload"))
+			continue
+		}
+
+		// The sender is always the user who opened the connection
+		message := event.ToMessage(senderID)
+
+		// Messages arriving once shutdown has begun are not accepted
+		done, ok := h.hub.BeginWork()
+		if !ok {
+			break
 		}
 
 		// Process and save the message
-		h.processMessage(&message)
+		ctx, span := tracing.Tracer().Start(connCtx, "websocket message",
+			trace.WithNewRoot(),
+			trace.WithLinks(connLink),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.Int("chat.sender_id", int(senderID)),
+				attribute.Int("chat.receiver_id", int(message.ReceiverID)),
+			))
+		h.processMessage(ctx, client, &message)
+		span.End()
+		done()
 	}
 }
 
 // processMessage saves the message using the service and forwards it to the recipient
-func (h *MessageHandler) processMessage(msg *models.Message) {
+func (h *MessageHandler) processMessage(ctx context.Context, client *realtime.Client, msg *models.Message) {
+	logger := client.Logger()
+
 	// Save the message to the database using the message service
-	createdMsg, err := h.messageService.CreateMessage(msg)
+	createdMsg, err := h.messageService.CreateMessage(ctx, msg)
+	if errors.Is(err, services.ErrMessageRejected) {
+		metrics.Messages.WithLabelValues(metrics.MessageRejected).Inc()
+		h.sendError(ctx, client, err)
+		return
+	}
 	if err != nil {
-		log.Printf("Error saving message: %v", err)
+		metrics.Messages.WithLabelValues(metrics.MessageFailed).Inc()
+		span := trace.SpanFromContext(ctx)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "failed to save message")
+		h.sendError(ctx, client, err)
 		return
 	}
+	metrics.Messages.WithLabelValues(metrics.MessageSent).Inc()
 
-	// Send the message to the recipient if they are connected
-	h.sendMessageToRecipient(createdMsg, msg.ReceiverID)
-	createdMsg.IsSelf = true
-	h.sendMessageToSender(createdMsg, msg.Sender.ID)
-}
-
-func (h *MessageHandler) sendMessageToRecipient(msg *services.RealTimeMessageResponse, recipientID uint) {
-	mu.Lock()
-	recipientWS, exists := clients[recipientID]
-	mu.Unlock()
-
-	if exists {
-		// Send the message to the recipient
-		err := recipientWS.WriteJSON(msg)
-		if err != nil {
-			log.Printf("Error sending message to recipient %d: %v", recipientID, err)
-		}
+	// Send the message to every connection of the recipient and echo it to the sender's tabs, on every instance.
+	// Only delivery on this instance is known; the other instances deliver after this returns.
+	delivered := h.hub.SendToUser(msg.ReceiverID, createdMsg)
+	if delivered {
+		metrics.Messages.WithLabelValues(metrics.MessageDelivered).Inc()
 	} else {
-		log.Printf("Recipient %d is not online", recipientID)
+		logger.DebugContext(ctx, "recipient not connected to this instance", "receiver_id", msg.ReceiverID)
 	}
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Bool("chat.delivered_locally", delivered))
+	selfMsg := *createdMsg
+	selfMsg.IsSelf = true
+	h.hub.SendToUser(msg.SenderID, &selfMsg)
 }
-func (h *MessageHandler) sendMessageToSender(msg *services.RealTimeMessageResponse, senderID uint) {
-	mu.Lock()
-	senderWS, exists := clients[senderID]
-	mu.Unlock()
-
-	if exists {
-		// Send the message to the recipient
-		err := senderWS.WriteJSON(msg)
-		if err != nil {
-			log.Printf("Error sending message to sender %d: %v", senderID, err)
-		}
-	} else {
-		log.Printf("Sender %d is not online", senderID)
+
+func (h *MessageHandler) sendError(ctx context.Context, client *realtime.Client, err error) {
+	apiErr := apierror.From(err)
+	if apiErr.Status >= http.StatusInternalServerError {
+		client.Logger().ErrorContext(ctx, "websocket event failed", "error", err)
 	}
+
+	h.hub.SendToClient(client, dto.ErrorEvent{Type: dto.ErrorEventType, Response: apiErr.Response(logging.RequestID(ctx))})
 }
 
 func (h *MessageHandler) GetFriendsWithLastMessage(c *gin.Context) {
-	currentUserIDString, exists := c.Get("UserID")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not logged in"})
-		return
-	}
-
-	currentUserID, err := strconv.ParseUint(currentUserIDString.(string), 10, 32)
+	currentUserID, err := getCurrentUserID(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		_ = c.Error(apierror.Unauthorized("User not logged in"))
 		return
 	}
 
-	messages, err := h.messageService.GetFriendListWithLastMessage(uint(currentUserID))
+	messages, err := h.messageService.GetFriendListWithLastMessage(c.Request.Context(), currentUserID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		_ = c.Error(err)
 		return
 	}
 
@@ -161,37 +235,28 @@ func (h *MessageHandler) GetFriendsWithLastMessage(c *gin.Context) {
 }
 
 func (h *MessageHandler) GetMessagesBySenderIdAndReceiverId(c *gin.Context) {
-	currentUserIDString, exists := c.Get("UserID")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not logged in"})
-		return
-	}
-
-	currentUserID, err := strconv.ParseUint(currentUserIDString.(string), 10, 32)
+	currentUserID, err := getCurrentUserID(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		_ = c.Error(apierror.Unauthorized("User not logged in"))
 		return
 	}
 
-	friendID, err := getUserIDFromQuery(c)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid receiver ID"})
+	var query dto.ConversationQuery
+	if !bindQuery(c, &query) {
 		return
 	}
 
-	messages, err := h.messageService.GetMessagesBySenderIdAndReceiverId(uint(currentUserID), friendID)
+	messages, err := h.messageService.GetMessagesBySenderIdAndReceiverId(c.Request.Context(), currentUserID, query.UserID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		_ = c.Error(err)
 		return
 	}
 
 	c.JSON(http.StatusOK, messages)
 }
 
-// Helper function to extract user ID from the query parameters
-func getUserIDFromQuery(c *gin.Context) (uint, error) {
-	userIDStr := c.Query("user_id")
-	var userID uint
-	_, err := fmt.Sscanf(userIDStr, "%d", &userID)
-	return userID, err
+// Helper function to read the authenticated user's ID set by the auth middleware
+func getCurrentUserID(c *gin.Context) (uint, error) {
+	userID, err := strconv.ParseUint(c.GetString("UserID"), 10, 32)
+	return uint(userID), err
 }
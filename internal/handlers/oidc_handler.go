@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"chat-app-api/internal/apierror"
+	"chat-app-api/internal/config"
+	"chat-app-api/internal/dto"
+	"chat-app-api/internal/services"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"log/slog"
+	"net/http"
+)
+
+// oidcStateCookie carries the state, nonce and PKCE verifier between the redirect and the callback
+const oidcStateCookie = "oidc_auth"
+
+type oidcState struct {
+	State        string `json:"state"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+}
+
+type OIDCHandler struct {
+	oidcService  services.OIDCService
+	cookieConfig config.CookieConfig
+	logger       *slog.Logger
+}
+
+func NewOIDCHandler(oidcService services.OIDCService, cookieConfig config.CookieConfig, logger *slog.Logger) *OIDCHandler {
+	return &OIDCHandler{oidcService: oidcService, cookieConfig: cookieConfig, logger: logger}
+}
+
+// Login redirects the browser to the provider's authorization endpoint
+func (h *OIDCHandler) Login(c *gin.Context) {
+	request, err := h.oidcService.AuthCodeURL(c.Request.Context())
+	if err != nil {
+		_ = c.Error(&apierror.Error{Status: http.StatusBadGateway, Code: apierror.CodeBadGateway, Message: "Identity provider is unavailable", Err: err})
+		return
+	}
+
+	cookie, _ := json.Marshal(oidcState{
+		State:        request.State,
+		Nonce:        request.Nonce,
+		CodeVerifier: request.CodeVerifier,
+	})
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, base64.RawURLEncoding.EncodeToString(cookie), 600, "/", "", c.Request.TLS != nil, true)
+
+	c.Redirect(http.StatusFound, request.URL)
+}
+
+// Callback completes the authorization code flow and issues our own tokens
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	var query dto.OIDCCallbackQuery
+	if !bindQuery(c, &query) {
+		return
+	}
+	if query.Error != "" {
+		_ = c.Error(apierror.Unauthorized("Identity provider rejected the login: " + query.Error))
+		return
+	}
+
+	rawCookie, err := c.Cookie(oidcStateCookie)
+	if err != nil {
+		_ = c.Error(apierror.BadRequest("Login session expired, please try again"))
+		return
+	}
+	c.SetCookie(oidcStateCookie, "", -1, "/", "", c.Request.TLS != nil, true)
+
+	var state oidcState
+	decoded, err := base64.RawURLEncoding.DecodeString(rawCookie)
+	if err != nil || json.Unmarshal(decoded, &state) != nil || state.State == "" || state.State != query.State {
+		_ = c.Error(apierror.BadRequest("Invalid login state"))
+		return
+	}
+
+	response, err := h.oidcService.Exchange(c.Request.Context(), query.Code, state.CodeVerifier, state.Nonce)
+	if err != nil {
+		h.logger.WarnContext(c.Request.Context(), "error completing oidc login", "error", err)
+		_ = c.Error(err)
+		return
+	}
+
+	// The callback is a browser navigation, so in cookie mode the tokens always go into cookies
+	if h.cookieConfig.Enabled {
+		if err := setAuthCookies(c, h.cookieConfig, response.AccessToken, response.RefreshToken); err != nil {
+			_ = c.Error(apierror.Internal(fmt.Errorf("failed to set auth cookies: %w", err)))
+			return
+		}
+		response.AccessToken = ""
+		response.RefreshToken = ""
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    response,
+		"message": "Login successful",
+	})
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"chat-app-api/internal/apierror"
+	"chat-app-api/internal/dto"
+	"chat-app-api/internal/services"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type ReportHandler struct {
+	reportService services.ReportService
+}
+
+func NewReportHandler(reportService services.ReportService) *ReportHandler {
+	return &ReportHandler{reportService: reportService}
+}
+
+func (h *ReportHandler) CreateReport(c *gin.Context) {
+	reporterID, err := getCurrentUserID(c)
+	if err != nil {
+		_ = c.Error(apierror.Unauthorized("User not logged in"))
+		return
+	}
+	var request dto.CreateReportRequest
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	report := request.ToReport(reporterID)
+	created, err := h.reportService.CreateReport(c.Request.Context(), &report)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusCreated, created)
+}
+
+func (h *ReportHandler) ListReports(c *gin.Context) {
+	var query dto.ListReportsQuery
+	if !bindQuery(c, &query) {
+		return
+	}
+
+	page, err := h.reportService.ListReports(c.Request.Context(), query.ToFilter())
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+func (h *ReportHandler) GetReport(c *gin.Context) {
+	var params dto.ReportIDParam
+	if !bindURI(c, &params) {
+		return
+	}
+
+	report, err := h.reportService.GetReport(c.Request.Context(), params.ID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+func (h *ReportHandler) ResolveReport(c *gin.Context) {
+	actorID, err := getCurrentUserID(c)
+	if err != nil {
+		_ = c.Error(apierror.Unauthorized("User not logged in"))
+		return
+	}
+	var params dto.ReportIDParam
+	if !bindURI(c, &params) {
+		return
+	}
+	var request dto.ResolveReportRequest
+	if !bindJSON(c, &request) {
+		return
+	}
+
+	report, err := h.reportService.ResolveReport(c.Request.Context(), actorID, params.ID, services.ReportResolution{
+		Status:       request.Status,
+		Actions:      request.Actions,
+		SuspendUntil: request.SuspendUntil,
+		Note:         request.Note,
+	})
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
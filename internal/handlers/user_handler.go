@@ -1,11 +1,11 @@
 package handlers
 
 import (
-	"chat-app-api/internal/models"
+	"chat-app-api/internal/apierror"
+	"chat-app-api/internal/dto"
 	"chat-app-api/internal/services"
 	"github.com/gin-gonic/gin"
 	"net/http"
-	"strconv"
 )
 
 type UserHandler struct {
@@ -17,71 +17,66 @@ func NewUserHandler(s services.UserService) *UserHandler {
 }
 
 func (ctrl *UserHandler) CreateUser(c *gin.Context) {
-	var user models.User
-	if err := c.ShouldBindJSON(&user); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var request dto.CreateUserRequest
+	if !bindJSON(c, &request) {
 		return
 	}
 
-	createdUser, err := ctrl.userService.CreateUser(&user)
+	createdUser, err := ctrl.userService.CreateUser(c.Request.Context(), request.ToUser())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		_ = c.Error(err)
 		return
 	}
 	c.JSON(http.StatusCreated, createdUser)
 }
 
 func (ctrl *UserHandler) GetUserByID(c *gin.Context) {
-	id, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+	var params dto.UserIDParam
+	if !bindURI(c, &params) {
 		return
 	}
-	user, err := ctrl.userService.GetUserByID(uint(id))
+	user, err := ctrl.userService.GetUserByID(c.Request.Context(), params.ID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		_ = c.Error(err)
 		return
 	}
 	c.JSON(http.StatusOK, user)
 }
 
-func (ctrl *UserHandler) GetAllUsers(c *gin.Context) {
-	users, err := ctrl.userService.GetAllUsers()
+func (ctrl *UserHandler) UpdateUser(c *gin.Context) {
+	currentUserID, err := getCurrentUserID(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		_ = c.Error(apierror.Unauthorized("User not logged in"))
 		return
 	}
-	c.JSON(http.StatusOK, users)
-}
-
-func (ctrl *UserHandler) UpdateUser(c *gin.Context) {
-	id, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+	var params dto.UserIDParam
+	if !bindURI(c, &params) {
 		return
 	}
-	var user models.User
-	if err := c.ShouldBindJSON(&user); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var request dto.UpdateUserRequest
+	if !bindJSON(c, &request) {
 		return
 	}
-	user.ID = uint(id)
-	updatedUser, err := ctrl.userService.UpdateUser(&user)
+	updatedUser, err := ctrl.userService.UpdateUser(c.Request.Context(), currentUserID, request.ToUser(params.ID))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		_ = c.Error(err)
 		return
 	}
 	c.JSON(http.StatusOK, updatedUser)
 }
 
 func (ctrl *UserHandler) DeleteUser(c *gin.Context) {
-	id, err := strconv.Atoi(c.Param("id"))
+	currentUserID, err := getCurrentUserID(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		_ = c.Error(apierror.Unauthorized("User not logged in"))
+		return
+	}
+	var params dto.UserIDParam
+	if !bindURI(c, &params) {
 		return
 	}
-	if err := ctrl.userService.DeleteUser(uint(id)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := ctrl.userService.DeleteUser(c.Request.Context(), currentUserID, params.ID); err != nil {
+		_ = c.Error(err)
 		return
 	}
 	c.Status(http.StatusNoContent)
@@ -90,14 +85,17 @@ func (ctrl *UserHandler) DeleteUser(c *gin.Context) {
 func (ctrl *UserHandler) SearchUser(c *gin.Context) {
 	currentUsername, exists := c.Get("Username")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not logged in"})
+		_ = c.Error(apierror.Unauthorized("User not logged in"))
 		return
 	}
 
-	searchContent := c.Query("search")
-	users, err := ctrl.userService.SearchUser(currentUsername.(string), searchContent)
+	var query dto.SearchUsersQuery
+	if !bindQuery(c, &query) {
+		return
+	}
+	users, err := ctrl.userService.SearchUser(c.Request.Context(), currentUsername.(string), query.Search)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		_ = c.Error(err)
 		return
 	}
 	c.JSON(http.StatusOK, users)
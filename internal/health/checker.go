@@ -0,0 +1,78 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrShuttingDown = errors.New("shutting down")
+	ErrNotListening = errors.New("not listening for events of other instances")
+)
+
+// SchemaChecker reports whether the database schema is up to date
+type SchemaChecker interface {
+	CheckSchema(ctx context.Context) error
+}
+
+// Listener reports whether the realtime broker receives the events of other instances
+type Listener interface {
+	Listening() bool
+}
+
+// Checker decides whether the instance can take traffic
+type Checker struct {
+	db           *gorm.DB
+	schema       SchemaChecker
+	broker       Listener
+	shuttingDown atomic.Bool
+}
+
+// NewChecker returns a checker of the database, its schema and the broker. A nil dependency is not
+// checked: the in-memory repositories have no database, and the broker of a single instance
+// keeps its events in process.
+func NewChecker(db *gorm.DB, schema SchemaChecker, broker Listener) *Checker {
+	return &Checker{db: db, schema: schema, broker: broker}
+}
+
+// StartShutdown makes readiness fail so the orchestrator stops routing new traffic here
+func (c *Checker) StartShutdown() {
+	c.shuttingDown.Store(true)
+}
+
+// Ready runs every readiness check and returns the result of each by name
+func (c *Checker) Ready(ctx context.Context) map[string]error {
+	results := map[string]error{"shutdown": nil}
+	if c.shuttingDown.Load() {
+		results["shutdown"] = ErrShuttingDown
+	}
+
+	if c.db != nil {
+		sqlDB, err := c.db.DB()
+		if err == nil {
+			err = sqlDB.PingContext(ctx)
+		}
+		results["database"] = err
+
+		if c.schema != nil {
+			if err == nil {
+				results["migrations"] = c.schema.CheckSchema(ctx)
+			} else {
+				results["migrations"] = errors.New("database unavailable")
+			}
+		}
+	}
+
+	// An instance that misses the events of the others would silently not deliver their messages
+	if c.broker != nil {
+		results["broker"] = nil
+		if !c.broker.Listening() {
+			results["broker"] = ErrNotListening
+		}
+	}
+
+	return results
+}
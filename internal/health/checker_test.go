@@ -0,0 +1,54 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// schemaFunc is a SchemaChecker returning the error of the function
+type schemaFunc func() error
+
+func (f schemaFunc) CheckSchema(context.Context) error { return f() }
+
+// listenerFunc is a Listener reporting the result of the function
+type listenerFunc func() bool
+
+func (f listenerFunc) Listening() bool { return f() }
+
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+	return db
+}
+
+func TestReadyChecksTheBroker(t *testing.T) {
+	db := openTestDB(t)
+	upToDate := schemaFunc(func() error { return nil })
+
+	// The in-process broker of a single instance has nothing to check
+	if results := NewChecker(db, upToDate, nil).Ready(context.Background()); len(results) != 3 {
+		t.Errorf("got %v, want only the shutdown, database and migrations checks", results)
+	}
+
+	listening := false
+	checker := NewChecker(db, upToDate, listenerFunc(func() bool { return listening }))
+	if err := checker.Ready(context.Background())["broker"]; !errors.Is(err, ErrNotListening) {
+		t.Errorf("got %v, want %v", err, ErrNotListening)
+	}
+	listening = true
+	if results := checker.Ready(context.Background()); results["broker"] != nil || len(results) != 4 {
+		t.Errorf("got %v, want every check to pass", results)
+	}
+}
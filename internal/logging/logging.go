@@ -0,0 +1,88 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"chat-app-api/internal/config"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are attribute names whose values are never written; matching is case-insensitive on substrings
+var sensitiveKeys = []string{"password", "token", "secret", "authorization", "cookie", "verifier", "api_key"}
+
+// jwtPattern catches tokens logged under an innocent key, e.g. inside an error message
+var jwtPattern = regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)
+
+// New builds the application logger. Records logged with a context carry its request ID and trace ID.
+func New(cfg config.LogConfig, w io.Writer) (*slog.Logger, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q", cfg.Level)
+	}
+
+	options := &slog.HandlerOptions{Level: level, ReplaceAttr: redact}
+
+	var handler slog.Handler
+	switch cfg.Format {
+	case "json":
+		handler = slog.NewJSONHandler(w, options)
+	case "text":
+		handler = slog.NewTextHandler(w, options)
+	default:
+		return nil, fmt.Errorf("invalid log format %q", cfg.Format)
+	}
+
+	return slog.New(&contextHandler{Handler: handler}), nil
+}
+
+// redact hides the values of sensitive attributes and any JWT found in string values
+func redact(groups []string, a slog.Attr) slog.Attr {
+	key := strings.ToLower(a.Key)
+	for _, sensitive := range sensitiveKeys {
+		if strings.Contains(key, sensitive) {
+			return slog.String(a.Key, redacted)
+		}
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		if value := a.Value.String(); jwtPattern.MatchString(value) {
+			return slog.String(a.Key, jwtPattern.ReplaceAllString(value, redacted))
+		}
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok && jwtPattern.MatchString(err.Error()) {
+			return slog.String(a.Key, jwtPattern.ReplaceAllString(err.Error(), redacted))
+		}
+	}
+	return a
+}
+
+// contextHandler adds the request ID and trace ID of the context to every record
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if requestID := RequestID(ctx); requestID != "" {
+		record.AddAttrs(slog.String("request_id", requestID))
+	}
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		record.AddAttrs(slog.String("trace_id", spanContext.TraceID().String()))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
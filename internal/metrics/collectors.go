@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"chat-app-api/internal/realtime"
+	"database/sql"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// RegisterDB exposes the connection pool statistics of the database, labelled with the driver name
+func RegisterDB(db *sql.DB, driver string) error {
+	return Registry.Register(collectors.NewDBStatsCollector(db, driver))
+}
+
+// RegisterHub exposes the WebSocket connections and outbound queues of the hub
+func RegisterHub(hub *realtime.Hub) error {
+	return Registry.Register(&hubCollector{hub: hub})
+}
+
+var (
+	connectionsDesc = prometheus.NewDesc(namespace+"_websocket_connections",
+		"Open WebSocket connections.", nil, nil)
+	queuedDesc = prometheus.NewDesc(namespace+"_websocket_send_queue_messages",
+		"Messages waiting in the outbound queues of all WebSocket connections.", nil, nil)
+	maxQueueDesc = prometheus.NewDesc(namespace+"_websocket_send_queue_max_messages",
+		"Depth of the fullest outbound WebSocket queue.", nil, nil)
+)
+
+// hubCollector reads the hub state at scrape time so nothing has to be updated on the hot path
+type hubCollector struct {
+	hub *realtime.Hub
+}
+
+func (c *hubCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- connectionsDesc
+	ch <- queuedDesc
+	ch <- maxQueueDesc
+}
+
+func (c *hubCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.hub.Stats()
+	ch <- prometheus.MustNewConstMetric(connectionsDesc, prometheus.GaugeValue, float64(stats.Connections))
+	ch <- prometheus.MustNewConstMetric(queuedDesc, prometheus.GaugeValue, float64(stats.QueuedMessages))
+	ch <- prometheus.MustNewConstMetric(maxQueueDesc, prometheus.GaugeValue, float64(stats.MaxQueueDepth))
+}
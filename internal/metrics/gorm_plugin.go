@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const startTimeKey = "metrics:start_time"
+
+// GormPlugin records the latency of every query GORM runs
+type GormPlugin struct{}
+
+// callbackRegistrar is implemented by GORM's callback chain positions
+type callbackRegistrar interface {
+	Register(name string, fn func(*gorm.DB)) error
+}
+
+func (GormPlugin) Name() string {
+	return "metrics"
+}
+
+func (GormPlugin) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	var errs []error
+	register := func(operation string, before, after callbackRegistrar) {
+		errs = append(errs,
+			before.Register("metrics:before_"+operation, startTimer),
+			after.Register("metrics:after_"+operation, observeQuery(operation)))
+	}
+
+	register("create", callbacks.Create().Before("gorm:create"), callbacks.Create().After("gorm:create"))
+	register("query", callbacks.Query().Before("gorm:query"), callbacks.Query().After("gorm:query"))
+	register("update", callbacks.Update().Before("gorm:update"), callbacks.Update().After("gorm:update"))
+	register("delete", callbacks.Delete().Before("gorm:delete"), callbacks.Delete().After("gorm:delete"))
+	register("row", callbacks.Row().Before("gorm:row"), callbacks.Row().After("gorm:row"))
+	register("raw", callbacks.Raw().Before("gorm:raw"), callbacks.Raw().After("gorm:raw"))
+
+	return errors.Join(errs...)
+}
+
+func startTimer(db *gorm.DB) {
+	db.InstanceSet(startTimeKey, time.Now())
+}
+
+func observeQuery(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(startTimeKey)
+		if !ok {
+			return
+		}
+		start, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+
+		table := db.Statement.Table
+		if table == "" {
+			table = "unknown"
+		}
+		DBQueryDuration.WithLabelValues(operation, table).Observe(time.Since(start).Seconds())
+	}
+}
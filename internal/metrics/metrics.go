@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const namespace = "chat"
+
+// Registry holds every collector exposed on /metrics
+var Registry = prometheus.NewRegistry()
+
+var (
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route and status code.",
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// Messages counts chat messages by outcome: sent (saved and passed on to the recipient), delivered
+	// (queued to at least one connection of the recipient on the same instance), rejected
+	// (by the message filter) or failed (could not be saved). With several instances, a recipient
+	// connected to another instance is not counted as delivered.
+	Messages = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_total",
+		Help:      "Chat messages by outcome.",
+	}, []string{"result"})
+
+	// RateLimited counts requests and WebSocket messages refused by a rate limit policy
+	RateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_total",
+		Help:      "Requests and WebSocket messages refused by rate limit policy.",
+	}, []string{"policy"})
+
+	DBQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Database query latency by operation and table.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation", "table"})
+)
+
+const (
+	MessageSent      = "sent"
+	MessageDelivered = "delivered"
+	MessageRejected  = "rejected"
+	MessageFailed    = "failed"
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPRequestDuration,
+		Messages,
+		RateLimited,
+		DBQueryDuration,
+	)
+}
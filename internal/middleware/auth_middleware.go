@@ -1,35 +1,69 @@
 package middleware
 
 import (
+	"chat-app-api/internal/apierror"
+	"chat-app-api/internal/services"
 	"chat-app-api/internal/utils"
+	"context"
+	"crypto/subtle"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
-func AuthMiddleware() gin.HandlerFunc {
+// SessionChecker rejects tokens that were revoked after they were issued, or whose user is suspended
+type SessionChecker interface {
+	CheckSession(ctx context.Context, userID uint, tokenVersion int) error
+}
+
+func AuthMiddleware(sessions SessionChecker) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		var tokenString string
 		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is missing"})
-			c.Abort()
+		fromCookie := false
+
+		if authHeader != "" {
+			// Check if the Authorization header is in the format "Bearer <token>"
+			tokenString = strings.TrimPrefix(authHeader, "Bearer ")
+			if tokenString == authHeader {
+				abortWithError(c, apierror.Unauthorized("Authorization header format must be 'Bearer <token>'"))
+				return
+			}
+		} else if cookie, err := c.Cookie(utils.AccessTokenCookie); err == nil && cookie != "" {
+			// Browser clients in cookie mode send the access token as an HttpOnly cookie
+			tokenString = cookie
+			fromCookie = true
+		} else if token := c.Query("access_token"); token != "" && c.IsWebsocket() {
+			// Browsers cannot set headers on WebSocket handshakes, bearer clients pass the token in the query
+			tokenString = token
+		} else {
+			abortWithError(c, apierror.Unauthorized("Authorization header is missing"))
 			return
 		}
 
-		// Check if the Authorization header is in the format "Bearer <token>"
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		if tokenString == authHeader {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header format must be 'Bearer <token>'"})
-			c.Abort()
+		// Cookies are sent automatically by the browser, so state-changing requests must prove same-origin
+		if fromCookie && !hasValidCSRFToken(c) {
+			abortWithError(c, apierror.Forbidden("Missing or invalid CSRF token"))
 			return
 		}
 
 		// Parse and validate the access token
 		claims, err := utils.ParseAccessToken(tokenString)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
-			c.Abort()
+			abortWithError(c, apierror.Unauthorized("Invalid or expired token"))
+			return
+		}
+		userID, err := strconv.ParseUint(claims.UserID, 10, 32)
+		if err != nil {
+			abortWithError(c, apierror.Unauthorized("Invalid or expired token"))
+			return
+		}
+
+		// Forced logouts and suspensions take effect immediately, not when the access token expires
+		if err := sessions.CheckSession(c.Request.Context(), uint(userID), claims.TokenVersion); err != nil {
+			abortWithError(c, apierror.From(err))
 			return
 		}
 
@@ -37,7 +71,49 @@ func AuthMiddleware() gin.HandlerFunc {
 		c.Set("UserID", claims.UserID)
 		c.Set("Username", claims.Username)
 		c.Set("Email", claims.Email)
+		c.Set("Role", claims.Role)
+
+		// The audit log attributes what the services do to the authenticated user
+		info := services.RequestInfoFrom(c.Request.Context())
+		info.UserID = uint(userID)
+		c.Request = c.Request.WithContext(services.WithRequestInfo(c.Request.Context(), info))
 
 		c.Next()
 	}
 }
+
+// CSRFMiddleware enforces the double-submit token on requests that authenticate with cookies
+// but do not go through AuthMiddleware, such as renewing the access token
+func CSRFMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" && hasAuthCookie(c) && !hasValidCSRFToken(c) {
+			abortWithError(c, apierror.Forbidden("Missing or invalid CSRF token"))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func hasAuthCookie(c *gin.Context) bool {
+	for _, name := range []string{utils.AccessTokenCookie, utils.RefreshTokenCookie} {
+		if cookie, err := c.Cookie(name); err == nil && cookie != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// hasValidCSRFToken checks that the X-CSRF-Token header matches the csrf_token cookie on unsafe methods
+func hasValidCSRFToken(c *gin.Context) bool {
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+
+	cookie, err := c.Cookie(utils.CSRFTokenCookie)
+	if err != nil || cookie == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(cookie), []byte(c.GetHeader(utils.CSRFTokenHeader))) == 1
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"chat-app-api/internal/apierror"
+	"chat-app-api/internal/logging"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorMiddleware renders the last error a handler or middleware attached with c.Error as the
+// {code, message, details, request_id} envelope. Internal errors are logged with their cause.
+func ErrorMiddleware(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 {
+			return
+		}
+
+		err := c.Errors.Last().Err
+		apiErr := apierror.From(err)
+		if apiErr.Status >= 500 {
+			logger.ErrorContext(c.Request.Context(), "request failed", "error", err)
+		}
+
+		if c.Writer.Written() {
+			return
+		}
+		c.JSON(apiErr.Status, apiErr.Response(logging.RequestID(c.Request.Context())))
+	}
+}
+
+// RecoveryMiddleware turns a panic into an internal error rendered by ErrorMiddleware
+func RecoveryMiddleware() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(nil, func(c *gin.Context, recovered any) {
+		_ = c.Error(fmt.Errorf("panic: %v\n%s", recovered, debug.Stack()))
+		c.Abort()
+	})
+}
+
+// abortWithError stops the chain and leaves the error for ErrorMiddleware to render
+func abortWithError(c *gin.Context, err error) {
+	_ = c.Error(err)
+	c.Abort()
+}
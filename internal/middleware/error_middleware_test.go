@@ -0,0 +1,160 @@
+package middleware
+
+import (
+	"bytes"
+	"chat-app-api/internal/apierror"
+	"chat-app-api/internal/config"
+	"chat-app-api/internal/logging"
+	"chat-app-api/internal/services"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// serve runs the handler behind the request ID, error and recovery middleware, and returns the
+// response and what was logged
+func serve(t *testing.T, handler gin.HandlerFunc) (*httptest.ResponseRecorder, string) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	var logs bytes.Buffer
+	logger, err := logging.New(config.LogConfig{Level: "info", Format: "text"}, &logs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := gin.New()
+	router.Use(RequestIDMiddleware(), ErrorMiddleware(logger), RecoveryMiddleware())
+	router.GET("/", handler)
+
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.Header.Set(RequestIDHeader, "req-1")
+	router.ServeHTTP(recorder, request)
+	return recorder, logs.String()
+}
+
+func decodeEnvelope(t *testing.T, recorder *httptest.ResponseRecorder) apierror.Response {
+	t.Helper()
+	var response apierror.Response
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decode %q: %v", recorder.Body.String(), err)
+	}
+	return response
+}
+
+func TestErrorMiddlewareMapsDomainErrors(t *testing.T) {
+	until := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	decision := services.FilterDecision{Stage: "blocklist", Action: services.FilterReject, Reason: "blocked word"}
+
+	for name, test := range map[string]struct {
+		err     error
+		status  int
+		code    apierror.Code
+		message string
+		details any
+	}{
+		"validation": {
+			services.NewValidationError(services.FieldError{Field: "email", Message: "must be an email address"}),
+			http.StatusBadRequest, apierror.CodeValidation, "Validation failed",
+			[]any{map[string]any{"field": "email", "message": "must be an email address"}},
+		},
+		"not found": {
+			&services.Error{Kind: services.ErrNotFound, Message: "user not found"},
+			http.StatusNotFound, apierror.CodeNotFound, "user not found", nil,
+		},
+		"wrapped conflict": {
+			fmt.Errorf("create: %w", &services.Error{Kind: services.ErrConflict, Message: "username already exists"}),
+			http.StatusConflict, apierror.CodeConflict, "username already exists", nil,
+		},
+		"forbidden": {
+			&services.Error{Kind: services.ErrForbidden, Message: "you can only update your own account"},
+			http.StatusForbidden, apierror.CodeForbidden, "you can only update your own account", nil,
+		},
+		"rejected message": {
+			&services.MessageRejectedError{Decision: decision},
+			http.StatusUnprocessableEntity, apierror.CodeMessageRejected, "Message rejected by the content filter",
+			map[string]any{"stage": "blocklist", "action": "reject", "reason": "blocked word"},
+		},
+		"locked account": {
+			&services.AccountLockedError{RetryAfter: time.Minute},
+			http.StatusTooManyRequests, apierror.CodeTooManyRequests, "Too many failed login attempts, try again later", nil,
+		},
+		"suspended account": {
+			&services.AccountSuspendedError{Until: until},
+			http.StatusForbidden, apierror.CodeAccountSuspended, "Account is suspended",
+			map[string]any{"suspended_until": "2030-01-01T00:00:00Z"},
+		},
+		"revoked session": {
+			services.ErrSessionRevoked,
+			http.StatusUnauthorized, apierror.CodeUnauthorized, "Session expired or revoked, please log in again", nil,
+		},
+		"invalid credentials": {
+			services.ErrInvalidCredentials,
+			http.StatusUnauthorized, apierror.CodeUnauthorized, "Invalid username or password", nil,
+		},
+		"failed single sign-on": {
+			fmt.Errorf("%w: state mismatch", services.ErrOIDCExchangeFailed),
+			http.StatusUnauthorized, apierror.CodeUnauthorized, "Single sign-on failed", nil,
+		},
+		"API error": {
+			apierror.BadRequest("Invalid message payload"),
+			http.StatusBadRequest, apierror.CodeBadRequest, "Invalid message payload", nil,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			recorder, logs := serve(t, func(c *gin.Context) { _ = c.Error(test.err) })
+
+			response := decodeEnvelope(t, recorder)
+			if recorder.Code != test.status || response.Code != test.code || response.Message != test.message || response.RequestID != "req-1" {
+				t.Errorf("got %d %+v, want %d with code %s and message %q", recorder.Code, response, test.status, test.code, test.message)
+			}
+			if !reflect.DeepEqual(response.Details, test.details) {
+				t.Errorf("got details %#v, want %#v", response.Details, test.details)
+			}
+			if logs != "" {
+				t.Errorf("a client error was logged: %s", logs)
+			}
+		})
+	}
+}
+
+func TestErrorMiddlewareHidesInternalErrors(t *testing.T) {
+	const cause = `ERROR: relation "users" does not exist (SQLSTATE 42P01)`
+	for name, handler := range map[string]gin.HandlerFunc{
+		"unknown error": func(c *gin.Context) { _ = c.Error(errors.New(cause)) },
+		"panic":         func(c *gin.Context) { panic(cause) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			recorder, logs := serve(t, handler)
+
+			response := decodeEnvelope(t, recorder)
+			if recorder.Code != http.StatusInternalServerError || response.Code != apierror.CodeInternal || response.Message != "Internal server error" {
+				t.Errorf("got %d %+v, want an internal error", recorder.Code, response)
+			}
+			if strings.Contains(recorder.Body.String(), "SQLSTATE") {
+				t.Errorf("the cause reached the client: %s", recorder.Body.String())
+			}
+			if !strings.Contains(logs, "request failed") || !strings.Contains(logs, "SQLSTATE") || !strings.Contains(logs, "request_id=req-1") {
+				t.Errorf("the cause was not logged with the request ID: %s", logs)
+			}
+		})
+	}
+}
+
+func TestErrorMiddlewareKeepsAWrittenResponse(t *testing.T) {
+	recorder, _ := serve(t, func(c *gin.Context) {
+		c.JSON(http.StatusAccepted, gin.H{"status": "queued"})
+		_ = c.Error(errors.New("notification failed"))
+	})
+	if recorder.Code != http.StatusAccepted || strings.TrimSpace(recorder.Body.String()) != `{"status":"queued"}` {
+		t.Errorf("got %d %s, want the response the handler wrote", recorder.Code, recorder.Body.String())
+	}
+}
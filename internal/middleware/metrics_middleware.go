@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"chat-app-api/internal/metrics"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MetricsMiddleware counts requests and records their latency by route template,
+// so /users/1 and /users/2 share one series
+func MetricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+
+		metrics.HTTPRequests.WithLabelValues(c.Request.Method, route, status).Inc()
+
+		// The handler of an upgraded WebSocket only returns when the connection closes
+		if c.IsWebsocket() {
+			return
+		}
+		metrics.HTTPRequestDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"chat-app-api/internal/apierror"
+	"chat-app-api/internal/metrics"
+	"chat-app-api/internal/ratelimit"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitHeaders are set on rate limited responses; browsers only let scripts read them once exposed by CORS
+var RateLimitHeaders = []string{"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "Retry-After"}
+
+// RateLimit lets each client take the policy's burst of requests, refilled over its period.
+// Clients are told apart by user ID when the route is authenticated and by IP otherwise.
+// Requests are let through when the store is unavailable, so an outage does not take the API down.
+func RateLimit(store ratelimit.Store, policy ratelimit.Policy, logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := "ip:" + c.ClientIP()
+		if userID := c.GetString("UserID"); userID != "" {
+			key = "user:" + userID
+		}
+
+		result, err := store.Take(c.Request.Context(), key, policy, time.Now())
+		if err != nil {
+			logger.WarnContext(c.Request.Context(), "rate limit store unavailable", "policy", policy.Name, "error", err)
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", seconds(result.Reset))
+		c.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%s", policy.Limit, seconds(policy.Period)))
+		if !result.Allowed {
+			metrics.RateLimited.WithLabelValues(policy.Name).Inc()
+			c.Header("Retry-After", seconds(result.RetryAfter))
+			abortWithError(c, apierror.New(http.StatusTooManyRequests, apierror.CodeTooManyRequests, "Too many requests, try again later"))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// seconds rounds up, so a client that waits that long is never refused again
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
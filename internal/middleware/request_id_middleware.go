@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"chat-app-api/internal/logging"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// validRequestID limits propagated IDs to what is safe to log and echo back
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestIDMiddleware keeps the X-Request-ID sent by a proxy or client, or assigns a new one,
+// echoes it in the response and stores it in the request context for logging
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			requestID = logging.NewID()
+		}
+
+		c.Header(RequestIDHeader, requestID)
+		c.Set("RequestID", requestID)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), requestID))
+		trace.SpanFromContext(c.Request.Context()).SetAttributes(attribute.String("http.request_id", requestID))
+
+		c.Next()
+	}
+}
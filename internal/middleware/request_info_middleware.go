@@ -0,0 +1,16 @@
+package middleware
+
+import (
+	"chat-app-api/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestInfoMiddleware stores the client IP and user agent in the request context for the audit log
+func RequestInfoMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		info := services.RequestInfo{ClientIP: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+		c.Request = c.Request.WithContext(services.WithRequestInfo(c.Request.Context(), info))
+		c.Next()
+	}
+}
@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"chat-app-api/internal/apierror"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireRole only lets through users with one of the roles; it must run after AuthMiddleware.
+// A role change revokes the user's tokens, so the role in the token is current.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !slices.Contains(roles, c.GetString("Role")) {
+			abortWithError(c, apierror.Forbidden("You do not have permission to access this resource"))
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Outcomes of an audited action
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// AuditEvent records a security-relevant action; events are only ever appended.
+// User IDs are not foreign keys so the events outlive the accounts they mention.
+type AuditEvent struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	Action    string         `gorm:"not null;index" json:"action"`
+	Outcome   string         `gorm:"not null" json:"outcome"`
+	ActorID   *uint          `gorm:"index" json:"actor_id,omitempty"`  // the user who acted, if known
+	TargetID  *uint          `gorm:"index" json:"target_id,omitempty"` // the user acted upon
+	IP        string         `json:"ip,omitempty"`
+	UserAgent string         `json:"user_agent,omitempty"`
+	RequestID string         `json:"request_id,omitempty"`
+	Details   map[string]any `gorm:"serializer:json" json:"details,omitempty"`
+	CreatedAt time.Time      `gorm:"autoCreateTime;index" json:"created_at"`
+}
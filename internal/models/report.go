@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+// What a report is about
+const (
+	ReportTargetMessage = "message"
+	ReportTargetUser    = "user"
+)
+
+// Reasons a message or account can be reported for
+const (
+	ReportReasonSpam          = "spam"
+	ReportReasonHarassment    = "harassment"
+	ReportReasonHateSpeech    = "hate_speech"
+	ReportReasonViolence      = "violence"
+	ReportReasonSexualContent = "sexual_content"
+	ReportReasonImpersonation = "impersonation"
+	ReportReasonOther         = "other"
+
+	// ReportReasonFlagged is given by the message filter, never by users
+	ReportReasonFlagged = "flagged"
+)
+
+// Open reports wait in the moderation queue until a moderator actions or dismisses them
+const (
+	ReportStatusOpen      = "open"
+	ReportStatusActioned  = "actioned"
+	ReportStatusDismissed = "dismissed"
+)
+
+// Moderation actions taken when a report is actioned
+const (
+	ModerationRemoveMessage = "remove_message"
+	ModerationWarn          = "warn"
+	ModerationSuspend       = "suspend"
+)
+
+// Report is a user's complaint about a message or another account, or a message flagged by the message filter
+type Report struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	ReporterID     *uint      `gorm:"index" json:"reporter_id,omitempty"` // nil for flagged messages and once the reporter is purged
+	TargetType     string     `gorm:"not null" json:"target_type"`
+	MessageID      *uint      `gorm:"index" json:"message_id,omitempty"`       // cleared once the message is removed
+	ReportedUserID uint       `gorm:"index" json:"reported_user_id,omitempty"` // the reported account or the author of the message; zero once purged
+	MessageContent string     `json:"message_content,omitempty"`               // the message as it was when reported
+	Reason         string     `gorm:"not null" json:"reason"`
+	Comment        string     `json:"comment,omitempty"`
+	Status         string     `gorm:"not null;index" json:"status"`
+	Actions        []string   `gorm:"serializer:json" json:"actions,omitempty"`
+	ModeratorNote  string     `json:"moderator_note,omitempty"`
+	ResolvedByID   *uint      `json:"resolved_by_id,omitempty"`
+	ResolvedAt     *time.Time `json:"resolved_at,omitempty"`
+	CreatedAt      time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt      time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (r *Report) IsOpen() bool {
+	return r.Status == ReportStatusOpen
+}
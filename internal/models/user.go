@@ -1,15 +1,46 @@
 package models
 
-import "time"
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// Roles a user can have; admins may use the /api/admin endpoints
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// DeletedUserName is shown in conversations in place of the name of a deleted user
+const DeletedUserName = "Deleted user"
 
 type User struct {
-	ID              uint      `gorm:"primaryKey" json:"id"`
-	Username        string    `gorm:"unique; not null" json:"username"`
-	FirstName       string    `json:"first_name"`
-	LastName        string    `json:"last_name"`
-	Email           string    `gorm:"unique;not null" json:"email"`
-	ProfileImageUrl string    `json:"profile_image"`
-	Password        string    `gorm:"not null" json:"password"`
-	CreatedAt       time.Time `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt       time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+	ID              uint           `gorm:"primaryKey" json:"id"`
+	Username        string         `gorm:"unique; not null" json:"username"`
+	FirstName       string         `json:"first_name"`
+	LastName        string         `json:"last_name"`
+	Email           string         `gorm:"unique;not null" json:"email"`
+	ProfileImageUrl string         `json:"profile_image"`
+	Password        string         `gorm:"not null" json:"-"` // hash, never serialized
+	Role            string         `gorm:"not null;default:user" json:"role"`
+	SuspendedUntil  *time.Time     `json:"suspended_until,omitempty"`
+	TokenVersion    int            `gorm:"not null;default:0" json:"-"` // bumped to revoke every token issued so far
+	CreatedAt       time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt       time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `gorm:"index" json:"deleted_at"` // set on deletion, the row is purged after a grace period
+}
+
+// IsSuspended reports whether the account is suspended at the given time
+func (u *User) IsSuspended(at time.Time) bool {
+	return u.SuspendedUntil != nil && u.SuspendedUntil.After(at)
+}
+
+// IsDeleted reports whether the account was deleted and is waiting to be purged
+func (u *User) IsDeleted() bool {
+	return u.DeletedAt.Valid
+}
+
+// Tombstone returns the profile shown in place of a deleted user: the ID and nothing that identifies them
+func (u *User) Tombstone() User {
+	return User{ID: u.ID, FirstName: DeletedUserName, DeletedAt: u.DeletedAt}
 }
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// UserIdentity links a user to an account at an external OIDC provider
+type UserIdentity struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"not null;index" json:"user_id"`
+	User      User      `gorm:"foreignKey:UserID" json:"-"`
+	Provider  string    `gorm:"not null;uniqueIndex:idx_user_identities_provider_subject" json:"provider"`
+	Subject   string    `gorm:"not null;uniqueIndex:idx_user_identities_provider_subject" json:"subject"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
@@ -0,0 +1,54 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepEvery is how many takes pass between removals of buckets that are full again
+const sweepEvery = 1024
+
+// MemoryStore keeps the buckets in this process; each instance limits on its own
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*memoryBucket
+	takes   int
+}
+
+type memoryBucket struct {
+	bucket
+	full time.Time // a full bucket is the same as no bucket, so it can be dropped
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*memoryBucket)}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, policy Policy, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.takes++
+	if s.takes%sweepEvery == 0 {
+		s.sweep(now)
+	}
+
+	key = bucketKey(policy, key)
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &memoryBucket{}
+		s.buckets[key] = b
+	}
+	result := policy.take(&b.bucket, now)
+	b.full = now.Add(result.Reset)
+	return result, nil
+}
+
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if !now.Before(b.full) {
+			delete(s.buckets, key)
+		}
+	}
+}
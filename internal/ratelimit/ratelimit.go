@@ -0,0 +1,104 @@
+// Package ratelimit implements token bucket rate limiting with buckets kept in memory or in Redis
+package ratelimit
+
+import (
+	"chat-app-api/internal/config"
+	"context"
+	"math"
+	"time"
+)
+
+// Policy refills a bucket of Limit tokens at Limit tokens per Period; every request takes one
+type Policy struct {
+	Name   string // namespaces the buckets of the policy
+	Limit  int
+	Period time.Duration
+}
+
+func NewPolicy(name string, rate config.RatePolicy) Policy {
+	return Policy{Name: name, Limit: rate.Requests, Period: rate.Period}
+}
+
+// perMillisecond is the refill rate
+func (p Policy) perMillisecond() float64 {
+	return float64(p.Limit) / float64(p.Period.Milliseconds())
+}
+
+// Result is the state of a bucket after a request tried to take a token
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int           // whole tokens left
+	Reset      time.Duration // until the bucket is full again
+	RetryAfter time.Duration // until the next token, zero when the request was allowed
+}
+
+// result describes a bucket left with the given tokens
+func (p Policy) result(allowed bool, tokens float64) Result {
+	rate := p.perMillisecond()
+	result := Result{
+		Allowed:   allowed,
+		Limit:     p.Limit,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     milliseconds((float64(p.Limit) - tokens) / rate),
+	}
+	if !allowed {
+		result.RetryAfter = milliseconds((1 - tokens) / rate)
+	}
+	return result
+}
+
+func milliseconds(ms float64) time.Duration {
+	return time.Duration(math.Ceil(ms)) * time.Millisecond
+}
+
+// Store keeps the buckets of every policy and key. Take refills the bucket for the time since
+// it was last used and takes a token if there is one. A store shared between instances may
+// go by its own clock rather than now.
+type Store interface {
+	Take(ctx context.Context, key string, policy Policy, now time.Time) (Result, error)
+}
+
+// bucketKey keeps the buckets of different policies apart
+func bucketKey(policy Policy, key string) string {
+	return "ratelimit:" + policy.Name + ":" + key
+}
+
+// bucket is the state of one token bucket
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// take refills the bucket and takes a token when there is a whole one left
+func (p Policy) take(b *bucket, now time.Time) Result {
+	if b.updated.IsZero() {
+		b.tokens = float64(p.Limit)
+	} else if elapsed := now.Sub(b.updated); elapsed > 0 {
+		b.tokens = math.Min(float64(p.Limit), b.tokens+float64(elapsed)/float64(time.Millisecond)*p.perMillisecond())
+	}
+	if now.After(b.updated) {
+		b.updated = now
+	}
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	return p.result(allowed, b.tokens)
+}
+
+// Bucket is the token bucket of a single client, such as one WebSocket connection.
+// It is not safe for concurrent use.
+type Bucket struct {
+	policy Policy
+	state  bucket
+}
+
+func NewBucket(policy Policy) *Bucket {
+	return &Bucket{policy: policy}
+}
+
+func (b *Bucket) Take(now time.Time) Result {
+	return b.policy.take(&b.state, now)
+}
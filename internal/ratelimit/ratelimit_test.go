@@ -0,0 +1,109 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+var testPolicy = Policy{Name: "test", Limit: 3, Period: 3 * time.Second}
+
+// runStoreContract checks the behaviour every Store must have
+func runStoreContract(t *testing.T, newStore func(t *testing.T) Store) {
+	tests := map[string]func(t *testing.T, store Store){
+		"BurstThenRefill":       testBurstThenRefill,
+		"KeysAndPoliciesApart":  testKeysAndPoliciesApart,
+		"RefillStopsAtTheLimit": testRefillStopsAtTheLimit,
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			test(t, newStore(t))
+		})
+	}
+}
+
+func take(t *testing.T, store Store, key string, policy Policy, now time.Time) Result {
+	t.Helper()
+	result, err := store.Take(context.Background(), key, policy, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func testBurstThenRefill(t *testing.T, store Store) {
+	now := time.Now()
+	for i := 2; i >= 0; i-- {
+		result := take(t, store, "alice", testPolicy, now)
+		if !result.Allowed || result.Remaining != i || result.Limit != 3 {
+			t.Fatalf("request %d: got %+v, want allowed with %d remaining", 3-i, result, i)
+		}
+	}
+	if result := take(t, store, "alice", testPolicy, now); result.Allowed || result.RetryAfter != time.Second || result.Reset != 3*time.Second {
+		t.Errorf("got %+v, want denied for a second", result)
+	}
+
+	// One token comes back per second
+	if result := take(t, store, "alice", testPolicy, now.Add(500*time.Millisecond)); result.Allowed || result.RetryAfter != 500*time.Millisecond {
+		t.Errorf("got %+v, want denied for another half second", result)
+	}
+	if result := take(t, store, "alice", testPolicy, now.Add(time.Second)); !result.Allowed || result.Remaining != 0 {
+		t.Errorf("got %+v, want allowed after a second", result)
+	}
+}
+
+func testKeysAndPoliciesApart(t *testing.T, store Store) {
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		take(t, store, "alice", testPolicy, now)
+	}
+
+	if result := take(t, store, "bob", testPolicy, now); !result.Allowed {
+		t.Errorf("bob got %+v, want his own bucket", result)
+	}
+	other := Policy{Name: "other", Limit: 1, Period: time.Minute}
+	if result := take(t, store, "alice", other, now); !result.Allowed {
+		t.Errorf("alice got %+v under another policy, want its own bucket", result)
+	}
+}
+
+func testRefillStopsAtTheLimit(t *testing.T, store Store) {
+	now := time.Now()
+	take(t, store, "alice", testPolicy, now)
+
+	if result := take(t, store, "alice", testPolicy, now.Add(time.Hour)); !result.Allowed || result.Remaining != 2 {
+		t.Errorf("got %+v, want a full bucket minus this request", result)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	runStoreContract(t, func(t *testing.T) Store { return NewMemoryStore() })
+}
+
+func TestMemoryStoreDropsFullBuckets(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Now()
+	take(t, store, "alice", testPolicy, now)
+
+	for i := 1; i < sweepEvery; i++ {
+		take(t, store, "bob", testPolicy, now.Add(time.Hour))
+	}
+	if _, ok := store.buckets[bucketKey(testPolicy, "alice")]; ok {
+		t.Error("alice's bucket is still kept after it filled up again")
+	}
+}
+
+func TestBucket(t *testing.T) {
+	bucket := NewBucket(Policy{Name: "connection", Limit: 2, Period: time.Second})
+	now := time.Now()
+
+	if !bucket.Take(now).Allowed || !bucket.Take(now).Allowed {
+		t.Fatal("the burst was not allowed")
+	}
+	if result := bucket.Take(now); result.Allowed || result.RetryAfter != 500*time.Millisecond {
+		t.Errorf("got %+v, want denied for half a second", result)
+	}
+	if !bucket.Take(now.Add(500 * time.Millisecond)).Allowed {
+		t.Error("the refilled token was not allowed")
+	}
+}
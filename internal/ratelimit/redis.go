@@ -0,0 +1,84 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// tokenBucketScript is the bucket update of Policy.take, run atomically by Redis so every
+// instance sees the same buckets. The time is Redis's own, so instances whose clocks disagree
+// still refill buckets alike. KEYS[1] is the bucket; ARGV holds the limit and the refill rate per
+// millisecond. It returns whether a token was taken and the tokens left, as a string because
+// Redis truncates numbers returned by scripts.
+var tokenBucketScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local clock = redis.call('TIME')
+local now = tonumber(clock[1]) * 1000 + math.floor(tonumber(clock[2]) / 1000)
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'updated')
+local tokens = tonumber(state[1])
+local updated = tonumber(state[2])
+if tokens == nil then
+	tokens, updated = limit, now
+elseif now > updated then
+	tokens = math.min(limit, tokens + (now - updated) * rate)
+	updated = now
+end
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'updated', updated)
+redis.call('PEXPIRE', KEYS[1], math.max(1, math.ceil((limit - tokens) / rate)))
+return {allowed, tostring(tokens)}
+`)
+
+// redisTimeout bounds a command when the context has no earlier deadline
+const redisTimeout = time.Second
+
+// RedisStore keeps the buckets in Redis, shared by every instance. Buckets expire once they
+// are full again, so Redis only holds the clients that were limited recently.
+type RedisStore struct {
+	client *redis.Client
+}
+
+func NewRedisStore(addr, password string) *RedisStore {
+	return &RedisStore{client: redis.NewClient(&redis.Options{
+		Addr:         addr,
+		Password:     password,
+		DialTimeout:  redisTimeout,
+		ReadTimeout:  redisTimeout,
+		WriteTimeout: redisTimeout,
+	})}
+}
+
+// Take ignores now and refills the bucket by the Redis clock
+func (s *RedisStore) Take(ctx context.Context, key string, policy Policy, now time.Time) (Result, error) {
+	// EVALSHA, or EVAL when the script cache is empty after a restart
+	reply, err := tokenBucketScript.Run(ctx, s.client, []string{bucketKey(policy, key)},
+		policy.Limit, strconv.FormatFloat(policy.perMillisecond(), 'g', -1, 64)).Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("redis rate limit: %w", err)
+	}
+
+	if len(reply) != 2 {
+		return Result{}, fmt.Errorf("redis rate limit: unexpected reply %v", reply)
+	}
+	allowed, _ := reply[0].(int64)
+	tokensText, _ := reply[1].(string)
+	tokens, err := strconv.ParseFloat(tokensText, 64)
+	if err != nil {
+		return Result{}, fmt.Errorf("redis rate limit: unexpected token count %q", tokensText)
+	}
+	return policy.result(allowed == 1, tokens), nil
+}
+
+// Close closes the connections to Redis
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
@@ -0,0 +1,122 @@
+package ratelimit
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// TestRedisStore runs the contract, and so the token bucket script, on miniredis with its clock
+// set to the time of each request. Point TEST_REDIS_ADDR at a disposable Redis to also check
+// the script against a real one, which keeps its own time.
+func TestRedisStore(t *testing.T) {
+	runStoreContract(t, func(t *testing.T) Store {
+		server := miniredis.RunT(t)
+		server.RequireAuth("secret")
+		store := NewRedisStore(server.Addr(), "secret")
+		t.Cleanup(func() { store.Close() })
+		return clockedStore{store: store, server: server}
+	})
+
+	addr := os.Getenv("TEST_REDIS_ADDR")
+	if addr == "" {
+		t.Log("TEST_REDIS_ADDR is not set, skipping the real Redis")
+		return
+	}
+	t.Run("Real", func(t *testing.T) {
+		store := NewRedisStore(addr, os.Getenv("TEST_REDIS_PASSWORD"))
+		defer store.Close()
+		// A policy of its own, so old buckets do not get in the way
+		policy := Policy{Name: "test" + strconv.FormatInt(time.Now().UnixNano(), 10), Limit: 2, Period: time.Minute}
+
+		for i := 1; i >= 0; i-- {
+			if result := take(t, store, "alice", policy, time.Now()); !result.Allowed || result.Remaining != i {
+				t.Fatalf("got %+v, want allowed with %d remaining", result, i)
+			}
+		}
+		if result := take(t, store, "alice", policy, time.Now()); result.Allowed || result.RetryAfter <= 0 || result.RetryAfter > 30*time.Second {
+			t.Errorf("got %+v, want denied until the next token", result)
+		}
+	})
+}
+
+func TestRedisStoreUsesTheRedisClock(t *testing.T) {
+	server := miniredis.RunT(t)
+	store := NewRedisStore(server.Addr(), "")
+	defer store.Close()
+	now := time.Now()
+
+	server.SetTime(now)
+	for i := 0; i < 3; i++ {
+		take(t, store, "alice", testPolicy, now)
+	}
+	// An instance whose clock is an hour ahead does not refill the bucket
+	if result := take(t, store, "alice", testPolicy, now.Add(time.Hour)); result.Allowed {
+		t.Errorf("got %+v, want the bucket still empty by the Redis clock", result)
+	}
+	server.SetTime(now.Add(time.Second))
+	if result := take(t, store, "alice", testPolicy, now); !result.Allowed {
+		t.Errorf("got %+v, want a token back a second later by the Redis clock", result)
+	}
+}
+
+func TestRedisStoreExpiresFullBuckets(t *testing.T) {
+	server := miniredis.RunT(t)
+	store := NewRedisStore(server.Addr(), "")
+	defer store.Close()
+
+	take(t, store, "alice", testPolicy, time.Now())
+	key := bucketKey(testPolicy, "alice")
+	if ttl := server.TTL(key); ttl <= 0 || ttl > time.Second {
+		t.Fatalf("the bucket expires in %v, want once its token is back", ttl)
+	}
+	server.FastForward(time.Second)
+	if server.Exists(key) {
+		t.Error("the bucket is still kept after it filled up again")
+	}
+}
+
+func TestRedisStoreLoadsTheScriptAgain(t *testing.T) {
+	server := miniredis.RunT(t)
+	store := NewRedisStore(server.Addr(), "")
+	defer store.Close()
+	now := time.Now()
+	server.SetTime(now)
+
+	take(t, store, "alice", testPolicy, now)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+	if err := client.ScriptFlush(context.Background()).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if result := take(t, store, "alice", testPolicy, now); !result.Allowed || result.Remaining != 1 {
+		t.Errorf("got %+v after the script cache was emptied, want the same bucket", result)
+	}
+}
+
+func TestRedisStoreRejectsWrongPassword(t *testing.T) {
+	server := miniredis.RunT(t)
+	server.RequireAuth("secret")
+	store := NewRedisStore(server.Addr(), "wrong")
+	defer store.Close()
+
+	if _, err := store.Take(context.Background(), "alice", testPolicy, time.Now()); err == nil {
+		t.Error("got no error with the wrong password")
+	}
+}
+
+// clockedStore sets the miniredis clock to the time of each request, which the store ignores
+type clockedStore struct {
+	store  Store
+	server *miniredis.Miniredis
+}
+
+func (s clockedStore) Take(ctx context.Context, key string, policy Policy, now time.Time) (Result, error) {
+	s.server.SetTime(now)
+	return s.store.Take(ctx, key, policy, now)
+}
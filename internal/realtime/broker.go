@@ -0,0 +1,55 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// Kinds of events hubs exchange through the broker
+const (
+	EventSend       = "send"       // deliver the payload to every connection of the user
+	EventDisconnect = "disconnect" // close every connection of the user, whose session was revoked
+)
+
+// Event is what a hub publishes so the hubs of other instances act on their own connections too
+type Event struct {
+	Origin  string          `json:"origin"` // the hub that published it, which has already acted on it
+	Kind    string          `json:"kind"`
+	UserID  uint            `json:"user_id"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// Broker carries events between the hubs of every instance. Subscribers receive every published
+// event, including their own, in the order each publisher published them.
+type Broker interface {
+	Publish(ctx context.Context, event Event) error
+	Subscribe(handler func(Event))
+}
+
+// LocalBroker connects the hubs of one process; with a single hub it has nothing to carry
+type LocalBroker struct {
+	mu       sync.RWMutex
+	handlers []func(Event)
+}
+
+func NewLocalBroker() *LocalBroker {
+	return &LocalBroker{}
+}
+
+func (b *LocalBroker) Publish(ctx context.Context, event Event) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, handler := range b.handlers {
+		handler(event)
+	}
+	return nil
+}
+
+func (b *LocalBroker) Subscribe(handler func(Event)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.handlers = append(b.handlers, handler)
+}
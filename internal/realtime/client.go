@@ -0,0 +1,123 @@
+package realtime
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// writeWait is the time allowed to write a single message to the peer
+	writeWait = 10 * time.Second
+
+	// sendQueueSize is how many outbound messages may wait for a slow client before it is dropped
+	sendQueueSize = 64
+
+	// maxMessageSize bounds an inbound frame; larger ones close the connection before they are buffered
+	maxMessageSize = 64 * 1024
+)
+
+// Client is one WebSocket connection of a user; a user may have several, one per tab or device
+type Client struct {
+	ID     string // identifies the connection in logs
+	UserID uint
+
+	conn        *websocket.Conn
+	logger      *slog.Logger
+	send        chan []byte
+	quit        chan struct{}
+	done        chan struct{}
+	closeOnce   sync.Once
+	closeCode   int
+	closeReason string
+}
+
+func newClient(id string, userID uint, conn *websocket.Conn, logger *slog.Logger) *Client {
+	conn.SetReadLimit(maxMessageSize)
+	return &Client{
+		ID:     id,
+		UserID: userID,
+		conn:   conn,
+		logger: logger.With("conn_id", id, "user_id", userID),
+		send:   make(chan []byte, sendQueueSize),
+		quit:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+// Logger returns a logger that tags every record with the connection and user ID
+func (c *Client) Logger() *slog.Logger {
+	return c.logger
+}
+
+// ReadMessage reads the next data message from the connection; only the connection's reader may call it
+func (c *Client) ReadMessage() ([]byte, error) {
+	_, data, err := c.conn.ReadMessage()
+	return data, err
+}
+
+// enqueue queues a message without blocking and reports whether there was room for it
+func (c *Client) enqueue(message []byte) bool {
+	select {
+	case <-c.quit:
+		return false
+	default:
+	}
+
+	select {
+	case c.send <- message:
+		return true
+	default:
+		return false
+	}
+}
+
+// writePump is the only goroutine writing to the connection. When the client is closed it
+// flushes what is still queued, sends the close frame and closes the connection.
+func (c *Client) writePump() {
+	defer close(c.done)
+	defer c.conn.Close()
+
+	for {
+		select {
+		case message := <-c.send:
+			if err := c.write(websocket.TextMessage, message); err != nil {
+				c.logger.Warn("error writing to websocket", "error", err)
+				c.close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+		case <-c.quit:
+			if c.closeCode == websocket.CloseAbnormalClosure {
+				return
+			}
+			for {
+				select {
+				case message := <-c.send:
+					if err := c.write(websocket.TextMessage, message); err != nil {
+						return
+					}
+				default:
+					_ = c.write(websocket.CloseMessage, websocket.FormatCloseMessage(c.closeCode, c.closeReason))
+					return
+				}
+			}
+		}
+	}
+}
+
+func (c *Client) write(messageType int, data []byte) error {
+	_ = c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	return c.conn.WriteMessage(messageType, data)
+}
+
+// close asks the write pump to close the connection with the given close code;
+// CloseAbnormalClosure drops the connection without a close frame
+func (c *Client) close(code int, reason string) {
+	c.closeOnce.Do(func() {
+		c.closeCode = code
+		c.closeReason = reason
+		close(c.quit)
+	})
+}
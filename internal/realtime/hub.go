@@ -0,0 +1,315 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"chat-app-api/internal/logging"
+	"github.com/gorilla/websocket"
+)
+
+var ErrShuttingDown = errors.New("server is shutting down")
+
+const (
+	// publishTimeout bounds how long the publisher waits for the broker with one event
+	publishTimeout = 5 * time.Second
+
+	// publishQueueSize is how many events may wait for the broker before new ones are dropped
+	publishQueueSize = 1024
+)
+
+// Hub tracks the WebSocket clients connected to this instance by user ID and delivers messages
+// to them. Sends and disconnects are also published to the broker, so the hubs of other
+// instances reach the connections of the user they hold. Events are published in the
+// background, in order, so a slow broker never holds up the connection that caused them.
+type Hub struct {
+	id             string // tells this hub's events apart from those of other instances
+	broker         Broker
+	mu             sync.RWMutex
+	clients        map[uint]map[*Client]struct{}
+	closing        bool
+	inFlight       sync.WaitGroup
+	queue          chan Event
+	stopPublishing context.CancelFunc
+	publisherDone  chan struct{}
+	logger         *slog.Logger
+}
+
+func NewHub(broker Broker, logger *slog.Logger) *Hub {
+	ctx, cancel := context.WithCancel(context.Background())
+	h := &Hub{
+		id:             logging.NewID(),
+		broker:         broker,
+		clients:        make(map[uint]map[*Client]struct{}),
+		queue:          make(chan Event, publishQueueSize),
+		stopPublishing: cancel,
+		publisherDone:  make(chan struct{}),
+		logger:         logger,
+	}
+	broker.Subscribe(h.receive)
+	go h.publishLoop(ctx)
+	return h
+}
+
+// Register starts tracking a new connection of the user and starts its writer
+func (h *Hub) Register(userID uint, conn *websocket.Conn) (*Client, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closing {
+		return nil, ErrShuttingDown
+	}
+
+	client := newClient(logging.NewID(), userID, conn, h.logger)
+	if h.clients[userID] == nil {
+		h.clients[userID] = make(map[*Client]struct{})
+	}
+	h.clients[userID][client] = struct{}{}
+
+	go client.writePump()
+	return client, nil
+}
+
+// Unregister stops tracking the connection and closes it
+func (h *Hub) Unregister(client *Client) {
+	h.Disconnect(client, websocket.CloseNormalClosure, "")
+}
+
+// Disconnect stops tracking the connection and closes it with the given close code and reason
+func (h *Hub) Disconnect(client *Client, code int, reason string) {
+	h.mu.Lock()
+	if connections, ok := h.clients[client.UserID]; ok {
+		delete(connections, client)
+		if len(connections) == 0 {
+			delete(h.clients, client.UserID)
+		}
+	}
+	h.mu.Unlock()
+
+	client.close(code, reason)
+}
+
+// SendToUser delivers the value as JSON to every connection of the user, on every instance.
+// It reports whether any was connected to this one: the other instances deliver later and do
+// not answer, so false does not mean the user is offline when several instances run.
+func (h *Hub) SendToUser(userID uint, v interface{}) bool {
+	message, err := json.Marshal(v)
+	if err != nil {
+		h.logger.Error("error encoding message", "user_id", userID, "error", err)
+		return false
+	}
+
+	delivered := h.deliver(userID, message)
+	h.publish(Event{Kind: EventSend, UserID: userID, Payload: message})
+	return delivered
+}
+
+// deliver queues the message to the connections of the user on this instance
+func (h *Hub) deliver(userID uint, message []byte) bool {
+	h.mu.RLock()
+	var slow []*Client
+	delivered := false
+	for client := range h.clients[userID] {
+		if client.enqueue(message) {
+			delivered = true
+		} else {
+			slow = append(slow, client)
+		}
+	}
+	h.mu.RUnlock()
+
+	// A client whose queue is full cannot keep up; drop it so it reconnects and refetches
+	for _, client := range slow {
+		client.logger.Warn("dropping slow connection", "queued", len(client.send))
+		h.Unregister(client)
+	}
+
+	return delivered
+}
+
+// SendToClient delivers the value as JSON to one connection, dropping it when it cannot keep up
+func (h *Hub) SendToClient(client *Client, v interface{}) bool {
+	message, err := json.Marshal(v)
+	if err != nil {
+		client.logger.Error("error encoding message", "error", err)
+		return false
+	}
+
+	if !client.enqueue(message) {
+		client.logger.Warn("dropping slow connection", "queued", len(client.send))
+		h.Unregister(client)
+		return false
+	}
+	return true
+}
+
+// DisconnectUser closes every connection of the user, whose session was revoked, on every instance.
+// It returns how many were connected to this one.
+func (h *Hub) DisconnectUser(userID uint) int {
+	closed := h.disconnectUser(userID)
+	h.publish(Event{Kind: EventDisconnect, UserID: userID})
+	return closed
+}
+
+func (h *Hub) disconnectUser(userID uint) int {
+	h.mu.Lock()
+	connections := h.clients[userID]
+	delete(h.clients, userID)
+	h.mu.Unlock()
+
+	for client := range connections {
+		client.close(websocket.ClosePolicyViolation, "session revoked")
+	}
+	return len(connections)
+}
+
+// publish queues the event for the other instances; delivery there is best effort, like
+// delivery to a slow client, so the event is dropped when the broker is too far behind
+func (h *Hub) publish(event Event) {
+	event.Origin = h.id
+	select {
+	case h.queue <- event:
+	default:
+		h.logger.Warn("dropping event, the broker is not keeping up", "kind", event.Kind, "user_id", event.UserID, "queued", len(h.queue))
+	}
+}
+
+// publishLoop hands queued events to the broker one at a time until ctx is done, then
+// publishes what is still queued
+func (h *Hub) publishLoop(ctx context.Context) {
+	defer close(h.publisherDone)
+	for {
+		select {
+		case event := <-h.queue:
+			h.publishNow(event)
+		case <-ctx.Done():
+			for {
+				select {
+				case event := <-h.queue:
+					h.publishNow(event)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (h *Hub) publishNow(event Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+	defer cancel()
+
+	if err := h.broker.Publish(ctx, event); err != nil {
+		h.logger.Warn("error publishing event to other instances", "kind", event.Kind, "user_id", event.UserID, "error", err)
+	}
+}
+
+// receive acts on an event of another instance; this hub acted on its own events when it published them
+func (h *Hub) receive(event Event) {
+	if event.Origin == h.id {
+		return
+	}
+
+	switch event.Kind {
+	case EventSend:
+		h.deliver(event.UserID, event.Payload)
+	case EventDisconnect:
+		h.disconnectUser(event.UserID)
+	default:
+		h.logger.Warn("ignoring unknown event", "kind", event.Kind, "origin", event.Origin)
+	}
+}
+
+// Stats is a snapshot of the connections and their outbound queues
+type Stats struct {
+	Connections    int
+	QueuedMessages int
+	MaxQueueDepth  int
+}
+
+func (h *Hub) Stats() Stats {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var stats Stats
+	for _, connections := range h.clients {
+		for client := range connections {
+			depth := len(client.send)
+			stats.Connections++
+			stats.QueuedMessages += depth
+			stats.MaxQueueDepth = max(stats.MaxQueueDepth, depth)
+		}
+	}
+	return stats
+}
+
+// BeginWork registers an in-flight operation that shutdown must wait for.
+// It returns false once shutdown has started; otherwise the caller must call the returned function when done.
+func (h *Hub) BeginWork() (func(), bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closing {
+		return nil, false
+	}
+	h.inFlight.Add(1)
+	return h.inFlight.Done, true
+}
+
+// Shutdown stops accepting connections and work, waits for in-flight work to finish and its
+// events to be published, and then tells every client the server is going away so it
+// reconnects to another instance
+func (h *Hub) Shutdown(ctx context.Context) error {
+	h.mu.Lock()
+	h.closing = true
+	h.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		h.inFlight.Wait()
+		close(drained)
+	}()
+
+	var err error
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	h.stopPublishing()
+	select {
+	case <-h.publisherDone:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	h.mu.Lock()
+	var all []*Client
+	for _, connections := range h.clients {
+		for client := range connections {
+			all = append(all, client)
+		}
+	}
+	h.clients = make(map[uint]map[*Client]struct{})
+	h.mu.Unlock()
+
+	for _, client := range all {
+		client.close(websocket.CloseGoingAway, "server shutting down")
+	}
+
+	// Give every write pump the chance to flush its queue and the close frame
+	for _, client := range all {
+		select {
+		case <-client.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,85 @@
+package realtime
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+// gatedBroker holds every publish until the test opens the gate
+type gatedBroker struct {
+	LocalBroker
+	entered chan struct{}
+	gate    chan struct{}
+
+	mu        sync.Mutex
+	published []Event
+}
+
+func newGatedBroker() *gatedBroker {
+	return &gatedBroker{entered: make(chan struct{}, 1), gate: make(chan struct{})}
+}
+
+func (b *gatedBroker) Publish(ctx context.Context, event Event) error {
+	select {
+	case b.entered <- struct{}{}:
+	default:
+	}
+	select {
+	case <-b.gate:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.published = append(b.published, event)
+	return nil
+}
+
+func (b *gatedBroker) events() []Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]Event(nil), b.published...)
+}
+
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func TestHubPublishesInTheBackground(t *testing.T) {
+	broker := newGatedBroker()
+	hub := NewHub(broker, discardLogger)
+
+	// The first event holds up the publisher; sending more does not wait for it, and once the
+	// queue is full the rest are dropped
+	start := time.Now()
+	hub.SendToUser(1, "first")
+	<-broker.entered
+	for i := 0; i < publishQueueSize+10; i++ {
+		hub.SendToUser(uint(2+i), "queued")
+	}
+	hub.DisconnectUser(9999)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("sending took %v while the broker was blocked", elapsed)
+	}
+
+	// Shutdown publishes what is still queued, in order
+	close(broker.gate)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := hub.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	published := broker.events()
+	if len(published) != 1+publishQueueSize {
+		t.Fatalf("published %d events, want the one in flight and a full queue (%d)", len(published), 1+publishQueueSize)
+	}
+	for i, event := range published {
+		if event.Kind != EventSend || event.UserID != uint(1+i) || event.Origin != hub.id {
+			t.Fatalf("event %d: got %s for user %d from %s, want the sends in order", i, event.Kind, event.UserID, event.Origin)
+		}
+	}
+}
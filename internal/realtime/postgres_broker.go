@@ -0,0 +1,231 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"chat-app-api/internal/logging"
+	"github.com/jackc/pgx/v5/stdlib"
+	"gorm.io/gorm"
+)
+
+const (
+	// notifyChannel is the LISTEN/NOTIFY channel every instance listens on
+	notifyChannel = "chat_realtime"
+
+	// maxChunkSize keeps each notification, with its chunk header, under PostgreSQL's 8000 byte payload limit
+	maxChunkSize = 7800
+
+	// maxListenBackoff is the longest wait between attempts to listen again
+	maxListenBackoff = 30 * time.Second
+)
+
+// PostgresBroker carries events between instances with LISTEN/NOTIFY on the database they share.
+// An event larger than one notification is split into chunks sent in a single transaction,
+// which PostgreSQL delivers to each listener together and in order.
+type PostgresBroker struct {
+	db        *gorm.DB
+	logger    *slog.Logger
+	listening atomic.Bool
+	mu        sync.RWMutex
+	handlers  []func(Event)
+}
+
+func NewPostgresBroker(db *gorm.DB, logger *slog.Logger) *PostgresBroker {
+	return &PostgresBroker{db: db, logger: logger}
+}
+
+func (b *PostgresBroker) Publish(ctx context.Context, event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	chunks := splitNotification(string(data), maxChunkSize)
+	if len(chunks) == 1 {
+		return b.db.WithContext(ctx).Exec("SELECT pg_notify(?, ?)", notifyChannel, chunks[0]).Error
+	}
+	return b.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, chunk := range chunks {
+			if err := tx.Exec("SELECT pg_notify(?, ?)", notifyChannel, chunk).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (b *PostgresBroker) Subscribe(handler func(Event)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.handlers = append(b.handlers, handler)
+}
+
+// Listening reports whether events published by other instances are being received
+func (b *PostgresBroker) Listening() bool {
+	return b.listening.Load()
+}
+
+// Run listens on a connection taken from the pool until ctx is done, listening again after a
+// failure. Events published while the listener is down are lost, as for a dropped WebSocket.
+func (b *PostgresBroker) Run(ctx context.Context) {
+	backoff := time.Second
+	for {
+		err := b.listen(ctx, func() { backoff = time.Second })
+		b.listening.Store(false)
+		if ctx.Err() != nil {
+			return
+		}
+
+		b.logger.Warn("realtime listener failed, listening again", "error", err, "retry_in", backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, maxListenBackoff)
+	}
+}
+
+// listen receives notifications until the connection fails or ctx is done
+func (b *PostgresBroker) listen(ctx context.Context, listening func()) error {
+	sqlDB, err := b.db.DB()
+	if err != nil {
+		return err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn any) error {
+		stdlibConn, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return fmt.Errorf("LISTEN needs the pgx driver, got %T", driverConn)
+		}
+		pgConn := stdlibConn.Conn()
+
+		if _, err := pgConn.Exec(ctx, "LISTEN "+notifyChannel); err != nil {
+			return err
+		}
+		// The connection goes back to the pool, where it must not keep collecting notifications
+		defer func() {
+			unlistenCtx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			_, _ = pgConn.Exec(unlistenCtx, "UNLISTEN *")
+		}()
+		listening()
+		b.listening.Store(true)
+		b.logger.Info("realtime listener started", "channel", notifyChannel)
+
+		var chunks chunkAssembler
+		for {
+			notification, err := pgConn.WaitForNotification(ctx)
+			if err != nil {
+				return err
+			}
+
+			data, complete, err := chunks.add(notification.Payload)
+			if err != nil {
+				b.logger.Warn("dropping malformed notification", "error", err)
+				continue
+			}
+			if !complete {
+				continue
+			}
+
+			var event Event
+			if err := json.Unmarshal([]byte(data), &event); err != nil {
+				b.logger.Warn("dropping malformed event", "error", err)
+				continue
+			}
+			b.dispatch(event)
+		}
+	})
+}
+
+func (b *PostgresBroker) dispatch(event Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, handler := range b.handlers {
+		handler(event)
+	}
+}
+
+// splitNotification returns the event as is when it fits in one notification. Otherwise it
+// returns chunks of at most size bytes, cut between UTF-8 characters since a payload must be
+// valid text, each prefixed with "id:index:count:".
+func splitNotification(data string, size int) []string {
+	if len(data) <= size {
+		return []string{data}
+	}
+
+	var parts []string
+	for len(data) > size {
+		cut := size
+		for cut > 0 && !utf8.RuneStart(data[cut]) {
+			cut--
+		}
+		parts = append(parts, data[:cut])
+		data = data[cut:]
+	}
+	parts = append(parts, data)
+
+	id := logging.NewID()
+	for i, part := range parts {
+		parts[i] = fmt.Sprintf("%s:%d:%d:%s", id, i, len(parts), part)
+	}
+	return parts
+}
+
+// chunkAssembler joins the chunks of split events. The chunks of one event arrive one after
+// the other, so only one event is assembled at a time.
+type chunkAssembler struct {
+	id    string
+	parts []string
+	count int
+}
+
+// add returns the event once its last chunk arrived; whole events are returned straight away
+func (a *chunkAssembler) add(payload string) (string, bool, error) {
+	if strings.HasPrefix(payload, "{") {
+		return payload, true, nil
+	}
+
+	header := strings.SplitN(payload, ":", 4)
+	if len(header) != 4 {
+		return "", false, fmt.Errorf("invalid chunk header %.40q", payload)
+	}
+	index, err1 := strconv.Atoi(header[1])
+	count, err2 := strconv.Atoi(header[2])
+	if err1 != nil || err2 != nil || index < 0 || index >= count {
+		return "", false, fmt.Errorf("invalid chunk header %.40q", payload)
+	}
+
+	if index == 0 {
+		a.id, a.parts, a.count = header[0], nil, count
+	}
+	if header[0] != a.id || index != len(a.parts) || count != a.count {
+		a.id, a.parts = "", nil
+		return "", false, fmt.Errorf("chunk %d of event %s arrived out of order", index, header[0])
+	}
+
+	a.parts = append(a.parts, header[3])
+	if len(a.parts) < a.count {
+		return "", false, nil
+	}
+	data := strings.Join(a.parts, "")
+	a.id, a.parts = "", nil
+	return data, true, nil
+}
@@ -0,0 +1,47 @@
+package realtime
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSplitNotification(t *testing.T) {
+	small := `{"kind":"send"}`
+	if chunks := splitNotification(small, 100); len(chunks) != 1 || chunks[0] != small {
+		t.Fatalf("got %q, want the event unchanged", chunks)
+	}
+
+	// Multi-byte characters straddle the chunk boundaries
+	large := `{"payload":"` + strings.Repeat("héllo wörld ✓ ", 50) + `"}`
+	chunks := splitNotification(large, 64)
+	if len(chunks) < 2 {
+		t.Fatalf("got %d chunks, want the event split", len(chunks))
+	}
+
+	var assembler chunkAssembler
+	for i, chunk := range chunks {
+		if !utf8.ValidString(chunk) {
+			t.Fatalf("chunk %d is not valid UTF-8", i)
+		}
+		data, complete, err := assembler.add(chunk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if complete != (i == len(chunks)-1) {
+			t.Fatalf("chunk %d: complete is %v", i, complete)
+		}
+		if complete && data != large {
+			t.Errorf("assembled %q, want the original event", data)
+		}
+	}
+
+	// A chunk from the middle of another event is rejected, and the next whole event still gets through
+	other := splitNotification(large, 64)
+	if _, _, err := assembler.add(other[1]); err == nil {
+		t.Error("a chunk without its first chunk was accepted")
+	}
+	if data, complete, err := assembler.add(small); err != nil || !complete || data != small {
+		t.Errorf("got %q, %v, %v after a lost chunk, want the next event", data, complete, err)
+	}
+}
@@ -0,0 +1,80 @@
+package realtime_test
+
+import (
+	"chat-app-api/internal/config"
+	"chat-app-api/internal/database"
+	"chat-app-api/internal/realtime"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestPostgresBroker exchanges events between two brokers on the database in TEST_DATABASE_DSN
+func TestPostgresBroker(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+	db, err := database.Connect(config.DatabaseConfig{DSN: dsn})
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	if db.Dialector.Name() != database.Postgres {
+		t.Skip("TEST_DATABASE_DSN is not a PostgreSQL database")
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	publisher := realtime.NewPostgresBroker(db, logger)
+	subscriber := realtime.NewPostgresBroker(db, logger)
+	received := make(chan realtime.Event, 10)
+	subscriber.Subscribe(func(event realtime.Event) { received <- event })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		subscriber.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	for deadline := time.Now().Add(5 * time.Second); !subscriber.Listening(); time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the listener")
+		}
+	}
+
+	// The second event needs several notifications
+	large, _ := json.Marshal(map[string]string{"message": strings.Repeat("✓", 5000)})
+	sent := []realtime.Event{
+		{Origin: "a", Kind: realtime.EventDisconnect, UserID: 1},
+		{Origin: "a", Kind: realtime.EventSend, UserID: 2, Payload: large},
+	}
+	for _, event := range sent {
+		if err := publisher.Publish(context.Background(), event); err != nil {
+			t.Fatalf("publish: %v", err)
+		}
+	}
+
+	for _, want := range sent {
+		select {
+		case got := <-received:
+			if got.Kind != want.Kind || got.UserID != want.UserID || string(got.Payload) != string(want.Payload) {
+				t.Errorf("got %s event for user %d, want %s for user %d", got.Kind, got.UserID, want.Kind, want.UserID)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for the %s event", want.Kind)
+		}
+	}
+}
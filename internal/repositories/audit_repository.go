@@ -0,0 +1,89 @@
+package repositories
+
+import (
+	"chat-app-api/internal/models"
+	"context"
+	"gorm.io/gorm"
+	"time"
+)
+
+// AuditFilter selects audit events; zero values match every event
+type AuditFilter struct {
+	Action   string
+	Outcome  string
+	ActorID  uint
+	TargetID uint
+	IP       string
+	Since    time.Time // events at or after this time
+	Until    time.Time // events before this time
+	Limit    int       // no limit when zero
+	Offset   int
+}
+
+// AuditRepository only appends and reads: the audit log is never changed
+type AuditRepository interface {
+	CreateEvent(ctx context.Context, event *models.AuditEvent) error
+	ListEvents(ctx context.Context, filter AuditFilter) ([]models.AuditEvent, error)
+	CountEvents(ctx context.Context, filter AuditFilter) (int64, error)
+}
+
+type auditRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return &auditRepository{db: db}
+}
+
+func (r *auditRepository) CreateEvent(ctx context.Context, event *models.AuditEvent) error {
+	return translateError(r.db.WithContext(ctx).Create(event).Error)
+}
+
+// ListEvents returns a page of the matching events, newest first
+func (r *auditRepository) ListEvents(ctx context.Context, filter AuditFilter) ([]models.AuditEvent, error) {
+	query := r.filterEvents(ctx, filter).Order("id DESC").Offset(filter.Offset)
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var events []models.AuditEvent
+	if err := query.Find(&events).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return events, nil
+}
+
+// CountEvents counts every event matching the filter, ignoring its limit and offset
+func (r *auditRepository) CountEvents(ctx context.Context, filter AuditFilter) (int64, error) {
+	var count int64
+	if err := r.filterEvents(ctx, filter).Count(&count).Error; err != nil {
+		return 0, translateError(err)
+	}
+	return count, nil
+}
+
+func (r *auditRepository) filterEvents(ctx context.Context, filter AuditFilter) *gorm.DB {
+	query := r.db.WithContext(ctx).Model(&models.AuditEvent{})
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.Outcome != "" {
+		query = query.Where("outcome = ?", filter.Outcome)
+	}
+	if filter.ActorID != 0 {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.TargetID != 0 {
+		query = query.Where("target_id = ?", filter.TargetID)
+	}
+	if filter.IP != "" {
+		query = query.Where("ip = ?", filter.IP)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("created_at >= ?", filter.Since.UTC())
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("created_at < ?", filter.Until.UTC())
+	}
+	return query
+}
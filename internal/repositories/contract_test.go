@@ -0,0 +1,929 @@
+package repositories
+
+import (
+	"chat-app-api/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// repositoryFactory returns empty repositories sharing one store
+type repositoryFactory func(t *testing.T) (UserRepository, MessageRepository)
+
+// runRepositoryContract is the behaviour every UserRepository and MessageRepository implementation must have
+func runRepositoryContract(t *testing.T, newRepositories repositoryFactory) {
+	tests := map[string]func(t *testing.T, users UserRepository, messages MessageRepository){
+		"CreateUser":                 testCreateUser,
+		"UniqueUsernameAndEmail":     testUniqueUsernameAndEmail,
+		"FindUserNotFound":           testFindUserNotFound,
+		"FindAllOrderedByID":         testFindAllOrderedByID,
+		"UpdateUser":                 testUpdateUser,
+		"UpdatePassword":             testUpdatePassword,
+		"DeleteUser":                 testDeleteUser,
+		"SearchUser":                 testSearchUser,
+		"CreateMessage":              testCreateMessage,
+		"MessageReferencesUsers":     testMessageReferencesUsers,
+		"ConversationNewestFirst":    testConversationNewestFirst,
+		"UpdateAndDeleteMessage":     testUpdateAndDeleteMessage,
+		"FriendListWithLastMessage":  testFriendListWithLastMessage,
+		"DeletedUserInConversations": testDeletedUserInConversations,
+		"RestoreUser":                testRestoreUser,
+		"PurgeDeletedUsers":          testPurgeDeletedUsers,
+		"ListAndCountUsers":          testListAndCountUsers,
+		"AdminUserFields":            testAdminUserFields,
+		"CountMessages":              testCountMessages,
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			users, messages := newRepositories(t)
+			test(t, users, messages)
+		})
+	}
+}
+
+func createUser(t *testing.T, users UserRepository, username string) *models.User {
+	t.Helper()
+	user, err := users.CreateUser(context.Background(), &models.User{
+		Username:  username,
+		FirstName: "First " + username,
+		LastName:  "Last",
+		Email:     username + "@example.com",
+		Password:  "hash",
+	})
+	if err != nil {
+		t.Fatalf("create user %s: %v", username, err)
+	}
+	return user
+}
+
+func createMessage(t *testing.T, messages MessageRepository, from, to uint, content string) *models.Message {
+	t.Helper()
+	message, err := messages.CreateMessage(context.Background(), &models.Message{SenderID: from, ReceiverID: to, Content: content})
+	if err != nil {
+		t.Fatalf("create message %q: %v", content, err)
+	}
+	return message
+}
+
+func assertError(t *testing.T, err, want error) {
+	t.Helper()
+	if !errors.Is(err, want) {
+		t.Fatalf("got error %v, want %v", err, want)
+	}
+}
+
+func testCreateUser(t *testing.T, users UserRepository, _ MessageRepository) {
+	ctx := context.Background()
+	user := createUser(t, users, "alice")
+	if user.ID == 0 || user.CreatedAt.IsZero() || user.UpdatedAt.IsZero() {
+		t.Fatalf("ID and timestamps not set: %+v", user)
+	}
+
+	for name, find := range map[string]func() (*models.User, error){
+		"FindByID":       func() (*models.User, error) { return users.FindByID(ctx, user.ID) },
+		"FindByUsername": func() (*models.User, error) { return users.FindByUsername(ctx, "alice") },
+		"FindByEmail":    func() (*models.User, error) { return users.FindByEmail(ctx, "alice@example.com") },
+	} {
+		found, err := find()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if found.ID != user.ID || found.Username != "alice" || found.Password != "hash" {
+			t.Errorf("%s returned %+v", name, found)
+		}
+	}
+
+	if !users.IsUsernameExist(ctx, "alice") || !users.IsEmailExist(ctx, "alice@example.com") {
+		t.Error("existing username or email reported as free")
+	}
+	if users.IsUsernameExist(ctx, "bob") || users.IsEmailExist(ctx, "bob@example.com") {
+		t.Error("free username or email reported as taken")
+	}
+}
+
+func testUniqueUsernameAndEmail(t *testing.T, users UserRepository, _ MessageRepository) {
+	ctx := context.Background()
+	createUser(t, users, "alice")
+
+	_, err := users.CreateUser(ctx, &models.User{Username: "alice", Email: "other@example.com", Password: "hash"})
+	assertError(t, err, ErrDuplicate)
+
+	_, err = users.CreateUser(ctx, &models.User{Username: "other", Email: "alice@example.com", Password: "hash"})
+	assertError(t, err, ErrDuplicate)
+}
+
+func testFindUserNotFound(t *testing.T, users UserRepository, _ MessageRepository) {
+	ctx := context.Background()
+	_, err := users.FindByID(ctx, 4242)
+	assertError(t, err, ErrNotFound)
+	_, err = users.FindByUsername(ctx, "nobody")
+	assertError(t, err, ErrNotFound)
+	_, err = users.FindByEmail(ctx, "nobody@example.com")
+	assertError(t, err, ErrNotFound)
+}
+
+func testFindAllOrderedByID(t *testing.T, users UserRepository, _ MessageRepository) {
+	var want []uint
+	for i := 0; i < 5; i++ {
+		want = append(want, createUser(t, users, "user"+strconv.Itoa(i)).ID)
+	}
+
+	all, err := users.FindAll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != len(want) {
+		t.Fatalf("got %d users, want %d", len(all), len(want))
+	}
+	for i, user := range all {
+		if user.ID != want[i] {
+			t.Fatalf("user %d has ID %d, want %d", i, user.ID, want[i])
+		}
+	}
+}
+
+func testUpdateUser(t *testing.T, users UserRepository, _ MessageRepository) {
+	ctx := context.Background()
+	alice := createUser(t, users, "alice")
+	createUser(t, users, "bob")
+
+	updated, err := users.UpdateUser(ctx, &models.User{
+		ID: alice.ID, Username: "alice2", FirstName: "Alice", Email: "alice2@example.com", Password: "new-hash",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !updated.CreatedAt.Equal(alice.CreatedAt) {
+		t.Errorf("created_at changed from %v to %v", alice.CreatedAt, updated.CreatedAt)
+	}
+
+	found, err := users.FindByUsername(ctx, "alice2")
+	if err != nil || found.ID != alice.ID || found.FirstName != "Alice" || found.Password != "new-hash" {
+		t.Fatalf("update not stored: %+v, %v", found, err)
+	}
+
+	_, err = users.UpdateUser(ctx, &models.User{ID: alice.ID, Username: "bob", Email: "alice2@example.com", Password: "hash"})
+	assertError(t, err, ErrDuplicate)
+
+	_, err = users.UpdateUser(ctx, &models.User{ID: 4242, Username: "ghost", Email: "ghost@example.com", Password: "hash"})
+	assertError(t, err, ErrNotFound)
+	if users.IsUsernameExist(ctx, "ghost") {
+		t.Error("updating a missing user created it")
+	}
+}
+
+func testUpdatePassword(t *testing.T, users UserRepository, _ MessageRepository) {
+	ctx := context.Background()
+	alice := createUser(t, users, "alice")
+
+	if err := users.UpdatePassword(ctx, alice.ID, "rehashed"); err != nil {
+		t.Fatal(err)
+	}
+	found, err := users.FindByID(ctx, alice.ID)
+	if err != nil || found.Password != "rehashed" {
+		t.Fatalf("password not updated: %+v, %v", found, err)
+	}
+}
+
+func testDeleteUser(t *testing.T, users UserRepository, _ MessageRepository) {
+	ctx := context.Background()
+	alice := createUser(t, users, "alice")
+
+	if err := users.DeleteUser(ctx, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	_, err := users.FindByID(ctx, alice.ID)
+	assertError(t, err, ErrNotFound)
+	assertError(t, users.DeleteUser(ctx, alice.ID), ErrNotFound)
+}
+
+func testSearchUser(t *testing.T, users UserRepository, _ MessageRepository) {
+	ctx := context.Background()
+	createUser(t, users, "alice")
+	createUser(t, users, "alina")
+	createUser(t, users, "bob")
+
+	usernames := func(found []models.User) []string {
+		var names []string
+		for _, user := range found {
+			names = append(names, user.Username)
+		}
+		return names
+	}
+
+	tests := []struct {
+		current, search string
+		want            []string
+	}{
+		{"bob", "", nil},
+		{"bob", "ali", []string{"alice", "alina"}},
+		{"alice", "ali", []string{"alina"}},
+		{"bob", "@lin", []string{"alina"}},
+		{"alice", "First bob", []string{"bob"}},
+		{"bob", "@First", nil},
+	}
+	for _, test := range tests {
+		found, err := users.SearchUser(ctx, test.current, test.search)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := usernames(found)
+		if len(got) != len(test.want) {
+			t.Errorf("SearchUser(%q, %q) = %v, want %v", test.current, test.search, got, test.want)
+			continue
+		}
+		want := map[string]bool{}
+		for _, name := range test.want {
+			want[name] = true
+		}
+		for _, name := range got {
+			if !want[name] {
+				t.Errorf("SearchUser(%q, %q) = %v, want %v", test.current, test.search, got, test.want)
+			}
+		}
+	}
+}
+
+func testCreateMessage(t *testing.T, users UserRepository, messages MessageRepository) {
+	alice := createUser(t, users, "alice")
+	bob := createUser(t, users, "bob")
+
+	message := createMessage(t, messages, alice.ID, bob.ID, "hi")
+	if message.ID == 0 || message.CreatedAt.IsZero() {
+		t.Fatalf("ID and timestamps not set: %+v", message)
+	}
+	if message.Sender.ID != alice.ID || message.Sender.Username != "alice" {
+		t.Errorf("sender not loaded: %+v", message.Sender)
+	}
+}
+
+func testMessageReferencesUsers(t *testing.T, users UserRepository, messages MessageRepository) {
+	alice := createUser(t, users, "alice")
+
+	_, err := messages.CreateMessage(context.Background(), &models.Message{SenderID: alice.ID, ReceiverID: 4242, Content: "hi"})
+	assertError(t, err, ErrMissingReference)
+}
+
+func testConversationNewestFirst(t *testing.T, users UserRepository, messages MessageRepository) {
+	alice := createUser(t, users, "alice")
+	bob := createUser(t, users, "bob")
+	carol := createUser(t, users, "carol")
+
+	first := createMessage(t, messages, alice.ID, bob.ID, "first")
+	second := createMessage(t, messages, bob.ID, alice.ID, "second")
+	createMessage(t, messages, alice.ID, carol.ID, "elsewhere")
+	third := createMessage(t, messages, alice.ID, bob.ID, "third")
+
+	for _, pair := range [][2]uint{{alice.ID, bob.ID}, {bob.ID, alice.ID}} {
+		conversation, err := messages.FindBySenderIdAndReceiverId(context.Background(), pair[0], pair[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []uint{third.ID, second.ID, first.ID}
+		if len(conversation) != len(want) {
+			t.Fatalf("got %d messages, want %d", len(conversation), len(want))
+		}
+		for i, message := range conversation {
+			if message.ID != want[i] {
+				t.Fatalf("message %d is %q, want ID %d", i, message.Content, want[i])
+			}
+		}
+	}
+}
+
+func testUpdateAndDeleteMessage(t *testing.T, users UserRepository, messages MessageRepository) {
+	ctx := context.Background()
+	alice := createUser(t, users, "alice")
+	bob := createUser(t, users, "bob")
+	message := createMessage(t, messages, alice.ID, bob.ID, "hi")
+
+	found, err := messages.FindMessageByID(ctx, message.ID)
+	if err != nil || found.Content != "hi" || found.SenderID != alice.ID || found.ReceiverID != bob.ID {
+		t.Fatalf("got message %+v (%v), want the one alice sent", found, err)
+	}
+
+	message.Content = "edited"
+	if _, err := messages.UpdateMessage(ctx, message); err != nil {
+		t.Fatal(err)
+	}
+	conversation, err := messages.FindBySenderIdAndReceiverId(ctx, alice.ID, bob.ID)
+	if err != nil || len(conversation) != 1 || conversation[0].Content != "edited" {
+		t.Fatalf("update not stored: %+v, %v", conversation, err)
+	}
+
+	_, err = messages.UpdateMessage(ctx, &models.Message{ID: 4242, SenderID: alice.ID, ReceiverID: bob.ID, Content: "ghost"})
+	assertError(t, err, ErrNotFound)
+
+	if err := messages.DeleteMessage(ctx, message.ID); err != nil {
+		t.Fatal(err)
+	}
+	assertError(t, messages.DeleteMessage(ctx, message.ID), ErrNotFound)
+	_, err = messages.FindMessageByID(ctx, message.ID)
+	assertError(t, err, ErrNotFound)
+}
+
+func testFriendListWithLastMessage(t *testing.T, users UserRepository, messages MessageRepository) {
+	alice := createUser(t, users, "alice")
+	bob := createUser(t, users, "bob")
+	carol := createUser(t, users, "carol")
+	dave := createUser(t, users, "dave")
+
+	createMessage(t, messages, alice.ID, bob.ID, "hi bob")
+	createMessage(t, messages, carol.ID, alice.ID, "hi alice")
+	createMessage(t, messages, bob.ID, alice.ID, "hi again")
+	createMessage(t, messages, bob.ID, dave.ID, "not alice's")
+
+	friends, err := messages.GetFriendListWithLastMessage(context.Background(), alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		username, content string
+	}{
+		{"bob", "hi again"},
+		{"carol", "hi alice"},
+	}
+	if len(friends) != len(want) {
+		t.Fatalf("got %d conversations, want %d: %+v", len(friends), len(want), friends)
+	}
+	for i, friend := range friends {
+		if friend.Profile.Username != want[i].username || friend.LastMessage.Content != want[i].content {
+			t.Errorf("conversation %d is %s/%q, want %s/%q", i,
+				friend.Profile.Username, friend.LastMessage.Content, want[i].username, want[i].content)
+		}
+	}
+}
+
+func testDeletedUserInConversations(t *testing.T, users UserRepository, messages MessageRepository) {
+	ctx := context.Background()
+	alice := createUser(t, users, "alice")
+	bob := createUser(t, users, "bob")
+	createMessage(t, messages, alice.ID, bob.ID, "hi")
+
+	if err := users.DeleteUser(ctx, bob.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// The conversation stays, with a tombstone in place of bob
+	friends, err := messages.GetFriendListWithLastMessage(ctx, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(friends) != 1 {
+		t.Fatalf("got %d conversations, want 1", len(friends))
+	}
+	profile := friends[0].Profile
+	if profile.ID != bob.ID || !profile.Deleted || profile.FirstName != models.DeletedUserName || profile.Username != "" || profile.LastName != "" {
+		t.Errorf("got profile %+v, want the tombstone of user %d", profile, bob.ID)
+	}
+	if conversation, err := messages.FindBySenderIdAndReceiverId(ctx, alice.ID, bob.ID); err != nil || len(conversation) != 1 {
+		t.Errorf("got %d messages (%v), want 1", len(conversation), err)
+	}
+
+	// Deleted users cannot receive, their name stays taken, and they are hidden from lookups
+	_, err = messages.CreateMessage(ctx, &models.Message{SenderID: alice.ID, ReceiverID: bob.ID, Content: "still there?"})
+	assertError(t, err, ErrMissingReference)
+	if !users.IsUsernameExist(ctx, "bob") || !users.IsEmailExist(ctx, "bob@example.com") {
+		t.Error("the username and email of a deleted user are available")
+	}
+	_, err = users.FindByUsername(ctx, "bob")
+	assertError(t, err, ErrNotFound)
+	if found, err := users.SearchUser(ctx, "alice", "bob"); err != nil || len(found) != 0 {
+		t.Errorf("search found %+v (%v)", found, err)
+	}
+	assertError(t, users.UpdateRole(ctx, bob.ID, models.RoleAdmin), ErrNotFound)
+}
+
+func testRestoreUser(t *testing.T, users UserRepository, _ MessageRepository) {
+	ctx := context.Background()
+	alice := createUser(t, users, "alice")
+	createUser(t, users, "bob")
+
+	assertError(t, users.RestoreUser(ctx, alice.ID), ErrNotFound)
+	if err := users.DeleteUser(ctx, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	deleted, err := users.ListUsers(ctx, UserFilter{Deleted: true})
+	if err != nil || len(deleted) != 1 || deleted[0].ID != alice.ID || !deleted[0].IsDeleted() {
+		t.Fatalf("deleted users are %+v (%v), want alice", deleted, err)
+	}
+	if count, err := users.CountUsers(ctx, UserFilter{}); err != nil || count != 1 {
+		t.Errorf("got %d live users (%v), want 1", count, err)
+	}
+
+	if err := users.RestoreUser(ctx, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	if found, err := users.FindByID(ctx, alice.ID); err != nil || found.IsDeleted() {
+		t.Fatalf("restored user: %+v, %v", found, err)
+	}
+	assertError(t, users.RestoreUser(ctx, 4242), ErrNotFound)
+}
+
+func testPurgeDeletedUsers(t *testing.T, users UserRepository, messages MessageRepository) {
+	ctx := context.Background()
+	alice := createUser(t, users, "alice")
+	bob := createUser(t, users, "bob")
+	carol := createUser(t, users, "carol")
+	dave := createUser(t, users, "dave")
+	toBob := createMessage(t, messages, alice.ID, bob.ID, "hi bob")
+	createMessage(t, messages, bob.ID, alice.ID, "hi alice")
+	createMessage(t, messages, alice.ID, carol.ID, "hi carol")
+	createMessage(t, messages, bob.ID, dave.ID, "hi dave")
+
+	for _, user := range []*models.User{bob, dave} {
+		if err := users.DeleteUser(ctx, user.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Users deleted after the cutoff are still within their grace period
+	if purged, err := users.PurgeDeletedUsers(ctx, time.Now().Add(-time.Hour)); err != nil || purged != 0 {
+		t.Fatalf("purged %d (%v) within the grace period", purged, err)
+	}
+	if purged, err := users.PurgeDeletedUsers(ctx, time.Now().Add(time.Minute)); err != nil || purged != 2 {
+		t.Fatalf("purged %d (%v), want 2", purged, err)
+	}
+
+	// Alice keeps the conversation, with the deleted user tombstone in place of bob
+	friends, err := messages.GetFriendListWithLastMessage(ctx, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(friends) != 2 || friends[0].Profile.Username != "carol" {
+		t.Fatalf("got conversations %+v, want carol's and the purged user's", friends)
+	}
+	if profile := friends[1].Profile; profile.ID != 0 || !profile.Deleted || profile.FirstName != models.DeletedUserName ||
+		profile.Username != "" || friends[1].LastMessage.Content != "hi alice" {
+		t.Errorf("got %+v, want the tombstone with the last message", friends[1])
+	}
+	kept, err := messages.FindMessageByID(ctx, toBob.ID)
+	if err != nil || kept.SenderID != alice.ID || kept.ReceiverID != 0 {
+		t.Fatalf("got message %+v (%v), want it kept without its receiver", kept, err)
+	}
+	kept.Content = "hi?"
+	if _, err := messages.UpdateMessage(ctx, kept); err != nil {
+		t.Errorf("updating a message to a purged user: %v", err)
+	}
+
+	// Nobody is left in the conversation between bob and dave
+	if count, err := messages.CountMessages(ctx, time.Time{}); err != nil || count != 3 {
+		t.Errorf("%d messages remain (%v), want 3", count, err)
+	}
+	if friends, err := messages.GetFriendListWithLastMessage(ctx, carol.ID); err != nil || len(friends) != 1 {
+		t.Errorf("carol has conversations %+v (%v), want the one with alice", friends, err)
+	}
+	assertError(t, users.RestoreUser(ctx, bob.ID), ErrNotFound)
+	if users.IsUsernameExist(ctx, "bob") {
+		t.Error("the username of a purged user is still taken")
+	}
+}
+
+func testListAndCountUsers(t *testing.T, users UserRepository, _ MessageRepository) {
+	ctx := context.Background()
+	now := time.Now()
+	alice := createUser(t, users, "alice")
+	bob := createUser(t, users, "bob")
+	carol := createUser(t, users, "carol")
+	createUser(t, users, "dave")
+
+	if err := users.UpdateRole(ctx, alice.ID, models.RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+	if err := users.UpdateSuspension(ctx, bob.ID, &future); err != nil {
+		t.Fatal(err)
+	}
+	if err := users.UpdateSuspension(ctx, carol.ID, &past); err != nil {
+		t.Fatal(err)
+	}
+
+	suspended, active := true, false
+	tests := map[string]struct {
+		filter UserFilter
+		want   []string
+		total  int64
+	}{
+		"everyone":            {UserFilter{}, []string{"alice", "bob", "carol", "dave"}, 4},
+		"page":                {UserFilter{Limit: 2, Offset: 1}, []string{"bob", "carol"}, 4},
+		"past the end":        {UserFilter{Offset: 10}, nil, 4},
+		"search ignores case": {UserFilter{Search: "ALI"}, []string{"alice"}, 1},
+		"search email":        {UserFilter{Search: "bob@example"}, []string{"bob"}, 1},
+		"role":                {UserFilter{Role: models.RoleAdmin}, []string{"alice"}, 1},
+		"suspended":           {UserFilter{Suspended: &suspended, At: now}, []string{"bob"}, 1},
+		"active":              {UserFilter{Suspended: &active, At: now}, []string{"alice", "carol", "dave"}, 3},
+		"combined":            {UserFilter{Search: "a", Suspended: &active, At: now, Limit: 1}, []string{"alice"}, 3},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			found, err := users.ListUsers(ctx, test.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, user := range found {
+				names = append(names, user.Username)
+			}
+			if strings.Join(names, ",") != strings.Join(test.want, ",") {
+				t.Errorf("got %v, want %v", names, test.want)
+			}
+
+			total, err := users.CountUsers(ctx, test.filter)
+			if err != nil || total != test.total {
+				t.Errorf("got count %d (%v), want %d", total, err, test.total)
+			}
+		})
+	}
+}
+
+func testAdminUserFields(t *testing.T, users UserRepository, _ MessageRepository) {
+	ctx := context.Background()
+	alice := createUser(t, users, "alice")
+	if alice.Role != models.RoleUser || alice.SuspendedUntil != nil || alice.TokenVersion != 0 {
+		t.Fatalf("new user has role %q, suspension %v and token version %d", alice.Role, alice.SuspendedUntil, alice.TokenVersion)
+	}
+
+	until := time.Now().Add(24 * time.Hour).Truncate(time.Millisecond)
+	if err := users.UpdateRole(ctx, alice.ID, models.RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+	if err := users.UpdateSuspension(ctx, alice.ID, &until); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := users.RevokeTokens(ctx, alice.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Profile updates leave the fields managed by admins alone
+	if _, err := users.UpdateUser(ctx, &models.User{ID: alice.ID, Username: "alice", Email: "alice@example.com", Password: "hash"}); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := users.FindByID(ctx, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.Role != models.RoleAdmin || found.TokenVersion != 2 || found.SuspendedUntil == nil || !found.SuspendedUntil.Equal(until) {
+		t.Fatalf("got role %q, token version %d and suspension %v", found.Role, found.TokenVersion, found.SuspendedUntil)
+	}
+
+	if err := users.UpdateSuspension(ctx, alice.ID, nil); err != nil {
+		t.Fatal(err)
+	}
+	if found, err := users.FindByID(ctx, alice.ID); err != nil || found.SuspendedUntil != nil {
+		t.Fatalf("suspension not lifted: %v, %v", found.SuspendedUntil, err)
+	}
+
+	assertError(t, users.UpdateRole(ctx, 4242, models.RoleAdmin), ErrNotFound)
+	assertError(t, users.UpdateSuspension(ctx, 4242, nil), ErrNotFound)
+	assertError(t, users.RevokeTokens(ctx, 4242), ErrNotFound)
+}
+
+func testCountMessages(t *testing.T, users UserRepository, messages MessageRepository) {
+	ctx := context.Background()
+	alice := createUser(t, users, "alice")
+	bob := createUser(t, users, "bob")
+	before := time.Now().Add(-time.Minute)
+	for i := 0; i < 3; i++ {
+		createMessage(t, messages, alice.ID, bob.ID, "hi")
+	}
+
+	for since, want := range map[time.Time]int64{{}: 3, before: 3, time.Now().Add(time.Minute): 0} {
+		if count, err := messages.CountMessages(ctx, since); err != nil || count != want {
+			t.Errorf("since %v: got %d (%v), want %d", since, count, err, want)
+		}
+	}
+}
+
+// runAuditContract is the behaviour every AuditRepository implementation must have
+func runAuditContract(t *testing.T, newRepository func(t *testing.T) AuditRepository) {
+	t.Run("CreateAndFilterEvents", func(t *testing.T) {
+		testAuditEvents(t, newRepository(t))
+	})
+}
+
+func testAuditEvents(t *testing.T, audit AuditRepository) {
+	ctx := context.Background()
+	alice, bob := uint(1), uint(2)
+	start := time.Now().Add(-time.Second)
+
+	events := []models.AuditEvent{
+		{Action: "auth.login", Outcome: models.OutcomeFailure, TargetID: &alice, IP: "192.0.2.1"},
+		{Action: "auth.login", Outcome: models.OutcomeSuccess, ActorID: &alice, TargetID: &alice, IP: "192.0.2.1", UserAgent: "curl/8.0"},
+		{Action: "admin.user_suspended", Outcome: models.OutcomeSuccess, ActorID: &bob, TargetID: &alice, IP: "198.51.100.7",
+			Details: map[string]any{"until": "2030-01-01T00:00:00Z"}},
+	}
+	for i := range events {
+		if err := audit.CreateEvent(ctx, &events[i]); err != nil {
+			t.Fatal(err)
+		}
+		if events[i].ID == 0 || events[i].CreatedAt.IsZero() {
+			t.Fatalf("event %d has no ID or creation time: %+v", i, events[i])
+		}
+	}
+
+	found, err := audit.ListEvents(ctx, AuditFilter{TargetID: alice, Limit: 1})
+	if err != nil || len(found) != 1 {
+		t.Fatalf("got %d events (%v), want 1", len(found), err)
+	}
+	latest := found[0]
+	if latest.ID != events[2].ID || latest.ActorID == nil || *latest.ActorID != bob || latest.Details["until"] != "2030-01-01T00:00:00Z" {
+		t.Errorf("newest event is %+v, want the suspension by bob with its details", latest)
+	}
+
+	tests := map[string]struct {
+		filter AuditFilter
+		want   []uint
+	}{
+		"everything":  {AuditFilter{}, []uint{events[2].ID, events[1].ID, events[0].ID}},
+		"action":      {AuditFilter{Action: "auth.login"}, []uint{events[1].ID, events[0].ID}},
+		"outcome":     {AuditFilter{Outcome: models.OutcomeFailure}, []uint{events[0].ID}},
+		"actor":       {AuditFilter{ActorID: alice}, []uint{events[1].ID}},
+		"ip":          {AuditFilter{IP: "198.51.100.7"}, []uint{events[2].ID}},
+		"page":        {AuditFilter{Limit: 1, Offset: 1}, []uint{events[1].ID}},
+		"time range":  {AuditFilter{Since: start, Until: time.Now().Add(time.Minute)}, []uint{events[2].ID, events[1].ID, events[0].ID}},
+		"in the past": {AuditFilter{Until: start}, nil},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			found, err := audit.ListEvents(ctx, test.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []uint
+			for _, event := range found {
+				ids = append(ids, event.ID)
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(test.want) {
+				t.Errorf("got events %v, want %v", ids, test.want)
+			}
+
+			count, err := audit.CountEvents(ctx, AuditFilter{Action: test.filter.Action, Outcome: test.filter.Outcome,
+				ActorID: test.filter.ActorID, IP: test.filter.IP, Since: test.filter.Since, Until: test.filter.Until})
+			if err != nil || (test.filter.Limit == 0 && count != int64(len(test.want))) {
+				t.Errorf("got count %d (%v), want %d", count, err, len(test.want))
+			}
+		})
+	}
+}
+
+// reportFactory returns empty report, user and message repositories sharing one store
+type reportFactory func(t *testing.T) (ReportRepository, UserRepository, MessageRepository)
+
+// runReportContract is the behaviour every ReportRepository implementation must have
+func runReportContract(t *testing.T, newRepositories reportFactory) {
+	tests := map[string]func(t *testing.T, reports ReportRepository, users UserRepository, messages MessageRepository){
+		"CreateAndFindReport":     testCreateAndFindReport,
+		"ListAndCountReports":     testListAndCountReports,
+		"ResolveReport":           testResolveReport,
+		"RemovedMessageIsCleared": testRemovedMessageIsCleared,
+		"PurgeKeepsReports":       testPurgeKeepsReports,
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			reports, users, messages := newRepositories(t)
+			test(t, reports, users, messages)
+		})
+	}
+}
+
+// reportMessage stores an open report about the message by its receiver
+func reportMessage(t *testing.T, reports ReportRepository, message *models.Message, reason string) *models.Report {
+	t.Helper()
+	reporterID := message.ReceiverID
+	report := &models.Report{
+		ReporterID:     &reporterID,
+		TargetType:     models.ReportTargetMessage,
+		MessageID:      &message.ID,
+		ReportedUserID: message.SenderID,
+		MessageContent: message.Content,
+		Reason:         reason,
+		Status:         models.ReportStatusOpen,
+	}
+	if err := reports.CreateReport(context.Background(), report); err != nil {
+		t.Fatalf("report message %d: %v", message.ID, err)
+	}
+	return report
+}
+
+func testCreateAndFindReport(t *testing.T, reports ReportRepository, users UserRepository, messages MessageRepository) {
+	ctx := context.Background()
+	alice := createUser(t, users, "alice")
+	bob := createUser(t, users, "bob")
+	message := createMessage(t, messages, alice.ID, bob.ID, "buy cheap watches")
+
+	report := reportMessage(t, reports, message, models.ReportReasonSpam)
+	if report.ID == 0 || report.CreatedAt.IsZero() {
+		t.Fatalf("report has no ID or creation time: %+v", report)
+	}
+
+	found, err := reports.FindReportByID(ctx, report.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.ReporterID == nil || *found.ReporterID != bob.ID || found.ReportedUserID != alice.ID || found.MessageID == nil || *found.MessageID != message.ID ||
+		found.MessageContent != "buy cheap watches" || !found.IsOpen() {
+		t.Errorf("got report %+v", found)
+	}
+
+	// The message filter reports messages without a reporter
+	flagged := &models.Report{TargetType: models.ReportTargetMessage, MessageID: &message.ID, ReportedUserID: alice.ID,
+		Reason: models.ReportReasonFlagged, Status: models.ReportStatusOpen}
+	if err := reports.CreateReport(ctx, flagged); err != nil {
+		t.Fatal(err)
+	}
+	found, err = reports.FindReportByID(ctx, flagged.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.ReporterID != nil || found.Reason != models.ReportReasonFlagged {
+		t.Errorf("got flagged report %+v, want no reporter", found)
+	}
+
+	_, err = reports.FindReportByID(ctx, 4242)
+	assertError(t, err, ErrNotFound)
+
+	ghost := uint(4242)
+	err = reports.CreateReport(ctx, &models.Report{ReporterID: &bob.ID, TargetType: models.ReportTargetUser, ReportedUserID: ghost,
+		Reason: models.ReportReasonOther, Status: models.ReportStatusOpen})
+	assertError(t, err, ErrMissingReference)
+	err = reports.CreateReport(ctx, &models.Report{ReporterID: &bob.ID, TargetType: models.ReportTargetMessage, MessageID: &ghost,
+		ReportedUserID: alice.ID, Reason: models.ReportReasonOther, Status: models.ReportStatusOpen})
+	assertError(t, err, ErrMissingReference)
+}
+
+func testListAndCountReports(t *testing.T, reports ReportRepository, users UserRepository, messages MessageRepository) {
+	ctx := context.Background()
+	alice := createUser(t, users, "alice")
+	bob := createUser(t, users, "bob")
+	carol := createUser(t, users, "carol")
+
+	spam := reportMessage(t, reports, createMessage(t, messages, alice.ID, bob.ID, "spam"), models.ReportReasonSpam)
+	insult := reportMessage(t, reports, createMessage(t, messages, alice.ID, carol.ID, "insult"), models.ReportReasonHarassment)
+	account := &models.Report{ReporterID: &alice.ID, TargetType: models.ReportTargetUser, ReportedUserID: bob.ID,
+		Reason: models.ReportReasonImpersonation, Status: models.ReportStatusOpen}
+	if err := reports.CreateReport(ctx, account); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	spam.Status, spam.ResolvedByID, spam.ResolvedAt = models.ReportStatusDismissed, &carol.ID, &now
+	if err := reports.ResolveReport(ctx, spam); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		filter ReportFilter
+		want   []uint
+	}{
+		"everything oldest first": {ReportFilter{}, []uint{spam.ID, insult.ID, account.ID}},
+		"status":                  {ReportFilter{Status: models.ReportStatusOpen}, []uint{insult.ID, account.ID}},
+		"target type":             {ReportFilter{TargetType: models.ReportTargetUser}, []uint{account.ID}},
+		"reason":                  {ReportFilter{Reason: models.ReportReasonHarassment}, []uint{insult.ID}},
+		"reporter":                {ReportFilter{ReporterID: alice.ID}, []uint{account.ID}},
+		"reported user":           {ReportFilter{ReportedUserID: alice.ID}, []uint{spam.ID, insult.ID}},
+		"message":                 {ReportFilter{MessageID: *insult.MessageID}, []uint{insult.ID}},
+		"page":                    {ReportFilter{Limit: 1, Offset: 1}, []uint{insult.ID}},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			found, err := reports.ListReports(ctx, test.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []uint
+			for _, report := range found {
+				ids = append(ids, report.ID)
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(test.want) {
+				t.Errorf("got reports %v, want %v", ids, test.want)
+			}
+
+			unpaged := test.filter
+			unpaged.Limit, unpaged.Offset = 0, 0
+			count, err := reports.CountReports(ctx, unpaged)
+			if err != nil || (test.filter.Limit == 0 && count != int64(len(test.want))) {
+				t.Errorf("got count %d (%v), want %d", count, err, len(test.want))
+			}
+		})
+	}
+}
+
+func testResolveReport(t *testing.T, reports ReportRepository, users UserRepository, messages MessageRepository) {
+	ctx := context.Background()
+	alice := createUser(t, users, "alice")
+	bob := createUser(t, users, "bob")
+	moderator := createUser(t, users, "moderator")
+	report := reportMessage(t, reports, createMessage(t, messages, alice.ID, bob.ID, "insult"), models.ReportReasonHarassment)
+
+	resolvedAt := time.Now().Truncate(time.Second)
+	report.Status = models.ReportStatusActioned
+	report.Actions = []string{models.ModerationRemoveMessage, models.ModerationWarn}
+	report.ModeratorNote = "first warning"
+	report.ResolvedByID, report.ResolvedAt = &moderator.ID, &resolvedAt
+	// Only the resolution is written, whatever else the caller changed
+	report.Reason = models.ReportReasonSpam
+	if err := reports.ResolveReport(ctx, report); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := reports.FindReportByID(ctx, report.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.Status != models.ReportStatusActioned || fmt.Sprint(found.Actions) != "[remove_message warn]" ||
+		found.ModeratorNote != "first warning" || found.ResolvedByID == nil || *found.ResolvedByID != moderator.ID ||
+		found.ResolvedAt == nil || !found.ResolvedAt.Equal(resolvedAt) || found.Reason != models.ReportReasonHarassment {
+		t.Errorf("got report %+v", found)
+	}
+
+	// A resolved report cannot be resolved again, for example by a second moderator at the same time
+	report.Status = models.ReportStatusDismissed
+	assertError(t, reports.ResolveReport(ctx, report), ErrNotFound)
+	assertError(t, reports.ResolveReport(ctx, &models.Report{ID: 4242, Status: models.ReportStatusDismissed}), ErrNotFound)
+
+	// A resolution that could not be completed is undone, and the report can be resolved again
+	if err := reports.ReopenReport(ctx, report.ID); err != nil {
+		t.Fatal(err)
+	}
+	found, err = reports.FindReportByID(ctx, report.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found.IsOpen() || len(found.Actions) != 0 || found.ModeratorNote != "" || found.ResolvedByID != nil || found.ResolvedAt != nil {
+		t.Errorf("got reopened report %+v", found)
+	}
+	assertError(t, reports.ReopenReport(ctx, report.ID), ErrNotFound)
+	if err := reports.ResolveReport(ctx, report); err != nil {
+		t.Errorf("resolve after reopening: %v", err)
+	}
+}
+
+func testRemovedMessageIsCleared(t *testing.T, reports ReportRepository, users UserRepository, messages MessageRepository) {
+	ctx := context.Background()
+	alice := createUser(t, users, "alice")
+	bob := createUser(t, users, "bob")
+	message := createMessage(t, messages, alice.ID, bob.ID, "insult")
+	report := reportMessage(t, reports, message, models.ReportReasonHarassment)
+
+	if err := messages.DeleteMessage(ctx, message.ID); err != nil {
+		t.Fatal(err)
+	}
+	found, err := reports.FindReportByID(ctx, report.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.MessageID != nil || found.MessageContent != "insult" {
+		t.Errorf("got report %+v, want the content without the removed message", found)
+	}
+}
+
+func testPurgeKeepsReports(t *testing.T, reports ReportRepository, users UserRepository, messages MessageRepository) {
+	ctx := context.Background()
+	alice := createUser(t, users, "alice")
+	bob := createUser(t, users, "bob")
+	carol := createUser(t, users, "carol")
+	byBob := reportMessage(t, reports, createMessage(t, messages, alice.ID, bob.ID, "insult"), models.ReportReasonHarassment)
+	aboutBob := reportMessage(t, reports, createMessage(t, messages, bob.ID, carol.ID, "threat"), models.ReportReasonViolence)
+	aboutCarol := reportMessage(t, reports, createMessage(t, messages, carol.ID, alice.ID, "spam"), models.ReportReasonSpam)
+	now := time.Now()
+	aboutCarol.Status, aboutCarol.ResolvedByID, aboutCarol.ResolvedAt = models.ReportStatusDismissed, &bob.ID, &now
+	if err := reports.ResolveReport(ctx, aboutCarol); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := users.DeleteUser(ctx, bob.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := users.PurgeDeletedUsers(ctx, time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := reports.FindReportByID(ctx, byBob.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.ReporterID != nil || found.ReportedUserID != alice.ID || found.MessageID == nil || *found.MessageID != *byBob.MessageID {
+		t.Errorf("got report %+v, want it kept without the purged reporter", found)
+	}
+	found, err = reports.FindReportByID(ctx, aboutBob.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.ReportedUserID != 0 || found.ReporterID == nil || *found.ReporterID != carol.ID || found.MessageContent != "threat" {
+		t.Errorf("got report %+v, want it kept without the purged author", found)
+	}
+	found, err = reports.FindReportByID(ctx, aboutCarol.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.ResolvedByID != nil {
+		t.Errorf("report %+v still names the purged moderator", found)
+	}
+}
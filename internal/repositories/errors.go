@@ -0,0 +1,27 @@
+package repositories
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// Errors every repository implementation returns, whatever the storage behind it
+var (
+	ErrNotFound         = errors.New("record not found")
+	ErrDuplicate        = errors.New("record already exists")
+	ErrMissingReference = errors.New("referenced record does not exist")
+)
+
+// translateError maps GORM errors to the repository errors and leaves the others as they are
+func translateError(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrNotFound
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return ErrDuplicate
+	case errors.Is(err, gorm.ErrForeignKeyViolated):
+		return ErrMissingReference
+	}
+	return err
+}
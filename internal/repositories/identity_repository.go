@@ -0,0 +1,35 @@
+package repositories
+
+import (
+	"chat-app-api/internal/models"
+	"context"
+	"gorm.io/gorm"
+)
+
+type IdentityRepository interface {
+	CreateIdentity(ctx context.Context, identity *models.UserIdentity) (*models.UserIdentity, error)
+	FindByProviderAndSubject(ctx context.Context, provider string, subject string) (*models.UserIdentity, error)
+}
+
+type identityRepository struct {
+	db *gorm.DB
+}
+
+func NewIdentityRepository(db *gorm.DB) IdentityRepository {
+	return &identityRepository{db: db}
+}
+
+func (r *identityRepository) CreateIdentity(ctx context.Context, identity *models.UserIdentity) (*models.UserIdentity, error) {
+	if err := r.db.WithContext(ctx).Create(identity).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return identity, nil
+}
+
+func (r *identityRepository) FindByProviderAndSubject(ctx context.Context, provider string, subject string) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+	if err := r.db.WithContext(ctx).Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &identity, nil
+}
@@ -0,0 +1,757 @@
+package repositories
+
+import (
+	"chat-app-api/internal/models"
+	"context"
+	"gorm.io/gorm"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps users and messages in memory for tests and local experiments.
+// The repositories built on it honour the same contracts as the GORM ones.
+type MemoryStore struct {
+	mu             sync.RWMutex
+	users          map[uint]models.User
+	messages       map[uint]models.Message
+	identities     map[uint]models.UserIdentity
+	auditEvents    []models.AuditEvent // in insertion order
+	reports        map[uint]models.Report
+	lastUserID     uint
+	lastMessageID  uint
+	lastIdentityID uint
+	lastReportID   uint
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		users:      map[uint]models.User{},
+		messages:   map[uint]models.Message{},
+		identities: map[uint]models.UserIdentity{},
+		reports:    map[uint]models.Report{},
+	}
+}
+
+// NewMemorySet returns repositories sharing a new, empty MemoryStore
+func NewMemorySet() Set {
+	store := NewMemoryStore()
+	return Set{
+		Users:      NewMemoryUserRepository(store),
+		Messages:   NewMemoryMessageRepository(store),
+		Identities: NewMemoryIdentityRepository(store),
+		Audit:      NewMemoryAuditRepository(store),
+		Reports:    NewMemoryReportRepository(store),
+	}
+}
+
+type memoryUserRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryUserRepository(store *MemoryStore) UserRepository {
+	return &memoryUserRepository{store: store}
+}
+
+func (r *memoryUserRepository) CreateUser(ctx context.Context, user *models.User) (*models.User, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.userConflict(user) {
+		return nil, ErrDuplicate
+	}
+
+	if user.Role == "" {
+		user.Role = models.RoleUser
+	}
+	s.lastUserID++
+	now := time.Now()
+	user.ID = s.lastUserID
+	user.CreatedAt = now
+	user.UpdatedAt = now
+	s.users[user.ID] = *user
+	return user, nil
+}
+
+func (r *memoryUserRepository) FindByID(ctx context.Context, id uint) (*models.User, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.users[id]
+	if !ok || user.IsDeleted() {
+		return nil, ErrNotFound
+	}
+	return &user, nil
+}
+
+func (r *memoryUserRepository) FindAll(ctx context.Context) ([]models.User, error) {
+	return r.store.findUsers(live(func(models.User) bool { return true })), nil
+}
+
+func (r *memoryUserRepository) UpdateUser(ctx context.Context, user *models.User) (*models.User, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.users[user.ID]
+	if !ok || existing.IsDeleted() {
+		return nil, ErrNotFound
+	}
+	if s.userConflict(user) {
+		return nil, ErrDuplicate
+	}
+
+	user.CreatedAt = existing.CreatedAt
+	user.Role = existing.Role
+	user.SuspendedUntil = existing.SuspendedUntil
+	user.TokenVersion = existing.TokenVersion
+	user.DeletedAt = existing.DeletedAt
+	user.UpdatedAt = time.Now()
+	s.users[user.ID] = *user
+	return user, nil
+}
+
+func (r *memoryUserRepository) UpdatePassword(ctx context.Context, id uint, hashedPassword string) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Like the UPDATE it stands for, updating a missing user is not an error
+	if user, ok := s.users[id]; ok && !user.IsDeleted() {
+		user.Password = hashedPassword
+		user.UpdatedAt = time.Now()
+		s.users[id] = user
+	}
+	return nil
+}
+
+func (r *memoryUserRepository) DeleteUser(ctx context.Context, id uint) error {
+	return r.store.updateUser(id, func(user *models.User) {
+		user.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	})
+}
+
+func (r *memoryUserRepository) RestoreUser(ctx context.Context, id uint) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[id]
+	if !ok || !user.IsDeleted() {
+		return ErrNotFound
+	}
+	user.DeletedAt = gorm.DeletedAt{}
+	user.UpdatedAt = time.Now()
+	s.users[id] = user
+	return nil
+}
+
+func (r *memoryUserRepository) PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purged := map[uint]bool{}
+	for id, user := range s.users {
+		if user.IsDeleted() && user.DeletedAt.Time.Before(deletedBefore) {
+			purged[id] = true
+		}
+	}
+	// Zero stands for the NULL the foreign keys set
+	gone := func(id uint) bool { return id == 0 || purged[id] }
+	for id, message := range s.messages {
+		if gone(message.SenderID) && gone(message.ReceiverID) {
+			delete(s.messages, id)
+			s.clearReportedMessage(id)
+			continue
+		}
+		if purged[message.SenderID] {
+			message.SenderID = 0
+		}
+		if purged[message.ReceiverID] {
+			message.ReceiverID = 0
+		}
+		s.messages[id] = message
+	}
+	for id, identity := range s.identities {
+		if purged[identity.UserID] {
+			delete(s.identities, id)
+		}
+	}
+	for id, report := range s.reports {
+		if report.ReporterID != nil && purged[*report.ReporterID] {
+			report.ReporterID = nil
+		}
+		if purged[report.ReportedUserID] {
+			report.ReportedUserID = 0
+		}
+		if report.ResolvedByID != nil && purged[*report.ResolvedByID] {
+			report.ResolvedByID = nil
+		}
+		s.reports[id] = report
+	}
+	for id := range purged {
+		delete(s.users, id)
+	}
+	return int64(len(purged)), nil
+}
+
+func (r *memoryUserRepository) FindByUsername(ctx context.Context, username string) (*models.User, error) {
+	return r.store.findUser(live(func(user models.User) bool { return user.Username == username }))
+}
+
+func (r *memoryUserRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	return r.store.findUser(live(func(user models.User) bool { return user.Email == email }))
+}
+
+// IsUsernameExist also counts deleted users: their username stays taken until they are purged
+func (r *memoryUserRepository) IsUsernameExist(ctx context.Context, username string) bool {
+	_, err := r.store.findUser(func(user models.User) bool { return user.Username == username })
+	return err == nil
+}
+
+func (r *memoryUserRepository) IsEmailExist(ctx context.Context, email string) bool {
+	_, err := r.store.findUser(func(user models.User) bool { return user.Email == email })
+	return err == nil
+}
+
+// SearchUser matches like the SQL LIKE patterns of the GORM implementation, case-sensitively
+func (r *memoryUserRepository) SearchUser(ctx context.Context, currentUsername string, searchContent string) ([]models.User, error) {
+	if searchContent == "" {
+		return nil, nil
+	}
+
+	if searchContent[0] == '@' {
+		return r.store.findUsers(live(func(user models.User) bool {
+			return user.Username != currentUsername && strings.Contains(user.Username, searchContent[1:])
+		})), nil
+	}
+	return r.store.findUsers(live(func(user models.User) bool {
+		return user.Username != currentUsername && (strings.Contains(user.FirstName, searchContent) ||
+			strings.Contains(user.LastName, searchContent) || strings.Contains(user.Username, searchContent))
+	})), nil
+}
+
+// ListUsers returns a page of the matching users ordered by ID
+func (r *memoryUserRepository) ListUsers(ctx context.Context, filter UserFilter) ([]models.User, error) {
+	users := r.store.findUsers(filter.matches)
+	users = users[min(filter.Offset, len(users)):]
+	if filter.Limit > 0 && len(users) > filter.Limit {
+		users = users[:filter.Limit]
+	}
+	return users, nil
+}
+
+func (r *memoryUserRepository) CountUsers(ctx context.Context, filter UserFilter) (int64, error) {
+	return int64(len(r.store.findUsers(filter.matches))), nil
+}
+
+func (f UserFilter) matches(user models.User) bool {
+	search := strings.ToLower(f.Search)
+	switch {
+	case user.IsDeleted() != f.Deleted:
+		return false
+	case search != "" && !strings.Contains(strings.ToLower(user.Username), search) &&
+		!strings.Contains(strings.ToLower(user.Email), search) &&
+		!strings.Contains(strings.ToLower(user.FirstName), search) &&
+		!strings.Contains(strings.ToLower(user.LastName), search):
+		return false
+	case f.Role != "" && user.Role != f.Role:
+		return false
+	case f.Suspended != nil && user.IsSuspended(f.At) != *f.Suspended:
+		return false
+	}
+	return true
+}
+
+func (r *memoryUserRepository) UpdateRole(ctx context.Context, id uint, role string) error {
+	return r.store.updateUser(id, func(user *models.User) { user.Role = role })
+}
+
+func (r *memoryUserRepository) UpdateSuspension(ctx context.Context, id uint, until *time.Time) error {
+	return r.store.updateUser(id, func(user *models.User) {
+		user.SuspendedUntil = nil
+		if until != nil {
+			copied := *until
+			user.SuspendedUntil = &copied
+		}
+	})
+}
+
+func (r *memoryUserRepository) RevokeTokens(ctx context.Context, id uint) error {
+	return r.store.updateUser(id, func(user *models.User) { user.TokenVersion++ })
+}
+
+// updateUser applies the change to a stored user
+func (s *MemoryStore) updateUser(id uint, change func(user *models.User)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[id]
+	if !ok || user.IsDeleted() {
+		return ErrNotFound
+	}
+	change(&user)
+	user.UpdatedAt = time.Now()
+	s.users[id] = user
+	return nil
+}
+
+// live restricts a match to the users that are not deleted, like the GORM soft delete scope
+func live(match func(models.User) bool) func(models.User) bool {
+	return func(user models.User) bool { return !user.IsDeleted() && match(user) }
+}
+
+// userConflict reports whether another user has the same username or email; the caller holds the lock
+func (s *MemoryStore) userConflict(user *models.User) bool {
+	for id, other := range s.users {
+		if id != user.ID && (other.Username == user.Username || other.Email == user.Email) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *MemoryStore) findUser(match func(models.User) bool) (*models.User, error) {
+	users := s.findUsers(match)
+	if len(users) == 0 {
+		return nil, ErrNotFound
+	}
+	return &users[0], nil
+}
+
+// findUsers returns the matching users ordered by ID
+func (s *MemoryStore) findUsers(match func(models.User) bool) []models.User {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var users []models.User
+	for _, user := range s.users {
+		if match(user) {
+			users = append(users, user)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users
+}
+
+type memoryMessageRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryMessageRepository(store *MemoryStore) MessageRepository {
+	return &memoryMessageRepository{store: store}
+}
+
+func (r *memoryMessageRepository) CreateMessage(ctx context.Context, message *models.Message) (*models.Message, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sender, senderOK := s.users[message.SenderID]
+	receiver, receiverOK := s.users[message.ReceiverID]
+	if !senderOK || !receiverOK || sender.IsDeleted() || receiver.IsDeleted() {
+		return nil, ErrMissingReference
+	}
+
+	s.lastMessageID++
+	now := time.Now()
+	message.ID = s.lastMessageID
+	message.CreatedAt = now
+	message.UpdatedAt = now
+	s.messages[message.ID] = *message
+
+	message.Sender = sender
+	return message, nil
+}
+
+func (r *memoryMessageRepository) FindMessageByID(ctx context.Context, id uint) (*models.Message, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	message, ok := s.messages[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &message, nil
+}
+
+func (r *memoryMessageRepository) UpdateMessage(ctx context.Context, message *models.Message) (*models.Message, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.messages[message.ID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	// The participants never change, as in the GORM implementation
+	message.SenderID, message.ReceiverID = existing.SenderID, existing.ReceiverID
+	message.CreatedAt = existing.CreatedAt
+	message.UpdatedAt = time.Now()
+	stored := *message
+	stored.Sender, stored.Receiver = models.User{}, models.User{}
+	s.messages[message.ID] = stored
+	return message, nil
+}
+
+func (r *memoryMessageRepository) DeleteMessage(ctx context.Context, id uint) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.messages[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.messages, id)
+	s.clearReportedMessage(id)
+	return nil
+}
+
+// clearReportedMessage keeps the reports of a deleted message, with the content but no longer the
+// reference, as the foreign key does; the caller holds the lock
+func (s *MemoryStore) clearReportedMessage(messageID uint) {
+	for id, report := range s.reports {
+		if report.MessageID != nil && *report.MessageID == messageID {
+			report.MessageID = nil
+			s.reports[id] = report
+		}
+	}
+}
+
+// FindBySenderIdAndReceiverId returns the conversation newest first
+func (r *memoryMessageRepository) FindBySenderIdAndReceiverId(ctx context.Context, currentID uint, friendID uint) ([]models.Message, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var messages []models.Message
+	for _, message := range s.messages {
+		if message.SenderID == currentID && message.ReceiverID == friendID ||
+			message.SenderID == friendID && message.ReceiverID == currentID {
+			messages = append(messages, message)
+		}
+	}
+	sort.Slice(messages, func(i, j int) bool {
+		if !messages[i].CreatedAt.Equal(messages[j].CreatedAt) {
+			return messages[i].CreatedAt.After(messages[j].CreatedAt)
+		}
+		return messages[i].ID > messages[j].ID
+	})
+	return messages, nil
+}
+
+// GetFriendListWithLastMessage returns one entry per conversation, most recent first
+func (r *memoryMessageRepository) GetFriendListWithLastMessage(ctx context.Context, userID uint) ([]FriendsList, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	lastMessages := map[uint]models.Message{}
+	for _, message := range s.messages {
+		friendID := message.ReceiverID
+		if message.ReceiverID == userID {
+			friendID = message.SenderID
+		} else if message.SenderID != userID {
+			continue
+		}
+		if friendID == userID {
+			continue
+		}
+		if last, ok := lastMessages[friendID]; !ok || message.ID > last.ID {
+			lastMessages[friendID] = message
+		}
+	}
+
+	friendsList := make([]FriendsList, 0, len(lastMessages))
+	lastIDs := make(map[uint]uint, len(lastMessages))
+	for friendID, message := range lastMessages {
+		var entry FriendsList
+		entry.setProfile(s.users[friendID])
+		entry.LastMessage.Content = message.Content
+		entry.LastMessage.Time = message.CreatedAt.Format("2006-01-02 15:04")
+
+		// sample data, as in the GORM implementation
+		entry.UnreadCount = 0
+		entry.LastSeen = "2021-09-01 15:04"
+
+		friendsList = append(friendsList, entry)
+		lastIDs[friendID] = message.ID
+	}
+	sort.Slice(friendsList, func(i, j int) bool {
+		return lastIDs[friendsList[i].Profile.ID] > lastIDs[friendsList[j].Profile.ID]
+	})
+	return friendsList, nil
+}
+
+type memoryIdentityRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryIdentityRepository(store *MemoryStore) IdentityRepository {
+	return &memoryIdentityRepository{store: store}
+}
+
+func (r *memoryIdentityRepository) CreateIdentity(ctx context.Context, identity *models.UserIdentity) (*models.UserIdentity, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[identity.UserID]; !ok {
+		return nil, ErrMissingReference
+	}
+	for _, other := range s.identities {
+		if other.Provider == identity.Provider && other.Subject == identity.Subject {
+			return nil, ErrDuplicate
+		}
+	}
+
+	s.lastIdentityID++
+	identity.ID = s.lastIdentityID
+	identity.CreatedAt = time.Now()
+	s.identities[identity.ID] = *identity
+	return identity, nil
+}
+
+func (r *memoryIdentityRepository) FindByProviderAndSubject(ctx context.Context, provider string, subject string) (*models.UserIdentity, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, identity := range s.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			return &identity, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryMessageRepository) CountMessages(ctx context.Context, since time.Time) (int64, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var count int64
+	for _, message := range s.messages {
+		if !message.CreatedAt.Before(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+type memoryAuditRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryAuditRepository(store *MemoryStore) AuditRepository {
+	return &memoryAuditRepository{store: store}
+}
+
+func (r *memoryAuditRepository) CreateEvent(ctx context.Context, event *models.AuditEvent) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	event.ID = uint(len(s.auditEvents) + 1)
+	event.CreatedAt = time.Now()
+	s.auditEvents = append(s.auditEvents, *event)
+	return nil
+}
+
+// ListEvents returns a page of the matching events, newest first
+func (r *memoryAuditRepository) ListEvents(ctx context.Context, filter AuditFilter) ([]models.AuditEvent, error) {
+	events := r.findEvents(filter)
+	events = events[min(filter.Offset, len(events)):]
+	if filter.Limit > 0 && len(events) > filter.Limit {
+		events = events[:filter.Limit]
+	}
+	return events, nil
+}
+
+func (r *memoryAuditRepository) CountEvents(ctx context.Context, filter AuditFilter) (int64, error) {
+	return int64(len(r.findEvents(filter))), nil
+}
+
+func (r *memoryAuditRepository) findEvents(filter AuditFilter) []models.AuditEvent {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var events []models.AuditEvent
+	for i := len(s.auditEvents) - 1; i >= 0; i-- {
+		if filter.matches(s.auditEvents[i]) {
+			events = append(events, s.auditEvents[i])
+		}
+	}
+	return events
+}
+
+func (f AuditFilter) matches(event models.AuditEvent) bool {
+	switch {
+	case f.Action != "" && event.Action != f.Action:
+		return false
+	case f.Outcome != "" && event.Outcome != f.Outcome:
+		return false
+	case f.ActorID != 0 && (event.ActorID == nil || *event.ActorID != f.ActorID):
+		return false
+	case f.TargetID != 0 && (event.TargetID == nil || *event.TargetID != f.TargetID):
+		return false
+	case f.IP != "" && event.IP != f.IP:
+		return false
+	case !f.Since.IsZero() && event.CreatedAt.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && !event.CreatedAt.Before(f.Until):
+		return false
+	}
+	return true
+}
+
+type memoryReportRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryReportRepository(store *MemoryStore) ReportRepository {
+	return &memoryReportRepository{store: store}
+}
+
+func (r *memoryReportRepository) CreateReport(ctx context.Context, report *models.Report) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[report.ReportedUserID]; !ok {
+		return ErrMissingReference
+	}
+	if report.ReporterID != nil {
+		if _, ok := s.users[*report.ReporterID]; !ok {
+			return ErrMissingReference
+		}
+	}
+	if report.MessageID != nil {
+		if _, ok := s.messages[*report.MessageID]; !ok {
+			return ErrMissingReference
+		}
+	}
+
+	s.lastReportID++
+	now := time.Now()
+	report.ID = s.lastReportID
+	report.CreatedAt = now
+	report.UpdatedAt = now
+	stored := *report
+	stored.Actions = slices.Clone(report.Actions)
+	s.reports[report.ID] = stored
+	return nil
+}
+
+func (r *memoryReportRepository) FindReportByID(ctx context.Context, id uint) (*models.Report, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	report, ok := s.reports[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	report.Actions = slices.Clone(report.Actions)
+	return &report, nil
+}
+
+// ListReports returns a page of the matching reports, oldest first
+func (r *memoryReportRepository) ListReports(ctx context.Context, filter ReportFilter) ([]models.Report, error) {
+	reports := r.findReports(filter)
+	reports = reports[min(filter.Offset, len(reports)):]
+	if filter.Limit > 0 && len(reports) > filter.Limit {
+		reports = reports[:filter.Limit]
+	}
+	return reports, nil
+}
+
+func (r *memoryReportRepository) CountReports(ctx context.Context, filter ReportFilter) (int64, error) {
+	return int64(len(r.findReports(filter))), nil
+}
+
+func (r *memoryReportRepository) ResolveReport(ctx context.Context, report *models.Report) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.reports[report.ID]
+	if !ok || !stored.IsOpen() {
+		return ErrNotFound
+	}
+	if report.ResolvedByID != nil {
+		if _, ok := s.users[*report.ResolvedByID]; !ok {
+			return ErrMissingReference
+		}
+	}
+
+	stored.Status = report.Status
+	stored.Actions = slices.Clone(report.Actions)
+	stored.ModeratorNote = report.ModeratorNote
+	stored.ResolvedByID = report.ResolvedByID
+	stored.ResolvedAt = report.ResolvedAt
+	stored.UpdatedAt = time.Now()
+	s.reports[report.ID] = stored
+	return nil
+}
+
+func (r *memoryReportRepository) ReopenReport(ctx context.Context, id uint) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.reports[id]
+	if !ok || stored.IsOpen() {
+		return ErrNotFound
+	}
+
+	stored.Status = models.ReportStatusOpen
+	stored.Actions = nil
+	stored.ModeratorNote = ""
+	stored.ResolvedByID = nil
+	stored.ResolvedAt = nil
+	stored.UpdatedAt = time.Now()
+	s.reports[id] = stored
+	return nil
+}
+
+func (r *memoryReportRepository) findReports(filter ReportFilter) []models.Report {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var reports []models.Report
+	for _, report := range s.reports {
+		if filter.matches(report) {
+			report.Actions = slices.Clone(report.Actions)
+			reports = append(reports, report)
+		}
+	}
+	sort.Slice(reports, func(i, j int) bool { return reports[i].ID < reports[j].ID })
+	return reports
+}
+
+func (f ReportFilter) matches(report models.Report) bool {
+	switch {
+	case f.Status != "" && report.Status != f.Status:
+		return false
+	case f.TargetType != "" && report.TargetType != f.TargetType:
+		return false
+	case f.Reason != "" && report.Reason != f.Reason:
+		return false
+	case f.ReporterID != 0 && (report.ReporterID == nil || *report.ReporterID != f.ReporterID):
+		return false
+	case f.ReportedUserID != 0 && report.ReportedUserID != f.ReportedUserID:
+		return false
+	case f.MessageID != 0 && (report.MessageID == nil || *report.MessageID != f.MessageID):
+		return false
+	}
+	return true
+}
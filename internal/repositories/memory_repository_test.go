@@ -0,0 +1,17 @@
+package repositories
+
+import "testing"
+
+func TestMemoryRepositories(t *testing.T) {
+	runRepositoryContract(t, func(t *testing.T) (UserRepository, MessageRepository) {
+		store := NewMemoryStore()
+		return NewMemoryUserRepository(store), NewMemoryMessageRepository(store)
+	})
+	runAuditContract(t, func(t *testing.T) AuditRepository {
+		return NewMemoryAuditRepository(NewMemoryStore())
+	})
+	runReportContract(t, func(t *testing.T) (ReportRepository, UserRepository, MessageRepository) {
+		store := NewMemoryStore()
+		return NewMemoryReportRepository(store), NewMemoryUserRepository(store), NewMemoryMessageRepository(store)
+	})
+}
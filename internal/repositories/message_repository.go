@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"chat-app-api/internal/models"
+	"context"
 	"database/sql"
 	"gorm.io/gorm"
 	"time"
@@ -14,6 +15,7 @@ type FriendsList struct {
 		LastName        string `json:"last_name"`
 		ProfileImageUrl string `json:"profile_image_url"`
 		Username        string `json:"username"`
+		Deleted         bool   `json:"deleted"`
 	} `json:"profile"`
 	LastSeen    string `json:"last_seen"`
 	UnreadCount int    `json:"unread_count"`
@@ -23,12 +25,29 @@ type FriendsList struct {
 	} `json:"last_message"`
 }
 
+// setProfile fills in the conversation partner, or their tombstone when they have been deleted.
+// A purged partner has no row left and comes as the zero user.
+func (f *FriendsList) setProfile(user models.User) {
+	deleted := user.IsDeleted() || user.ID == 0
+	if deleted {
+		user = user.Tombstone()
+	}
+	f.Profile.ID = user.ID
+	f.Profile.FirstName = user.FirstName
+	f.Profile.LastName = user.LastName
+	f.Profile.ProfileImageUrl = user.ProfileImageUrl
+	f.Profile.Username = user.Username
+	f.Profile.Deleted = deleted
+}
+
 type MessageRepository interface {
-	CreateMessage(message *models.Message) (*models.Message, error)
-	UpdateMessage(message *models.Message) (*models.Message, error)
-	DeleteMessage(id uint) error
-	FindBySenderIdAndReceiverId(senderID uint, receiverID uint) ([]models.Message, error)
-	GetFriendListWithLastMessage(userID uint) ([]FriendsList, error)
+	CreateMessage(ctx context.Context, message *models.Message) (*models.Message, error)
+	FindMessageByID(ctx context.Context, id uint) (*models.Message, error)
+	UpdateMessage(ctx context.Context, message *models.Message) (*models.Message, error)
+	DeleteMessage(ctx context.Context, id uint) error
+	FindBySenderIdAndReceiverId(ctx context.Context, senderID uint, receiverID uint) ([]models.Message, error)
+	GetFriendListWithLastMessage(ctx context.Context, userID uint) ([]FriendsList, error)
+	CountMessages(ctx context.Context, since time.Time) (int64, error)
 }
 
 type messageRepository struct {
@@ -39,54 +58,83 @@ func NewMessageRepository(db *gorm.DB) MessageRepository {
 	return &messageRepository{db: db}
 }
 
-func (r *messageRepository) CreateMessage(message *models.Message) (*models.Message, error) {
-	if err := r.db.Create(message).Error; err != nil {
-		return nil, err
+// CreateMessage stores the message; deleted users, whose rows remain until they are purged,
+// can neither send nor receive
+func (r *messageRepository) CreateMessage(ctx context.Context, message *models.Message) (*models.Message, error) {
+	var live int64
+	if err := r.db.WithContext(ctx).Model(&models.User{}).Where("id IN ?", []uint{message.SenderID, message.ReceiverID}).Count(&live).Error; err != nil {
+		return nil, translateError(err)
+	}
+	if live == 0 || (live == 1 && message.SenderID != message.ReceiverID) {
+		return nil, ErrMissingReference
+	}
+
+	if err := r.db.WithContext(ctx).Create(message).Error; err != nil {
+		return nil, translateError(err)
 	}
 
 	// Preload the sender information
-	if err := r.db.Preload("Sender").First(message, message.ID).Error; err != nil {
-		return nil, err
+	if err := r.db.WithContext(ctx).Preload("Sender").First(message, message.ID).Error; err != nil {
+		return nil, translateError(err)
 	}
 
 	return message, nil
 }
 
-func (r *messageRepository) UpdateMessage(message *models.Message) (*models.Message, error) {
-	if err := r.db.Save(message).Error; err != nil {
-		return nil, err
+func (r *messageRepository) FindMessageByID(ctx context.Context, id uint) (*models.Message, error) {
+	var message models.Message
+	if err := r.db.WithContext(ctx).First(&message, id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &message, nil
+}
+
+// UpdateMessage writes every column but created_at and the participants, which a purge may have
+// cleared; unlike Save it never inserts a missing message
+func (r *messageRepository) UpdateMessage(ctx context.Context, message *models.Message) (*models.Message, error) {
+	result := r.db.WithContext(ctx).Model(message).Select("*").Omit("CreatedAt", "SenderID", "ReceiverID", "Sender", "Receiver").Updates(message)
+	if result.Error != nil {
+		return nil, translateError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrNotFound
 	}
 	return message, nil
 }
 
-func (r *messageRepository) DeleteMessage(id uint) error {
-	if err := r.db.Delete(&models.Message{}, id).Error; err != nil {
-		return err
+func (r *messageRepository) DeleteMessage(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Delete(&models.Message{}, id)
+	if result.Error != nil {
+		return translateError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
 	}
 	return nil
 }
 
-func (r *messageRepository) FindBySenderIdAndReceiverId(currentID uint, friendID uint) ([]models.Message, error) {
+func (r *messageRepository) FindBySenderIdAndReceiverId(ctx context.Context, currentID uint, friendID uint) ([]models.Message, error) {
 	var messages []models.Message
 
 	// Query to fetch all messages between current user and friend, regardless of who sent it
-	if err := r.db.Where("(sender_id = ? AND receiver_id = ?) OR (sender_id = ? AND receiver_id = ?)",
+	if err := r.db.WithContext(ctx).Where("(sender_id = ? AND receiver_id = ?) OR (sender_id = ? AND receiver_id = ?)",
 		currentID, friendID, friendID, currentID).
-		Order("created_at desc").
+		Order("created_at desc, id desc").
 		Find(&messages).Error; err != nil {
-		return nil, err
+		return nil, translateError(err)
 	}
 
 	return messages, nil
 }
 
-func (r *messageRepository) GetFriendListWithLastMessage(userID uint) ([]FriendsList, error) {
+func (r *messageRepository) GetFriendListWithLastMessage(ctx context.Context, userID uint) ([]FriendsList, error) {
 	var friendsList []FriendsList
 
 	// Query to fetch the friend and the last message details
-	rows, err := r.db.Raw(`
+	rows, err := r.db.WithContext(ctx).Raw(`
 		SELECT 
-			u.id, u.first_name, u.last_name, u.profile_image_url, u.username,
+			COALESCE(u.id, 0), COALESCE(u.first_name, ''), COALESCE(u.last_name, ''),
+			COALESCE(u.profile_image_url, ''), COALESCE(u.username, ''), u.deleted_at,
 			m1.content AS last_message_content, 
 			m1.created_at AS last_message_time
 		FROM messages m1
@@ -94,14 +142,17 @@ func (r *messageRepository) GetFriendListWithLastMessage(userID uint) ([]Friends
 			SELECT MAX(id) AS id
 			FROM messages
 			WHERE sender_id = ? OR receiver_id = ?
-			GROUP BY LEAST(sender_id, receiver_id), GREATEST(sender_id, receiver_id)
+			-- One group per conversation partner; portable unlike LEAST/GREATEST, which SQLite lacks.
+			-- Purged partners are NULL and share a group.
+			GROUP BY CASE WHEN sender_id = ? THEN receiver_id ELSE sender_id END
 		) m2 ON m1.id = m2.id
-		INNER JOIN users u ON (u.id = m1.sender_id OR u.id = m1.receiver_id)
-		WHERE u.id != ?
-	`, userID, userID, userID).Rows()
+		LEFT JOIN users u ON u.id = CASE WHEN m1.sender_id = ? THEN m1.receiver_id ELSE m1.sender_id END
+		WHERE u.id IS NULL OR u.id != ?
+		ORDER BY m1.id DESC
+	`, userID, userID, userID, userID, userID).Rows()
 
 	if err != nil {
-		return nil, err
+		return nil, translateError(err)
 	}
 	defer func(rows *sql.Rows) {
 		err := rows.Close()
@@ -113,21 +164,25 @@ func (r *messageRepository) GetFriendListWithLastMessage(userID uint) ([]Friends
 	// Process rows and map to FriendsList struct
 	for rows.Next() {
 		var friend FriendsList
+		var user models.User
 		var lastMessageTime time.Time
 
 		// Scan the results into the FriendsList structure
 		if err := rows.Scan(
-			&friend.Profile.ID,
-			&friend.Profile.FirstName,
-			&friend.Profile.LastName,
-			&friend.Profile.ProfileImageUrl,
-			&friend.Profile.Username,
+			&user.ID,
+			&user.FirstName,
+			&user.LastName,
+			&user.ProfileImageUrl,
+			&user.Username,
+			&user.DeletedAt,
 			&friend.LastMessage.Content,
 			&lastMessageTime,
 		); err != nil {
-			return nil, err
+			return nil, translateError(err)
 		}
 
+		friend.setProfile(user)
+
 		// Format the last message time as a string (if you need a specific format)
 		friend.LastMessage.Time = lastMessageTime.Format("2006-01-02 15:04")
 
@@ -141,3 +196,17 @@ func (r *messageRepository) GetFriendListWithLastMessage(userID uint) ([]Friends
 
 	return friendsList, nil
 }
+
+// CountMessages counts the messages sent since the given time, or every message when it is zero
+func (r *messageRepository) CountMessages(ctx context.Context, since time.Time) (int64, error) {
+	query := r.db.WithContext(ctx).Model(&models.Message{})
+	if !since.IsZero() {
+		query = query.Where("created_at >= ?", since.UTC())
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return 0, translateError(err)
+	}
+	return count, nil
+}
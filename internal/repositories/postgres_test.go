@@ -0,0 +1,52 @@
+package repositories
+
+import (
+	"chat-app-api/internal/config"
+	"chat-app-api/internal/database"
+	"context"
+	"os"
+	"testing"
+)
+
+// TestPostgresRepositories runs the contract against a real database. Point TEST_DATABASE_DSN
+// at a disposable database: it is migrated and its tables are emptied before every test.
+func TestPostgresRepositories(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+
+	db, err := database.Connect(config.DatabaseConfig{DSN: dsn})
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	if db.Dialector.Name() != database.Postgres {
+		t.Skip("TEST_DATABASE_DSN is not a PostgreSQL database")
+	}
+	migrator, err := database.NewMigrator(db)
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	// TRUNCATE also empties audit_events, whose triggers only forbid UPDATE and DELETE
+	emptyTables := func(t *testing.T) {
+		if err := db.Exec("TRUNCATE reports, messages, user_identities, users, audit_events RESTART IDENTITY CASCADE").Error; err != nil {
+			t.Fatalf("empty tables: %v", err)
+		}
+	}
+	runRepositoryContract(t, func(t *testing.T) (UserRepository, MessageRepository) {
+		emptyTables(t)
+		return NewUserRepository(db), NewMessageRepository(db)
+	})
+	runAuditContract(t, func(t *testing.T) AuditRepository {
+		emptyTables(t)
+		return NewAuditRepository(db)
+	})
+	runReportContract(t, func(t *testing.T) (ReportRepository, UserRepository, MessageRepository) {
+		emptyTables(t)
+		return NewReportRepository(db), NewUserRepository(db), NewMessageRepository(db)
+	})
+}
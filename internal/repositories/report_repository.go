@@ -0,0 +1,122 @@
+package repositories
+
+import (
+	"chat-app-api/internal/models"
+	"context"
+	"gorm.io/gorm"
+)
+
+// ReportFilter selects reports for the moderation queue; zero values match every report
+type ReportFilter struct {
+	Status         string
+	TargetType     string
+	Reason         string
+	ReporterID     uint
+	ReportedUserID uint
+	MessageID      uint
+	Limit          int // no limit when zero
+	Offset         int
+}
+
+type ReportRepository interface {
+	CreateReport(ctx context.Context, report *models.Report) error
+	FindReportByID(ctx context.Context, id uint) (*models.Report, error)
+	ListReports(ctx context.Context, filter ReportFilter) ([]models.Report, error)
+	CountReports(ctx context.Context, filter ReportFilter) (int64, error)
+	ResolveReport(ctx context.Context, report *models.Report) error
+	ReopenReport(ctx context.Context, id uint) error
+}
+
+type reportRepository struct {
+	db *gorm.DB
+}
+
+func NewReportRepository(db *gorm.DB) ReportRepository {
+	return &reportRepository{db: db}
+}
+
+func (r *reportRepository) CreateReport(ctx context.Context, report *models.Report) error {
+	return translateError(r.db.WithContext(ctx).Create(report).Error)
+}
+
+func (r *reportRepository) FindReportByID(ctx context.Context, id uint) (*models.Report, error) {
+	var report models.Report
+	if err := r.db.WithContext(ctx).First(&report, id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &report, nil
+}
+
+// ListReports returns a page of the matching reports, oldest first like a queue
+func (r *reportRepository) ListReports(ctx context.Context, filter ReportFilter) ([]models.Report, error) {
+	query := r.filterReports(ctx, filter).Order("id").Offset(filter.Offset)
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var reports []models.Report
+	if err := query.Find(&reports).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return reports, nil
+}
+
+// CountReports counts every report matching the filter, ignoring its limit and offset
+func (r *reportRepository) CountReports(ctx context.Context, filter ReportFilter) (int64, error) {
+	var count int64
+	if err := r.filterReports(ctx, filter).Count(&count).Error; err != nil {
+		return 0, translateError(err)
+	}
+	return count, nil
+}
+
+// ResolveReport writes the status, actions, note and moderator of a report that is still open.
+// It returns ErrNotFound when the report does not exist or was resolved in the meantime.
+func (r *reportRepository) ResolveReport(ctx context.Context, report *models.Report) error {
+	result := r.db.WithContext(ctx).Model(report).Where("status = ?", models.ReportStatusOpen).
+		Select("Status", "Actions", "ModeratorNote", "ResolvedByID", "ResolvedAt").Updates(report)
+	if result.Error != nil {
+		return translateError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ReopenReport clears the resolution of a resolved report, for a resolution that could not be completed.
+// It returns ErrNotFound when the report does not exist or is open.
+func (r *reportRepository) ReopenReport(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Model(&models.Report{}).Where("id = ? AND status <> ?", id, models.ReportStatusOpen).
+		Updates(map[string]any{"status": models.ReportStatusOpen, "actions": nil, "moderator_note": "", "resolved_by_id": nil, "resolved_at": nil})
+	if result.Error != nil {
+		return translateError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *reportRepository) filterReports(ctx context.Context, filter ReportFilter) *gorm.DB {
+	query := r.db.WithContext(ctx).Model(&models.Report{})
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.TargetType != "" {
+		query = query.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.Reason != "" {
+		query = query.Where("reason = ?", filter.Reason)
+	}
+	if filter.ReporterID != 0 {
+		query = query.Where("reporter_id = ?", filter.ReporterID)
+	}
+	if filter.ReportedUserID != 0 {
+		query = query.Where("reported_user_id = ?", filter.ReportedUserID)
+	}
+	if filter.MessageID != 0 {
+		query = query.Where("message_id = ?", filter.MessageID)
+	}
+	return query
+}
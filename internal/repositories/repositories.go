@@ -0,0 +1,23 @@
+package repositories
+
+import "gorm.io/gorm"
+
+// Set is the storage the services are built on
+type Set struct {
+	Users      UserRepository
+	Messages   MessageRepository
+	Identities IdentityRepository
+	Audit      AuditRepository
+	Reports    ReportRepository
+}
+
+// NewSet returns the GORM repositories backed by the database
+func NewSet(db *gorm.DB) Set {
+	return Set{
+		Users:      NewUserRepository(db),
+		Messages:   NewMessageRepository(db),
+		Identities: NewIdentityRepository(db),
+		Audit:      NewAuditRepository(db),
+		Reports:    NewReportRepository(db),
+	}
+}
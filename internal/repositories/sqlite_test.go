@@ -0,0 +1,64 @@
+package repositories
+
+import (
+	"chat-app-api/internal/config"
+	"chat-app-api/internal/database"
+	"chat-app-api/internal/models"
+	"context"
+	"gorm.io/gorm"
+	"testing"
+)
+
+// TestSQLiteRepositories runs the contract against a fresh in-memory SQLite database per test,
+// which also covers the raw friend list query and the SQLite migrations
+func TestSQLiteRepositories(t *testing.T) {
+	runRepositoryContract(t, func(t *testing.T) (UserRepository, MessageRepository) {
+		db := newSQLiteDatabase(t)
+		return NewUserRepository(db), NewMessageRepository(db)
+	})
+	runAuditContract(t, func(t *testing.T) AuditRepository {
+		return NewAuditRepository(newSQLiteDatabase(t))
+	})
+	runReportContract(t, func(t *testing.T) (ReportRepository, UserRepository, MessageRepository) {
+		db := newSQLiteDatabase(t)
+		return NewReportRepository(db), NewUserRepository(db), NewMessageRepository(db)
+	})
+}
+
+func TestSQLiteAuditEventsAreAppendOnly(t *testing.T) {
+	db := newSQLiteDatabase(t)
+	event := &models.AuditEvent{Action: "auth.login", Outcome: models.OutcomeSuccess}
+	if err := NewAuditRepository(db).CreateEvent(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.Model(event).Update("outcome", models.OutcomeFailure).Error; err == nil {
+		t.Error("an audit event was updated")
+	}
+	if err := db.Delete(event).Error; err == nil {
+		t.Error("an audit event was deleted")
+	}
+}
+
+// newSQLiteDatabase returns a migrated in-memory database that is closed after the test
+func newSQLiteDatabase(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := database.Connect(config.DatabaseConfig{DSN: "sqlite://:memory:"})
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+
+	migrator, err := database.NewMigrator(db)
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
@@ -2,19 +2,42 @@ package repositories
 
 import (
 	"chat-app-api/internal/models"
+	"context"
 	"gorm.io/gorm"
+	"strings"
+	"time"
 )
 
+// UserFilter selects users for the admin listing; zero values match every user
+type UserFilter struct {
+	Search    string // part of the username, email, first or last name, case-insensitively
+	Role      string
+	Suspended *bool     // only suspended or only active accounts
+	Deleted   bool      // only deleted accounts awaiting their purge instead of the live ones
+	At        time.Time // the time suspension is evaluated at
+	Limit     int       // no limit when zero
+	Offset    int
+}
+
 type UserRepository interface {
-	CreateUser(user *models.User) (*models.User, error)
-	FindByID(id uint) (*models.User, error)
-	FindAll() ([]models.User, error)
-	UpdateUser(user *models.User) (*models.User, error)
-	DeleteUser(id uint) error
-	FindByUsername(username string) (*models.User, error)
-	IsUsernameExist(username string) bool
-	IsEmailExist(email string) bool
-	SearchUser(currentUsername string, searchContent string) ([]models.User, error)
+	CreateUser(ctx context.Context, user *models.User) (*models.User, error)
+	FindByID(ctx context.Context, id uint) (*models.User, error)
+	FindAll(ctx context.Context) ([]models.User, error)
+	UpdateUser(ctx context.Context, user *models.User) (*models.User, error)
+	UpdatePassword(ctx context.Context, id uint, hashedPassword string) error
+	DeleteUser(ctx context.Context, id uint) error
+	RestoreUser(ctx context.Context, id uint) error
+	PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time) (int64, error)
+	FindByUsername(ctx context.Context, username string) (*models.User, error)
+	FindByEmail(ctx context.Context, email string) (*models.User, error)
+	IsUsernameExist(ctx context.Context, username string) bool
+	IsEmailExist(ctx context.Context, email string) bool
+	SearchUser(ctx context.Context, currentUsername string, searchContent string) ([]models.User, error)
+	ListUsers(ctx context.Context, filter UserFilter) ([]models.User, error)
+	CountUsers(ctx context.Context, filter UserFilter) (int64, error)
+	UpdateRole(ctx context.Context, id uint, role string) error
+	UpdateSuspension(ctx context.Context, id uint, until *time.Time) error
+	RevokeTokens(ctx context.Context, id uint) error
 }
 
 type userRepository struct {
@@ -25,66 +48,138 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 	return &userRepository{db: db}
 }
 
-func (r *userRepository) CreateUser(user *models.User) (*models.User, error) {
-	if err := r.db.Create(user).Error; err != nil {
-		return nil, err
+func (r *userRepository) CreateUser(ctx context.Context, user *models.User) (*models.User, error) {
+	if user.Role == "" {
+		user.Role = models.RoleUser
+	}
+	if err := r.db.WithContext(ctx).Create(user).Error; err != nil {
+		return nil, translateError(err)
 	}
 	return user, nil
 }
 
-func (r *userRepository) FindByID(id uint) (*models.User, error) {
+func (r *userRepository) FindByID(ctx context.Context, id uint) (*models.User, error) {
 	var user models.User
-	if err := r.db.First(&user, id).Error; err != nil {
-		return nil, err
+	if err := r.db.WithContext(ctx).First(&user, id).Error; err != nil {
+		return nil, translateError(err)
 	}
 	return &user, nil
 }
 
-func (r *userRepository) FindAll() ([]models.User, error) {
+func (r *userRepository) FindAll(ctx context.Context) ([]models.User, error) {
 	var users []models.User
-	if err := r.db.Find(&users).Error; err != nil {
-		return nil, err
+	if err := r.db.WithContext(ctx).Order("id").Find(&users).Error; err != nil {
+		return nil, translateError(err)
 	}
 	return users, nil
 }
 
-func (r *userRepository) UpdateUser(user *models.User) (*models.User, error) {
-	if err := r.db.Save(user).Error; err != nil {
-		return nil, err
+// UpdateUser writes the profile and password; unlike Save it never inserts a missing user.
+// The role, suspension, token version and deletion only change through their own methods.
+func (r *userRepository) UpdateUser(ctx context.Context, user *models.User) (*models.User, error) {
+	result := r.db.WithContext(ctx).Model(user).Select("*").Omit("CreatedAt", "Role", "SuspendedUntil", "TokenVersion", "DeletedAt").Updates(user)
+	if result.Error != nil {
+		return nil, translateError(result.Error)
 	}
-	return user, nil
+	if result.RowsAffected == 0 {
+		return nil, ErrNotFound
+	}
+	return r.FindByID(ctx, user.ID)
+}
+
+func (r *userRepository) UpdatePassword(ctx context.Context, id uint, hashedPassword string) error {
+	return translateError(r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).Update("password", hashedPassword).Error)
 }
 
-func (r *userRepository) DeleteUser(id uint) error {
-	if err := r.db.Delete(&models.User{}, id).Error; err != nil {
-		return err
+// DeleteUser marks the user deleted; the row stays, hidden from every lookup, until PurgeDeletedUsers
+func (r *userRepository) DeleteUser(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Delete(&models.User{}, id)
+	if result.Error != nil {
+		return translateError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
 	}
 	return nil
 }
 
-func (r *userRepository) FindByUsername(username string) (*models.User, error) {
+// RestoreUser undoes DeleteUser as long as the user has not been purged
+func (r *userRepository) RestoreUser(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Unscoped().Model(&models.User{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).Update("deleted_at", nil)
+	if result.Error != nil {
+		return translateError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// PurgeDeletedUsers removes the users deleted before the given time with their linked identities,
+// and returns how many users were removed. Their messages and the reports by or about them stay,
+// with the purged user set to NULL by the foreign keys; conversations left without anyone go.
+func (r *userRepository) PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	var purged int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var ids []uint
+		if err := tx.Unscoped().Model(&models.User{}).Where("deleted_at < ?", deletedBefore.UTC()).Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		if err := tx.Where("(sender_id IN ? OR sender_id IS NULL) AND (receiver_id IN ? OR receiver_id IS NULL)", ids, ids).
+			Delete(&models.Message{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id IN ?", ids).Delete(&models.UserIdentity{}).Error; err != nil {
+			return err
+		}
+		result := tx.Unscoped().Delete(&models.User{}, ids)
+		purged = result.RowsAffected
+		return result.Error
+	})
+	if err != nil {
+		return 0, translateError(err)
+	}
+	return purged, nil
+}
+
+func (r *userRepository) FindByUsername(ctx context.Context, username string) (*models.User, error) {
 	var user models.User
-	if err := r.db.Where("username = ?", username).First(&user).Error; err != nil {
-		return nil, err
+	if err := r.db.WithContext(ctx).Where("username = ?", username).First(&user).Error; err != nil {
+		return nil, translateError(err)
 	}
 	return &user, nil
 }
 
-func (r *userRepository) IsUsernameExist(username string) bool {
-	if err := r.db.Where("username = ?", username).First(&models.User{}).Error; err != nil {
+func (r *userRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	var user models.User
+	if err := r.db.WithContext(ctx).Where("email = ?", email).First(&user).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &user, nil
+}
+
+// IsUsernameExist also counts deleted users: their username stays taken until they are purged
+func (r *userRepository) IsUsernameExist(ctx context.Context, username string) bool {
+	if err := r.db.WithContext(ctx).Unscoped().Where("username = ?", username).First(&models.User{}).Error; err != nil {
 		return false
 	}
 	return true
 }
 
-func (r *userRepository) IsEmailExist(email string) bool {
-	if err := r.db.Where("email = ?", email).First(&models.User{}).Error; err != nil {
+// IsEmailExist also counts deleted users, like IsUsernameExist
+func (r *userRepository) IsEmailExist(ctx context.Context, email string) bool {
+	if err := r.db.WithContext(ctx).Unscoped().Where("email = ?", email).First(&models.User{}).Error; err != nil {
 		return false
 	}
 	return true
 }
 
-func (r *userRepository) SearchUser(currentUsername string, searchContent string) ([]models.User, error) {
+func (r *userRepository) SearchUser(ctx context.Context, currentUsername string, searchContent string) ([]models.User, error) {
 	var users []models.User
 
 	// Return empty list if no search content is provided
@@ -93,20 +188,93 @@ func (r *userRepository) SearchUser(currentUsername string, searchContent string
 	}
 
 	// Create a base query that excludes the current user's username
-	query := r.db.Where("username != ?", currentUsername)
+	query := r.db.WithContext(ctx).Where("username != ?", currentUsername)
 
 	// Search by username if searchContent starts with '@'
 	if searchContent[0] == '@' {
 		// Search for usernames starting with the specified content
 		if err := query.Where("username LIKE ?", "%"+searchContent[1:]+"%").Find(&users).Error; err != nil {
-			return nil, err
+			return nil, translateError(err)
 		}
 	} else {
 		// Search by first name, last name, or username
 		if err := query.Where("(first_name LIKE ? OR last_name LIKE ? OR username LIKE ?) AND username != ?", "%"+searchContent+"%", "%"+searchContent+"%", "%"+searchContent+"%", currentUsername).Find(&users).Error; err != nil {
-			return nil, err
+			return nil, translateError(err)
 		}
 	}
 
 	return users, nil
 }
+
+// ListUsers returns a page of the matching users ordered by ID
+func (r *userRepository) ListUsers(ctx context.Context, filter UserFilter) ([]models.User, error) {
+	query := r.filterUsers(ctx, filter).Order("id").Offset(filter.Offset)
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var users []models.User
+	if err := query.Find(&users).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return users, nil
+}
+
+// CountUsers counts every user matching the filter, ignoring its limit and offset
+func (r *userRepository) CountUsers(ctx context.Context, filter UserFilter) (int64, error) {
+	var count int64
+	if err := r.filterUsers(ctx, filter).Count(&count).Error; err != nil {
+		return 0, translateError(err)
+	}
+	return count, nil
+}
+
+func (r *userRepository) filterUsers(ctx context.Context, filter UserFilter) *gorm.DB {
+	query := r.db.WithContext(ctx).Model(&models.User{})
+	if filter.Deleted {
+		query = query.Unscoped().Where("deleted_at IS NOT NULL")
+	}
+	if filter.Search != "" {
+		pattern := "%" + strings.ToLower(filter.Search) + "%"
+		query = query.Where("(LOWER(username) LIKE ? OR LOWER(email) LIKE ? OR LOWER(first_name) LIKE ? OR LOWER(last_name) LIKE ?)",
+			pattern, pattern, pattern, pattern)
+	}
+	if filter.Role != "" {
+		query = query.Where("role = ?", filter.Role)
+	}
+	if filter.Suspended != nil && *filter.Suspended {
+		query = query.Where("suspended_until > ?", filter.At.UTC())
+	} else if filter.Suspended != nil {
+		query = query.Where("(suspended_until IS NULL OR suspended_until <= ?)", filter.At.UTC())
+	}
+	return query
+}
+
+func (r *userRepository) UpdateRole(ctx context.Context, id uint, role string) error {
+	return r.updateColumn(ctx, id, "role", role)
+}
+
+// UpdateSuspension suspends the user until the given time, or lifts the suspension when it is nil
+func (r *userRepository) UpdateSuspension(ctx context.Context, id uint, until *time.Time) error {
+	if until != nil {
+		utc := until.UTC()
+		until = &utc
+	}
+	return r.updateColumn(ctx, id, "suspended_until", until)
+}
+
+// RevokeTokens bumps the token version, invalidating every token issued to the user so far
+func (r *userRepository) RevokeTokens(ctx context.Context, id uint) error {
+	return r.updateColumn(ctx, id, "token_version", gorm.Expr("token_version + 1"))
+}
+
+func (r *userRepository) updateColumn(ctx context.Context, id uint, column string, value any) error {
+	result := r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).Update(column, value)
+	if result.Error != nil {
+		return translateError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package routes
+
+import (
+	"chat-app-api/internal/handlers"
+	"chat-app-api/internal/middleware"
+	"chat-app-api/internal/models"
+	"chat-app-api/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+func SetupAdminRoutes(router *gin.RouterGroup, adminService services.AdminService, authService services.AuthService,
+	reportService services.ReportService, requireAuth gin.HandlerFunc) {
+	adminHandler := handlers.NewAdminHandler(adminService, authService)
+	reportHandler := handlers.NewReportHandler(reportService)
+
+	adminRoutes := router.Group("")
+	{
+		adminRoutes.Use(requireAuth, middleware.RequireRole(models.RoleAdmin))
+
+		adminRoutes.GET("/users", adminHandler.ListUsers)
+		adminRoutes.PUT("/users/:id/role", adminHandler.ChangeRole)
+		adminRoutes.POST("/users/:id/suspend", adminHandler.SuspendUser)
+		adminRoutes.POST("/users/:id/unsuspend", adminHandler.UnsuspendUser)
+		adminRoutes.POST("/users/:id/restore", adminHandler.RestoreUser)
+		adminRoutes.POST("/users/:id/logout", adminHandler.ForceLogout)
+		adminRoutes.POST("/users/:id/password", adminHandler.ResetPassword)
+		adminRoutes.POST("/users/:id/unlock", adminHandler.UnlockUser)
+		adminRoutes.GET("/stats", adminHandler.Stats)
+		adminRoutes.GET("/audit-events", adminHandler.ListAuditEvents)
+		adminRoutes.GET("/reports", reportHandler.ListReports)
+		adminRoutes.GET("/reports/:id", reportHandler.GetReport)
+		adminRoutes.POST("/reports/:id/resolve", reportHandler.ResolveReport)
+	}
+}
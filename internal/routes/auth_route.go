@@ -1,17 +1,29 @@
 package routes
 
 import (
+	"chat-app-api/internal/config"
 	"chat-app-api/internal/handlers"
+	"chat-app-api/internal/middleware"
 	"chat-app-api/internal/services"
 	"github.com/gin-gonic/gin"
+	"log/slog"
 )
 
-func SetupAuthRoutes(router *gin.RouterGroup, authService services.AuthService) {
-	authHandler := handlers.NewAuthHandler(authService)
+func SetupAuthRoutes(router *gin.RouterGroup, authService services.AuthService, oidcService services.OIDCService, cookieConfig config.CookieConfig, loginLimit gin.HandlerFunc, logger *slog.Logger) {
+	authHandler := handlers.NewAuthHandler(authService, cookieConfig)
 
 	authRouter := router.Group("")
 	{
-		authRouter.POST("/login", authHandler.Login)
-		authRouter.POST("/renew", authHandler.RenewAccessToken)
+		authRouter.POST("/login", loginLimit, authHandler.Login)
+		authRouter.POST("/renew", middleware.CSRFMiddleware(), authHandler.RenewAccessToken)
+		authRouter.POST("/logout", middleware.CSRFMiddleware(), authHandler.Logout)
+	}
+
+	// Single sign-on is only exposed when a provider is configured
+	if oidcService != nil {
+		oidcHandler := handlers.NewOIDCHandler(oidcService, cookieConfig, logger)
+
+		authRouter.GET("/oidc/login", oidcHandler.Login)
+		authRouter.GET("/oidc/callback", oidcHandler.Callback)
 	}
 }
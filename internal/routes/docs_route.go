@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"chat-app-api/internal/handlers"
+	"github.com/gin-gonic/gin"
+)
+
+func SetupDocsRoutes(router *gin.RouterGroup) error {
+	docsHandler, err := handlers.NewDocsHandler()
+	if err != nil {
+		return err
+	}
+
+	docsRoutes := router.Group("")
+	{
+		docsRoutes.GET("/openapi.json", docsHandler.OpenAPI)
+		docsRoutes.GET("/asyncapi.json", docsHandler.AsyncAPI)
+		docsRoutes.GET("/docs", docsHandler.SwaggerUI)
+	}
+	return nil
+}
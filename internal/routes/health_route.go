@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"chat-app-api/internal/handlers"
+	"chat-app-api/internal/health"
+	"github.com/gin-gonic/gin"
+)
+
+func SetupHealthRoutes(router *gin.RouterGroup, checker *health.Checker) {
+	healthHandler := handlers.NewHealthHandler(checker)
+
+	healthRoutes := router.Group("")
+	{
+		healthRoutes.GET("/healthz", healthHandler.Liveness)
+		healthRoutes.GET("/readyz", healthHandler.Readiness)
+		healthRoutes.GET("/version", healthHandler.Version)
+	}
+}
@@ -0,0 +1,125 @@
+package routes
+
+import (
+	"chat-app-api/internal/health"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// schemaFunc is a health.SchemaChecker returning the error of the function
+type schemaFunc func() error
+
+func (f schemaFunc) CheckSchema(context.Context) error { return f() }
+
+type readiness struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+func newHealthRouter(checker *health.Checker) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	SetupHealthRoutes(router.Group("/"), checker)
+	return router
+}
+
+// get requests the path and decodes the JSON response into out
+func get(t *testing.T, router *gin.Engine, path string, out any) int {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+	if err := json.Unmarshal(recorder.Body.Bytes(), out); err != nil {
+		t.Fatalf("GET %s: decode %q: %v", path, recorder.Body.String(), err)
+	}
+	return recorder.Code
+}
+
+func openHealthDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+	return db
+}
+
+func TestReadinessFlipsAtShutdown(t *testing.T) {
+	checker := health.NewChecker(openHealthDB(t), schemaFunc(func() error { return nil }), nil)
+	router := newHealthRouter(checker)
+
+	var ready readiness
+	if status := get(t, router, "/readyz", &ready); status != http.StatusOK || ready.Status != "ok" {
+		t.Errorf("got %d %+v, want ready", status, ready)
+	}
+	for _, check := range []string{"shutdown", "database", "migrations"} {
+		if ready.Checks[check] != "ok" {
+			t.Errorf("check %s: got %q, want ok", check, ready.Checks[check])
+		}
+	}
+
+	// Once shutdown starts the instance stops being ready, but stays alive until it exits
+	checker.StartShutdown()
+	if status := get(t, router, "/readyz", &ready); status != http.StatusServiceUnavailable || ready.Status != "unavailable" || ready.Checks["shutdown"] != health.ErrShuttingDown.Error() {
+		t.Errorf("got %d %+v during shutdown, want the shutdown check to fail", status, ready)
+	}
+	var live map[string]string
+	if status := get(t, router, "/healthz", &live); status != http.StatusOK || live["status"] != "ok" {
+		t.Errorf("liveness during shutdown: got %d %v, want ok", status, live)
+	}
+}
+
+func TestReadinessReportsAFailingSchema(t *testing.T) {
+	checker := health.NewChecker(openHealthDB(t), schemaFunc(func() error { return errors.New("2 migrations pending") }), nil)
+
+	var ready readiness
+	status := get(t, newHealthRouter(checker), "/readyz", &ready)
+	if status != http.StatusServiceUnavailable || ready.Checks["migrations"] != "2 migrations pending" || ready.Checks["database"] != "ok" {
+		t.Errorf("got %d %+v, want only the migrations check to fail", status, ready)
+	}
+}
+
+func TestReadinessReportsAnUnreachableDatabase(t *testing.T) {
+	db := openHealthDB(t)
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = sqlDB.Close()
+	checker := health.NewChecker(db, schemaFunc(func() error { return nil }), nil)
+
+	var ready readiness
+	status := get(t, newHealthRouter(checker), "/readyz", &ready)
+	if status != http.StatusServiceUnavailable || ready.Checks["database"] == "ok" || ready.Checks["migrations"] != "database unavailable" {
+		t.Errorf("got %d %+v, want the database and migrations checks to fail", status, ready)
+	}
+}
+
+func TestReadinessWithoutADatabase(t *testing.T) {
+	var ready readiness
+	status := get(t, newHealthRouter(health.NewChecker(nil, nil, nil)), "/readyz", &ready)
+	if status != http.StatusOK || len(ready.Checks) != 1 || ready.Checks["shutdown"] != "ok" {
+		t.Errorf("got %d %+v, want ready with only the shutdown check", status, ready)
+	}
+}
+
+func TestVersion(t *testing.T) {
+	var version map[string]string
+	status := get(t, newHealthRouter(health.NewChecker(nil, nil, nil)), "/version", &version)
+	if status != http.StatusOK || version["go_version"] != runtime.Version() || version["commit"] == "" || version["build_time"] == "" {
+		t.Errorf("got %d %v, want the build information", status, version)
+	}
+}
@@ -2,18 +2,21 @@ package routes
 
 import (
 	"chat-app-api/internal/handlers"
-	"chat-app-api/internal/middleware"
+	"chat-app-api/internal/ratelimit"
+	"chat-app-api/internal/realtime"
 	"chat-app-api/internal/services"
 	"github.com/gin-gonic/gin"
+	"log/slog"
 )
 
-func SetupMessageRoutes(router *gin.RouterGroup, messageService services.MessageService) {
-	messageHandler := handlers.NewMessageHandler(messageService)
+func SetupMessageRoutes(router *gin.RouterGroup, messageService services.MessageService, hub *realtime.Hub, allowedOrigins []string, requireAuth gin.HandlerFunc,
+	connectionLimit gin.HandlerFunc, messageLimit ratelimit.Policy, maxThrottled int, logger *slog.Logger) {
+	messageHandler := handlers.NewMessageHandler(messageService, hub, allowedOrigins, messageLimit, maxThrottled, logger)
 
 	messageRoutes := router.Group("/")
 	{
-		messageRoutes.GET("/ws", messageHandler.HandleConnections)
-		messageRoutes.GET("/friends", middleware.AuthMiddleware(), messageHandler.GetFriendsWithLastMessage)
-		messageRoutes.GET("/friend/chats", middleware.AuthMiddleware(), messageHandler.GetMessagesBySenderIdAndReceiverId)
+		messageRoutes.GET("/ws", requireAuth, connectionLimit, messageHandler.HandleConnections)
+		messageRoutes.GET("/friends", requireAuth, messageHandler.GetFriendsWithLastMessage)
+		messageRoutes.GET("/friend/chats", requireAuth, messageHandler.GetMessagesBySenderIdAndReceiverId)
 	}
 }
@@ -0,0 +1,11 @@
+package routes
+
+import (
+	"chat-app-api/internal/metrics"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+func SetupMetricsRoutes(router *gin.RouterGroup) {
+	router.GET("/metrics", gin.WrapH(promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{})))
+}
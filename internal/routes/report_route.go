@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"chat-app-api/internal/handlers"
+	"chat-app-api/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+func SetupReportRoutes(router *gin.RouterGroup, reportService services.ReportService, requireAuth gin.HandlerFunc) {
+	reportHandler := handlers.NewReportHandler(reportService)
+
+	reportRoutes := router.Group("")
+	{
+		reportRoutes.POST("", requireAuth, reportHandler.CreateReport)
+	}
+}
@@ -0,0 +1,57 @@
+package routes
+
+import (
+	"chat-app-api/internal/apierror"
+	"chat-app-api/internal/config"
+	"chat-app-api/internal/health"
+	"chat-app-api/internal/middleware"
+	"chat-app-api/internal/realtime"
+	"chat-app-api/internal/repositories"
+	"chat-app-api/internal/utils"
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"log/slog"
+	"time"
+)
+
+// NewRouter builds the complete HTTP handler: middleware, probes, metrics, documentation and the API
+func NewRouter(cfg *config.Config, repos repositories.Set, hub *realtime.Hub, checker *health.Checker, logger *slog.Logger) (*gin.Engine, error) {
+	router := gin.New()
+	router.Use(otelgin.Middleware(cfg.Tracing.ServiceName))
+	router.Use(middleware.RequestIDMiddleware())
+	router.Use(middleware.RequestInfoMiddleware())
+	router.Use(middleware.LoggerMiddleware(logger))
+	router.Use(middleware.MetricsMiddleware())
+	router.Use(middleware.ErrorMiddleware(logger))
+	router.Use(middleware.RecoveryMiddleware())
+
+	// CORS configuration
+	router.Use(cors.New(cors.Config{
+		AllowOrigins:     cfg.CORS.AllowedOrigins,                                                                                                      // Allow specific origins
+		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE"},                                                                                     // Allow specific HTTP methods
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", utils.CSRFTokenHeader, utils.AuthModeHeader, middleware.RequestIDHeader}, // Allow specific headers
+		ExposeHeaders:    append([]string{"Content-Length", middleware.RequestIDHeader}, middleware.RateLimitHeaders...),
+		AllowCredentials: true, // Allow credentials (cookies, authorization headers)
+		MaxAge:           12 * time.Hour,
+	}))
+
+	SetupWellKnownRoutes(router.Group("/.well-known"))
+
+	// Probes for the orchestrator and build information
+	SetupHealthRoutes(router.Group("/"), checker)
+	SetupMetricsRoutes(router.Group("/"))
+	if err := SetupDocsRoutes(router.Group("/")); err != nil {
+		return nil, err
+	}
+
+	if err := SetupRoutes(router.Group("/api"), repos, cfg, hub, logger); err != nil {
+		return nil, err
+	}
+
+	router.NoRoute(func(c *gin.Context) {
+		_ = c.Error(apierror.NotFound("Route not found"))
+	})
+
+	return router, nil
+}
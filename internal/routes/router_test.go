@@ -0,0 +1,100 @@
+package routes
+
+import (
+	"chat-app-api/internal/apidocs"
+	"chat-app-api/internal/config"
+	"chat-app-api/internal/health"
+	"chat-app-api/internal/realtime"
+	"chat-app-api/internal/repositories"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newTestRouter builds the full router with every optional feature enabled
+func newTestRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	cfg := config.Default()
+	cfg.OIDC.IssuerURL = "https://issuer.invalid"
+	cfg.OIDC.ClientID = "chat-app"
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	router, err := NewRouter(cfg, repositories.NewMemorySet(), realtime.NewHub(realtime.NewLocalBroker(), logger), health.NewChecker(nil, nil, nil), logger)
+	if err != nil {
+		t.Fatalf("build router: %v", err)
+	}
+	return router
+}
+
+func TestEveryRouteIsDocumented(t *testing.T) {
+	router := newTestRouter(t)
+
+	documented := map[string]bool{}
+	for _, op := range apidocs.Operations() {
+		documented[op.Method+" "+op.Path] = true
+	}
+
+	registered := map[string]bool{}
+	for _, route := range router.Routes() {
+		key := route.Method + " " + route.Path
+		registered[key] = true
+		if !documented[key] {
+			t.Errorf("route %s has no entry in apidocs.Operations", key)
+		}
+	}
+
+	for key := range documented {
+		if !registered[key] {
+			t.Errorf("apidocs.Operations documents %s, which is not registered", key)
+		}
+	}
+}
+
+func TestOpenAPIDocumentResolves(t *testing.T) {
+	doc := apidocs.OpenAPI()
+	schemas := doc["components"].(apidocs.Schema)["schemas"]
+	assertRefsResolve(t, doc, schemas)
+
+	paths := doc["paths"].(apidocs.Schema)
+	if _, ok := paths[apidocs.OpenAPIPath("/api/users/:id")]; !ok {
+		t.Errorf("path parameters are not converted, got paths %v", paths)
+	}
+}
+
+func TestAsyncAPIDocumentResolves(t *testing.T) {
+	doc := apidocs.AsyncAPI()
+	assertRefsResolve(t, doc, doc["components"].(apidocs.Schema)["schemas"])
+	if _, ok := doc["channels"].(apidocs.Schema)[apidocs.WebSocketPath]; !ok {
+		t.Errorf("channel %s is missing", apidocs.WebSocketPath)
+	}
+}
+
+// assertRefsResolve checks that every schema reference points at a defined component
+func assertRefsResolve(t *testing.T, node any, schemas any) {
+	t.Helper()
+	const prefix = "#/components/schemas/"
+
+	switch v := node.(type) {
+	case apidocs.Schema:
+		if ref, ok := v["$ref"].(string); ok && len(ref) > len(prefix) && ref[:len(prefix)] == prefix {
+			if _, ok := schemas.(map[string]apidocs.Schema)[ref[len(prefix):]]; !ok {
+				t.Errorf("unresolved reference %s", ref)
+			}
+		}
+		for _, child := range v {
+			assertRefsResolve(t, child, schemas)
+		}
+	case map[string]apidocs.Schema:
+		for _, child := range v {
+			assertRefsResolve(t, child, schemas)
+		}
+	case []apidocs.Schema:
+		for _, child := range v {
+			assertRefsResolve(t, child, schemas)
+		}
+	}
+}
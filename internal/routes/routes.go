@@ -1,29 +1,110 @@
 package routes
 
 import (
+	"chat-app-api/internal/config"
+	"chat-app-api/internal/middleware"
+	"chat-app-api/internal/ratelimit"
+	"chat-app-api/internal/realtime"
 	"chat-app-api/internal/repositories"
 	"chat-app-api/internal/services"
+	"chat-app-api/internal/validation"
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
+	"log/slog"
 )
 
-func SetupRoutes(router *gin.RouterGroup, db *gorm.DB) {
-	// Set up repositories
-	userRepo := repositories.NewUserRepository(db)
-	messageRepo := repositories.NewMessageRepository(db)
+func SetupRoutes(router *gin.RouterGroup, repos repositories.Set, cfg *config.Config, hub *realtime.Hub, logger *slog.Logger) error {
+	// Request DTOs use the custom username and content validators
+	if err := validation.Register(cfg.Message); err != nil {
+		return err
+	}
+
+	userRepo := repos.Users
+	messageRepo := repos.Messages
+	identityRepo := repos.Identities
+
+	passwordPolicy, err := services.NewPasswordPolicy(cfg.Password)
+	if err != nil {
+		return err
+	}
+	messageFilter, err := services.NewMessageFilterFromConfig(cfg.Message)
+	if err != nil {
+		return err
+	}
+
+	// Audit events go to the database and optionally to a file; the file stays open for the process lifetime
+	var auditSinks []services.AuditSink
+	if cfg.Audit.File != "" {
+		sink, err := services.OpenJSONLinesSink(cfg.Audit.File)
+		if err != nil {
+			return err
+		}
+		auditSinks = append(auditSinks, sink)
+	}
+	auditLog := services.NewAuditLog(repos.Audit, logger, auditSinks...)
 
 	// Set up services
-	userService := services.NewUserService(userRepo)
-	authService := services.NewAuthService(userRepo)
-	messageService := services.NewMessageService(messageRepo)
+	userService := services.NewUserService(userRepo, passwordPolicy, hub, auditLog)
+	loginGuard := services.NewLoginGuard(cfg.Login)
+	notifier := services.NewLogNotifier(logger)
+	authService := services.NewAuthService(userRepo, loginGuard, notifier, auditLog, logger)
+	messageService := services.NewMessageService(messageRepo, repos.Reports, messageFilter, auditLog, logger)
+	adminService := services.NewAdminService(userRepo, messageRepo, repos.Audit, passwordPolicy, hub, auditLog, logger)
+	reportService := services.NewReportService(repos.Reports, userRepo, messageRepo, adminService, hub, notifier, auditLog, logger)
+
+	var oidcService services.OIDCService
+	if cfg.OIDC.Enabled() {
+		oidcService = services.NewOIDCService(cfg.OIDC, userRepo, identityRepo, auditLog)
+	}
+
+	// Every authenticated request checks that its token has not been revoked
+	requireAuth := middleware.AuthMiddleware(authService)
+
+	limits := newRateLimits(cfg.RateLimit, logger)
 
 	// routes
 	authRoutes := router.Group("/auth")
 	userRoutes := router.Group("/users")
 	messageRoutes := router.Group("/messages")
+	adminRoutes := router.Group("/admin")
+	reportRoutes := router.Group("/reports")
 
 	// Setup routes
-	SetupAuthRoutes(authRoutes, authService)
-	SetupUserRoutes(userRoutes, userService)
-	SetupMessageRoutes(messageRoutes, messageService)
+	SetupAuthRoutes(authRoutes, authService, oidcService, cfg.Cookie, limits.login, logger)
+	SetupUserRoutes(userRoutes, userService, requireAuth, limits.signup, limits.search)
+	SetupMessageRoutes(messageRoutes, messageService, hub, cfg.CORS.AllowedOrigins, requireAuth, limits.webSocket,
+		limits.webSocketMessages, cfg.RateLimit.MaxThrottled, logger)
+	SetupAdminRoutes(adminRoutes, adminService, authService, reportService, requireAuth)
+	SetupReportRoutes(reportRoutes, reportService, requireAuth)
+
+	return nil
+}
+
+// rateLimits holds the middleware of every rate limited route and the per-connection message policy
+type rateLimits struct {
+	login, signup, search, webSocket gin.HandlerFunc
+	webSocketMessages                ratelimit.Policy
+}
+
+// newRateLimits builds the policies over one store; when rate limiting is off every middleware
+// lets requests through and the message policy has no limit
+func newRateLimits(cfg config.RateLimitConfig, logger *slog.Logger) rateLimits {
+	if !cfg.Enabled {
+		pass := func(c *gin.Context) { c.Next() }
+		return rateLimits{login: pass, signup: pass, search: pass, webSocket: pass}
+	}
+
+	var store ratelimit.Store = ratelimit.NewMemoryStore()
+	if cfg.Store == "redis" {
+		store = ratelimit.NewRedisStore(cfg.RedisAddr, cfg.RedisPassword)
+	}
+	limit := func(name string, rate config.RatePolicy) gin.HandlerFunc {
+		return middleware.RateLimit(store, ratelimit.NewPolicy(name, rate), logger)
+	}
+	return rateLimits{
+		login:             limit("login", cfg.Login),
+		signup:            limit("signup", cfg.Signup),
+		search:            limit("search", cfg.Search),
+		webSocket:         limit("websocket", cfg.WebSocket),
+		webSocketMessages: ratelimit.NewPolicy("websocket_message", cfg.WebSocketMessages),
+	}
 }
@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"chat-app-api/internal/handlers"
+	"github.com/gin-gonic/gin"
+)
+
+func SetupWellKnownRoutes(router *gin.RouterGroup) {
+	wellKnownRoutes := router.Group("")
+	{
+		wellKnownRoutes.GET("/jwks.json", handlers.GetJWKS)
+	}
+}
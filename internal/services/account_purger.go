@@ -0,0 +1,47 @@
+package services
+
+import (
+	"chat-app-api/internal/config"
+	"chat-app-api/internal/repositories"
+	"context"
+	"log/slog"
+	"time"
+)
+
+// AccountPurger removes deleted accounts once the grace period is over; their messages stay with the other side
+type AccountPurger struct {
+	userRepo repositories.UserRepository
+	config   config.AccountConfig
+	logger   *slog.Logger
+}
+
+func NewAccountPurger(userRepo repositories.UserRepository, cfg config.AccountConfig, logger *slog.Logger) *AccountPurger {
+	return &AccountPurger{userRepo: userRepo, config: cfg, logger: logger}
+}
+
+// PurgeExpired purges the accounts deleted more than the grace period before now
+func (p *AccountPurger) PurgeExpired(ctx context.Context, now time.Time) (int64, error) {
+	return p.userRepo.PurgeDeletedUsers(ctx, now.Add(-p.config.DeletionGracePeriod))
+}
+
+// Run purges at every interval until the context is cancelled
+func (p *AccountPurger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.config.PurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := p.PurgeExpired(ctx, time.Now())
+		switch {
+		case err != nil && ctx.Err() == nil:
+			p.logger.ErrorContext(ctx, "could not purge deleted accounts", "error", err)
+		case purged > 0:
+			p.logger.InfoContext(ctx, "purged deleted accounts", "count", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package services
+
+import (
+	"chat-app-api/internal/config"
+	"chat-app-api/internal/models"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPurgeExpiredKeepsAccountsWithinTheGracePeriod(t *testing.T) {
+	env := newTestEnv(t)
+	alice := createTestUser(t, env.users, "alice", "correct horse battery")
+	bob := createTestUser(t, env.users, "bob", "correct horse battery")
+	ctx := context.Background()
+
+	if _, err := env.messages.CreateMessage(ctx, &models.Message{SenderID: alice.ID, ReceiverID: bob.ID, Content: "hi"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.users.DeleteUser(ctx, bob.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	purger := NewAccountPurger(env.users, config.AccountConfig{DeletionGracePeriod: 24 * time.Hour, PurgeInterval: time.Hour}, discardLogger)
+
+	if purged, err := purger.PurgeExpired(ctx, time.Now()); err != nil || purged != 0 {
+		t.Fatalf("purged %d (%v) within the grace period", purged, err)
+	}
+	if friends, err := env.messages.GetFriendListWithLastMessage(ctx, alice.ID); err != nil || len(friends) != 1 || !friends[0].Profile.Deleted {
+		t.Fatalf("got conversations %+v (%v), want the tombstone of bob", friends, err)
+	}
+
+	if purged, err := purger.PurgeExpired(ctx, time.Now().Add(25*time.Hour)); err != nil || purged != 1 {
+		t.Fatalf("purged %d (%v) after the grace period, want 1", purged, err)
+	}
+	if _, err := env.users.FindByID(ctx, bob.ID); err == nil {
+		t.Error("bob was not purged")
+	}
+	if friends, err := env.messages.GetFriendListWithLastMessage(ctx, alice.ID); err != nil || len(friends) != 1 || !friends[0].Profile.Deleted ||
+		friends[0].LastMessage.Content != "hi" {
+		t.Errorf("got conversations %+v (%v) after the purge, want the tombstone kept", friends, err)
+	}
+}
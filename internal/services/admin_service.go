@@ -0,0 +1,282 @@
+package services
+
+import (
+	"chat-app-api/internal/models"
+	"chat-app-api/internal/realtime"
+	"chat-app-api/internal/repositories"
+	"chat-app-api/internal/utils"
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// ConnectionRegistry is the part of the real-time hub administration needs
+type ConnectionRegistry interface {
+	DisconnectUser(userID uint) int
+	Stats() realtime.Stats
+}
+
+// UserPage is one page of the admin user listing
+type UserPage struct {
+	Users  []models.User `json:"users"`
+	Total  int64         `json:"total"`
+	Limit  int           `json:"limit"`
+	Offset int           `json:"offset"`
+}
+
+type UserStats struct {
+	Total     int64 `json:"total"`
+	Admins    int64 `json:"admins"`
+	Suspended int64 `json:"suspended"`
+	Deleted   int64 `json:"deleted"` // awaiting their purge
+}
+
+type MessageStats struct {
+	Total       int64 `json:"total"`
+	Last24Hours int64 `json:"last_24_hours"`
+}
+
+type ConnectionStats struct {
+	Connections    int `json:"connections"`
+	QueuedMessages int `json:"queued_messages"`
+}
+
+// AuditPage is one page of the audit log, newest events first
+type AuditPage struct {
+	Events []models.AuditEvent `json:"events"`
+	Total  int64               `json:"total"`
+	Limit  int                 `json:"limit"`
+	Offset int                 `json:"offset"`
+}
+
+// SystemStats is the overview shown on the admin dashboard
+type SystemStats struct {
+	Users     UserStats       `json:"users"`
+	Messages  MessageStats    `json:"messages"`
+	WebSocket ConnectionStats `json:"websocket"`
+}
+
+type AdminService interface {
+	ListUsers(ctx context.Context, filter repositories.UserFilter) (UserPage, error)
+	ChangeRole(ctx context.Context, actorID, userID uint, role string) (*models.User, error)
+	SuspendUser(ctx context.Context, actorID, userID uint, until time.Time) (*models.User, error)
+	UnsuspendUser(ctx context.Context, userID uint) (*models.User, error)
+	RestoreUser(ctx context.Context, userID uint) (*models.User, error)
+	ForceLogout(ctx context.Context, userID uint) error
+	ResetPassword(ctx context.Context, userID uint, password string) error
+	Stats(ctx context.Context) (SystemStats, error)
+	ListAuditEvents(ctx context.Context, filter repositories.AuditFilter) (AuditPage, error)
+}
+
+type adminService struct {
+	userRepo       repositories.UserRepository
+	messageRepo    repositories.MessageRepository
+	auditRepo      repositories.AuditRepository
+	passwordPolicy *PasswordPolicy
+	connections    ConnectionRegistry
+	audit          AuditLog
+	logger         *slog.Logger
+}
+
+func NewAdminService(userRepo repositories.UserRepository, messageRepo repositories.MessageRepository, auditRepo repositories.AuditRepository,
+	passwordPolicy *PasswordPolicy, connections ConnectionRegistry, audit AuditLog, logger *slog.Logger) AdminService {
+	return &adminService{
+		userRepo:       userRepo,
+		messageRepo:    messageRepo,
+		auditRepo:      auditRepo,
+		passwordPolicy: passwordPolicy,
+		connections:    connections,
+		audit:          audit,
+		logger:         logger,
+	}
+}
+
+func (s *adminService) ListUsers(ctx context.Context, filter repositories.UserFilter) (UserPage, error) {
+	if filter.At.IsZero() {
+		filter.At = time.Now()
+	}
+
+	users, err := s.userRepo.ListUsers(ctx, filter)
+	if err != nil {
+		return UserPage{}, err
+	}
+	total, err := s.userRepo.CountUsers(ctx, filter)
+	if err != nil {
+		return UserPage{}, err
+	}
+
+	if users == nil {
+		users = []models.User{}
+	}
+	return UserPage{Users: users, Total: total, Limit: filter.Limit, Offset: filter.Offset}, nil
+}
+
+// ChangeRole grants or withdraws admin rights; the user's tokens are revoked so the new role applies at once
+func (s *adminService) ChangeRole(ctx context.Context, actorID, userID uint, role string) (*models.User, error) {
+	if role != models.RoleUser && role != models.RoleAdmin {
+		return nil, NewValidationError(FieldError{Field: "role", Message: "must be one of: user admin"})
+	}
+	if actorID == userID {
+		return nil, forbidden("you cannot change your own role")
+	}
+
+	if err := s.userRepo.UpdateRole(ctx, userID, role); err != nil {
+		return nil, repositoryError(err, "user")
+	}
+	if err := s.revokeSessions(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "user role changed", "actor_id", actorID, "user_id", userID, "role", role)
+	s.record(ctx, actorID, succeeded(AuditRoleChange, userID, map[string]any{"role": role}))
+	return s.findUser(ctx, userID)
+}
+
+// SuspendUser blocks the account until the given time and ends its sessions
+func (s *adminService) SuspendUser(ctx context.Context, actorID, userID uint, until time.Time) (*models.User, error) {
+	if !until.After(time.Now()) {
+		return nil, NewValidationError(FieldError{Field: "until", Message: "must be in the future"})
+	}
+	if actorID == userID {
+		return nil, forbidden("you cannot suspend your own account")
+	}
+
+	if err := s.userRepo.UpdateSuspension(ctx, userID, &until); err != nil {
+		return nil, repositoryError(err, "user")
+	}
+	if err := s.revokeSessions(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "user suspended", "actor_id", actorID, "user_id", userID, "until", until)
+	s.record(ctx, actorID, succeeded(AuditSuspension, userID, map[string]any{"until": until.UTC()}))
+	return s.findUser(ctx, userID)
+}
+
+func (s *adminService) UnsuspendUser(ctx context.Context, userID uint) (*models.User, error) {
+	if err := s.userRepo.UpdateSuspension(ctx, userID, nil); err != nil {
+		return nil, repositoryError(err, "user")
+	}
+
+	s.logger.InfoContext(ctx, "user suspension lifted", "user_id", userID)
+	s.audit.Record(ctx, succeeded(AuditUnsuspension, userID, nil))
+	return s.findUser(ctx, userID)
+}
+
+// RestoreUser undoes the deletion of an account that has not been purged yet
+func (s *adminService) RestoreUser(ctx context.Context, userID uint) (*models.User, error) {
+	if err := s.userRepo.RestoreUser(ctx, userID); err != nil {
+		return nil, repositoryError(err, "deleted user")
+	}
+
+	s.logger.InfoContext(ctx, "deleted user restored", "user_id", userID)
+	s.audit.Record(ctx, succeeded(AuditRestore, userID, nil))
+	return s.findUser(ctx, userID)
+}
+
+// ForceLogout revokes every token issued to the user and closes their WebSocket connections
+func (s *adminService) ForceLogout(ctx context.Context, userID uint) error {
+	if err := s.revokeSessions(ctx, userID); err != nil {
+		return err
+	}
+
+	s.logger.InfoContext(ctx, "user logged out by an admin", "user_id", userID)
+	s.audit.Record(ctx, succeeded(AuditForcedLogout, userID, nil))
+	return nil
+}
+
+// ResetPassword sets a new password chosen by an admin and logs the user out everywhere
+func (s *adminService) ResetPassword(ctx context.Context, userID uint, password string) error {
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.passwordPolicy.Validate(password, user.Username, user.Email); err != nil {
+		return err
+	}
+
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+	if err := s.userRepo.UpdatePassword(ctx, userID, hashedPassword); err != nil {
+		return repositoryError(err, "user")
+	}
+	if err := s.revokeSessions(ctx, userID); err != nil {
+		return err
+	}
+
+	s.logger.InfoContext(ctx, "user password reset by an admin", "user_id", userID)
+	s.audit.Record(ctx, succeeded(AuditPasswordReset, userID, nil))
+	return nil
+}
+
+func (s *adminService) Stats(ctx context.Context) (SystemStats, error) {
+	now := time.Now()
+	suspended := true
+
+	var stats SystemStats
+	var err error
+	if stats.Users.Total, err = s.userRepo.CountUsers(ctx, repositories.UserFilter{}); err != nil {
+		return SystemStats{}, err
+	}
+	if stats.Users.Admins, err = s.userRepo.CountUsers(ctx, repositories.UserFilter{Role: models.RoleAdmin}); err != nil {
+		return SystemStats{}, err
+	}
+	if stats.Users.Suspended, err = s.userRepo.CountUsers(ctx, repositories.UserFilter{Suspended: &suspended, At: now}); err != nil {
+		return SystemStats{}, err
+	}
+	if stats.Users.Deleted, err = s.userRepo.CountUsers(ctx, repositories.UserFilter{Deleted: true}); err != nil {
+		return SystemStats{}, err
+	}
+	if stats.Messages.Total, err = s.messageRepo.CountMessages(ctx, time.Time{}); err != nil {
+		return SystemStats{}, err
+	}
+	if stats.Messages.Last24Hours, err = s.messageRepo.CountMessages(ctx, now.Add(-24*time.Hour)); err != nil {
+		return SystemStats{}, err
+	}
+
+	hubStats := s.connections.Stats()
+	stats.WebSocket = ConnectionStats{Connections: hubStats.Connections, QueuedMessages: hubStats.QueuedMessages}
+	return stats, nil
+}
+
+func (s *adminService) ListAuditEvents(ctx context.Context, filter repositories.AuditFilter) (AuditPage, error) {
+	events, err := s.auditRepo.ListEvents(ctx, filter)
+	if err != nil {
+		return AuditPage{}, err
+	}
+	total, err := s.auditRepo.CountEvents(ctx, filter)
+	if err != nil {
+		return AuditPage{}, err
+	}
+
+	if events == nil {
+		events = []models.AuditEvent{}
+	}
+	return AuditPage{Events: events, Total: total, Limit: filter.Limit, Offset: filter.Offset}, nil
+}
+
+// record audits an action taken by the given admin
+func (s *adminService) record(ctx context.Context, actorID uint, event models.AuditEvent) {
+	event.ActorID = &actorID
+	s.audit.Record(ctx, event)
+}
+
+// revokeSessions invalidates the user's tokens and drops the connections opened with them
+func (s *adminService) revokeSessions(ctx context.Context, userID uint) error {
+	if err := s.userRepo.RevokeTokens(ctx, userID); err != nil {
+		return repositoryError(err, "user")
+	}
+	s.connections.DisconnectUser(userID)
+	return nil
+}
+
+func (s *adminService) findUser(ctx context.Context, userID uint) (*models.User, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, repositoryError(err, "user")
+	}
+	return user, nil
+}
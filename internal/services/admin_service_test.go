@@ -0,0 +1,205 @@
+package services
+
+import (
+	"chat-app-api/internal/models"
+	"chat-app-api/internal/repositories"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSuspendUserBlocksLoginAndSessions(t *testing.T) {
+	env := newTestEnv(t)
+	admin := createTestUser(t, env.users, "admin", "correct horse battery")
+	alice := createTestUser(t, env.users, "alice", "correct horse battery")
+	ctx := context.Background()
+
+	if err := env.authService.CheckSession(ctx, alice.ID, 0); err != nil {
+		t.Fatalf("session before suspension: %v", err)
+	}
+
+	until := time.Now().Add(time.Hour)
+	user, err := env.adminService.SuspendUser(ctx, admin.ID, alice.ID, until)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !user.IsSuspended(time.Now()) {
+		t.Errorf("user %+v is not suspended", user)
+	}
+	if len(env.connections.disconnected) != 1 || env.connections.disconnected[0] != alice.ID {
+		t.Errorf("disconnected %v, want user %d", env.connections.disconnected, alice.ID)
+	}
+
+	_, err = env.authService.Login(ctx, "alice", "correct horse battery", "192.0.2.1")
+	var suspendedErr *AccountSuspendedError
+	if !errors.As(err, &suspendedErr) || !suspendedErr.Until.Equal(until) {
+		t.Fatalf("got %v, want an AccountSuspendedError until %v", err, until)
+	}
+	assertErrorIs(t, err, ErrAccountSuspended)
+	assertErrorIs(t, env.authService.CheckSession(ctx, alice.ID, 0), ErrSessionRevoked)
+
+	if _, err := env.adminService.UnsuspendUser(ctx, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.authService.Login(ctx, "alice", "correct horse battery", "192.0.2.1"); err != nil {
+		t.Fatalf("login after the suspension was lifted: %v", err)
+	}
+}
+
+func TestSuspendUserValidation(t *testing.T) {
+	env := newTestEnv(t)
+	admin := createTestUser(t, env.users, "admin", "correct horse battery")
+	alice := createTestUser(t, env.users, "alice", "correct horse battery")
+	ctx := context.Background()
+
+	_, err := env.adminService.SuspendUser(ctx, admin.ID, alice.ID, time.Now().Add(-time.Minute))
+	assertErrorIs(t, err, ErrValidation)
+
+	_, err = env.adminService.SuspendUser(ctx, admin.ID, admin.ID, time.Now().Add(time.Hour))
+	assertErrorIs(t, err, ErrForbidden)
+
+	_, err = env.adminService.SuspendUser(ctx, admin.ID, 4242, time.Now().Add(time.Hour))
+	assertErrorIs(t, err, ErrNotFound)
+}
+
+func TestForceLogoutRevokesIssuedTokens(t *testing.T) {
+	env := newTestEnv(t)
+	alice := createTestUser(t, env.users, "alice", "correct horse battery")
+	ctx := context.Background()
+
+	response, err := env.authService.Login(ctx, "alice", "correct horse battery", "192.0.2.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := env.adminService.ForceLogout(ctx, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	assertErrorIs(t, env.authService.CheckSession(ctx, alice.ID, 0), ErrSessionRevoked)
+	_, err = env.authService.RenewAccessToken(ctx, response.RefreshToken)
+	assertErrorIs(t, err, ErrSessionRevoked)
+
+	// A new login issues tokens with the new version
+	if _, err := env.authService.Login(ctx, "alice", "correct horse battery", "192.0.2.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.authService.CheckSession(ctx, alice.ID, 1); err != nil {
+		t.Errorf("session after logging in again: %v", err)
+	}
+
+	assertErrorIs(t, env.adminService.ForceLogout(ctx, 4242), ErrNotFound)
+}
+
+func TestChangeRole(t *testing.T) {
+	env := newTestEnv(t)
+	admin := createTestUser(t, env.users, "admin", "correct horse battery")
+	alice := createTestUser(t, env.users, "alice", "correct horse battery")
+	ctx := context.Background()
+
+	user, err := env.adminService.ChangeRole(ctx, admin.ID, alice.ID, models.RoleAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Role != models.RoleAdmin {
+		t.Errorf("role is %q, want %q", user.Role, models.RoleAdmin)
+	}
+	assertErrorIs(t, env.authService.CheckSession(ctx, alice.ID, 0), ErrSessionRevoked)
+
+	_, err = env.adminService.ChangeRole(ctx, admin.ID, alice.ID, "owner")
+	assertErrorIs(t, err, ErrValidation)
+	_, err = env.adminService.ChangeRole(ctx, admin.ID, admin.ID, models.RoleUser)
+	assertErrorIs(t, err, ErrForbidden)
+}
+
+func TestResetPassword(t *testing.T) {
+	env := newTestEnv(t)
+	alice := createTestUser(t, env.users, "alice", "correct horse battery")
+	ctx := context.Background()
+
+	assertErrorIs(t, env.adminService.ResetPassword(ctx, alice.ID, "short"), ErrValidation)
+
+	if err := env.adminService.ResetPassword(ctx, alice.ID, "staple battery horse"); err != nil {
+		t.Fatal(err)
+	}
+	_, err := env.authService.Login(ctx, "alice", "correct horse battery", "192.0.2.1")
+	assertErrorIs(t, err, ErrInvalidCredentials)
+	if _, err := env.authService.Login(ctx, "alice", "staple battery horse", "192.0.2.1"); err != nil {
+		t.Fatalf("login with the new password: %v", err)
+	}
+}
+
+func TestListUsersAndStats(t *testing.T) {
+	env := newTestEnv(t)
+	admin := createTestUser(t, env.users, "admin", "correct horse battery")
+	alice := createTestUser(t, env.users, "alice", "correct horse battery")
+	createTestUser(t, env.users, "bob", "correct horse battery")
+	ctx := context.Background()
+
+	if err := env.users.UpdateRole(ctx, admin.ID, models.RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.adminService.SuspendUser(ctx, admin.ID, alice.ID, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.messages.CreateMessage(ctx, &models.Message{SenderID: alice.ID, ReceiverID: admin.ID, Content: "hi"}); err != nil {
+		t.Fatal(err)
+	}
+
+	page, err := env.adminService.ListUsers(ctx, repositories.UserFilter{Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Users) != 2 || page.Total != 3 || page.Limit != 2 {
+		t.Errorf("got %d users of %d (limit %d), want 2 of 3", len(page.Users), page.Total, page.Limit)
+	}
+
+	suspended := true
+	page, err = env.adminService.ListUsers(ctx, repositories.UserFilter{Suspended: &suspended, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Users) != 1 || page.Users[0].ID != alice.ID {
+		t.Errorf("suspended users are %+v, want only alice", page.Users)
+	}
+
+	stats, err := env.adminService.Stats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := SystemStats{
+		Users:     UserStats{Total: 3, Admins: 1, Suspended: 1},
+		Messages:  MessageStats{Total: 1, Last24Hours: 1},
+		WebSocket: ConnectionStats{Connections: 3, QueuedMessages: 2},
+	}
+	if stats != want {
+		t.Errorf("got stats %+v, want %+v", stats, want)
+	}
+}
+
+func TestRestoreDeletedUser(t *testing.T) {
+	env := newTestEnv(t)
+	alice := createTestUser(t, env.users, "alice", "correct horse battery")
+	ctx := context.Background()
+
+	_, err := env.adminService.RestoreUser(ctx, alice.ID)
+	assertErrorIs(t, err, ErrNotFound)
+
+	if err := env.users.DeleteUser(ctx, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	assertErrorIs(t, env.authService.CheckSession(ctx, alice.ID, 0), ErrSessionRevoked)
+	_, err = env.authService.Login(ctx, "alice", "correct horse battery", "192.0.2.1")
+	assertErrorIs(t, err, ErrInvalidCredentials)
+
+	user, err := env.adminService.RestoreUser(ctx, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.IsDeleted() {
+		t.Errorf("restored user %+v is still deleted", user)
+	}
+	if _, err := env.authService.Login(ctx, "alice", "correct horse battery", "192.0.2.1"); err != nil {
+		t.Fatalf("login after restore: %v", err)
+	}
+}
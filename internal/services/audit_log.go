@@ -0,0 +1,175 @@
+package services
+
+import (
+	"chat-app-api/internal/logging"
+	"chat-app-api/internal/models"
+	"chat-app-api/internal/repositories"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+)
+
+// Audited actions
+const (
+	AuditLogin            = "auth.login"
+	AuditTokenRenewal     = "auth.token_renewal"
+	AuditPasswordChange   = "user.password_change"
+	AuditEmailChange      = "user.email_change"
+	AuditAccountDeletion  = "user.deletion"
+	AuditRoleChange       = "admin.role_change"
+	AuditSuspension       = "admin.suspension"
+	AuditUnsuspension     = "admin.unsuspension"
+	AuditRestore          = "admin.restore"
+	AuditForcedLogout     = "admin.forced_logout"
+	AuditPasswordReset    = "admin.password_reset"
+	AuditUnlock           = "admin.unlock"
+	AuditMessageFiltered  = "message.filter"
+	AuditReportCreation   = "report.creation"
+	AuditReportResolution = "admin.report_resolution"
+	AuditMessageRemoval   = "admin.message_removal"
+	AuditWarning          = "admin.warning"
+)
+
+// RequestInfo identifies the client and user behind a request; the audit log reads it from the context
+type RequestInfo struct {
+	ClientIP  string
+	UserAgent string
+	UserID    uint // the authenticated user, zero before authentication
+}
+
+type requestInfoKey struct{}
+
+// WithRequestInfo returns a context carrying the request information
+func WithRequestInfo(ctx context.Context, info RequestInfo) context.Context {
+	return context.WithValue(ctx, requestInfoKey{}, info)
+}
+
+// RequestInfoFrom returns the request information of the context, or the zero value
+func RequestInfoFrom(ctx context.Context) RequestInfo {
+	info, _ := ctx.Value(requestInfoKey{}).(RequestInfo)
+	return info
+}
+
+// AuditLog records security-relevant events. Recording never fails the audited operation:
+// errors are logged instead.
+type AuditLog interface {
+	Record(ctx context.Context, event models.AuditEvent)
+}
+
+// AuditSink receives a copy of every recorded event
+type AuditSink interface {
+	WriteAuditEvent(event *models.AuditEvent) error
+}
+
+type auditLog struct {
+	repo   repositories.AuditRepository
+	sinks  []AuditSink
+	logger *slog.Logger
+}
+
+// NewAuditLog stores events in the repository and forwards them to the sinks
+func NewAuditLog(repo repositories.AuditRepository, logger *slog.Logger, sinks ...AuditSink) AuditLog {
+	return &auditLog{repo: repo, sinks: sinks, logger: logger}
+}
+
+// Record completes the event from the request information in the context and stores it
+func (a *auditLog) Record(ctx context.Context, event models.AuditEvent) {
+	info := RequestInfoFrom(ctx)
+	if event.ActorID == nil && info.UserID != 0 {
+		actorID := info.UserID
+		event.ActorID = &actorID
+	}
+	if event.IP == "" {
+		event.IP = info.ClientIP
+	}
+	event.UserAgent = info.UserAgent
+	event.RequestID = logging.RequestID(ctx)
+
+	// The event is written even when the client went away in the meantime
+	ctx = context.WithoutCancel(ctx)
+	if err := a.repo.CreateEvent(ctx, &event); err != nil {
+		a.logger.ErrorContext(ctx, "could not record audit event", "action", event.Action, "error", err)
+	}
+	for _, sink := range a.sinks {
+		if err := sink.WriteAuditEvent(&event); err != nil {
+			a.logger.ErrorContext(ctx, "could not write audit event to sink", "action", event.Action, "error", err)
+		}
+	}
+}
+
+// succeeded and failed build events about the given user; a zero target means none
+func succeeded(action string, targetID uint, details map[string]any) models.AuditEvent {
+	return auditEvent(action, models.OutcomeSuccess, targetID, details)
+}
+
+func failed(action string, targetID uint, details map[string]any) models.AuditEvent {
+	return auditEvent(action, models.OutcomeFailure, targetID, details)
+}
+
+func auditEvent(action, outcome string, targetID uint, details map[string]any) models.AuditEvent {
+	event := models.AuditEvent{Action: action, Outcome: outcome, Details: details}
+	if targetID != 0 {
+		event.TargetID = &targetID
+	}
+	return event
+}
+
+// failureReason names the reason an authentication failed, for the details of its event
+func failureReason(err error) string {
+	switch {
+	case errors.Is(err, ErrInvalidCredentials):
+		return "invalid_credentials"
+	case errors.Is(err, ErrAccountLocked):
+		return "account_locked"
+	case errors.Is(err, ErrAccountSuspended):
+		return "account_suspended"
+	case errors.Is(err, ErrSessionRevoked):
+		return "session_revoked"
+	case errors.Is(err, ErrOIDCExchangeFailed):
+		return "oidc_exchange_failed"
+	case errors.Is(err, ErrOIDCInvalidIDToken):
+		return "oidc_invalid_id_token"
+	case errors.Is(err, ErrOIDCIdentityMissing):
+		return "oidc_identity_missing"
+	case errors.Is(err, ErrForbidden):
+		return "forbidden"
+	case errors.Is(err, ErrConflict):
+		return "conflict"
+	}
+	return "error"
+}
+
+// JSONLinesSink appends every event to a file as one JSON object per line, for log shippers
+type JSONLinesSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// OpenJSONLinesSink opens the file for appending, creating it readable by its owner only
+func OpenJSONLinesSink(path string) (*JSONLinesSink, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log file: %w", err)
+	}
+	return &JSONLinesSink{file: file}, nil
+}
+
+func (s *JSONLinesSink) WriteAuditEvent(event *models.AuditEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.file.Write(append(line, '\n'))
+	return err
+}
+
+func (s *JSONLinesSink) Close() error {
+	return s.file.Close()
+}
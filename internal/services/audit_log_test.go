@@ -0,0 +1,122 @@
+package services
+
+import (
+	"bufio"
+	"chat-app-api/internal/models"
+	"chat-app-api/internal/repositories"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestAuditLogCompletesEventsFromTheRequest(t *testing.T) {
+	repo := repositories.NewMemoryAuditRepository(repositories.NewMemoryStore())
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	sink, err := OpenJSONLinesSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+	audit := NewAuditLog(repo, discardLogger, sink)
+
+	ctx := WithRequestInfo(context.Background(), RequestInfo{ClientIP: "192.0.2.1", UserAgent: "test-agent", UserID: 7})
+	audit.Record(ctx, succeeded(AuditPasswordChange, 7, nil))
+	audit.Record(ctx, failed(AuditLogin, 0, map[string]any{"reason": "invalid_credentials"}))
+
+	events, err := repo.ListEvents(context.Background(), repositories.AuditFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	for _, event := range events {
+		if event.ActorID == nil || *event.ActorID != 7 || event.IP != "192.0.2.1" || event.UserAgent != "test-agent" {
+			t.Errorf("event %+v was not completed from the request", event)
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	var actions []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event models.AuditEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("line %q: %v", scanner.Text(), err)
+		}
+		actions = append(actions, event.Action)
+	}
+	if want := []string{AuditPasswordChange, AuditLogin}; !slices.Equal(actions, want) {
+		t.Errorf("file has actions %v, want %v", actions, want)
+	}
+}
+
+func TestLoginAttemptsAreAudited(t *testing.T) {
+	env := newTestEnv(t)
+	alice := createTestUser(t, env.users, "alice", "correct horse battery")
+	ctx := context.Background()
+
+	if _, err := env.authService.Login(ctx, "alice", "wrong password", "192.0.2.1"); err == nil {
+		t.Fatal("login with a wrong password succeeded")
+	}
+	if _, err := env.authService.Login(ctx, "alice", "correct horse battery", "192.0.2.2"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(env.audit.events) != 2 {
+		t.Fatalf("recorded %v, want two logins", env.audit.actions())
+	}
+	failure, success := env.audit.events[0], env.audit.events[1]
+	if failure.Outcome != models.OutcomeFailure || failure.IP != "192.0.2.1" || failure.Details["reason"] != "invalid_credentials" {
+		t.Errorf("failed login recorded as %+v", failure)
+	}
+	if success.Outcome != models.OutcomeSuccess || success.IP != "192.0.2.2" || success.ActorID == nil || *success.ActorID != alice.ID {
+		t.Errorf("successful login recorded as %+v", success)
+	}
+}
+
+func TestAccountChangesAreAudited(t *testing.T) {
+	env := newTestEnv(t)
+	alice := createTestUser(t, env.users, "alice", "correct horse battery")
+	ctx := context.Background()
+
+	_, err := env.userService.UpdateUser(ctx, alice.ID, &models.User{ID: alice.ID, Username: "alice", Email: "alice@example.org", Password: "a new long passphrase"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.userService.DeleteUser(ctx, alice.ID, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{AuditPasswordChange, AuditEmailChange, AuditAccountDeletion}; !slices.Equal(env.audit.actions(), want) {
+		t.Fatalf("recorded %v, want %v", env.audit.actions(), want)
+	}
+	if email := env.audit.events[1].Details; email["old_email"] != "alice@example.com" || email["new_email"] != "alice@example.org" {
+		t.Errorf("email change details are %v", email)
+	}
+}
+
+func TestAdminActionsAreAttributedToTheAdmin(t *testing.T) {
+	env := newTestEnv(t)
+	admin := createTestUser(t, env.users, "admin", "correct horse battery")
+	alice := createTestUser(t, env.users, "alice", "correct horse battery")
+
+	if _, err := env.adminService.ChangeRole(context.Background(), admin.ID, alice.ID, models.RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(env.audit.events) != 1 {
+		t.Fatalf("recorded %v, want one role change", env.audit.actions())
+	}
+	event := env.audit.events[0]
+	if event.Action != AuditRoleChange || event.ActorID == nil || *event.ActorID != admin.ID || event.TargetID == nil || *event.TargetID != alice.ID {
+		t.Errorf("role change recorded as %+v", event)
+	}
+}
@@ -1,11 +1,52 @@
 package services
 
 import (
+	"chat-app-api/internal/models"
 	"chat-app-api/internal/repositories"
 	"chat-app-api/internal/utils"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
+var (
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	ErrAccountLocked      = errors.New("account temporarily locked")
+	ErrAccountSuspended   = errors.New("account suspended")
+	ErrSessionRevoked     = errors.New("session revoked")
+)
+
+// AccountLockedError is returned by Login while the account or client IP is locked out
+type AccountLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *AccountLockedError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", ErrAccountLocked, e.RetryAfter.Round(time.Second))
+}
+
+func (e *AccountLockedError) Is(target error) bool {
+	return target == ErrAccountLocked
+}
+
+// AccountSuspendedError is returned when a suspended user logs in or renews a token
+type AccountSuspendedError struct {
+	Until time.Time
+}
+
+func (e *AccountSuspendedError) Error() string {
+	return fmt.Sprintf("%s until %s", ErrAccountSuspended, e.Until.UTC().Format(time.RFC3339))
+}
+
+func (e *AccountSuspendedError) Is(target error) bool {
+	return target == ErrAccountSuspended
+}
+
 type LoginResponse struct {
 	AccessToken  string `json:"access_token"`
 	RefreshToken string `json:"refresh_token"`
@@ -15,40 +56,209 @@ type LoginResponse struct {
 		FirstName       string `json:"first_name"`
 		LastName        string `json:"last_name"`
 		ProfileImageUrl string `json:"profile_image_url"`
+		Role            string `json:"role"`
 	} `json:"user"`
 }
 
 type AuthService interface {
-	Login(username, password string) (LoginResponse, error)
-	RenewAccessToken(refreshToken string) (string, error)
+	Login(ctx context.Context, identifier, password, clientIP string) (LoginResponse, error)
+	RenewAccessToken(ctx context.Context, refreshToken string) (string, error)
+	CheckSession(ctx context.Context, userID uint, tokenVersion int) error
+	UnlockAccount(ctx context.Context, userID uint) error
 }
 
 type AuthServiceImpl struct {
-	userRepo repositories.UserRepository
+	userRepo   repositories.UserRepository
+	loginGuard LoginGuard
+	notifier   Notifier
+	audit      AuditLog
+	logger     *slog.Logger
 }
 
-func NewAuthService(userRepo repositories.UserRepository) AuthService {
-	return &AuthServiceImpl{userRepo: userRepo}
+func NewAuthService(userRepo repositories.UserRepository, loginGuard LoginGuard, notifier Notifier, audit AuditLog, logger *slog.Logger) AuthService {
+	return &AuthServiceImpl{userRepo: userRepo, loginGuard: loginGuard, notifier: notifier, audit: audit, logger: logger}
 }
 
-func (s *AuthServiceImpl) Login(username, password string) (LoginResponse, error) {
-	user, err := s.userRepo.FindByUsername(username)
+// dummyPasswordHash is compared against when the user does not exist, so unknown
+// usernames take as long to reject as wrong passwords
+var dummyPasswordHash = sync.OnceValue(func() string {
+	hash, _ := utils.HashPassword("dummy-password-for-timing")
+	return hash
+})
+
+// Login authenticates a user by username or email address; every attempt is audited
+func (s *AuthServiceImpl) Login(ctx context.Context, identifier, password, clientIP string) (LoginResponse, error) {
+	response, user, err := s.login(ctx, identifier, password, clientIP)
+
+	var userID uint
+	if user != nil {
+		userID = user.ID
+	}
 	if err != nil {
+		event := failed(AuditLogin, userID, map[string]any{"identifier": identifier, "reason": failureReason(err)})
+		event.IP = clientIP
+		s.audit.Record(ctx, event)
 		return LoginResponse{}, err
 	}
 
-	if err := utils.ComparePassword(user.Password, password); err != nil {
-		return LoginResponse{}, err
+	event := succeeded(AuditLogin, userID, map[string]any{"method": "password"})
+	event.ActorID, event.IP = &userID, clientIP
+	s.audit.Record(ctx, event)
+	return response, nil
+}
+
+// login returns the user the identifier belongs to, if any, along with the outcome
+func (s *AuthServiceImpl) login(ctx context.Context, identifier, password, clientIP string) (LoginResponse, *models.User, error) {
+	user, err := s.findByLogin(ctx, identifier)
+	if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+		return LoginResponse{}, nil, err
+	}
+
+	// Unknown users are tracked by login name so they lock out exactly like real accounts
+	accountKey := "login:" + strings.ToLower(identifier)
+	if err == nil {
+		accountKey = accountLockKey(user.ID)
+	}
+
+	if retryAfter := s.loginGuard.Check(accountKey, clientIP); retryAfter > 0 {
+		s.logger.WarnContext(ctx, "login rejected, account or client locked", "client_ip", clientIP, "retry_after", retryAfter)
+		return LoginResponse{}, user, &AccountLockedError{RetryAfter: retryAfter}
+	}
+
+	hashedPassword := dummyPasswordHash()
+	if err == nil {
+		hashedPassword = user.Password
 	}
 
-	userClaims := utils.UserClaim{
-		UserID:    strconv.FormatUint(uint64(user.ID), 10),
-		Username:  user.Username,
-		Email:     user.Email,
-		TokenType: "access",
+	if utils.ComparePassword(hashedPassword, password) != nil || err != nil {
+		lockedUntil, newlyLocked := s.loginGuard.RecordFailure(accountKey, clientIP)
+		if newlyLocked && err == nil {
+			s.notifier.NotifyAccountLocked(user, lockedUntil, clientIP)
+		}
+		return LoginResponse{}, user, ErrInvalidCredentials
 	}
 
-	accessToken, refreshToken, err := utils.GenerateAccessAndRefreshTokens(userClaims)
+	s.loginGuard.RecordSuccess(accountKey)
+	s.upgradePasswordHash(ctx, user, password)
+
+	response, err := newLoginResponse(user)
+	return response, user, err
+}
+
+// upgradePasswordHash transparently rehashes the password when the stored hash uses an older algorithm or cost
+func (s *AuthServiceImpl) upgradePasswordHash(ctx context.Context, user *models.User, password string) {
+	if !utils.PasswordNeedsRehash(user.Password) {
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "error rehashing password", "user_id", user.ID, "error", err)
+		return
+	}
+	if err := s.userRepo.UpdatePassword(ctx, user.ID, hashedPassword); err != nil {
+		s.logger.ErrorContext(ctx, "error storing rehashed password", "user_id", user.ID, "error", err)
+		return
+	}
+	user.Password = hashedPassword
+}
+
+// findByLogin looks the identifier up as a username first and as an email address second. Both
+// lookups always run, so the response time does not tell which kind of identifier exists.
+func (s *AuthServiceImpl) findByLogin(ctx context.Context, identifier string) (*models.User, error) {
+	byUsername, usernameErr := s.userRepo.FindByUsername(ctx, identifier)
+	byEmail, emailErr := s.userRepo.FindByEmail(ctx, identifier)
+	if usernameErr == nil || !errors.Is(usernameErr, repositories.ErrNotFound) {
+		return byUsername, usernameErr
+	}
+	return byEmail, emailErr
+}
+
+// RenewAccessToken issues an access token carrying the user's current role, unless the refresh
+// token was revoked or the account is suspended
+func (s *AuthServiceImpl) RenewAccessToken(ctx context.Context, refreshToken string) (string, error) {
+	claims, err := utils.ParseRefreshToken(refreshToken)
+	if err != nil {
+		s.audit.Record(ctx, failed(AuditTokenRenewal, 0, map[string]any{"reason": "invalid_token"}))
+		return "", fmt.Errorf("%w: %v", ErrSessionRevoked, err)
+	}
+
+	userID, err := strconv.ParseUint(claims.UserID, 10, 32)
+	if err != nil {
+		s.audit.Record(ctx, failed(AuditTokenRenewal, 0, map[string]any{"reason": "invalid_token"}))
+		return "", fmt.Errorf("%w: invalid user id %q", ErrSessionRevoked, claims.UserID)
+	}
+	user, err := s.session(ctx, uint(userID), claims.TokenVersion)
+	if err != nil {
+		s.audit.Record(ctx, failed(AuditTokenRenewal, uint(userID), map[string]any{"reason": failureReason(err)}))
+		return "", err
+	}
+
+	accessToken, err := utils.GenerateAccessToken(userClaims(user))
+	if err != nil {
+		return "", err
+	}
+	event := succeeded(AuditTokenRenewal, user.ID, nil)
+	event.ActorID = &user.ID
+	s.audit.Record(ctx, event)
+	return accessToken, nil
+}
+
+// CheckSession confirms that tokens of the given version are still valid for the user
+func (s *AuthServiceImpl) CheckSession(ctx context.Context, userID uint, tokenVersion int) error {
+	_, err := s.session(ctx, userID, tokenVersion)
+	return err
+}
+
+func (s *AuthServiceImpl) session(ctx context.Context, userID uint, tokenVersion int) (*models.User, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, fmt.Errorf("%w: user %d no longer exists", ErrSessionRevoked, userID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if user.TokenVersion != tokenVersion {
+		return nil, fmt.Errorf("%w: token version %d, current %d", ErrSessionRevoked, tokenVersion, user.TokenVersion)
+	}
+	if user.IsSuspended(time.Now()) {
+		return nil, &AccountSuspendedError{Until: *user.SuspendedUntil}
+	}
+	return user, nil
+}
+
+func (s *AuthServiceImpl) UnlockAccount(ctx context.Context, userID uint) error {
+	if _, err := s.userRepo.FindByID(ctx, userID); err != nil {
+		return repositoryError(err, "user")
+	}
+
+	s.loginGuard.Unlock(accountLockKey(userID))
+	s.audit.Record(ctx, succeeded(AuditUnlock, userID, nil))
+	return nil
+}
+
+func accountLockKey(userID uint) string {
+	return "user:" + strconv.FormatUint(uint64(userID), 10)
+}
+
+func userClaims(user *models.User) utils.UserClaim {
+	return utils.UserClaim{
+		UserID:       strconv.FormatUint(uint64(user.ID), 10),
+		Username:     user.Username,
+		Email:        user.Email,
+		Role:         user.Role,
+		TokenVersion: user.TokenVersion,
+	}
+}
+
+// newLoginResponse issues a token pair for the user, unless the account is suspended
+func newLoginResponse(user *models.User) (LoginResponse, error) {
+	if user.IsSuspended(time.Now()) {
+		return LoginResponse{}, &AccountSuspendedError{Until: *user.SuspendedUntil}
+	}
+
+	accessToken, refreshToken, err := utils.GenerateAccessAndRefreshTokens(userClaims(user))
 	if err != nil {
 		return LoginResponse{}, err
 	}
@@ -61,10 +271,7 @@ func (s *AuthServiceImpl) Login(username, password string) (LoginResponse, error
 	response.User.FirstName = user.FirstName
 	response.User.LastName = user.LastName
 	response.User.ProfileImageUrl = user.ProfileImageUrl
+	response.User.Role = user.Role
 
 	return response, nil
 }
-
-func (s *AuthServiceImpl) RenewAccessToken(refreshToken string) (string, error) {
-	return utils.RenewAccessToken(refreshToken)
-}
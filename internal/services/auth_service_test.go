@@ -0,0 +1,177 @@
+package services
+
+import (
+	"chat-app-api/internal/models"
+	"chat-app-api/internal/repositories"
+	"chat-app-api/internal/utils"
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestLoginByUsernameOrEmail(t *testing.T) {
+	env := newTestEnv(t)
+	alice := createTestUser(t, env.users, "alice", "correct horse battery")
+
+	for _, identifier := range []string{"alice", "alice@example.com"} {
+		response, err := env.authService.Login(context.Background(), identifier, "correct horse battery", "192.0.2.1")
+		if err != nil {
+			t.Fatalf("login as %s: %v", identifier, err)
+		}
+		if response.User.ID != alice.ID || response.User.Username != "alice" {
+			t.Errorf("login as %s returned user %+v", identifier, response.User)
+		}
+
+		claims, err := utils.ParseAccessToken(response.AccessToken)
+		if err != nil {
+			t.Fatalf("access token does not parse: %v", err)
+		}
+		if claims.UserID != strconv.FormatUint(uint64(alice.ID), 10) {
+			t.Errorf("access token is for user %s, want %d", claims.UserID, alice.ID)
+		}
+	}
+}
+
+func TestLoginRejectsWrongPasswordAndUnknownUser(t *testing.T) {
+	env := newTestEnv(t)
+	createTestUser(t, env.users, "alice", "correct horse battery")
+
+	_, err := env.authService.Login(context.Background(), "alice", "wrong", "192.0.2.1")
+	assertErrorIs(t, err, ErrInvalidCredentials)
+
+	_, err = env.authService.Login(context.Background(), "nobody", "correct horse battery", "192.0.2.1")
+	assertErrorIs(t, err, ErrInvalidCredentials)
+}
+
+func TestLoginLocksAccountAfterRepeatedFailures(t *testing.T) {
+	env := newTestEnv(t)
+	alice := createTestUser(t, env.users, "alice", "correct horse battery")
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		_, err := env.authService.Login(ctx, "alice", "wrong", "192.0.2.1")
+		assertErrorIs(t, err, ErrInvalidCredentials)
+	}
+	if len(env.notifier.locked) != 1 || env.notifier.locked[0] != alice.ID {
+		t.Errorf("notified %v, want the lock of user %d", env.notifier.locked, alice.ID)
+	}
+
+	// Locked accounts are rejected even with the right password, from any address
+	_, err := env.authService.Login(ctx, "alice@example.com", "correct horse battery", "198.51.100.7")
+	var lockedErr *AccountLockedError
+	if !errors.As(err, &lockedErr) || lockedErr.RetryAfter <= 0 {
+		t.Fatalf("got %v, want an AccountLockedError with a retry delay", err)
+	}
+
+	if err := env.authService.UnlockAccount(ctx, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.authService.Login(ctx, "alice", "correct horse battery", "192.0.2.1"); err != nil {
+		t.Fatalf("login after unlock: %v", err)
+	}
+
+	assertErrorIs(t, env.authService.UnlockAccount(ctx, 4242), ErrNotFound)
+}
+
+func TestLoginLocksUnknownIdentifiers(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		_, err := env.authService.Login(ctx, "Nobody", "wrong", "192.0.2.1")
+		assertErrorIs(t, err, ErrInvalidCredentials)
+	}
+
+	// An unknown name locks out like an account does, whatever its case, and nobody is notified
+	_, err := env.authService.Login(ctx, "nobody", "wrong", "198.51.100.7")
+	var lockedErr *AccountLockedError
+	if !errors.As(err, &lockedErr) {
+		t.Errorf("got %v, want an AccountLockedError", err)
+	}
+	if len(env.notifier.locked) != 0 {
+		t.Errorf("notified %v for an unknown identifier", env.notifier.locked)
+	}
+}
+
+// countingUsers counts the lookups a login makes
+type countingUsers struct {
+	repositories.UserRepository
+	lookups []string
+}
+
+func (r *countingUsers) FindByUsername(ctx context.Context, username string) (*models.User, error) {
+	r.lookups = append(r.lookups, "username")
+	return r.UserRepository.FindByUsername(ctx, username)
+}
+
+func (r *countingUsers) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	r.lookups = append(r.lookups, "email")
+	return r.UserRepository.FindByEmail(ctx, email)
+}
+
+func TestLoginLooksUpEveryIdentifierTheSameWay(t *testing.T) {
+	env := newTestEnv(t)
+	createTestUser(t, env.users, "alice", "correct horse battery")
+	users := &countingUsers{UserRepository: env.users}
+	authService := NewAuthService(users, NewLoginGuard(testLoginConfig), env.notifier, env.audit, discardLogger)
+
+	// Otherwise the time an unknown identifier takes tells whether it looks like an email address
+	for _, identifier := range []string{"alice", "alice@example.com", "nobody", "nobody@example.com"} {
+		users.lookups = nil
+		_, _ = authService.Login(context.Background(), identifier, "wrong", "192.0.2.1")
+		if !slices.Equal(users.lookups, []string{"username", "email"}) {
+			t.Errorf("login as %s looked up %v, want the username then the email", identifier, users.lookups)
+		}
+	}
+}
+
+func TestLoginUpgradesOutdatedPasswordHash(t *testing.T) {
+	env := newTestEnv(t)
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct horse battery"), testPasswordConfig.BcryptCost+1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice, err := env.users.CreateUser(context.Background(), &models.User{Username: "alice", Email: "alice@example.com", Password: string(hash)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := env.authService.Login(context.Background(), "alice", "correct horse battery", "192.0.2.1"); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := env.users.FindByID(context.Background(), alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cost, err := bcrypt.Cost([]byte(stored.Password)); err != nil || cost != testPasswordConfig.BcryptCost {
+		t.Errorf("stored hash has cost %d (%v), want %d", cost, err, testPasswordConfig.BcryptCost)
+	}
+}
+
+func TestRenewAccessToken(t *testing.T) {
+	env := newTestEnv(t)
+	createTestUser(t, env.users, "alice", "correct horse battery")
+	ctx := context.Background()
+
+	response, err := env.authService.Login(ctx, "alice", "correct horse battery", "192.0.2.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	accessToken, err := env.authService.RenewAccessToken(ctx, response.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := utils.ParseAccessToken(accessToken); err != nil {
+		t.Errorf("renewed access token does not parse: %v", err)
+	}
+
+	if _, err := env.authService.RenewAccessToken(ctx, response.AccessToken); err == nil {
+		t.Error("an access token was accepted as a refresh token")
+	}
+}
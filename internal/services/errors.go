@@ -0,0 +1,84 @@
+package services
+
+import (
+	"chat-app-api/internal/repositories"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Error kinds returned by every service; match them with errors.Is
+var (
+	ErrNotFound   = errors.New("not found")
+	ErrConflict   = errors.New("conflict")
+	ErrForbidden  = errors.New("forbidden")
+	ErrValidation = errors.New("validation failed")
+)
+
+// Error is a domain error of one of the kinds above whose message is safe to show to clients
+type Error struct {
+	Kind    error
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+func notFound(message string) error {
+	return &Error{Kind: ErrNotFound, Message: message}
+}
+
+func conflict(message string) error {
+	return &Error{Kind: ErrConflict, Message: message}
+}
+
+func forbidden(message string) error {
+	return &Error{Kind: ErrForbidden, Message: message}
+}
+
+// FieldError describes why one input field was rejected
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every invalid field of a request
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func NewValidationError(fields ...FieldError) *ValidationError {
+	return &ValidationError{Fields: fields}
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		messages = append(messages, fmt.Sprintf("%s: %s", field.Field, field.Message))
+	}
+	return fmt.Sprintf("%s: %s", ErrValidation, strings.Join(messages, "; "))
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrValidation
+}
+
+func (e *ValidationError) FieldErrors() []FieldError {
+	return e.Fields
+}
+
+// repositoryError turns the storage errors of a lookup or write of resource into domain errors
+func repositoryError(err error, resource string) error {
+	switch {
+	case errors.Is(err, repositories.ErrNotFound):
+		return notFound(resource + " not found")
+	case errors.Is(err, repositories.ErrDuplicate):
+		return conflict(resource + " already exists")
+	}
+	return err
+}
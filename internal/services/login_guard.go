@@ -0,0 +1,121 @@
+package services
+
+import (
+	"chat-app-api/internal/config"
+	"sync"
+	"time"
+)
+
+// LoginGuard tracks failed login attempts per account and per client IP
+type LoginGuard interface {
+	// Check returns how long the account or IP is still locked, or zero when login may proceed
+	Check(accountKey, ip string) time.Duration
+	// RecordFailure registers a failed attempt and reports whether it caused the account to become locked
+	RecordFailure(accountKey, ip string) (lockedUntil time.Time, newlyLocked bool)
+	// RecordSuccess clears the failure history of the account
+	RecordSuccess(accountKey string)
+	// Unlock removes any lock and failure history of the account
+	Unlock(accountKey string)
+}
+
+type attemptRecord struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+type loginGuard struct {
+	config    config.LoginConfig
+	mu        sync.Mutex
+	accounts  map[string]*attemptRecord
+	ips       map[string]*attemptRecord
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewLoginGuard(cfg config.LoginConfig) LoginGuard {
+	return &loginGuard{
+		config:   cfg,
+		accounts: make(map[string]*attemptRecord),
+		ips:      make(map[string]*attemptRecord),
+		now:      time.Now,
+	}
+}
+
+func (g *loginGuard) Check(accountKey, ip string) time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+	var remaining time.Duration
+	if record, ok := g.accounts[accountKey]; ok && record.lockedUntil.After(now) {
+		remaining = record.lockedUntil.Sub(now)
+	}
+	if record, ok := g.ips[ip]; ok && record.lockedUntil.After(now) {
+		remaining = max(remaining, record.lockedUntil.Sub(now))
+	}
+	return remaining
+}
+
+func (g *loginGuard) RecordFailure(accountKey, ip string) (time.Time, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+	g.sweep(now)
+
+	g.fail(g.ips, ip, g.config.MaxIPFailures, now)
+	account := g.fail(g.accounts, accountKey, g.config.MaxAccountFailures, now)
+
+	newlyLocked := account.failures == g.config.MaxAccountFailures
+	return account.lockedUntil, newlyLocked
+}
+
+func (g *loginGuard) RecordSuccess(accountKey string) {
+	g.Unlock(accountKey)
+}
+
+func (g *loginGuard) Unlock(accountKey string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	delete(g.accounts, accountKey)
+}
+
+// fail increments the failure counter of key and applies an exponential lockout once the limit is reached
+func (g *loginGuard) fail(records map[string]*attemptRecord, key string, limit int, now time.Time) *attemptRecord {
+	record, ok := records[key]
+	if !ok || (now.Sub(record.lastFailure) > g.config.FailureWindow && !record.lockedUntil.After(now)) {
+		record = &attemptRecord{}
+		records[key] = record
+	}
+
+	record.failures++
+	record.lastFailure = now
+
+	if limit > 0 && record.failures >= limit {
+		lockout := g.config.BaseLockout << min(record.failures-limit, 30)
+		if lockout <= 0 || lockout > g.config.MaxLockout {
+			lockout = g.config.MaxLockout
+		}
+		record.lockedUntil = now.Add(lockout)
+	}
+
+	return record
+}
+
+// sweep drops records that are neither locked nor inside the failure window
+func (g *loginGuard) sweep(now time.Time) {
+	if now.Sub(g.lastSweep) < g.config.FailureWindow {
+		return
+	}
+	g.lastSweep = now
+
+	for _, records := range []map[string]*attemptRecord{g.accounts, g.ips} {
+		for key, record := range records {
+			if now.Sub(record.lastFailure) > g.config.FailureWindow && !record.lockedUntil.After(now) {
+				delete(records, key)
+			}
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"chat-app-api/internal/config"
+	"fmt"
+	"testing"
+	"time"
+)
+
+var guardTestConfig = config.LoginConfig{
+	MaxAccountFailures: 3,
+	MaxIPFailures:      5,
+	BaseLockout:        time.Minute,
+	MaxLockout:         10 * time.Minute,
+	FailureWindow:      15 * time.Minute,
+}
+
+// testClock is a clock that only moves when the test advances it
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time { return c.now }
+
+func (c *testClock) advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func newTestGuard(cfg config.LoginConfig) (*loginGuard, *testClock) {
+	clock := &testClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	guard := NewLoginGuard(cfg).(*loginGuard)
+	guard.now = clock.Now
+	return guard, clock
+}
+
+// otherIP returns a different client address for every attempt, so only the account limit applies
+func otherIP(i int) string {
+	return fmt.Sprintf("192.0.2.%d", i)
+}
+
+func TestLoginGuardBacksOffExponentially(t *testing.T) {
+	guard, clock := newTestGuard(guardTestConfig)
+
+	for i := 1; i < 3; i++ {
+		if _, locked := guard.RecordFailure("alice", otherIP(i)); locked || guard.Check("alice", otherIP(i)) != 0 {
+			t.Fatalf("locked after %d failures, want the lock on the third", i)
+		}
+	}
+
+	// The lockout doubles with every further failure until it reaches the maximum
+	for i, want := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 8 * time.Minute, 10 * time.Minute, 10 * time.Minute} {
+		lockedUntil, newlyLocked := guard.RecordFailure("alice", otherIP(10+i))
+		if newlyLocked != (i == 0) {
+			t.Errorf("failure %d: newly locked %t, want it reported only for the first lock", 3+i, newlyLocked)
+		}
+		if got := lockedUntil.Sub(clock.now); got != want {
+			t.Errorf("failure %d: locked for %v, want %v", 3+i, got, want)
+		}
+		if got := guard.Check("alice", "198.51.100.1"); got != want {
+			t.Errorf("failure %d: check reports %v remaining, want %v", 3+i, got, want)
+		}
+	}
+
+	clock.advance(10 * time.Minute)
+	if got := guard.Check("alice", "198.51.100.1"); got != 0 {
+		t.Errorf("still locked for %v after the lockout ended", got)
+	}
+}
+
+func TestLoginGuardLocksAnIPAcrossAccounts(t *testing.T) {
+	guard, _ := newTestGuard(guardTestConfig)
+
+	for i := 1; i <= 5; i++ {
+		guard.RecordFailure(fmt.Sprintf("account-%d", i), "192.0.2.1")
+	}
+
+	if got := guard.Check("bob", "192.0.2.1"); got != time.Minute {
+		t.Errorf("got %v remaining for another account from the address, want %v", got, time.Minute)
+	}
+	if got := guard.Check("bob", "198.51.100.1"); got != 0 {
+		t.Errorf("got %v remaining from another address, want no lock", got)
+	}
+	// Unlocking an account leaves the address locked
+	guard.Unlock("account-5")
+	if guard.Check("account-5", "192.0.2.1") == 0 {
+		t.Error("unlocking the account lifted the lock of the address")
+	}
+}
+
+func TestLoginGuardForgetsOldFailures(t *testing.T) {
+	guard, clock := newTestGuard(guardTestConfig)
+
+	guard.RecordFailure("alice", otherIP(1))
+	guard.RecordFailure("alice", otherIP(2))
+	clock.advance(16 * time.Minute)
+	if _, locked := guard.RecordFailure("alice", otherIP(3)); locked {
+		t.Error("failures outside the window counted towards the lock")
+	}
+}
+
+func TestLoginGuardRecordSuccessResetsTheAccount(t *testing.T) {
+	guard, _ := newTestGuard(guardTestConfig)
+
+	guard.RecordFailure("alice", "192.0.2.1")
+	guard.RecordFailure("alice", "192.0.2.1")
+	guard.RecordSuccess("alice")
+	guard.RecordFailure("alice", "192.0.2.1")
+	if _, locked := guard.RecordFailure("alice", "192.0.2.1"); locked {
+		t.Error("failures before the successful login counted towards the lock")
+	}
+
+	// The address keeps its failures: four so far, so the next one locks it
+	guard.RecordFailure("bob", "192.0.2.1")
+	if guard.Check("carol", "192.0.2.1") == 0 {
+		t.Error("a successful login reset the failures of the address")
+	}
+}
+
+func TestLoginGuardSweepsExpiredRecords(t *testing.T) {
+	cfg := guardTestConfig
+	cfg.BaseLockout, cfg.MaxLockout = time.Hour, time.Hour
+	guard, clock := newTestGuard(cfg)
+
+	guard.RecordFailure("stale", "192.0.2.1")
+	for i := 0; i < 3; i++ {
+		guard.RecordFailure("locked", otherIP(10+i))
+	}
+
+	// Sweeps run at most once per failure window, on the next failure
+	clock.advance(10 * time.Minute)
+	guard.RecordFailure("fresh", "198.51.100.1")
+	if _, ok := guard.accounts["stale"]; !ok {
+		t.Fatal("swept a record inside the failure window")
+	}
+
+	clock.advance(10 * time.Minute)
+	guard.RecordFailure("fresh", "198.51.100.1")
+	if _, ok := guard.accounts["stale"]; ok {
+		t.Error("the record outside the failure window was kept")
+	}
+	if _, ok := guard.ips["192.0.2.1"]; ok {
+		t.Error("the address record outside the failure window was kept")
+	}
+	if _, ok := guard.accounts["locked"]; !ok {
+		t.Error("swept the record of an account that is still locked")
+	}
+	if _, ok := guard.accounts["fresh"]; !ok {
+		t.Error("swept the record of the failure that triggered the sweep")
+	}
+}
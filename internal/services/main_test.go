@@ -0,0 +1,214 @@
+package services
+
+import (
+	"chat-app-api/internal/config"
+	"chat-app-api/internal/models"
+	"chat-app-api/internal/realtime"
+	"chat-app-api/internal/repositories"
+	"chat-app-api/internal/utils"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+)
+
+// testPasswordConfig hashes with the cheapest bcrypt cost so the tests stay fast
+var testPasswordConfig = func() config.PasswordConfig {
+	cfg := config.Default().Password
+	cfg.HashAlgorithm = utils.PasswordAlgorithmBcrypt
+	cfg.BcryptCost = 4
+	return cfg
+}()
+
+func TestMain(m *testing.M) {
+	if err := utils.ConfigurePasswordHashing(testPasswordConfig); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	jwtConfig := config.Default().JWT
+	jwtConfig.AccessTokenSecret = "test-access-secret"
+	jwtConfig.RefreshTokenSecret = "test-refresh-secret"
+	if err := utils.InitJWT(jwtConfig); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	os.Exit(m.Run())
+}
+
+// testLoginConfig locks accounts after a few failures so the lockout tests stay short
+var testLoginConfig = func() config.LoginConfig {
+	cfg := config.Default().Login
+	cfg.MaxAccountFailures = 3
+	return cfg
+}()
+
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// testEnv wires every service to one in-memory store, with recording stand-ins for the hub,
+// the notifier and the audit log
+type testEnv struct {
+	users      repositories.UserRepository
+	messages   repositories.MessageRepository
+	reports    repositories.ReportRepository
+	identities repositories.IdentityRepository
+
+	userService    UserService
+	authService    AuthService
+	adminService   AdminService
+	messageService MessageService
+	reportService  ReportService
+	oidcService    OIDCService // only with withOIDC
+
+	connections *recordingConnections
+	events      *recordingEvents
+	notifier    *recordingNotifier
+	audit       *recordingAuditLog
+}
+
+// testEnvOptions are the collaborators tests change from their defaults
+type testEnvOptions struct {
+	filter *MessageFilter
+	oidc   *config.OIDCConfig
+}
+
+type testEnvOption func(options *testEnvOptions)
+
+// withMessageFilter runs new messages through the filter instead of the default one
+func withMessageFilter(filter *MessageFilter) testEnvOption {
+	return func(options *testEnvOptions) { options.filter = filter }
+}
+
+// withOIDC signs users in through the provider
+func withOIDC(cfg config.OIDCConfig) testEnvOption {
+	return func(options *testEnvOptions) { options.oidc = &cfg }
+}
+
+func newTestEnv(t *testing.T, opts ...testEnvOption) testEnv {
+	t.Helper()
+	options := testEnvOptions{filter: NewMessageFilter()}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	policy, err := NewPasswordPolicy(testPasswordConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := repositories.NewMemoryStore()
+	env := testEnv{
+		users:       repositories.NewMemoryUserRepository(store),
+		messages:    repositories.NewMemoryMessageRepository(store),
+		reports:     repositories.NewMemoryReportRepository(store),
+		identities:  repositories.NewMemoryIdentityRepository(store),
+		connections: &recordingConnections{},
+		events:      &recordingEvents{},
+		notifier:    &recordingNotifier{},
+		audit:       &recordingAuditLog{},
+	}
+
+	env.userService = NewUserService(env.users, policy, env.connections, env.audit)
+	env.authService = NewAuthService(env.users, NewLoginGuard(testLoginConfig), env.notifier, env.audit, discardLogger)
+	env.adminService = NewAdminService(env.users, env.messages, repositories.NewMemoryAuditRepository(store), policy, env.connections, env.audit, discardLogger)
+	env.messageService = NewMessageService(env.messages, env.reports, options.filter, env.audit, discardLogger)
+	env.reportService = NewReportService(env.reports, env.users, env.messages, env.adminService, env.events, env.notifier, env.audit, discardLogger)
+	if options.oidc != nil {
+		env.oidcService = NewOIDCService(*options.oidc, env.users, env.identities, env.audit)
+	}
+	return env
+}
+
+// createTestUser stores a user whose password is hashed like a real signup
+func createTestUser(t *testing.T, users repositories.UserRepository, username, password string) *models.User {
+	t.Helper()
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := users.CreateUser(context.Background(), &models.User{
+		Username:  username,
+		FirstName: "First " + username,
+		LastName:  "Last",
+		Email:     username + "@example.com",
+		Password:  hash,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// recordingConnections stands in for the hub and remembers whose connections were closed
+type recordingConnections struct {
+	disconnected []uint
+}
+
+func (c *recordingConnections) DisconnectUser(userID uint) int {
+	c.disconnected = append(c.disconnected, userID)
+	return 1
+}
+
+func (c *recordingConnections) Stats() realtime.Stats {
+	return realtime.Stats{Connections: 3, QueuedMessages: 2}
+}
+
+// recordingEvents stands in for the hub and remembers the events sent to each user
+type recordingEvents struct {
+	sent map[uint][]any
+}
+
+func (e *recordingEvents) SendToUser(userID uint, v interface{}) bool {
+	if e.sent == nil {
+		e.sent = map[uint][]any{}
+	}
+	e.sent[userID] = append(e.sent[userID], v)
+	return true
+}
+
+type recordingNotifier struct {
+	locked   []uint
+	warned   []uint // user IDs
+	resolved []uint // report IDs
+}
+
+func (n *recordingNotifier) NotifyAccountLocked(user *models.User, until time.Time, ip string) {
+	n.locked = append(n.locked, user.ID)
+}
+
+func (n *recordingNotifier) NotifyWarned(user *models.User, report *models.Report) {
+	n.warned = append(n.warned, user.ID)
+}
+
+func (n *recordingNotifier) NotifyReportResolved(reporter *models.User, report *models.Report) {
+	n.resolved = append(n.resolved, report.ID)
+}
+
+// recordingAuditLog keeps the recorded events in memory
+type recordingAuditLog struct {
+	events []models.AuditEvent
+}
+
+func (a *recordingAuditLog) Record(ctx context.Context, event models.AuditEvent) {
+	a.events = append(a.events, event)
+}
+
+// actions lists the recorded actions in order
+func (a *recordingAuditLog) actions() []string {
+	actions := make([]string, len(a.events))
+	for i, event := range a.events {
+		actions[i] = event.Action
+	}
+	return actions
+}
+
+func assertErrorIs(t *testing.T, err, want error) {
+	t.Helper()
+	if !errors.Is(err, want) {
+		t.Fatalf("got error %v, want %v", err, want)
+	}
+}
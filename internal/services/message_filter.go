@@ -0,0 +1,292 @@
+package services
+
+import (
+	"bufio"
+	"chat-app-api/internal/config"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// What a filter stage decided about a message
+const (
+	FilterAllow  = "allow"
+	FilterMask   = "mask"   // deliver the message with the offending parts masked
+	FilterReject = "reject" // refuse the message and tell the sender why
+	FilterFlag   = "flag"   // deliver the message and report it to moderators
+)
+
+var ErrMessageRejected = errors.New("message rejected")
+
+// MessageRejectedError is returned when a filter stage refuses a message
+type MessageRejectedError struct {
+	Decision FilterDecision
+}
+
+func (e *MessageRejectedError) Error() string {
+	return fmt.Sprintf("%s by the %s filter: %s", ErrMessageRejected, e.Decision.Stage, e.Decision.Reason)
+}
+
+func (e *MessageRejectedError) Is(target error) bool {
+	return target == ErrMessageRejected
+}
+
+// FilterDecision is the outcome of one stage for one message
+type FilterDecision struct {
+	Stage  string `json:"stage"`
+	Action string `json:"action"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// FilterStage inspects message content. A mask decision comes with the masked content,
+// which the following stages see instead of the original.
+type FilterStage interface {
+	Filter(content string) (FilterDecision, string)
+}
+
+// FilterResult is what the pipeline made of a message
+type FilterResult struct {
+	Content   string           // the content to store, masked where a stage asked for it
+	Decisions []FilterDecision // every decision other than allow, in stage order
+}
+
+// Rejection returns the decision that refused the message, if any
+func (r FilterResult) Rejection() *FilterDecision {
+	for i, decision := range r.Decisions {
+		if decision.Action == FilterReject {
+			return &r.Decisions[i]
+		}
+	}
+	return nil
+}
+
+// Flagged reports whether a stage asked for the message to be reviewed by moderators
+func (r FilterResult) Flagged() bool {
+	for _, decision := range r.Decisions {
+		if decision.Action == FilterFlag {
+			return true
+		}
+	}
+	return false
+}
+
+// MessageFilter runs message content through its stages in order, stopping at the first rejection
+type MessageFilter struct {
+	stages []FilterStage
+}
+
+func NewMessageFilter(stages ...FilterStage) *MessageFilter {
+	return &MessageFilter{stages: stages}
+}
+
+// NewMessageFilterFromConfig builds the pipeline of the built-in stages and loads the blocklist file
+func NewMessageFilterFromConfig(cfg config.MessageConfig) (*MessageFilter, error) {
+	stages := []FilterStage{maxLengthStage{max: cfg.MaxLength}}
+	if cfg.MaxRepeatedChars > 0 {
+		stages = append(stages, repeatedCharsStage{max: cfg.MaxRepeatedChars, action: cfg.RepeatedCharsAction})
+	}
+
+	patterns, err := blocklistPatterns(cfg.BlockedWords, cfg.BlocklistFile)
+	if err != nil {
+		return nil, err
+	}
+	if len(patterns) > 0 {
+		stages = append(stages, blocklistStage{patterns: patterns, action: cfg.BlocklistAction})
+	}
+
+	if len(cfg.DeniedDomains) > 0 {
+		domains := make([]string, 0, len(cfg.DeniedDomains))
+		for _, domain := range cfg.DeniedDomains {
+			domains = append(domains, strings.TrimPrefix(strings.ToLower(domain), "."))
+		}
+		stages = append(stages, deniedDomainsStage{domains: domains, action: cfg.DeniedDomainsAction})
+	}
+
+	return NewMessageFilter(stages...), nil
+}
+
+func (f *MessageFilter) Apply(content string) FilterResult {
+	result := FilterResult{Content: content}
+	for _, stage := range f.stages {
+		decision, masked := stage.Filter(result.Content)
+		if decision.Action == FilterAllow {
+			continue
+		}
+		result.Decisions = append(result.Decisions, decision)
+
+		switch decision.Action {
+		case FilterReject:
+			return result
+		case FilterMask:
+			result.Content = masked
+		}
+	}
+	return result
+}
+
+func allow(stage string) FilterDecision {
+	return FilterDecision{Stage: stage, Action: FilterAllow}
+}
+
+// maskMatches replaces every match with as many asterisks as it has characters
+func maskMatches(pattern *regexp.Regexp, content string) string {
+	return pattern.ReplaceAllStringFunc(content, func(match string) string {
+		return strings.Repeat("*", utf8.RuneCountInString(match))
+	})
+}
+
+// maxLengthStage rejects messages longer than the configured limit; they cannot be masked
+type maxLengthStage struct {
+	max int
+}
+
+func (s maxLengthStage) Filter(content string) (FilterDecision, string) {
+	if utf8.RuneCountInString(content) <= s.max {
+		return allow("max_length"), content
+	}
+	return FilterDecision{Stage: "max_length", Action: FilterReject, Reason: fmt.Sprintf("message is longer than %d characters", s.max)}, content
+}
+
+// repeatedCharsStage catches runs of one character such as "!!!!!!!!"; masking shortens them to the limit
+type repeatedCharsStage struct {
+	max    int
+	action string
+}
+
+func (s repeatedCharsStage) Filter(content string) (FilterDecision, string) {
+	var shortened strings.Builder
+	var previous rune
+	run, found := 0, false
+	for _, r := range content {
+		if r == previous {
+			run++
+		} else {
+			previous, run = r, 1
+		}
+		if run > s.max {
+			found = true
+			continue
+		}
+		shortened.WriteRune(r)
+	}
+
+	if !found {
+		return allow("repeated_chars"), content
+	}
+	reason := fmt.Sprintf("message repeats a character more than %d times", s.max)
+	return FilterDecision{Stage: "repeated_chars", Action: s.action, Reason: reason}, shortened.String()
+}
+
+// blocklistStage catches blocked words and patterns; masking replaces them with asterisks
+type blocklistStage struct {
+	patterns []*regexp.Regexp
+	action   string
+}
+
+func (s blocklistStage) Filter(content string) (FilterDecision, string) {
+	masked, found := content, false
+	for _, pattern := range s.patterns {
+		if pattern.MatchString(masked) {
+			found = true
+			masked = maskMatches(pattern, masked)
+		}
+	}
+
+	if !found {
+		return allow("blocklist"), content
+	}
+	return FilterDecision{Stage: "blocklist", Action: s.action, Reason: "message contains blocked language"}, masked
+}
+
+// blocklistPatterns compiles the blocked words, matched as whole words, and the lines of the
+// blocklist file, where /.../ is a regular expression. All of them ignore case.
+func blocklistPatterns(words []string, path string) ([]*regexp.Regexp, error) {
+	var patterns []*regexp.Regexp
+	for _, word := range words {
+		patterns = append(patterns, wordPattern(word))
+	}
+	if path == "" {
+		return patterns, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open message blocklist: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		entry := strings.TrimSpace(scanner.Text())
+		switch {
+		case entry == "":
+		case len(entry) > 2 && strings.HasPrefix(entry, "/") && strings.HasSuffix(entry, "/"):
+			pattern, err := regexp.Compile("(?i)" + entry[1:len(entry)-1])
+			if err != nil {
+				return nil, fmt.Errorf("message blocklist line %d: %w", line, err)
+			}
+			patterns = append(patterns, pattern)
+		default:
+			patterns = append(patterns, wordPattern(entry))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read message blocklist: %w", err)
+	}
+	return patterns, nil
+}
+
+// wordPattern matches the word unless it is part of a longer word
+func wordPattern(word string) *regexp.Regexp {
+	pattern := regexp.QuoteMeta(word)
+	if first, _ := utf8.DecodeRuneInString(word); isWordRune(first) {
+		pattern = `\b` + pattern
+	}
+	if last, _ := utf8.DecodeLastRuneInString(word); isWordRune(last) {
+		pattern += `\b`
+	}
+	return regexp.MustCompile("(?i)" + pattern)
+}
+
+// isWordRune reports whether \b treats the rune as part of a word
+func isWordRune(r rune) bool {
+	return r == '_' || ('0' <= r && r <= '9') || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z')
+}
+
+// linkPattern finds links with or without a scheme; the first group is the host
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://)?((?:[a-z0-9](?:[a-z0-9-]*[a-z0-9])?\.)+[a-z]{2,})\b(?:[/?#:][^\s]*)?`)
+
+// deniedDomainsStage catches links to the denied domains and their subdomains
+type deniedDomainsStage struct {
+	domains []string
+	action  string
+}
+
+func (s deniedDomainsStage) Filter(content string) (FilterDecision, string) {
+	found := false
+	masked := linkPattern.ReplaceAllStringFunc(content, func(link string) string {
+		host := strings.ToLower(linkPattern.FindStringSubmatch(link)[1])
+		if !s.denied(host) {
+			return link
+		}
+		found = true
+		return strings.Repeat("*", utf8.RuneCountInString(link))
+	})
+
+	if !found {
+		return allow("denied_domains"), content
+	}
+	return FilterDecision{Stage: "denied_domains", Action: s.action, Reason: "message links to a domain that is not allowed"}, masked
+}
+
+func (s deniedDomainsStage) denied(host string) bool {
+	for _, domain := range s.domains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"chat-app-api/internal/config"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestMessageFilterStages(t *testing.T) {
+	blocklist := filepath.Join(t.TempDir(), "blocklist.txt")
+	if err := os.WriteFile(blocklist, []byte("heck\n\n/fr+ee\\s+money/\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.Default().Message
+	cfg.MaxLength = 40
+	cfg.MaxRepeatedChars = 3
+	cfg.BlockedWords = []string{"darn"}
+	cfg.BlocklistFile = blocklist
+	cfg.DeniedDomains = []string{"scam.example"}
+	filter, err := NewMessageFilterFromConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		content string
+		want    string
+		actions []string
+	}{
+		"clean":              {"see you tomorrow", "see you tomorrow", nil},
+		"blocked word":       {"Darn, what the HECK", "****, what the ****", []string{FilterMask}},
+		"part of a word":     {"darned socks", "darned socks", nil},
+		"blocked pattern":    {"get FRRREE   money", "get **************", []string{FilterMask}},
+		"repeated character": {"nooooooo!!!!", "nooo!!!", []string{FilterMask}},
+		"denied domain":      {"win at www.scam.example/now", "", []string{FilterReject}},
+		"other domain":       {"read https://go.dev/doc", "read https://go.dev/doc", nil},
+		"too long":           {strings.Repeat("x ", 21), "", []string{FilterReject}},
+		"masked then denied": {"darn, see scam.example", "", []string{FilterMask, FilterReject}},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			result := filter.Apply(test.content)
+			var actions []string
+			for _, decision := range result.Decisions {
+				actions = append(actions, decision.Action)
+			}
+			if !slices.Equal(actions, test.actions) {
+				t.Fatalf("got decisions %+v, want actions %v", result.Decisions, test.actions)
+			}
+			if result.Rejection() == nil && result.Content != test.want {
+				t.Errorf("got content %q, want %q", result.Content, test.want)
+			}
+		})
+	}
+}
+
+func TestMessageFilterFlags(t *testing.T) {
+	cfg := config.Default().Message
+	cfg.BlockedWords = []string{"darn"}
+	cfg.BlocklistAction = FilterFlag
+	filter, err := NewMessageFilterFromConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := filter.Apply("darn it")
+	if !result.Flagged() || result.Rejection() != nil || result.Content != "darn it" {
+		t.Errorf("got %+v, want the message flagged and left as it is", result)
+	}
+}
+
+func TestMessageFilterRejectsInvalidBlocklist(t *testing.T) {
+	blocklist := filepath.Join(t.TempDir(), "blocklist.txt")
+	if err := os.WriteFile(blocklist, []byte("fine\n/(unclosed/\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.Default().Message
+	cfg.BlocklistFile = blocklist
+
+	_, err := NewMessageFilterFromConfig(cfg)
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("got %v, want an error pointing at line 2", err)
+	}
+}
@@ -3,9 +3,14 @@ package services
 import (
 	"chat-app-api/internal/models"
 	"chat-app-api/internal/repositories"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
 )
 
-type messageResponse struct {
+// MessageResponse is one message of a conversation as seen by the current user
+type MessageResponse struct {
 	ID      uint   `json:"id"`
 	IsSelf  bool   `json:"is_self"`
 	IsRead  bool   `json:"is_read"`
@@ -29,23 +34,59 @@ type RealTimeMessageResponse struct {
 }
 
 type MessageService interface {
-	CreateMessage(message *models.Message) (*RealTimeMessageResponse, error)
-	UpdateMessage(message *models.Message) (*models.Message, error)
-	DeleteMessage(id uint) error
-	GetMessagesBySenderIdAndReceiverId(senderId, receiverId uint) ([]messageResponse, error)
-	GetFriendListWithLastMessage(userID uint) ([]repositories.FriendsList, error)
+	CreateMessage(ctx context.Context, message *models.Message) (*RealTimeMessageResponse, error)
+	UpdateMessage(ctx context.Context, message *models.Message) (*models.Message, error)
+	DeleteMessage(ctx context.Context, id uint) error
+	GetMessagesBySenderIdAndReceiverId(ctx context.Context, senderId, receiverId uint) ([]MessageResponse, error)
+	GetFriendListWithLastMessage(ctx context.Context, userID uint) ([]repositories.FriendsList, error)
 }
 
 type messageService struct {
 	messageRepository repositories.MessageRepository
+	reportRepository  repositories.ReportRepository
+	filter            *MessageFilter
+	audit             AuditLog
+	logger            *slog.Logger
 }
 
-func NewMessageService(repo repositories.MessageRepository) MessageService {
-	return &messageService{messageRepository: repo}
+// NewMessageService runs every new message through the filter; flagged messages are reported to the moderators
+func NewMessageService(repo repositories.MessageRepository, reportRepo repositories.ReportRepository, filter *MessageFilter,
+	audit AuditLog, logger *slog.Logger) MessageService {
+	return &messageService{
+		messageRepository: repo,
+		reportRepository:  reportRepo,
+		filter:            filter,
+		audit:             audit,
+		logger:            logger,
+	}
 }
 
-func (s *messageService) CreateMessage(message *models.Message) (*RealTimeMessageResponse, error) {
-	response, _ := s.messageRepository.CreateMessage(message)
+func (s *messageService) CreateMessage(ctx context.Context, message *models.Message) (*RealTimeMessageResponse, error) {
+	if strings.TrimSpace(message.Content) == "" {
+		return nil, NewValidationError(FieldError{Field: "content", Message: "must not be empty"})
+	}
+
+	result := s.filter.Apply(message.Content)
+	if rejection := result.Rejection(); rejection != nil {
+		s.recordFilterDecisions(ctx, message, result)
+		return nil, &MessageRejectedError{Decision: *rejection}
+	}
+	original := message.Content
+	message.Content = result.Content
+
+	response, err := s.messageRepository.CreateMessage(ctx, message)
+	if errors.Is(err, repositories.ErrMissingReference) {
+		return nil, NewValidationError(FieldError{Field: "receiver_id", Message: "user does not exist"})
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Decisions) > 0 {
+		s.recordFilterDecisions(ctx, response, result)
+	}
+	if result.Flagged() {
+		s.flag(ctx, response, original, result)
+	}
 
 	realTimeResponse := &RealTimeMessageResponse{
 		ID:         response.ID,
@@ -70,23 +111,63 @@ func (s *messageService) CreateMessage(message *models.Message) (*RealTimeMessag
 	return realTimeResponse, nil
 }
 
-func (s *messageService) UpdateMessage(message *models.Message) (*models.Message, error) {
-	return s.messageRepository.UpdateMessage(message)
+// recordFilterDecisions audits what the filter did to a message; the message has no ID when it was rejected
+func (s *messageService) recordFilterDecisions(ctx context.Context, message *models.Message, result FilterResult) {
+	details := map[string]any{"decisions": result.Decisions}
+	event := succeeded(AuditMessageFiltered, message.ReceiverID, details)
+	if result.Rejection() != nil {
+		event = failed(AuditMessageFiltered, message.ReceiverID, details)
+	} else {
+		details["message_id"] = message.ID
+	}
+	event.ActorID = &message.SenderID
+	s.audit.Record(ctx, event)
+}
+
+// flag puts a delivered message in the moderation queue with the content the sender wrote
+func (s *messageService) flag(ctx context.Context, message *models.Message, original string, result FilterResult) {
+	var reasons []string
+	for _, decision := range result.Decisions {
+		if decision.Action == FilterFlag {
+			reasons = append(reasons, decision.Reason)
+		}
+	}
+
+	report := &models.Report{
+		TargetType:     models.ReportTargetMessage,
+		MessageID:      &message.ID,
+		ReportedUserID: message.SenderID,
+		MessageContent: original,
+		Reason:         models.ReportReasonFlagged,
+		Comment:        strings.Join(reasons, "; "),
+		Status:         models.ReportStatusOpen,
+	}
+	if err := s.reportRepository.CreateReport(ctx, report); err != nil {
+		s.logger.ErrorContext(ctx, "failed to report a flagged message", "message_id", message.ID, "error", err)
+	}
+}
+
+func (s *messageService) UpdateMessage(ctx context.Context, message *models.Message) (*models.Message, error) {
+	updatedMessage, err := s.messageRepository.UpdateMessage(ctx, message)
+	if err != nil {
+		return nil, repositoryError(err, "message")
+	}
+	return updatedMessage, nil
 }
 
-func (s *messageService) DeleteMessage(id uint) error {
-	return s.messageRepository.DeleteMessage(id)
+func (s *messageService) DeleteMessage(ctx context.Context, id uint) error {
+	return repositoryError(s.messageRepository.DeleteMessage(ctx, id), "message")
 }
 
-func (s *messageService) GetMessagesBySenderIdAndReceiverId(currentID uint, friendID uint) ([]messageResponse, error) {
-	messages, err := s.messageRepository.FindBySenderIdAndReceiverId(currentID, friendID)
+func (s *messageService) GetMessagesBySenderIdAndReceiverId(ctx context.Context, currentID uint, friendID uint) ([]MessageResponse, error) {
+	messages, err := s.messageRepository.FindBySenderIdAndReceiverId(ctx, currentID, friendID)
 	if err != nil {
 		return nil, err
 	}
 
-	var response []messageResponse
+	var response []MessageResponse
 	for _, message := range messages {
-		response = append(response, messageResponse{
+		response = append(response, MessageResponse{
 			ID:      message.ID,
 			IsSelf:  message.SenderID == currentID,
 			Message: message.Content,
@@ -98,6 +179,6 @@ func (s *messageService) GetMessagesBySenderIdAndReceiverId(currentID uint, frie
 	return response, nil
 }
 
-func (s *messageService) GetFriendListWithLastMessage(userID uint) ([]repositories.FriendsList, error) {
-	return s.messageRepository.GetFriendListWithLastMessage(userID)
+func (s *messageService) GetFriendListWithLastMessage(ctx context.Context, userID uint) ([]repositories.FriendsList, error) {
+	return s.messageRepository.GetFriendListWithLastMessage(ctx, userID)
 }
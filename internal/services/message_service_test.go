@@ -0,0 +1,147 @@
+package services
+
+import (
+	"chat-app-api/internal/config"
+	"chat-app-api/internal/models"
+	"chat-app-api/internal/repositories"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCreateMessage(t *testing.T) {
+	env := newTestEnv(t)
+	alice := createTestUser(t, env.users, "alice", "correct horse battery")
+	bob := createTestUser(t, env.users, "bob", "correct horse battery")
+
+	response, err := env.messageService.CreateMessage(context.Background(), &models.Message{SenderID: alice.ID, ReceiverID: bob.ID, Content: "hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.ID == 0 || response.Message != "hi" || response.ReceiverID != bob.ID || response.IsSelf {
+		t.Errorf("unexpected response %+v", response)
+	}
+	if response.Sender.ID != alice.ID || response.Sender.FirstName != alice.FirstName {
+		t.Errorf("sender is %+v, want alice", response.Sender)
+	}
+}
+
+func TestCreateMessageValidation(t *testing.T) {
+	env := newTestEnv(t)
+	alice := createTestUser(t, env.users, "alice", "correct horse battery")
+
+	tests := map[string]struct {
+		message models.Message
+		field   string
+	}{
+		"blank content":    {models.Message{SenderID: alice.ID, ReceiverID: alice.ID, Content: "  "}, "content"},
+		"unknown receiver": {models.Message{SenderID: alice.ID, ReceiverID: 4242, Content: "hi"}, "receiver_id"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := env.messageService.CreateMessage(context.Background(), &test.message)
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("got %v, want a ValidationError", err)
+			}
+			if len(validationErr.Fields) != 1 || validationErr.Fields[0].Field != test.field {
+				t.Errorf("got fields %+v, want %s", validationErr.Fields, test.field)
+			}
+		})
+	}
+}
+
+func TestGetMessagesMarksOwnMessages(t *testing.T) {
+	env := newTestEnv(t)
+	alice := createTestUser(t, env.users, "alice", "correct horse battery")
+	bob := createTestUser(t, env.users, "bob", "correct horse battery")
+	ctx := context.Background()
+
+	for _, message := range []models.Message{
+		{SenderID: alice.ID, ReceiverID: bob.ID, Content: "hi bob"},
+		{SenderID: bob.ID, ReceiverID: alice.ID, Content: "hi alice"},
+	} {
+		if _, err := env.messageService.CreateMessage(ctx, &message); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	messages, err := env.messageService.GetMessagesBySenderIdAndReceiverId(ctx, alice.ID, bob.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 2 {
+		t.Fatalf("got %d messages, want 2", len(messages))
+	}
+	for _, message := range messages {
+		if message.IsSelf != (message.Message == "hi bob") {
+			t.Errorf("message %q has is_self %v", message.Message, message.IsSelf)
+		}
+	}
+}
+
+func TestUpdateAndDeleteMissingMessage(t *testing.T) {
+	env := newTestEnv(t)
+
+	_, err := env.messageService.UpdateMessage(context.Background(), &models.Message{ID: 42, Content: "edited"})
+	assertErrorIs(t, err, ErrNotFound)
+	assertErrorIs(t, env.messageService.DeleteMessage(context.Background(), 42), ErrNotFound)
+}
+
+func TestFilteredMessages(t *testing.T) {
+	cfg := config.Default().Message
+	cfg.BlockedWords = []string{"darn"}
+	cfg.DeniedDomains = []string{"scam.example"}
+	cfg.DeniedDomainsAction = FilterFlag
+	filter, err := NewMessageFilterFromConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := newTestEnv(t, withMessageFilter(filter))
+	alice := createTestUser(t, env.users, "alice", "correct horse battery")
+	bob := createTestUser(t, env.users, "bob", "correct horse battery")
+	ctx := context.Background()
+
+	masked, err := env.messageService.CreateMessage(ctx, &models.Message{SenderID: alice.ID, ReceiverID: bob.ID, Content: "darn it"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if masked.Message != "**** it" {
+		t.Errorf("delivered %q, want the blocked word masked", masked.Message)
+	}
+
+	flagged, err := env.messageService.CreateMessage(ctx, &models.Message{SenderID: alice.ID, ReceiverID: bob.ID, Content: "free coins at https://scam.example/win"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reports, err := env.reports.ListReports(ctx, repositories.ReportFilter{Reason: models.ReportReasonFlagged})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 1 || reports[0].ReporterID != nil || reports[0].MessageID == nil || *reports[0].MessageID != flagged.ID ||
+		reports[0].ReportedUserID != alice.ID || !reports[0].IsOpen() {
+		t.Errorf("got reports %+v, want the flagged message in the queue", reports)
+	}
+
+	_, err = env.messageService.CreateMessage(ctx, &models.Message{SenderID: alice.ID, ReceiverID: bob.ID, Content: strings.Repeat("a", cfg.MaxLength+1)})
+	var rejected *MessageRejectedError
+	if !errors.As(err, &rejected) || rejected.Decision.Stage != "max_length" {
+		t.Fatalf("got %v, want the message rejected for its length", err)
+	}
+
+	// Every filtered message is audited, the rejected one as a failure
+	if want := []string{AuditMessageFiltered, AuditMessageFiltered, AuditMessageFiltered}; !slices.Equal(env.audit.actions(), want) {
+		t.Fatalf("recorded %v, want %v", env.audit.actions(), want)
+	}
+	for i, outcome := range []string{models.OutcomeSuccess, models.OutcomeSuccess, models.OutcomeFailure} {
+		event := env.audit.events[i]
+		if event.Outcome != outcome || event.ActorID == nil || *event.ActorID != alice.ID || *event.TargetID != bob.ID {
+			t.Errorf("event %d is %+v, want a %s by alice", i, event, outcome)
+		}
+	}
+	if _, ok := env.audit.events[2].Details["message_id"]; ok {
+		t.Errorf("rejected message recorded with an ID: %v", env.audit.events[2].Details)
+	}
+}
@@ -0,0 +1,38 @@
+package services
+
+import (
+	"chat-app-api/internal/models"
+	"log/slog"
+	"time"
+)
+
+// Notifier delivers out-of-band notifications to account owners
+type Notifier interface {
+	NotifyAccountLocked(user *models.User, until time.Time, ip string)
+	NotifyWarned(user *models.User, report *models.Report)
+	NotifyReportResolved(reporter *models.User, report *models.Report)
+}
+
+type logNotifier struct {
+	logger *slog.Logger
+}
+
+// NewLogNotifier returns a Notifier that writes notifications to the application log
+func NewLogNotifier(logger *slog.Logger) Notifier {
+	return &logNotifier{logger: logger}
+}
+
+func (n *logNotifier) NotifyAccountLocked(user *models.User, until time.Time, ip string) {
+	n.logger.Info("notify account locked after repeated failed logins",
+		"user_id", user.ID, "email", user.Email, "until", until.Format(time.RFC3339), "client_ip", ip)
+}
+
+func (n *logNotifier) NotifyWarned(user *models.User, report *models.Report) {
+	n.logger.Info("notify user warned by a moderator",
+		"user_id", user.ID, "email", user.Email, "report_id", report.ID, "reason", report.Reason)
+}
+
+func (n *logNotifier) NotifyReportResolved(reporter *models.User, report *models.Report) {
+	n.logger.Info("notify reporter that their report was resolved",
+		"user_id", reporter.ID, "email", reporter.Email, "report_id", report.ID, "status", report.Status)
+}
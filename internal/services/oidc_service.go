@@ -0,0 +1,266 @@
+package services
+
+import (
+	"chat-app-api/internal/config"
+	"chat-app-api/internal/models"
+	"chat-app-api/internal/repositories"
+	"chat-app-api/internal/utils"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+var (
+	ErrOIDCExchangeFailed  = errors.New("oidc code exchange failed")
+	ErrOIDCInvalidIDToken  = errors.New("oidc id token is invalid")
+	ErrOIDCIdentityMissing = errors.New("oidc provider did not return an email address")
+	ErrOIDCEmailConflict   = conflict("an account with this email already exists and the provider did not verify it")
+)
+
+// OIDCAuthRequest holds the values that must survive the round trip to the provider
+type OIDCAuthRequest struct {
+	URL          string
+	State        string
+	Nonce        string
+	CodeVerifier string
+}
+
+type OIDCService interface {
+	ProviderName() string
+	AuthCodeURL(ctx context.Context) (OIDCAuthRequest, error)
+	Exchange(ctx context.Context, code, codeVerifier, nonce string) (LoginResponse, error)
+}
+
+type oidcService struct {
+	config       config.OIDCConfig
+	userRepo     repositories.UserRepository
+	identityRepo repositories.IdentityRepository
+	audit        AuditLog
+
+	mu       sync.Mutex
+	oauth2   *oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+func NewOIDCService(cfg config.OIDCConfig, userRepo repositories.UserRepository, identityRepo repositories.IdentityRepository, audit AuditLog) OIDCService {
+	return &oidcService{config: cfg, userRepo: userRepo, identityRepo: identityRepo, audit: audit}
+}
+
+func (s *oidcService) ProviderName() string {
+	return s.config.ProviderName
+}
+
+// discover fetches the provider metadata on first use so the API can start while the provider is down
+func (s *oidcService) discover(ctx context.Context) (*oauth2.Config, *oidc.IDTokenVerifier, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.oauth2 != nil {
+		return s.oauth2, s.verifier, nil
+	}
+
+	provider, err := oidc.NewProvider(ctx, s.config.IssuerURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to discover oidc provider: %w", err)
+	}
+
+	s.oauth2 = &oauth2.Config{
+		ClientID:     s.config.ClientID,
+		ClientSecret: s.config.ClientSecret,
+		RedirectURL:  s.config.RedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       s.config.Scopes,
+	}
+	s.verifier = provider.Verifier(&oidc.Config{ClientID: s.config.ClientID})
+
+	return s.oauth2, s.verifier, nil
+}
+
+func (s *oidcService) AuthCodeURL(ctx context.Context) (OIDCAuthRequest, error) {
+	oauth2Config, _, err := s.discover(ctx)
+	if err != nil {
+		return OIDCAuthRequest{}, err
+	}
+
+	request := OIDCAuthRequest{
+		State:        randomToken(),
+		Nonce:        randomToken(),
+		CodeVerifier: oauth2.GenerateVerifier(),
+	}
+	request.URL = oauth2Config.AuthCodeURL(request.State,
+		oidc.Nonce(request.Nonce),
+		oauth2.S256ChallengeOption(request.CodeVerifier),
+	)
+
+	return request, nil
+}
+
+type oidcClaims struct {
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"email_verified"`
+	PreferredUsername string `json:"preferred_username"`
+	GivenName         string `json:"given_name"`
+	FamilyName        string `json:"family_name"`
+	Picture           string `json:"picture"`
+}
+
+// Exchange completes a single sign-on; every attempt is audited like a password login
+func (s *oidcService) Exchange(ctx context.Context, code, codeVerifier, nonce string) (LoginResponse, error) {
+	response, user, err := s.exchange(ctx, code, codeVerifier, nonce)
+
+	details := map[string]any{"method": "oidc", "provider": s.config.ProviderName}
+	var userID uint
+	if user != nil {
+		userID = user.ID
+	}
+	if err != nil {
+		details["reason"] = failureReason(err)
+		s.audit.Record(ctx, failed(AuditLogin, userID, details))
+		return LoginResponse{}, err
+	}
+
+	event := succeeded(AuditLogin, userID, details)
+	event.ActorID = &userID
+	s.audit.Record(ctx, event)
+	return response, nil
+}
+
+func (s *oidcService) exchange(ctx context.Context, code, codeVerifier, nonce string) (LoginResponse, *models.User, error) {
+	oauth2Config, verifier, err := s.discover(ctx)
+	if err != nil {
+		return LoginResponse{}, nil, err
+	}
+
+	token, err := oauth2Config.Exchange(ctx, code, oauth2.VerifierOption(codeVerifier))
+	if err != nil {
+		return LoginResponse{}, nil, fmt.Errorf("%w: %v", ErrOIDCExchangeFailed, err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return LoginResponse{}, nil, fmt.Errorf("%w: token response has no id_token", ErrOIDCInvalidIDToken)
+	}
+
+	idToken, err := verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return LoginResponse{}, nil, fmt.Errorf("%w: %v", ErrOIDCInvalidIDToken, err)
+	}
+	if idToken.Nonce != nonce {
+		return LoginResponse{}, nil, fmt.Errorf("%w: nonce mismatch", ErrOIDCInvalidIDToken)
+	}
+
+	var claims oidcClaims
+	if err := idToken.Claims(&claims); err != nil {
+		return LoginResponse{}, nil, fmt.Errorf("%w: %v", ErrOIDCInvalidIDToken, err)
+	}
+
+	user, err := s.resolveUser(ctx, idToken.Subject, claims)
+	if err != nil {
+		return LoginResponse{}, nil, err
+	}
+
+	response, err := newLoginResponse(user)
+	return response, user, err
+}
+
+// resolveUser finds the account linked to the external identity, linking or provisioning one if needed
+func (s *oidcService) resolveUser(ctx context.Context, subject string, claims oidcClaims) (*models.User, error) {
+	provider := s.config.ProviderName
+
+	identity, err := s.identityRepo.FindByProviderAndSubject(ctx, provider, subject)
+	if err == nil {
+		user, err := s.userRepo.FindByID(ctx, identity.UserID)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, forbidden("this account has been deleted")
+		}
+		return user, err
+	}
+	if !errors.Is(err, repositories.ErrNotFound) {
+		return nil, err
+	}
+
+	if claims.Email == "" {
+		return nil, ErrOIDCIdentityMissing
+	}
+
+	user, err := s.userRepo.FindByEmail(ctx, claims.Email)
+	if err == nil && !claims.EmailVerified {
+		return nil, ErrOIDCEmailConflict
+	}
+	if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+		return nil, err
+	}
+	if err != nil && s.userRepo.IsEmailExist(ctx, claims.Email) {
+		// Only a deleted account still holds the address
+		return nil, forbidden("this account has been deleted")
+	}
+	if err != nil {
+		user, err = s.provisionUser(ctx, claims)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	identity = &models.UserIdentity{
+		UserID:   user.ID,
+		Provider: provider,
+		Subject:  subject,
+		Email:    claims.Email,
+	}
+	if _, err := s.identityRepo.CreateIdentity(ctx, identity); err != nil {
+		return nil, fmt.Errorf("failed to link identity: %w", err)
+	}
+
+	return user, nil
+}
+
+var usernameCleaner = regexp.MustCompile(`[^a-zA-Z0-9_.]+`)
+
+// provisionUser creates a local account for a first-time SSO user
+func (s *oidcService) provisionUser(ctx context.Context, claims oidcClaims) (*models.User, error) {
+	base := claims.PreferredUsername
+	if base == "" || strings.Contains(base, "@") {
+		base = strings.SplitN(claims.Email, "@", 2)[0]
+	}
+	base = usernameCleaner.ReplaceAllString(base, "")
+	if base == "" {
+		base = "user"
+	}
+
+	username := base
+	for i := 1; s.userRepo.IsUsernameExist(ctx, username); i++ {
+		username = base + strconv.Itoa(i)
+	}
+
+	// SSO accounts get a random password nobody knows; they sign in through the provider
+	hashedPassword, err := utils.HashPassword(randomToken())
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	return s.userRepo.CreateUser(ctx, &models.User{
+		Username:        username,
+		FirstName:       claims.GivenName,
+		LastName:        claims.FamilyName,
+		Email:           claims.Email,
+		ProfileImageUrl: claims.Picture,
+		Password:        hashedPassword,
+	})
+}
+
+func randomToken() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,244 @@
+package services
+
+import (
+	"chat-app-api/internal/config"
+	"chat-app-api/internal/models"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testOIDCClientID = "chat-app"
+
+// fakeOIDCProvider serves discovery, its signing keys and a token endpoint that answers each
+// code with the ID token issued for it
+type fakeOIDCProvider struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu     sync.Mutex
+	tokens map[string]string // code -> signed ID token
+}
+
+func newFakeOIDCProvider(t *testing.T) *fakeOIDCProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &fakeOIDCProvider{key: key, tokens: map[string]string{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{
+			"issuer":                                p.server.URL,
+			"authorization_endpoint":                p.server.URL + "/authorize",
+			"token_endpoint":                        p.server.URL + "/token",
+			"jwks_uri":                              p.server.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test",
+			"alg": "RS256",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		p.mu.Lock()
+		idToken, ok := p.tokens[r.FormValue("code")]
+		p.mu.Unlock()
+		if !ok || r.FormValue("code_verifier") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			writeJSON(w, map[string]string{"error": "invalid_grant"})
+			return
+		}
+		writeJSON(w, map[string]any{"access_token": "access", "token_type": "Bearer", "expires_in": 3600, "id_token": idToken})
+	})
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+// issue makes the token endpoint answer code with an ID token for the subject
+func (p *fakeOIDCProvider) issue(t *testing.T, code, subject, nonce string, claims oidcClaims) {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":                p.server.URL,
+		"aud":                testOIDCClientID,
+		"sub":                subject,
+		"iat":                time.Now().Unix(),
+		"exp":                time.Now().Add(time.Hour).Unix(),
+		"nonce":              nonce,
+		"email":              claims.Email,
+		"email_verified":     claims.EmailVerified,
+		"preferred_username": claims.PreferredUsername,
+		"given_name":         claims.GivenName,
+		"family_name":        claims.FamilyName,
+	})
+	token.Header["kid"] = "test"
+	signed, err := token.SignedString(p.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.tokens[code] = signed
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// oidcTestEnv signs users in through a fake provider
+type oidcTestEnv struct {
+	testEnv
+	provider *fakeOIDCProvider
+}
+
+func newTestOIDCService(t *testing.T) oidcTestEnv {
+	t.Helper()
+	provider := newFakeOIDCProvider(t)
+	env := newTestEnv(t, withOIDC(config.OIDCConfig{
+		ProviderName: "acme",
+		IssuerURL:    provider.server.URL,
+		ClientID:     testOIDCClientID,
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost/api/auth/oidc/callback",
+		Scopes:       []string{"openid", "email"},
+	}))
+	return oidcTestEnv{testEnv: env, provider: provider}
+}
+
+// signIn issues an ID token for the subject and exchanges a code for it
+func (env oidcTestEnv) signIn(t *testing.T, subject string, claims oidcClaims) (LoginResponse, error) {
+	t.Helper()
+	code := "code-" + subject
+	env.provider.issue(t, code, subject, "nonce", claims)
+	return env.oidcService.Exchange(context.Background(), code, "verifier", "nonce")
+}
+
+func TestOIDCProvisionsUserOnFirstSignIn(t *testing.T) {
+	env := newTestOIDCService(t)
+	createTestUser(t, env.users, "alice", "correct horse battery")
+	claims := oidcClaims{Email: "alice.smith@example.org", EmailVerified: true, PreferredUsername: "alice", GivenName: "Alice", FamilyName: "Smith"}
+
+	response, err := env.signIn(t, "subject-1", claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The preferred username is taken by another account
+	if response.User.Username != "alice1" || response.User.FirstName != "Alice" || response.AccessToken == "" {
+		t.Errorf("got login %+v, want a new account named alice1", response.User)
+	}
+	identity, err := env.identities.FindByProviderAndSubject(context.Background(), "acme", "subject-1")
+	if err != nil || identity.UserID != response.User.ID {
+		t.Fatalf("got identity %+v (%v), want it linked to the new account", identity, err)
+	}
+
+	// The next sign-in finds the account through the identity
+	again, err := env.signIn(t, "subject-1", claims)
+	if err != nil || again.User.ID != response.User.ID {
+		t.Errorf("second sign-in got user %d (%v), want %d", again.User.ID, err, response.User.ID)
+	}
+	if env.users.IsUsernameExist(context.Background(), "alice2") {
+		t.Error("the second sign-in provisioned another account")
+	}
+	for _, event := range env.audit.events {
+		if event.Action != AuditLogin || event.Outcome != models.OutcomeSuccess || event.ActorID == nil || *event.ActorID != response.User.ID {
+			t.Errorf("recorded %+v, want a successful login", event)
+		}
+	}
+}
+
+func TestOIDCLinksUserWithVerifiedEmail(t *testing.T) {
+	env := newTestOIDCService(t)
+	alice := createTestUser(t, env.users, "alice", "correct horse battery")
+
+	response, err := env.signIn(t, "subject-1", oidcClaims{Email: alice.Email, EmailVerified: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.User.ID != alice.ID {
+		t.Errorf("signed in as %+v, want alice", response.User)
+	}
+	identity, err := env.identities.FindByProviderAndSubject(context.Background(), "acme", "subject-1")
+	if err != nil || identity.UserID != alice.ID || identity.Email != alice.Email {
+		t.Errorf("got identity %+v (%v), want it linked to alice", identity, err)
+	}
+}
+
+func TestOIDCExchangeFailures(t *testing.T) {
+	env := newTestOIDCService(t)
+	alice := createTestUser(t, env.users, "alice", "correct horse battery")
+	bob := createTestUser(t, env.users, "bob", "correct horse battery")
+	carol := createTestUser(t, env.users, "carol", "correct horse battery")
+	ctx := context.Background()
+
+	// Carol signed in through the provider before deleting the account; bob deleted theirs without
+	if _, err := env.signIn(t, "carol", oidcClaims{Email: carol.Email, EmailVerified: true}); err != nil {
+		t.Fatal(err)
+	}
+	for _, user := range []*models.User{bob, carol} {
+		if err := env.users.DeleteUser(ctx, user.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	env.provider.issue(t, "alice", "alice", "nonce", oidcClaims{Email: alice.Email})
+	env.provider.issue(t, "carol", "carol", "nonce", oidcClaims{Email: carol.Email, EmailVerified: true})
+	env.provider.issue(t, "bob", "bob", "nonce", oidcClaims{Email: bob.Email, EmailVerified: true})
+	env.provider.issue(t, "wrong-nonce", "dave", "another nonce", oidcClaims{Email: "dave@example.org", EmailVerified: true})
+
+	tests := map[string]struct {
+		code   string
+		want   error
+		reason string
+	}{
+		"unverified email of an account": {"alice", ErrOIDCEmailConflict, "conflict"},
+		"linked account deleted":         {"carol", ErrForbidden, "forbidden"},
+		"email of a deleted account":     {"bob", ErrForbidden, "forbidden"},
+		"nonce mismatch":                 {"wrong-nonce", ErrOIDCInvalidIDToken, "oidc_invalid_id_token"},
+		"unknown code":                   {"unknown", ErrOIDCExchangeFailed, "oidc_exchange_failed"},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			recorded := len(env.audit.events)
+			_, err := env.oidcService.Exchange(ctx, test.code, "verifier", "nonce")
+			assertErrorIs(t, err, test.want)
+
+			if len(env.audit.events) != recorded+1 {
+				t.Fatalf("recorded %d events, want 1", len(env.audit.events)-recorded)
+			}
+			event := env.audit.events[recorded]
+			if event.Action != AuditLogin || event.Outcome != models.OutcomeFailure || event.Details["reason"] != test.reason {
+				t.Errorf("recorded %+v, want a failed login for %s", event, test.reason)
+			}
+		})
+	}
+
+	// Nothing was linked or provisioned
+	if _, err := env.identities.FindByProviderAndSubject(ctx, "acme", "alice"); err == nil {
+		t.Error("the unverified email was linked to alice")
+	}
+	if env.users.IsEmailExist(ctx, "dave@example.org") {
+		t.Error("an account was provisioned despite the nonce mismatch")
+	}
+}
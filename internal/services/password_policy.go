@@ -0,0 +1,103 @@
+package services
+
+import (
+	"bufio"
+	"chat-app-api/internal/config"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+var ErrWeakPassword = errors.New("password does not meet the password policy")
+
+// PasswordPolicyError lists every rule a password broke
+type PasswordPolicyError struct {
+	Reasons []string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrWeakPassword, strings.Join(e.Reasons, "; "))
+}
+
+func (e *PasswordPolicyError) Is(target error) bool {
+	return target == ErrWeakPassword || target == ErrValidation
+}
+
+// FieldErrors reports every broken rule against the password field
+func (e *PasswordPolicyError) FieldErrors() []FieldError {
+	fields := make([]FieldError, 0, len(e.Reasons))
+	for _, reason := range e.Reasons {
+		fields = append(fields, FieldError{Field: "password", Message: reason})
+	}
+	return fields
+}
+
+// builtinCommonPasswords are rejected even when no blocklist file is configured
+var builtinCommonPasswords = []string{
+	"password", "password1", "password123", "12345678", "123456789", "1234567890",
+	"qwerty123", "qwertyuiop", "iloveyou", "11111111", "abc12345", "letmein1",
+	"welcome1", "admin123", "changeme", "passw0rd",
+}
+
+type PasswordPolicy struct {
+	config          config.PasswordConfig
+	commonPasswords map[string]struct{}
+}
+
+// NewPasswordPolicy builds the policy and loads the common password list
+func NewPasswordPolicy(cfg config.PasswordConfig) (*PasswordPolicy, error) {
+	policy := &PasswordPolicy{config: cfg, commonPasswords: make(map[string]struct{})}
+	for _, password := range builtinCommonPasswords {
+		policy.commonPasswords[password] = struct{}{}
+	}
+
+	if cfg.CommonPasswordsFile == "" {
+		return policy, nil
+	}
+
+	file, err := os.Open(cfg.CommonPasswordsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open password blocklist: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if password := strings.TrimSpace(scanner.Text()); password != "" {
+			policy.commonPasswords[strings.ToLower(password)] = struct{}{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read password blocklist: %w", err)
+	}
+
+	return policy, nil
+}
+
+// Validate checks the password against every rule and reports all violations at once
+func (p *PasswordPolicy) Validate(password, username, email string) error {
+	var reasons []string
+
+	length := utf8.RuneCountInString(password)
+	if length < p.config.MinLength {
+		reasons = append(reasons, fmt.Sprintf("must be at least %d characters", p.config.MinLength))
+	}
+	if p.config.MaxLength > 0 && length > p.config.MaxLength {
+		reasons = append(reasons, fmt.Sprintf("must be at most %d characters", p.config.MaxLength))
+	}
+
+	lower := strings.ToLower(password)
+	if _, common := p.commonPasswords[lower]; common {
+		reasons = append(reasons, "is too common")
+	}
+	if (username != "" && lower == strings.ToLower(username)) || (email != "" && lower == strings.ToLower(email)) {
+		reasons = append(reasons, "must not be the same as the username or email")
+	}
+
+	if len(reasons) > 0 {
+		return &PasswordPolicyError{Reasons: reasons}
+	}
+	return nil
+}
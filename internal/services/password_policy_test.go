@@ -0,0 +1,80 @@
+package services
+
+import (
+	"chat-app-api/internal/config"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPasswordPolicyRules(t *testing.T) {
+	blocklist := filepath.Join(t.TempDir(), "common.txt")
+	if err := os.WriteFile(blocklist, []byte("Tr0ub4dor&3\n\n  sunshine99  \nqwerty\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	policy, err := NewPasswordPolicy(config.PasswordConfig{MinLength: 8, MaxLength: 12, CommonPasswordsFile: blocklist})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		password string
+		want     []string
+	}{
+		"acceptable":            {"horse staple", nil},
+		"too short":             {"horse", []string{"must be at least 8 characters"}},
+		"counted in characters": {"ééééééééééé", nil},
+		"too long":              {"horse staples!", []string{"must be at most 12 characters"}},
+		"built-in common":       {"Password123", []string{"is too common"}},
+		"in the blocklist":      {"tr0ub4dor&3", []string{"is too common"}},
+		"trimmed in blocklist":  {"SUNSHINE99", []string{"is too common"}},
+		"same as username":      {"AliceSmith", []string{"must not be the same as the username or email"}},
+		"same as email":         {"A@Example.io", []string{"must not be the same as the username or email"}},
+		"several rules at once": {"QWERTY", []string{"must be at least 8 characters", "is too common"}},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := policy.Validate(test.password, "alicesmith", "a@example.io")
+			if test.want == nil {
+				if err != nil {
+					t.Fatalf("got %v, want the password accepted", err)
+				}
+				return
+			}
+
+			var policyErr *PasswordPolicyError
+			if !errors.As(err, &policyErr) || !errors.Is(err, ErrWeakPassword) || !errors.Is(err, ErrValidation) {
+				t.Fatalf("got %v, want a password policy error", err)
+			}
+			if !slices.Equal(policyErr.Reasons, test.want) {
+				t.Errorf("got reasons %q, want %q", policyErr.Reasons, test.want)
+			}
+			for _, field := range policyErr.FieldErrors() {
+				if field.Field != "password" {
+					t.Errorf("reported against %q, want password", field.Field)
+				}
+			}
+		})
+	}
+}
+
+func TestPasswordPolicyWithoutLimits(t *testing.T) {
+	policy, err := NewPasswordPolicy(config.PasswordConfig{MinLength: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// No maximum is configured, and empty usernames and emails match nothing
+	if err := policy.Validate(strings.Repeat("x", 1000), "", ""); err != nil {
+		t.Errorf("got %v, want a long password accepted", err)
+	}
+}
+
+func TestPasswordPolicyMissingBlocklist(t *testing.T) {
+	_, err := NewPasswordPolicy(config.PasswordConfig{CommonPasswordsFile: filepath.Join(t.TempDir(), "missing.txt")})
+	if err == nil || !strings.Contains(err.Error(), "password blocklist") {
+		t.Errorf("got %v, want the blocklist reported missing", err)
+	}
+}
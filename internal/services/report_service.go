@@ -0,0 +1,366 @@
+package services
+
+import (
+	"chat-app-api/internal/models"
+	"chat-app-api/internal/repositories"
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+// Types of the moderation events sent over the WebSocket
+const (
+	MessageRemovedEventType = "message_removed"
+	WarningEventType        = "warning"
+	ReportResolvedEventType = "report_resolved"
+)
+
+// MessageRemovedEvent tells both participants of a conversation that a moderator removed a message
+type MessageRemovedEvent struct {
+	Type      string `json:"type"`
+	MessageID uint   `json:"message_id"`
+}
+
+// WarningEvent tells a user that a moderator warned them about something they were reported for
+type WarningEvent struct {
+	Type     string `json:"type"`
+	ReportID uint   `json:"report_id"`
+	Reason   string `json:"reason"`
+	Note     string `json:"note,omitempty"`
+}
+
+// ReportResolvedEvent tells the reporter the outcome of their report, without the actions taken
+type ReportResolvedEvent struct {
+	Type     string `json:"type"`
+	ReportID uint   `json:"report_id"`
+	Status   string `json:"status"`
+}
+
+// EventSender delivers real-time events to every connection of a user
+type EventSender interface {
+	SendToUser(userID uint, v interface{}) bool
+}
+
+// ReportPage is one page of the moderation queue, oldest reports first
+type ReportPage struct {
+	Reports []models.Report `json:"reports"`
+	Total   int64           `json:"total"`
+	Limit   int             `json:"limit"`
+	Offset  int             `json:"offset"`
+}
+
+// ReportResolution is a moderator's decision on a report
+type ReportResolution struct {
+	Status       string   // actioned or dismissed
+	Actions      []string // at least one when actioned, none when dismissed
+	SuspendUntil time.Time
+	Note         string
+}
+
+// moderationActions are applied in this order, so a removed message is gone before its author is suspended
+var moderationActions = []string{models.ModerationRemoveMessage, models.ModerationWarn, models.ModerationSuspend}
+
+type ReportService interface {
+	CreateReport(ctx context.Context, report *models.Report) (*models.Report, error)
+	ListReports(ctx context.Context, filter repositories.ReportFilter) (ReportPage, error)
+	GetReport(ctx context.Context, id uint) (*models.Report, error)
+	ResolveReport(ctx context.Context, actorID, reportID uint, resolution ReportResolution) (*models.Report, error)
+}
+
+type reportService struct {
+	reportRepo  repositories.ReportRepository
+	userRepo    repositories.UserRepository
+	messageRepo repositories.MessageRepository
+	admin       AdminService
+	events      EventSender
+	notifier    Notifier
+	audit       AuditLog
+	logger      *slog.Logger
+}
+
+// NewReportService suspends through the admin service, so suspensions from reports revoke sessions and are audited alike
+func NewReportService(reportRepo repositories.ReportRepository, userRepo repositories.UserRepository, messageRepo repositories.MessageRepository,
+	admin AdminService, events EventSender, notifier Notifier, audit AuditLog, logger *slog.Logger) ReportService {
+	return &reportService{
+		reportRepo:  reportRepo,
+		userRepo:    userRepo,
+		messageRepo: messageRepo,
+		admin:       admin,
+		events:      events,
+		notifier:    notifier,
+		audit:       audit,
+		logger:      logger,
+	}
+}
+
+// CreateReport files an open report by report.ReporterID about a message they received or another account
+func (s *reportService) CreateReport(ctx context.Context, report *models.Report) (*models.Report, error) {
+	if report.ReporterID == nil {
+		return nil, errors.New("report has no reporter")
+	}
+	reporterID := *report.ReporterID
+	filter := repositories.ReportFilter{ReporterID: reporterID, Status: models.ReportStatusOpen}
+
+	switch report.TargetType {
+	case models.ReportTargetMessage:
+		if report.MessageID == nil {
+			return nil, NewValidationError(FieldError{Field: "message_id", Message: "is required"})
+		}
+		message, err := s.messageRepo.FindMessageByID(ctx, *report.MessageID)
+		if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+			return nil, err
+		}
+		// Messages of other conversations are as good as missing
+		if err != nil || (message.SenderID != reporterID && message.ReceiverID != reporterID) {
+			return nil, notFound("message not found")
+		}
+		if message.SenderID == reporterID {
+			return nil, NewValidationError(FieldError{Field: "message_id", Message: "you cannot report your own message"})
+		}
+		if message.SenderID == 0 {
+			return nil, NewValidationError(FieldError{Field: "message_id", Message: "the author's account has been deleted"})
+		}
+		report.ReportedUserID = message.SenderID
+		report.MessageContent = message.Content
+		filter.MessageID = message.ID
+
+	case models.ReportTargetUser:
+		if report.ReportedUserID == 0 {
+			return nil, NewValidationError(FieldError{Field: "user_id", Message: "is required"})
+		}
+		if report.ReportedUserID == reporterID {
+			return nil, NewValidationError(FieldError{Field: "user_id", Message: "you cannot report yourself"})
+		}
+		if _, err := s.userRepo.FindByID(ctx, report.ReportedUserID); err != nil {
+			return nil, repositoryError(err, "user")
+		}
+		report.MessageID = nil
+		filter.TargetType = models.ReportTargetUser
+		filter.ReportedUserID = report.ReportedUserID
+
+	default:
+		return nil, NewValidationError(FieldError{Field: "target_type", Message: "must be one of message, user"})
+	}
+
+	// Reporting the same thing twice does not move it up the queue
+	open, err := s.reportRepo.CountReports(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if open > 0 {
+		return nil, conflict("you have already reported this " + report.TargetType)
+	}
+
+	report.Status = models.ReportStatusOpen
+	if err := s.reportRepo.CreateReport(ctx, report); errors.Is(err, repositories.ErrMissingReference) {
+		return nil, notFound(report.TargetType + " not found")
+	} else if err != nil {
+		return nil, repositoryError(err, "report")
+	}
+
+	s.audit.Record(ctx, succeeded(AuditReportCreation, report.ReportedUserID, map[string]any{
+		"report_id": report.ID, "target_type": report.TargetType, "reason": report.Reason,
+	}))
+	return report, nil
+}
+
+func (s *reportService) ListReports(ctx context.Context, filter repositories.ReportFilter) (ReportPage, error) {
+	reports, err := s.reportRepo.ListReports(ctx, filter)
+	if err != nil {
+		return ReportPage{}, err
+	}
+	total, err := s.reportRepo.CountReports(ctx, filter)
+	if err != nil {
+		return ReportPage{}, err
+	}
+
+	if reports == nil {
+		reports = []models.Report{}
+	}
+	return ReportPage{Reports: reports, Total: total, Limit: filter.Limit, Offset: filter.Offset}, nil
+}
+
+func (s *reportService) GetReport(ctx context.Context, id uint) (*models.Report, error) {
+	report, err := s.reportRepo.FindReportByID(ctx, id)
+	if err != nil {
+		return nil, repositoryError(err, "report")
+	}
+	return report, nil
+}
+
+// ResolveReport closes the report, takes the moderation actions and then tells the people concerned.
+// The report is claimed before anything is changed, so of two moderators resolving it at once only
+// one acts. The actions are not one transaction. A message removal takes full effect, with its audit
+// and events, before the suspension runs; when a later action fails the report is reopened with the
+// message still removed. Resolving it again completes it, since a removed message is skipped.
+// Warnings, the resolution audit and the reporter's notification wait until every action succeeded,
+// so they are never repeated.
+func (s *reportService) ResolveReport(ctx context.Context, actorID, reportID uint, resolution ReportResolution) (*models.Report, error) {
+	report, err := s.GetReport(ctx, reportID)
+	if err != nil {
+		return nil, err
+	}
+	if !report.IsOpen() {
+		return nil, conflict("report has already been resolved")
+	}
+	if err := validateResolution(report, resolution); err != nil {
+		return nil, err
+	}
+	if slices.Contains(resolution.Actions, models.ModerationSuspend) && report.ReportedUserID == actorID {
+		return nil, forbidden("you cannot suspend your own account")
+	}
+
+	var actions []string
+	for _, action := range moderationActions {
+		if slices.Contains(resolution.Actions, action) {
+			actions = append(actions, action)
+		}
+	}
+
+	now := time.Now()
+	report.Status = resolution.Status
+	report.Actions = actions
+	report.ModeratorNote = resolution.Note
+	report.ResolvedByID = &actorID
+	report.ResolvedAt = &now
+	if err := s.reportRepo.ResolveReport(ctx, report); errors.Is(err, repositories.ErrNotFound) {
+		return nil, conflict("report has already been resolved")
+	} else if err != nil {
+		return nil, err
+	}
+
+	var announcements []func()
+	for _, action := range actions {
+		announce, err := s.takeAction(ctx, actorID, report, action, resolution)
+		if err != nil {
+			if reopenErr := s.reportRepo.ReopenReport(ctx, report.ID); reopenErr != nil {
+				s.logger.ErrorContext(ctx, "could not reopen report after a failed action", "report_id", report.ID, "error", reopenErr)
+			}
+			return nil, err
+		}
+		if announce != nil {
+			announcements = append(announcements, announce)
+		}
+	}
+	for _, announce := range announcements {
+		announce()
+	}
+
+	s.logger.InfoContext(ctx, "report resolved", "actor_id", actorID, "report_id", report.ID, "status", report.Status, "actions", actions)
+	s.record(ctx, actorID, succeeded(AuditReportResolution, report.ReportedUserID, map[string]any{
+		"report_id": report.ID, "status": report.Status, "actions": actions,
+	}))
+	s.notifyReporter(ctx, report)
+	return s.GetReport(ctx, report.ID)
+}
+
+func validateResolution(report *models.Report, resolution ReportResolution) error {
+	var fields []FieldError
+	switch resolution.Status {
+	case models.ReportStatusActioned:
+		if len(resolution.Actions) == 0 {
+			fields = append(fields, FieldError{Field: "actions", Message: "must name at least one action"})
+		}
+	case models.ReportStatusDismissed:
+		if len(resolution.Actions) > 0 {
+			fields = append(fields, FieldError{Field: "actions", Message: "must be empty when the report is dismissed"})
+		}
+	default:
+		fields = append(fields, FieldError{Field: "status", Message: "must be one of actioned, dismissed"})
+	}
+
+	for _, action := range resolution.Actions {
+		switch {
+		case !slices.Contains(moderationActions, action):
+			fields = append(fields, FieldError{Field: "actions", Message: "must be one of remove_message, warn, suspend"})
+		case action == models.ModerationRemoveMessage && report.TargetType != models.ReportTargetMessage:
+			fields = append(fields, FieldError{Field: "actions", Message: "remove_message only applies to reported messages"})
+		case action == models.ModerationSuspend && !resolution.SuspendUntil.After(time.Now()):
+			fields = append(fields, FieldError{Field: "suspend_until", Message: "must be in the future"})
+		case (action == models.ModerationWarn || action == models.ModerationSuspend) && report.ReportedUserID == 0:
+			fields = append(fields, FieldError{Field: "actions", Message: action + " does not apply once the account has been purged"})
+		}
+	}
+
+	if len(fields) > 0 {
+		return NewValidationError(fields...)
+	}
+	return nil
+}
+
+// takeAction changes what the action calls for and returns how to announce it once every action succeeded
+func (s *reportService) takeAction(ctx context.Context, actorID uint, report *models.Report, action string, resolution ReportResolution) (func(), error) {
+	switch action {
+	case models.ModerationRemoveMessage:
+		return s.removeMessage(ctx, actorID, report)
+	case models.ModerationWarn:
+		return s.warn(ctx, actorID, report, resolution.Note)
+	case models.ModerationSuspend:
+		_, err := s.admin.SuspendUser(ctx, actorID, report.ReportedUserID, resolution.SuspendUntil)
+		return nil, err
+	}
+	return nil, nil
+}
+
+// removeMessage deletes the reported message for everyone and tells both participants straight away,
+// since the removal stands even when a later action fails. The message may already be gone after an
+// earlier report or attempt.
+func (s *reportService) removeMessage(ctx context.Context, actorID uint, report *models.Report) (func(), error) {
+	if report.MessageID == nil {
+		return nil, nil
+	}
+	message, err := s.messageRepo.FindMessageByID(ctx, *report.MessageID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.messageRepo.DeleteMessage(ctx, message.ID); err != nil && !errors.Is(err, repositories.ErrNotFound) {
+		return nil, err
+	}
+	s.record(ctx, actorID, succeeded(AuditMessageRemoval, message.SenderID, map[string]any{"report_id": report.ID, "message_id": message.ID}))
+
+	event := MessageRemovedEvent{Type: MessageRemovedEventType, MessageID: message.ID}
+	s.events.SendToUser(message.SenderID, event)
+	s.events.SendToUser(message.ReceiverID, event)
+	return nil, nil
+}
+
+// warn only looks the user up; the warning is given when the resolution is announced
+func (s *reportService) warn(ctx context.Context, actorID uint, report *models.Report, note string) (func(), error) {
+	user, err := s.userRepo.FindByID(ctx, report.ReportedUserID)
+	if err != nil {
+		return nil, repositoryError(err, "user")
+	}
+
+	return func() {
+		s.notifier.NotifyWarned(user, report)
+		s.events.SendToUser(user.ID, WarningEvent{Type: WarningEventType, ReportID: report.ID, Reason: report.Reason, Note: note})
+		s.record(ctx, actorID, succeeded(AuditWarning, user.ID, map[string]any{"report_id": report.ID}))
+	}, nil
+}
+
+// notifyReporter tells the reporter, if they still have an account, that their report was handled.
+// Flagged messages have no reporter to tell.
+func (s *reportService) notifyReporter(ctx context.Context, report *models.Report) {
+	if report.ReporterID == nil {
+		return
+	}
+	reporter, err := s.userRepo.FindByID(ctx, *report.ReporterID)
+	if err != nil {
+		s.logger.WarnContext(ctx, "not notifying the reporter", "report_id", report.ID, "error", err)
+		return
+	}
+
+	s.notifier.NotifyReportResolved(reporter, report)
+	s.events.SendToUser(reporter.ID, ReportResolvedEvent{Type: ReportResolvedEventType, ReportID: report.ID, Status: report.Status})
+}
+
+// record audits an action taken by the given moderator
+func (s *reportService) record(ctx context.Context, actorID uint, event models.AuditEvent) {
+	event.ActorID = &actorID
+	s.audit.Record(ctx, event)
+}
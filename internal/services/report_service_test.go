@@ -0,0 +1,336 @@
+package services
+
+import (
+	"chat-app-api/internal/models"
+	"chat-app-api/internal/repositories"
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func (env testEnv) sendMessage(t *testing.T, from, to *models.User, content string) *models.Message {
+	t.Helper()
+	message, err := env.messages.CreateMessage(context.Background(), &models.Message{SenderID: from.ID, ReceiverID: to.ID, Content: content})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return message
+}
+
+func (env testEnv) reportMessage(t *testing.T, reporter *models.User, message *models.Message) *models.Report {
+	t.Helper()
+	report, err := env.reportService.CreateReport(context.Background(), &models.Report{
+		ReporterID: &reporter.ID, TargetType: models.ReportTargetMessage, MessageID: &message.ID, Reason: models.ReportReasonHarassment,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return report
+}
+
+func TestCreateReport(t *testing.T) {
+	env := newTestEnv(t)
+	alice := createTestUser(t, env.users, "alice", "correct horse battery")
+	bob := createTestUser(t, env.users, "bob", "correct horse battery")
+	carol := createTestUser(t, env.users, "carol", "correct horse battery")
+	message := env.sendMessage(t, alice, bob, "you are awful")
+	ctx := context.Background()
+
+	report := env.reportMessage(t, bob, message)
+	if report.ReportedUserID != alice.ID || report.MessageContent != "you are awful" || !report.IsOpen() {
+		t.Errorf("got report %+v, want an open report about alice with the message content", report)
+	}
+	if event := env.audit.events[0]; event.Action != AuditReportCreation || *event.TargetID != alice.ID {
+		t.Errorf("recorded %+v, want the report about alice", event)
+	}
+
+	reportMessage := func(reporter *models.User) error {
+		_, err := env.reportService.CreateReport(ctx, &models.Report{
+			ReporterID: &reporter.ID, TargetType: models.ReportTargetMessage, MessageID: &message.ID, Reason: models.ReportReasonSpam,
+		})
+		return err
+	}
+	assertErrorIs(t, reportMessage(bob), ErrConflict)
+	assertErrorIs(t, reportMessage(alice), ErrValidation)
+	// Only the participants know the message exists
+	assertErrorIs(t, reportMessage(carol), ErrNotFound)
+
+	reportUser := func(reporter *models.User, userID uint) error {
+		_, err := env.reportService.CreateReport(ctx, &models.Report{
+			ReporterID: &reporter.ID, TargetType: models.ReportTargetUser, ReportedUserID: userID, Reason: models.ReportReasonImpersonation,
+		})
+		return err
+	}
+	if err := reportUser(carol, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	assertErrorIs(t, reportUser(carol, alice.ID), ErrConflict)
+	assertErrorIs(t, reportUser(carol, carol.ID), ErrValidation)
+	assertErrorIs(t, reportUser(carol, 4242), ErrNotFound)
+}
+
+func TestResolveReportTakesActionsAndNotifiesTheReporter(t *testing.T) {
+	env := newTestEnv(t)
+	admin := createTestUser(t, env.users, "admin", "correct horse battery")
+	alice := createTestUser(t, env.users, "alice", "correct horse battery")
+	bob := createTestUser(t, env.users, "bob", "correct horse battery")
+	message := env.sendMessage(t, alice, bob, "you are awful")
+	report := env.reportMessage(t, bob, message)
+	ctx := context.Background()
+
+	resolution := ReportResolution{
+		Status:       models.ReportStatusActioned,
+		Actions:      []string{models.ModerationSuspend, models.ModerationWarn, models.ModerationRemoveMessage},
+		SuspendUntil: time.Now().Add(24 * time.Hour),
+		Note:         "keep it civil",
+	}
+	resolved, err := env.reportService.ResolveReport(ctx, admin.ID, report.ID, resolution)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolved.Status != models.ReportStatusActioned || !slices.Equal(resolved.Actions, []string{"remove_message", "warn", "suspend"}) ||
+		resolved.MessageID != nil || resolved.ResolvedByID == nil || *resolved.ResolvedByID != admin.ID || resolved.ModeratorNote != "keep it civil" {
+		t.Errorf("got report %+v", resolved)
+	}
+
+	_, err = env.messages.FindMessageByID(ctx, message.ID)
+	if !errors.Is(err, repositories.ErrNotFound) {
+		t.Errorf("reported message still exists (%v)", err)
+	}
+	removed := MessageRemovedEvent{Type: MessageRemovedEventType, MessageID: message.ID}
+	warning := WarningEvent{Type: WarningEventType, ReportID: report.ID, Reason: models.ReportReasonHarassment, Note: "keep it civil"}
+	if got := env.events.sent[alice.ID]; len(got) != 2 || got[0] != removed || got[1] != warning {
+		t.Errorf("alice got events %+v, want the removal and the warning", got)
+	}
+	if got := env.events.sent[bob.ID]; len(got) != 2 || got[0] != removed ||
+		got[1] != (ReportResolvedEvent{Type: ReportResolvedEventType, ReportID: report.ID, Status: models.ReportStatusActioned}) {
+		t.Errorf("bob got events %+v, want the removal and the resolution", got)
+	}
+	if !slices.Equal(env.notifier.warned, []uint{alice.ID}) || !slices.Equal(env.notifier.resolved, []uint{report.ID}) {
+		t.Errorf("notified warnings %v and resolutions %v", env.notifier.warned, env.notifier.resolved)
+	}
+
+	user, err := env.users.FindByID(ctx, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !user.IsSuspended(time.Now()) {
+		t.Error("the author was not suspended")
+	}
+
+	// The warning is recorded when it is given, once every action succeeded
+	want := []string{AuditReportCreation, AuditMessageRemoval, AuditSuspension, AuditWarning, AuditReportResolution}
+	if !slices.Equal(env.audit.actions(), want) {
+		t.Errorf("recorded %v, want %v", env.audit.actions(), want)
+	}
+	for _, event := range env.audit.events[1:] {
+		if event.ActorID == nil || *event.ActorID != admin.ID {
+			t.Errorf("%s was not attributed to the moderator: %+v", event.Action, event)
+		}
+	}
+
+	_, err = env.reportService.ResolveReport(ctx, admin.ID, report.ID, resolution)
+	assertErrorIs(t, err, ErrConflict)
+	if len(env.notifier.warned) != 1 || len(env.notifier.resolved) != 1 || len(env.events.sent[alice.ID]) != 2 || len(env.audit.events) != len(want) {
+		t.Errorf("resolving again repeated the warnings %v, notifications %v or events %v", env.notifier.warned, env.notifier.resolved, env.events.sent)
+	}
+}
+
+func TestResolveReportConcurrently(t *testing.T) {
+	env := newTestEnv(t)
+	admin := createTestUser(t, env.users, "admin", "correct horse battery")
+	moderator := createTestUser(t, env.users, "moderator", "correct horse battery")
+	alice := createTestUser(t, env.users, "alice", "correct horse battery")
+	bob := createTestUser(t, env.users, "bob", "correct horse battery")
+	report := env.reportMessage(t, bob, env.sendMessage(t, alice, bob, "you are awful"))
+
+	resolution := ReportResolution{Status: models.ReportStatusActioned, Actions: []string{models.ModerationWarn}, Note: "keep it civil"}
+	errs := make(chan error, 2)
+	for _, actor := range []*models.User{admin, moderator} {
+		go func() {
+			_, err := env.reportService.ResolveReport(context.Background(), actor.ID, report.ID, resolution)
+			errs <- err
+		}()
+	}
+
+	var conflicts int
+	for range 2 {
+		if err := <-errs; errors.Is(err, ErrConflict) {
+			conflicts++
+		} else if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if conflicts != 1 {
+		t.Errorf("%d of the moderators were refused, want 1", conflicts)
+	}
+	if !slices.Equal(env.notifier.warned, []uint{alice.ID}) || len(env.events.sent[alice.ID]) != 1 || len(env.notifier.resolved) != 1 {
+		t.Errorf("alice was warned %v with events %v and %d reporters notified, want everything once",
+			env.notifier.warned, env.events.sent[alice.ID], len(env.notifier.resolved))
+	}
+}
+
+// failingSuspensions is an admin service whose suspensions fail
+type failingSuspensions struct {
+	AdminService
+}
+
+func (failingSuspensions) SuspendUser(ctx context.Context, actorID, userID uint, until time.Time) (*models.User, error) {
+	return nil, errors.New("database is down")
+}
+
+func TestResolveReportReopensAfterAFailedAction(t *testing.T) {
+	env := newTestEnv(t)
+	admin := createTestUser(t, env.users, "admin", "correct horse battery")
+	alice := createTestUser(t, env.users, "alice", "correct horse battery")
+	bob := createTestUser(t, env.users, "bob", "correct horse battery")
+	report := env.reportMessage(t, bob, env.sendMessage(t, alice, bob, "you are awful"))
+	ctx := context.Background()
+
+	failing := NewReportService(env.reports, env.users, env.messages, failingSuspensions{env.adminService}, env.events, env.notifier, env.audit, discardLogger)
+	resolution := ReportResolution{
+		Status:       models.ReportStatusActioned,
+		Actions:      []string{models.ModerationWarn, models.ModerationSuspend},
+		SuspendUntil: time.Now().Add(time.Hour),
+	}
+	if _, err := failing.ResolveReport(ctx, admin.ID, report.ID, resolution); err == nil {
+		t.Fatal("the resolution succeeded without the suspension")
+	}
+	if len(env.notifier.warned) != 0 || len(env.notifier.resolved) != 0 || len(env.events.sent) != 0 {
+		t.Errorf("warned %v, notified %v and sent %v for a resolution that failed", env.notifier.warned, env.notifier.resolved, env.events.sent)
+	}
+
+	// The report is back in the queue, and resolving it again warns alice once
+	reopened, err := env.reportService.GetReport(ctx, report.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reopened.IsOpen() || len(reopened.Actions) != 0 || reopened.ResolvedByID != nil {
+		t.Errorf("got report %+v, want it open again", reopened)
+	}
+	if _, err := env.reportService.ResolveReport(ctx, admin.ID, report.ID, resolution); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(env.notifier.warned, []uint{alice.ID}) || len(env.events.sent[alice.ID]) != 1 {
+		t.Errorf("alice was warned %v with events %v, want once", env.notifier.warned, env.events.sent[alice.ID])
+	}
+}
+
+func TestResolveReportKeepsARemovalAfterAFailedAction(t *testing.T) {
+	env := newTestEnv(t)
+	admin := createTestUser(t, env.users, "admin", "correct horse battery")
+	alice := createTestUser(t, env.users, "alice", "correct horse battery")
+	bob := createTestUser(t, env.users, "bob", "correct horse battery")
+	message := env.sendMessage(t, alice, bob, "you are awful")
+	report := env.reportMessage(t, bob, message)
+	ctx := context.Background()
+
+	failing := NewReportService(env.reports, env.users, env.messages, failingSuspensions{env.adminService}, env.events, env.notifier, env.audit, discardLogger)
+	resolution := ReportResolution{
+		Status:       models.ReportStatusActioned,
+		Actions:      []string{models.ModerationRemoveMessage, models.ModerationSuspend},
+		SuspendUntil: time.Now().Add(time.Hour),
+	}
+	if _, err := failing.ResolveReport(ctx, admin.ID, report.ID, resolution); err == nil {
+		t.Fatal("the resolution succeeded without the suspension")
+	}
+
+	// The removal stands, audited and announced, while the report is open again
+	if _, err := env.messages.FindMessageByID(ctx, message.ID); !errors.Is(err, repositories.ErrNotFound) {
+		t.Errorf("the reported message was kept (%v)", err)
+	}
+	removed := MessageRemovedEvent{Type: MessageRemovedEventType, MessageID: message.ID}
+	if got := env.events.sent[alice.ID]; len(got) != 1 || got[0] != removed {
+		t.Errorf("alice got events %+v, want the removal", got)
+	}
+	if reopened, err := env.reportService.GetReport(ctx, report.ID); err != nil || !reopened.IsOpen() {
+		t.Errorf("got report %+v (%v), want it open again", reopened, err)
+	}
+
+	// Resolving it again suspends alice without removing or announcing the message twice
+	if _, err := env.reportService.ResolveReport(ctx, admin.ID, report.ID, resolution); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{AuditReportCreation, AuditMessageRemoval, AuditSuspension, AuditReportResolution}
+	if !slices.Equal(env.audit.actions(), want) {
+		t.Errorf("recorded %v, want %v", env.audit.actions(), want)
+	}
+	if got := env.events.sent[alice.ID]; len(got) != 1 {
+		t.Errorf("alice got events %+v, want the removal once", got)
+	}
+}
+
+func TestDismissReport(t *testing.T) {
+	env := newTestEnv(t)
+	admin := createTestUser(t, env.users, "admin", "correct horse battery")
+	alice := createTestUser(t, env.users, "alice", "correct horse battery")
+	bob := createTestUser(t, env.users, "bob", "correct horse battery")
+	message := env.sendMessage(t, alice, bob, "see you tomorrow")
+	report := env.reportMessage(t, bob, message)
+
+	resolved, err := env.reportService.ResolveReport(context.Background(), admin.ID, report.ID, ReportResolution{Status: models.ReportStatusDismissed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolved.Status != models.ReportStatusDismissed || len(resolved.Actions) != 0 || resolved.MessageID == nil {
+		t.Errorf("got report %+v, want it dismissed with the message kept", resolved)
+	}
+	if len(env.events.sent[alice.ID]) != 0 || len(env.notifier.resolved) != 1 {
+		t.Errorf("alice got %v and %d reporters were notified, want nothing for alice and bob notified",
+			env.events.sent[alice.ID], len(env.notifier.resolved))
+	}
+
+	page, err := env.reportService.ListReports(context.Background(), repositories.ReportFilter{Status: models.ReportStatusOpen})
+	if err != nil || page.Total != 0 || len(page.Reports) != 0 {
+		t.Errorf("got queue %+v (%v), want it empty", page, err)
+	}
+}
+
+func TestResolveReportValidation(t *testing.T) {
+	env := newTestEnv(t)
+	admin := createTestUser(t, env.users, "admin", "correct horse battery")
+	alice := createTestUser(t, env.users, "alice", "correct horse battery")
+	ctx := context.Background()
+
+	aboutAlice, err := env.reportService.CreateReport(ctx, &models.Report{
+		ReporterID: &admin.ID, TargetType: models.ReportTargetUser, ReportedUserID: alice.ID, Reason: models.ReportReasonSpam,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	aboutAdmin := env.reportMessage(t, alice, env.sendMessage(t, admin, alice, "hello"))
+
+	tests := map[string]struct {
+		reportID   uint
+		resolution ReportResolution
+		want       error
+	}{
+		"unknown status":        {aboutAlice.ID, ReportResolution{Status: models.ReportStatusOpen}, ErrValidation},
+		"actioned without any":  {aboutAlice.ID, ReportResolution{Status: models.ReportStatusActioned}, ErrValidation},
+		"dismissed with action": {aboutAlice.ID, ReportResolution{Status: models.ReportStatusDismissed, Actions: []string{models.ModerationWarn}}, ErrValidation},
+		"unknown action":        {aboutAlice.ID, ReportResolution{Status: models.ReportStatusActioned, Actions: []string{"ban"}}, ErrValidation},
+		"remove a user":         {aboutAlice.ID, ReportResolution{Status: models.ReportStatusActioned, Actions: []string{models.ModerationRemoveMessage}}, ErrValidation},
+		"suspend in the past": {aboutAlice.ID, ReportResolution{Status: models.ReportStatusActioned, Actions: []string{models.ModerationSuspend},
+			SuspendUntil: time.Now().Add(-time.Hour)}, ErrValidation},
+		"suspend yourself": {aboutAdmin.ID, ReportResolution{Status: models.ReportStatusActioned, Actions: []string{models.ModerationSuspend},
+			SuspendUntil: time.Now().Add(time.Hour)}, ErrForbidden},
+		"unknown report": {4242, ReportResolution{Status: models.ReportStatusDismissed}, ErrNotFound},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := env.reportService.ResolveReport(ctx, admin.ID, test.reportID, test.resolution)
+			assertErrorIs(t, err, test.want)
+		})
+	}
+
+	// Nothing happened while the resolutions were rejected
+	page, err := env.reportService.ListReports(ctx, repositories.ReportFilter{Status: models.ReportStatusOpen})
+	if err != nil || page.Total != 2 {
+		t.Errorf("got %d open reports (%v), want 2", page.Total, err)
+	}
+	if len(env.events.sent) != 0 {
+		t.Errorf("sent events %v", env.events.sent)
+	}
+}
@@ -4,9 +4,44 @@ import (
 	"chat-app-api/internal/models"
 	"chat-app-api/internal/repositories"
 	"chat-app-api/internal/utils"
+	"context"
 	"fmt"
+	"time"
 )
 
+// UserProfile is what any logged in user may see of an account. The role, moderation state and
+// email address are left out; admins see those through the full model on the admin routes. The
+// field names stay those of the model, which the signup and update requests use as well.
+type UserProfile struct {
+	ID              uint   `json:"id"`
+	Username        string `json:"username"`
+	FirstName       string `json:"first_name"`
+	LastName        string `json:"last_name"`
+	ProfileImageUrl string `json:"profile_image"`
+}
+
+func NewUserProfile(user *models.User) UserProfile {
+	return UserProfile{
+		ID:              user.ID,
+		Username:        user.Username,
+		FirstName:       user.FirstName,
+		LastName:        user.LastName,
+		ProfileImageUrl: user.ProfileImageUrl,
+	}
+}
+
+// Account is what the owner of an account sees of it after signing up or updating it
+type Account struct {
+	UserProfile
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func NewAccount(user *models.User) *Account {
+	return &Account{UserProfile: NewUserProfile(user), Email: user.Email, CreatedAt: user.CreatedAt, UpdatedAt: user.UpdatedAt}
+}
+
 type SearchResponse struct {
 	Profile struct {
 		ID              uint   `json:"id"`
@@ -19,29 +54,35 @@ type SearchResponse struct {
 }
 
 type UserService interface {
-	CreateUser(user *models.User) (*models.User, error)
-	GetUserByID(id uint) (*models.User, error)
-	GetAllUsers() ([]models.User, error)
-	UpdateUser(user *models.User) (*models.User, error)
-	DeleteUser(id uint) error
-	SearchUser(currentUsername string, searchContent string) ([]SearchResponse, error)
+	CreateUser(ctx context.Context, user *models.User) (*Account, error)
+	GetUserByID(ctx context.Context, id uint) (*UserProfile, error)
+	UpdateUser(ctx context.Context, actorID uint, user *models.User) (*Account, error)
+	DeleteUser(ctx context.Context, actorID uint, id uint) error
+	SearchUser(ctx context.Context, currentUsername string, searchContent string) ([]SearchResponse, error)
 }
 
 type userService struct {
 	userRepository repositories.UserRepository
+	passwordPolicy *PasswordPolicy
+	connections    ConnectionRegistry
+	audit          AuditLog
 }
 
-func NewUserService(repo repositories.UserRepository) UserService {
-	return &userService{userRepository: repo}
+func NewUserService(repo repositories.UserRepository, passwordPolicy *PasswordPolicy, connections ConnectionRegistry, audit AuditLog) UserService {
+	return &userService{userRepository: repo, passwordPolicy: passwordPolicy, connections: connections, audit: audit}
 }
 
-func (s *userService) CreateUser(user *models.User) (*models.User, error) {
-	if s.userRepository.IsEmailExist(user.Email) {
-		return nil, fmt.Errorf("email already exists")
+func (s *userService) CreateUser(ctx context.Context, user *models.User) (*Account, error) {
+	if s.userRepository.IsEmailExist(ctx, user.Email) {
+		return nil, conflict("email already exists")
+	}
+
+	if s.userRepository.IsUsernameExist(ctx, user.Username) {
+		return nil, conflict("username already exists")
 	}
 
-	if s.userRepository.IsUsernameExist(user.Username) {
-		return nil, fmt.Errorf("username already exists")
+	if err := s.passwordPolicy.Validate(user.Password, user.Username, user.Email); err != nil {
+		return nil, err
 	}
 
 	hashedPassword, err := utils.HashPassword(user.Password)
@@ -50,27 +91,81 @@ func (s *userService) CreateUser(user *models.User) (*models.User, error) {
 	}
 
 	user.Password = hashedPassword
-	return s.userRepository.CreateUser(user)
+	createdUser, err := s.userRepository.CreateUser(ctx, user)
+	if err != nil {
+		return nil, repositoryError(err, "user")
+	}
+	return NewAccount(createdUser), nil
 }
 
-func (s *userService) GetUserByID(id uint) (*models.User, error) {
-	return s.userRepository.FindByID(id)
+func (s *userService) GetUserByID(ctx context.Context, id uint) (*UserProfile, error) {
+	user, err := s.userRepository.FindByID(ctx, id)
+	if err != nil {
+		return nil, repositoryError(err, "user")
+	}
+	profile := NewUserProfile(user)
+	return &profile, nil
 }
 
-func (s *userService) GetAllUsers() ([]models.User, error) {
-	return s.userRepository.FindAll()
-}
+func (s *userService) UpdateUser(ctx context.Context, actorID uint, user *models.User) (*Account, error) {
+	if actorID != user.ID {
+		return nil, forbidden("you can only update your own account")
+	}
+
+	existing, err := s.userRepository.FindByID(ctx, user.ID)
+	if err != nil {
+		return nil, repositoryError(err, "user")
+	}
+
+	// An empty password keeps the current one, a new one must satisfy the policy
+	passwordChanged := user.Password != ""
+	if !passwordChanged {
+		user.Password = existing.Password
+	} else {
+		if err := s.passwordPolicy.Validate(user.Password, user.Username, user.Email); err != nil {
+			return nil, err
+		}
+
+		hashedPassword, err := utils.HashPassword(user.Password)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash password: %w", err)
+		}
+		user.Password = hashedPassword
+	}
+
+	updatedUser, err := s.userRepository.UpdateUser(ctx, user)
+	if err != nil {
+		return nil, repositoryError(err, "user")
+	}
 
-func (s *userService) UpdateUser(user *models.User) (*models.User, error) {
-	return s.userRepository.UpdateUser(user)
+	if passwordChanged {
+		s.audit.Record(ctx, succeeded(AuditPasswordChange, user.ID, nil))
+	}
+	if updatedUser.Email != existing.Email {
+		s.audit.Record(ctx, succeeded(AuditEmailChange, user.ID, map[string]any{"old_email": existing.Email, "new_email": updatedUser.Email}))
+	}
+	return NewAccount(updatedUser), nil
 }
 
-func (s *userService) DeleteUser(id uint) error {
-	return s.userRepository.DeleteUser(id)
+// DeleteUser deletes the account; it can be restored by an admin until it is purged after the
+// grace period. Its tokens stop working at once since deleted users are not found.
+func (s *userService) DeleteUser(ctx context.Context, actorID uint, id uint) error {
+	if actorID != id {
+		return forbidden("you can only delete your own account")
+	}
+	if err := s.userRepository.DeleteUser(ctx, id); err != nil {
+		return repositoryError(err, "user")
+	}
+	s.connections.DisconnectUser(id)
+	s.audit.Record(ctx, succeeded(AuditAccountDeletion, id, nil))
+	return nil
 }
 
-func (s *userService) SearchUser(currentUsername string, searchContent string) ([]SearchResponse, error) {
-	response, err := s.userRepository.SearchUser(currentUsername, searchContent)
+func (s *userService) SearchUser(ctx context.Context, currentUsername string, searchContent string) ([]SearchResponse, error) {
+	response, err := s.userRepository.SearchUser(ctx, currentUsername, searchContent)
+	if err != nil {
+		return nil, err
+	}
 
 	var searchResponse []SearchResponse
 	for _, user := range response {
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"chat-app-api/internal/models"
+	"chat-app-api/internal/utils"
+	"context"
+	"testing"
+)
+
+func TestCreateUserHashesPassword(t *testing.T) {
+	env := newTestEnv(t)
+
+	created, err := env.userService.CreateUser(context.Background(), &models.User{
+		Username: "alice", Email: "alice@example.com", Password: "correct horse battery",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := env.users.FindByID(context.Background(), created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Password == "correct horse battery" || utils.ComparePassword(stored.Password, "correct horse battery") != nil {
+		t.Errorf("password stored as %q, want a hash of it", stored.Password)
+	}
+}
+
+func TestCreateUserRejectsTakenUsernameAndEmail(t *testing.T) {
+	env := newTestEnv(t)
+	createTestUser(t, env.users, "alice", "correct horse battery")
+
+	_, err := env.userService.CreateUser(context.Background(), &models.User{
+		Username: "alice", Email: "other@example.com", Password: "correct horse battery",
+	})
+	assertErrorIs(t, err, ErrConflict)
+
+	_, err = env.userService.CreateUser(context.Background(), &models.User{
+		Username: "other", Email: "alice@example.com", Password: "correct horse battery",
+	})
+	assertErrorIs(t, err, ErrConflict)
+}
+
+func TestCreateUserEnforcesPasswordPolicy(t *testing.T) {
+	env := newTestEnv(t)
+
+	_, err := env.userService.CreateUser(context.Background(), &models.User{
+		Username: "alice", Email: "alice@example.com", Password: "password",
+	})
+	assertErrorIs(t, err, ErrValidation)
+	assertErrorIs(t, err, ErrWeakPassword)
+}
+
+func TestGetUserByIDNotFound(t *testing.T) {
+	env := newTestEnv(t)
+
+	_, err := env.userService.GetUserByID(context.Background(), 42)
+	assertErrorIs(t, err, ErrNotFound)
+}
+
+func TestUpdateUser(t *testing.T) {
+	env := newTestEnv(t)
+	alice := createTestUser(t, env.users, "alice", "correct horse battery")
+	bob := createTestUser(t, env.users, "bob", "correct horse battery")
+	ctx := context.Background()
+
+	_, err := env.userService.UpdateUser(ctx, bob.ID, &models.User{ID: alice.ID, Username: "alice", Email: "alice@example.com"})
+	assertErrorIs(t, err, ErrForbidden)
+
+	// An empty password keeps the current hash
+	updated, err := env.userService.UpdateUser(ctx, alice.ID, &models.User{ID: alice.ID, Username: "alice", FirstName: "Alice", Email: "alice@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := env.users.FindByID(ctx, alice.ID)
+	if updated.FirstName != "Alice" || stored.Password != alice.Password {
+		t.Errorf("got %+v, want first name Alice and the old password hash", stored)
+	}
+
+	_, err = env.userService.UpdateUser(ctx, alice.ID, &models.User{ID: alice.ID, Username: "alice", Email: "alice@example.com", Password: "short"})
+	assertErrorIs(t, err, ErrValidation)
+
+	if _, err := env.userService.UpdateUser(ctx, alice.ID, &models.User{ID: alice.ID, Username: "alice", Email: "alice@example.com", Password: "a new long passphrase"}); err != nil {
+		t.Fatal(err)
+	}
+	stored, _ = env.users.FindByID(ctx, alice.ID)
+	if utils.ComparePassword(stored.Password, "a new long passphrase") != nil {
+		t.Error("new password was not hashed and stored")
+	}
+
+	_, err = env.userService.UpdateUser(ctx, alice.ID, &models.User{ID: alice.ID, Username: "bob", Email: "alice@example.com"})
+	assertErrorIs(t, err, ErrConflict)
+}
+
+func TestDeleteUser(t *testing.T) {
+	env := newTestEnv(t)
+	alice := createTestUser(t, env.users, "alice", "correct horse battery")
+	bob := createTestUser(t, env.users, "bob", "correct horse battery")
+	ctx := context.Background()
+
+	assertErrorIs(t, env.userService.DeleteUser(ctx, bob.ID, alice.ID), ErrForbidden)
+
+	if err := env.userService.DeleteUser(ctx, alice.ID, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	if len(env.connections.disconnected) != 1 || env.connections.disconnected[0] != alice.ID {
+		t.Errorf("disconnected %v, want user %d", env.connections.disconnected, alice.ID)
+	}
+	assertErrorIs(t, env.userService.DeleteUser(ctx, alice.ID, alice.ID), ErrNotFound)
+	_, err := env.userService.GetUserByID(ctx, alice.ID)
+	assertErrorIs(t, err, ErrNotFound)
+
+	// The username stays taken while the account can still be restored
+	_, err = env.userService.CreateUser(ctx, &models.User{Username: "alice", Email: "new@example.com", Password: "correct horse battery"})
+	assertErrorIs(t, err, ErrConflict)
+}
+
+func TestSearchUser(t *testing.T) {
+	env := newTestEnv(t)
+	createTestUser(t, env.users, "alice", "correct horse battery")
+	alina := createTestUser(t, env.users, "alina", "correct horse battery")
+
+	results, err := env.userService.SearchUser(context.Background(), "alice", "@ali")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Profile.ID != alina.ID || results[0].Profile.Username != "alina" {
+		t.Errorf("got %+v, want only alina", results)
+	}
+}
@@ -0,0 +1,101 @@
+package tracing
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const spanKey = "tracing:span"
+
+// GormPlugin creates a span for every query GORM runs, as a child of the span in the statement context
+type GormPlugin struct{}
+
+// callbackRegistrar is implemented by GORM's callback chain positions
+type callbackRegistrar interface {
+	Register(name string, fn func(*gorm.DB)) error
+}
+
+func (GormPlugin) Name() string {
+	return "tracing"
+}
+
+func (GormPlugin) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	var errs []error
+	register := func(operation string, before, after callbackRegistrar) {
+		errs = append(errs,
+			before.Register("tracing:before_"+operation, startSpan(operation)),
+			after.Register("tracing:after_"+operation, endSpan))
+	}
+
+	register("create", callbacks.Create().Before("gorm:create"), callbacks.Create().After("gorm:create"))
+	register("query", callbacks.Query().Before("gorm:query"), callbacks.Query().After("gorm:query"))
+	register("update", callbacks.Update().Before("gorm:update"), callbacks.Update().After("gorm:update"))
+	register("delete", callbacks.Delete().Before("gorm:delete"), callbacks.Delete().After("gorm:delete"))
+	register("row", callbacks.Row().Before("gorm:row"), callbacks.Row().After("gorm:row"))
+	register("raw", callbacks.Raw().Before("gorm:raw"), callbacks.Raw().After("gorm:raw"))
+
+	return errors.Join(errs...)
+}
+
+func startSpan(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx := db.Statement.Context
+		if ctx == nil {
+			return
+		}
+
+		name := "db." + operation
+		if db.Statement.Table != "" {
+			name += " " + db.Statement.Table
+		}
+		_, span := Tracer().Start(ctx, name,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				dbSystem(db),
+				semconv.DBOperationName(operation),
+				semconv.DBCollectionName(db.Statement.Table),
+			))
+		db.InstanceSet(spanKey, span)
+	}
+}
+
+// dbSystem names the database behind GORM's dialector; the names are those of database.Postgres
+// and database.SQLite, which imports this package
+func dbSystem(db *gorm.DB) attribute.KeyValue {
+	switch db.Dialector.Name() {
+	case "postgres":
+		return semconv.DBSystemPostgreSQL
+	case "sqlite":
+		return semconv.DBSystemSqlite
+	default:
+		return semconv.DBSystemOtherSQL
+	}
+}
+
+func endSpan(db *gorm.DB) {
+	value, ok := db.InstanceGet(spanKey)
+	if !ok {
+		return
+	}
+	span, ok := value.(trace.Span)
+	if !ok {
+		return
+	}
+
+	// The statement is recorded with placeholders, never with the bound values
+	span.SetAttributes(
+		semconv.DBQueryText(db.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+	)
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		span.RecordError(db.Error)
+		span.SetStatus(codes.Error, db.Error.Error())
+	}
+	span.End()
+}
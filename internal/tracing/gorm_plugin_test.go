@@ -0,0 +1,47 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"gorm.io/gorm"
+)
+
+func TestGormPluginNamesTheDatabaseSystem(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Use(GormPlugin{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var one int
+	if err := db.WithContext(context.Background()).Raw("SELECT 1").Scan(&one).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) == 0 {
+		t.Fatal("the query was not traced")
+	}
+	for _, attr := range spans[0].Attributes() {
+		if attr.Key == semconv.DBSystemKey {
+			if attr != semconv.DBSystemSqlite {
+				t.Errorf("got %s=%s, want sqlite", attr.Key, attr.Value.Emit())
+			}
+			return
+		}
+	}
+	t.Errorf("span %s has no %s attribute", spans[0].Name(), semconv.DBSystemKey)
+}
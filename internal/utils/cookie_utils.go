@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+)
+
+const (
+	AccessTokenCookie  = "access_token"
+	RefreshTokenCookie = "refresh_token"
+	CSRFTokenCookie    = "csrf_token"
+	CSRFTokenHeader    = "X-CSRF-Token"
+
+	// AuthModeHeader lets browser clients opt into cookie authentication with "X-Auth-Mode: cookie"
+	AuthModeHeader = "X-Auth-Mode"
+	AuthModeCookie = "cookie"
+
+	// RefreshTokenCookiePath limits the refresh cookie to the endpoints that consume it
+	RefreshTokenCookiePath = "/api/auth"
+)
+
+// GenerateCSRFToken returns a random token for the double-submit CSRF cookie
+func GenerateCSRFToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,216 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWK is the public part of a verification key as published in the JWKS document
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set (RFC 7517)
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+type verificationKey struct {
+	method jwt.SigningMethod
+	public crypto.PublicKey
+	jwk    JWK
+}
+
+// KeySet holds the key used to sign new tokens and every key that is still accepted for verification
+type KeySet struct {
+	signingKID    string
+	signingMethod jwt.SigningMethod
+	signingKey    crypto.Signer
+	verification  map[string]verificationKey
+}
+
+// LoadKeySet reads a PEM private signing key and any number of extra PEM verification keys.
+// Verification files may hold either public or private keys; old signing keys can be kept
+// there during a rotation so tokens they issued stay valid until they expire.
+func LoadKeySet(signingKeyFile string, verificationKeyFiles []string) (*KeySet, error) {
+	signingKey, err := readPrivateKey(signingKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("signing key %s: %w", signingKeyFile, err)
+	}
+
+	keySet := &KeySet{signingKey: signingKey, verification: make(map[string]verificationKey)}
+	keySet.signingKID, keySet.signingMethod, err = keySet.add(signingKey.Public())
+	if err != nil {
+		return nil, fmt.Errorf("signing key %s: %w", signingKeyFile, err)
+	}
+
+	for _, file := range verificationKeyFiles {
+		publicKey, err := readPublicKey(file)
+		if err != nil {
+			return nil, fmt.Errorf("verification key %s: %w", file, err)
+		}
+		if _, _, err := keySet.add(publicKey); err != nil {
+			return nil, fmt.Errorf("verification key %s: %w", file, err)
+		}
+	}
+
+	return keySet, nil
+}
+
+// add registers a verification key under its RFC 7638 thumbprint
+func (k *KeySet) add(publicKey crypto.PublicKey) (string, jwt.SigningMethod, error) {
+	var key verificationKey
+	switch pub := publicKey.(type) {
+	case *rsa.PublicKey:
+		key.method = jwt.SigningMethodRS256
+		key.jwk = JWK{
+			Kty: "RSA",
+			N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}
+	case ed25519.PublicKey:
+		key.method = jwt.SigningMethodEdDSA
+		key.jwk = JWK{
+			Kty: "OKP",
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(pub),
+		}
+	default:
+		return "", nil, fmt.Errorf("unsupported key type %T, use RSA or Ed25519", publicKey)
+	}
+
+	key.public = publicKey
+	key.jwk.Kid = thumbprint(key.jwk)
+	key.jwk.Use = "sig"
+	key.jwk.Alg = key.method.Alg()
+	k.verification[key.jwk.Kid] = key
+
+	return key.jwk.Kid, key.method, nil
+}
+
+// sign creates a token signed with the current signing key and tagged with its kid
+func (k *KeySet) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.signingMethod, claims)
+	token.Header["kid"] = k.signingKID
+	return token.SignedString(k.signingKey)
+}
+
+// keyFunc resolves the verification key named by the token's kid header
+func (k *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := k.verification[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s for key %q", token.Method.Alg(), kid)
+	}
+	return key.public, nil
+}
+
+// JWKS returns the public verification keys, signing key first
+func (k *KeySet) JWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{k.verification[k.signingKID].jwk}}
+	for kid, key := range k.verification {
+		if kid != k.signingKID {
+			jwks.Keys = append(jwks.Keys, key.jwk)
+		}
+	}
+	return jwks
+}
+
+func thumbprint(jwk JWK) string {
+	// RFC 7638 requires the required members only, in lexicographic order
+	var canonical []byte
+	switch jwk.Kty {
+	case "RSA":
+		canonical, _ = json.Marshal(struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N})
+	case "OKP":
+		canonical, _ = json.Marshal(struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X})
+	}
+	sum := sha256.Sum256(canonical)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func readPEM(file string) (*pem.Block, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+	return block, nil
+}
+
+func readPrivateKey(file string) (crypto.Signer, error) {
+	block, err := readPEM(file)
+	if err != nil {
+		return nil, err
+	}
+	return parsePrivateKey(block)
+}
+
+func parsePrivateKey(block *pem.Block) (crypto.Signer, error) {
+	if block.Type == "RSA PRIVATE KEY" {
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	return signer, nil
+}
+
+func readPublicKey(file string) (crypto.PublicKey, error) {
+	block, err := readPEM(file)
+	if err != nil {
+		return nil, err
+	}
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		signer, err := parsePrivateKey(block)
+		if err != nil {
+			return nil, err
+		}
+		return signer.Public(), nil
+	}
+}
@@ -0,0 +1,264 @@
+package utils
+
+import (
+	"chat-app-api/internal/config"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// writeKey stores the key as PEM in the test's directory: a private key as PKCS#8, anything else
+// as a PKIX public key
+func writeKey(t *testing.T, name string, key any) string {
+	t.Helper()
+
+	var block *pem.Block
+	if signer, ok := key.(crypto.Signer); ok {
+		der, err := x509.MarshalPKCS8PrivateKey(signer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	} else {
+		der, err := x509.MarshalPKIXPublicKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block = &pem.Block{Type: "PUBLIC KEY", Bytes: der}
+	}
+
+	file := filepath.Join(t.TempDir(), name+".pem")
+	if err := os.WriteFile(file, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func newRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func newEd25519Key(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// useJWT configures token signing until the test ends
+func useJWT(t *testing.T, cfg config.JWTConfig) {
+	t.Helper()
+	t.Cleanup(func() { _ = InitJWT(config.JWTConfig{}) })
+
+	cfg.RefreshTokenSecret = "refresh"
+	cfg.AccessTokenTTL = time.Minute
+	cfg.RefreshTokenTTL = time.Hour
+	if err := InitJWT(cfg); err != nil {
+		t.Fatal(err)
+	}
+}
+
+var testUserClaim = UserClaim{UserID: "1", Username: "alice", Email: "alice@example.com", Role: "user"}
+
+func TestKeySetSignsTokens(t *testing.T) {
+	for name, test := range map[string]struct {
+		key crypto.Signer
+		alg string
+		kty string
+	}{
+		"RSA":     {newRSAKey(t), "RS256", "RSA"},
+		"Ed25519": {newEd25519Key(t), "EdDSA", "OKP"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			useJWT(t, config.JWTConfig{SigningKeyFile: writeKey(t, "signing", test.key)})
+
+			access, refresh, err := GenerateAccessAndRefreshTokens(testUserClaim)
+			if err != nil {
+				t.Fatal(err)
+			}
+			jwks := PublicJWKS()
+			if len(jwks.Keys) != 1 || jwks.Keys[0].Alg != test.alg || jwks.Keys[0].Kty != test.kty || jwks.Keys[0].Use != "sig" {
+				t.Fatalf("got JWKS %+v, want the %s signing key", jwks, test.alg)
+			}
+
+			token, _, err := jwt.NewParser().ParseUnverified(access, &Claims{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if token.Method.Alg() != test.alg || token.Header["kid"] != jwks.Keys[0].Kid {
+				t.Errorf("got header %v, want %s signed with kid %s", token.Header, test.alg, jwks.Keys[0].Kid)
+			}
+
+			claims, err := ParseAccessToken(access)
+			if err != nil || claims.Username != "alice" {
+				t.Errorf("got claims %+v (%v), want alice's access token", claims, err)
+			}
+			if _, err := ParseAccessToken(refresh); err == nil {
+				t.Error("a refresh token was accepted as an access token")
+			}
+
+			// Refresh tokens are signed with the secret, so the published keys cannot verify them
+			token, _, err = jwt.NewParser().ParseUnverified(refresh, &Claims{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if token.Method.Alg() != "HS256" || token.Header["kid"] != nil {
+				t.Errorf("got refresh token header %v, want HS256 without a kid", token.Header)
+			}
+			if _, err := ParseRefreshToken(refresh); err != nil {
+				t.Errorf("the refresh token was rejected: %v", err)
+			}
+		})
+	}
+}
+
+func TestRefreshTokensOfTheSigningKeyAreRejected(t *testing.T) {
+	key := newEd25519Key(t)
+	useJWT(t, config.JWTConfig{SigningKeyFile: writeKey(t, "signing", key)})
+
+	// As issued before refresh tokens got their own secret
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, &Claims{
+		UserClaim:        UserClaim{UserID: "1", TokenType: refreshTokenType},
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))},
+	})
+	token.Header["kid"] = PublicJWKS().Keys[0].Kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseRefreshToken(signed); err == nil {
+		t.Error("a refresh token signed with the published key was accepted")
+	}
+}
+
+func TestKeySetVerifiesRetiredKeys(t *testing.T) {
+	retired := newRSAKey(t)
+	useJWT(t, config.JWTConfig{SigningKeyFile: writeKey(t, "retired", retired)})
+	oldToken, err := GenerateAccessToken(testUserClaim)
+	if err != nil {
+		t.Fatal(err)
+	}
+	retiredKID := PublicJWKS().Keys[0].Kid
+
+	// During the rotation the retired key only verifies, from its public half
+	useJWT(t, config.JWTConfig{
+		SigningKeyFile:       writeKey(t, "signing", newEd25519Key(t)),
+		VerificationKeyFiles: []string{writeKey(t, "retired", retired.Public())},
+	})
+	if _, err := ParseAccessToken(oldToken); err != nil {
+		t.Errorf("the token of the retired key was rejected: %v", err)
+	}
+	newToken, err := GenerateAccessToken(testUserClaim)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token, _, _ := jwt.NewParser().ParseUnverified(newToken, &Claims{}); token.Method.Alg() != "EdDSA" {
+		t.Errorf("new tokens are signed with %s, want the new signing key", token.Method.Alg())
+	}
+	jwks := PublicJWKS()
+	if len(jwks.Keys) != 2 || jwks.Keys[0].Alg != "EdDSA" || jwks.Keys[1].Kid != retiredKID {
+		t.Errorf("got JWKS %+v, want the signing key then the retired one", jwks)
+	}
+
+	// Once the retired key is dropped its tokens stop being accepted
+	useJWT(t, config.JWTConfig{SigningKeyFile: writeKey(t, "signing", newEd25519Key(t))})
+	if _, err := ParseAccessToken(oldToken); err == nil {
+		t.Error("the token of a dropped key was accepted")
+	}
+}
+
+func TestKeySetRejectsForeignTokens(t *testing.T) {
+	key := newRSAKey(t)
+	useJWT(t, config.JWTConfig{SigningKeyFile: writeKey(t, "signing", key)})
+	kid := PublicJWKS().Keys[0].Kid
+
+	sign := func(method jwt.SigningMethod, kid string, key any) string {
+		t.Helper()
+		token := jwt.NewWithClaims(method, &Claims{
+			UserClaim:        UserClaim{UserID: "1", TokenType: accessTokenType},
+			RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))},
+		})
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+
+	if _, err := ParseAccessToken(sign(jwt.SigningMethodRS256, kid, key)); err != nil {
+		t.Fatalf("a token of the signing key was rejected: %v", err)
+	}
+	for name, token := range map[string]string{
+		"unknown kid":     sign(jwt.SigningMethodRS256, "unknown", key),
+		"no kid":          sign(jwt.SigningMethodRS256, "", key),
+		"another key":     sign(jwt.SigningMethodRS256, kid, newRSAKey(t)),
+		"other algorithm": sign(jwt.SigningMethodRS512, kid, key),
+		"shared secret":   sign(jwt.SigningMethodHS256, kid, []byte("secret")),
+	} {
+		if _, err := ParseAccessToken(token); err == nil {
+			t.Errorf("%s: the token was accepted", name)
+		}
+	}
+}
+
+func TestParseChecksTheIssuer(t *testing.T) {
+	useJWT(t, config.JWTConfig{AccessTokenSecret: "secret", Issuer: "https://chat.example.com"})
+	token, err := GenerateAccessToken(testUserClaim)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseAccessToken(token); err != nil {
+		t.Errorf("the token of our issuer was rejected: %v", err)
+	}
+
+	// Another deployment sharing the secret
+	useJWT(t, config.JWTConfig{AccessTokenSecret: "secret", Issuer: "https://other.example.com"})
+	if _, err := ParseAccessToken(token); err == nil {
+		t.Error("the token of another issuer was accepted")
+	}
+}
+
+func TestThumbprint(t *testing.T) {
+	for name, test := range map[string]struct {
+		jwk  JWK
+		want string
+	}{
+		// RFC 7638, section 3.1
+		"RSA": {JWK{
+			Kty: "RSA",
+			E:   "AQAB",
+			N:   "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+			Alg: "RS256",
+			Kid: "ignored",
+		}, "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs"},
+		// RFC 8037, appendix A.3
+		"Ed25519": {JWK{
+			Kty: "OKP",
+			Crv: "Ed25519",
+			X:   "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo",
+		}, "kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k"},
+	} {
+		if got := thumbprint(test.jwk); got != test.want {
+			t.Errorf("%s: got thumbprint %s, want %s", name, got, test.want)
+		}
+	}
+}
@@ -1,11 +1,10 @@
 package utils
 
 import (
+	"chat-app-api/internal/config"
 	"errors"
-	"github.com/dgrijalva/jwt-go"
-	"github.com/joho/godotenv"
-	"log"
-	"os"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
 	"time"
 )
 
@@ -14,54 +13,76 @@ var (
 	refreshTokenSecret []byte
 	accessTokenExp     time.Duration
 	refreshTokenExp    time.Duration
+	tokenIssuer        string
+
+	// keySet signs access tokens when asymmetric signing is configured; otherwise the HS256 secret
+	// is used. Refresh tokens are always signed with their HS256 secret.
+	keySet *KeySet
 )
 
-func init() {
-	// Load environment variables
-	if err := godotenv.Load(); err != nil {
-		log.Fatalf("Error loading .env file")
+// InitJWT configures token signing; it must be called before any token is issued or parsed
+func InitJWT(cfg config.JWTConfig) error {
+	accessTokenSecret = []byte(cfg.AccessTokenSecret)
+	refreshTokenSecret = []byte(cfg.RefreshTokenSecret)
+	accessTokenExp = cfg.AccessTokenTTL
+	refreshTokenExp = cfg.RefreshTokenTTL
+	tokenIssuer = cfg.Issuer
+
+	keySet = nil
+	if cfg.SigningKeyFile != "" {
+		var err error
+		keySet, err = LoadKeySet(cfg.SigningKeyFile, cfg.VerificationKeyFiles)
+		if err != nil {
+			return fmt.Errorf("failed to load JWT keys: %w", err)
+		}
 	}
 
-	// Read environment variables
-	accessTokenSecret = []byte(os.Getenv("ACCESS_TOKEN_SECRET"))
-	refreshTokenSecret = []byte(os.Getenv("REFRESH_TOKEN_SECRET"))
-
-	accessTokenExpiration := os.Getenv("ACCESS_TOKEN_EXPIRATION")
-	refreshTokenExpiration := os.Getenv("REFRESH_TOKEN_EXPIRATION")
-
-	var err error
-	accessTokenExp, err = time.ParseDuration(accessTokenExpiration)
-	if err != nil {
-		log.Fatalf("Error parsing ACCESS_TOKEN_EXPIRATION: %v", err)
-	}
-
-	refreshTokenExp, err = time.ParseDuration(refreshTokenExpiration)
-	if err != nil {
-		log.Fatalf("Error parsing REFRESH_TOKEN_EXPIRATION: %v", err)
-	}
+	return nil
 }
 
+const (
+	accessTokenType  = "access"
+	refreshTokenType = "refresh"
+)
+
 type UserClaim struct {
-	UserID    string `json:"user_id"`
-	Username  string `json:"username"`
-	Email     string `json:"email"`
-	TokenType string `json:"token_type"`
+	UserID       string `json:"user_id"`
+	Username     string `json:"username"`
+	Email        string `json:"email"`
+	Role         string `json:"role"`
+	TokenVersion int    `json:"ver"` // tokens of an older version than the user's have been revoked
+	TokenType    string `json:"token_type"`
 }
 
 type Claims struct {
 	UserClaim
-	jwt.StandardClaims
+	jwt.RegisteredClaims
 }
 
-func generateToken(userClaims UserClaim, secret []byte, exp time.Duration) (string, error) {
-	expirationTime := time.Now().Add(exp)
+// usesKeySet reports whether tokens of the type are signed with the published keys. Refresh tokens
+// are only read back by this service, so a key other services can verify with would only let them
+// accept a refresh token as an access token if they skipped the token_type claim.
+func usesKeySet(tokenType string) bool {
+	return keySet != nil && tokenType == accessTokenType
+}
+
+func generateToken(userClaims UserClaim, tokenType string, secret []byte, exp time.Duration) (string, error) {
+	now := time.Now()
+	userClaims.TokenType = tokenType
 	claims := &Claims{
 		UserClaim: userClaims,
-		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: expirationTime.Unix(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    tokenIssuer,
+			Subject:   userClaims.UserID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(exp)),
 		},
 	}
 
+	if usesKeySet(tokenType) {
+		return keySet.sign(claims)
+	}
+
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	tokenString, err := token.SignedString(secret)
 	if err != nil {
@@ -71,11 +92,20 @@ func generateToken(userClaims UserClaim, secret []byte, exp time.Duration) (stri
 	return tokenString, nil
 }
 
-func parseToken(tokenString string, secret []byte) (*Claims, error) {
+// parseToken checks the signature, expiry and type of a token, and its issuer when one is configured
+func parseToken(tokenString string, tokenType string, secret []byte) (*Claims, error) {
 	claims := &Claims{}
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return secret, nil
-	})
+
+	var token *jwt.Token
+	var err error
+	if usesKeySet(tokenType) {
+		token, err = jwt.ParseWithClaims(tokenString, claims, keySet.keyFunc,
+			jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}), jwt.WithIssuer(tokenIssuer))
+	} else {
+		token, err = jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+			return secret, nil
+		}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithIssuer(tokenIssuer))
+	}
 
 	if err != nil {
 		return nil, err
@@ -85,46 +115,57 @@ func parseToken(tokenString string, secret []byte) (*Claims, error) {
 		return nil, jwt.ErrSignatureInvalid
 	}
 
+	// Access and refresh tokens may share a secret, so the type claim keeps them apart
+	if claims.TokenType != tokenType {
+		return nil, errors.New("unexpected token type")
+	}
+
 	return claims, nil
 }
 
 func ParseAccessToken(tokenString string) (*Claims, error) {
-	return parseToken(tokenString, accessTokenSecret)
+	return parseToken(tokenString, accessTokenType, accessTokenSecret)
 }
 
-func RenewAccessToken(refreshTokenString string) (string, error) {
-	// Parse and validate the refresh token
-	claims, err := parseToken(refreshTokenString, refreshTokenSecret)
-
-	if err != nil {
-		return "", err
-	}
-
-	// Ensure the refresh token is valid and not expired
-	if claims.ExpiresAt < time.Now().Unix() {
-		return "", errors.New("refresh token has expired")
-	}
+// ParseRefreshToken validates a refresh token; expiry is checked while parsing
+func ParseRefreshToken(tokenString string) (*Claims, error) {
+	return parseToken(tokenString, refreshTokenType, refreshTokenSecret)
+}
 
-	// Generate a new access token
-	userClaims := UserClaim{
-		UserID:    claims.UserID,
-		Username:  claims.Username,
-		Email:     claims.Email,
-		TokenType: "access",
-	}
-	return generateToken(userClaims, accessTokenSecret, accessTokenExp)
+// GenerateAccessToken issues a new access token, used when renewing with a refresh token
+func GenerateAccessToken(userClaims UserClaim) (string, error) {
+	return generateToken(userClaims, accessTokenType, accessTokenSecret, accessTokenExp)
 }
 
 func GenerateAccessAndRefreshTokens(userClaims UserClaim) (string, string, error) {
-	accessToken, err := generateToken(userClaims, accessTokenSecret, accessTokenExp)
+	accessToken, err := generateToken(userClaims, accessTokenType, accessTokenSecret, accessTokenExp)
 	if err != nil {
 		return "", "", err
 	}
 
-	refreshToken, err := generateToken(userClaims, refreshTokenSecret, refreshTokenExp)
+	refreshToken, err := generateToken(userClaims, refreshTokenType, refreshTokenSecret, refreshTokenExp)
 	if err != nil {
 		return "", "", err
 	}
 
 	return accessToken, refreshToken, nil
 }
+
+// AccessTokenTTL is how long newly issued access tokens stay valid
+func AccessTokenTTL() time.Duration {
+	return accessTokenExp
+}
+
+// RefreshTokenTTL is how long newly issued refresh tokens stay valid
+func RefreshTokenTTL() time.Duration {
+	return refreshTokenExp
+}
+
+// PublicJWKS returns the keys other services can use to verify our access tokens.
+// It is empty when they are signed with a shared HS256 secret.
+func PublicJWKS() JWKS {
+	if keySet == nil {
+		return JWKS{Keys: []JWK{}}
+	}
+	return keySet.JWKS()
+}
@@ -1,17 +1,136 @@
 package utils
 
 import (
+	"chat-app-api/internal/config"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
 )
 
+const (
+	PasswordAlgorithmArgon2id = "argon2id"
+	PasswordAlgorithmBcrypt   = "bcrypt"
+)
+
+var (
+	ErrPasswordMismatch    = errors.New("password does not match")
+	ErrUnknownPasswordHash = errors.New("unknown password hash format")
+)
+
+// passwordHashConfig selects the algorithm and cost used for new password hashes
+var passwordHashConfig = config.Default().Password
+
+// ConfigurePasswordHashing changes the algorithm and cost used by HashPassword
+func ConfigurePasswordHashing(cfg config.PasswordConfig) error {
+	if cfg.HashAlgorithm != PasswordAlgorithmArgon2id && cfg.HashAlgorithm != PasswordAlgorithmBcrypt {
+		return fmt.Errorf("unsupported password hash algorithm %q", cfg.HashAlgorithm)
+	}
+
+	passwordHashConfig = cfg
+	return nil
+}
+
 func HashPassword(password string) (string, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
+	if passwordHashConfig.HashAlgorithm == PasswordAlgorithmBcrypt {
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), passwordHashConfig.BcryptCost)
+		if err != nil {
+			return "", err
+		}
+		return string(hashedPassword), nil
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
 		return "", err
 	}
-	return string(hashedPassword), nil
+
+	params := argon2Params{
+		memory:      passwordHashConfig.Argon2Memory,
+		iterations:  passwordHashConfig.Argon2Iterations,
+		parallelism: passwordHashConfig.Argon2Parallelism,
+	}
+	key := argon2.IDKey([]byte(password), salt, params.iterations, params.memory, params.parallelism, 32)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version,
+		params.memory, params.iterations, params.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
 }
 
+// ComparePassword checks a password against a bcrypt or argon2id hash
 func ComparePassword(hashedPassword, password string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	if !strings.HasPrefix(hashedPassword, "$argon2id$") {
+		return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	}
+
+	params, salt, key, err := decodeArgon2id(hashedPassword)
+	if err != nil {
+		return err
+	}
+
+	candidate := argon2.IDKey([]byte(password), salt, params.iterations, params.memory, params.parallelism, uint32(len(key)))
+	if subtle.ConstantTimeCompare(candidate, key) != 1 {
+		return ErrPasswordMismatch
+	}
+	return nil
+}
+
+// PasswordNeedsRehash reports whether a stored hash was made with an older algorithm or cost
+func PasswordNeedsRehash(hashedPassword string) bool {
+	if passwordHashConfig.HashAlgorithm == PasswordAlgorithmBcrypt {
+		cost, err := bcrypt.Cost([]byte(hashedPassword))
+		return err != nil || cost != passwordHashConfig.BcryptCost
+	}
+
+	params, _, _, err := decodeArgon2id(hashedPassword)
+	if err != nil {
+		return true
+	}
+	return params.memory != passwordHashConfig.Argon2Memory ||
+		params.iterations != passwordHashConfig.Argon2Iterations ||
+		params.parallelism != passwordHashConfig.Argon2Parallelism
+}
+
+type argon2Params struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+}
+
+// decodeArgon2id parses the PHC string format: $argon2id$v=19$m=...,t=...,p=...$salt$hash
+func decodeArgon2id(encoded string) (argon2Params, []byte, []byte, error) {
+	var params argon2Params
+
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != PasswordAlgorithmArgon2id {
+		return params, nil, nil, ErrUnknownPasswordHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, ErrUnknownPasswordHash
+	}
+
+	// argon2 panics on zero iterations or parallelism, which only a corrupted hash has
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism); err != nil ||
+		params.iterations == 0 || params.parallelism == 0 {
+		return params, nil, nil, ErrUnknownPasswordHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, ErrUnknownPasswordHash
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, ErrUnknownPasswordHash
+	}
+
+	return params, salt, key, nil
 }
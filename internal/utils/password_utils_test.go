@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"chat-app-api/internal/config"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// useArgon2id hashes with cheap argon2id parameters until the test ends
+func useArgon2id(t *testing.T, memory, iterations uint32) {
+	t.Helper()
+	previous := passwordHashConfig
+	t.Cleanup(func() { passwordHashConfig = previous })
+
+	cfg := config.Default().Password
+	cfg.HashAlgorithm = PasswordAlgorithmArgon2id
+	cfg.Argon2Memory = memory
+	cfg.Argon2Iterations = iterations
+	cfg.Argon2Parallelism = 1
+	if err := ConfigurePasswordHashing(cfg); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestArgon2idRoundTrip(t *testing.T) {
+	useArgon2id(t, 64, 1)
+
+	hash, err := HashPassword("correct horse battery")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=64,t=1,p=1$") {
+		t.Errorf("got hash %q, want the PHC format with the configured parameters", hash)
+	}
+	if err := ComparePassword(hash, "correct horse battery"); err != nil {
+		t.Errorf("the right password was rejected: %v", err)
+	}
+	if err := ComparePassword(hash, "correct horse batterY"); !errors.Is(err, ErrPasswordMismatch) {
+		t.Errorf("got %v for a wrong password, want ErrPasswordMismatch", err)
+	}
+
+	// Every hash has its own salt
+	other, err := HashPassword("correct horse battery")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == hash {
+		t.Error("two hashes of the same password are equal")
+	}
+}
+
+func TestArgon2idRejectsMalformedHashes(t *testing.T) {
+	useArgon2id(t, 64, 1)
+
+	for name, hash := range map[string]string{
+		"too few fields":   "$argon2id$v=19$m=64,t=1,p=1$c2FsdHNhbHQ",
+		"other version":    "$argon2id$v=16$m=64,t=1,p=1$c2FsdHNhbHQ$aGFzaA",
+		"bad parameters":   "$argon2id$v=19$m=64;t=1;p=1$c2FsdHNhbHQ$aGFzaA",
+		"no iterations":    "$argon2id$v=19$m=64,t=0,p=1$c2FsdHNhbHQ$aGFzaA",
+		"no parallelism":   "$argon2id$v=19$m=64,t=1,p=0$c2FsdHNhbHQ$aGFzaA",
+		"salt not base64":  "$argon2id$v=19$m=64,t=1,p=1$salt!$aGFzaA",
+		"hash not base64":  "$argon2id$v=19$m=64,t=1,p=1$c2FsdHNhbHQ$hash!",
+		"empty hash":       "$argon2id$v=19$m=64,t=1,p=1$c2FsdHNhbHQ$",
+		"trailing garbage": "$argon2id$v=19$m=64,t=1,p=1$c2FsdHNhbHQ$aGFzaA$",
+	} {
+		t.Run(name, func(t *testing.T) {
+			if err := ComparePassword(hash, "password"); !errors.Is(err, ErrUnknownPasswordHash) {
+				t.Errorf("got %v, want ErrUnknownPasswordHash", err)
+			}
+			if !PasswordNeedsRehash(hash) {
+				t.Error("a malformed hash does not need a rehash")
+			}
+		})
+	}
+}
+
+func TestPasswordNeedsRehashWhenParametersChange(t *testing.T) {
+	useArgon2id(t, 64, 1)
+	hash, err := HashPassword("correct horse battery")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if PasswordNeedsRehash(hash) {
+		t.Error("a hash made with the current parameters needs a rehash")
+	}
+
+	useArgon2id(t, 128, 1)
+	if !PasswordNeedsRehash(hash) {
+		t.Error("a hash made with less memory does not need a rehash")
+	}
+	useArgon2id(t, 64, 2)
+	if !PasswordNeedsRehash(hash) {
+		t.Error("a hash made with fewer iterations does not need a rehash")
+	}
+
+	// The old hash still verifies until it is replaced
+	if err := ComparePassword(hash, "correct horse battery"); err != nil {
+		t.Errorf("the old hash no longer verifies: %v", err)
+	}
+}
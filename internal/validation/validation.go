@@ -0,0 +1,138 @@
+package validation
+
+import (
+	"chat-app-api/internal/config"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// UsernamePattern allows 3 to 32 letters, digits, underscores or dots, starting with a letter or digit
+var UsernamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.]{2,31}$`)
+
+// Register adds the custom validators to gin's validator and makes it report fields by their
+// JSON, form or URI names. The "content" rule accepts up to cfg.MaxLength characters. It must run
+// before the first request is bound, as the validator keeps the rules of a type once it has seen it.
+func Register(cfg config.MessageConfig) error {
+	engine, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return errors.New("gin is not using go-playground/validator")
+	}
+
+	engine.RegisterTagNameFunc(fieldName)
+
+	if err := engine.RegisterValidation("username", validateUsername); err != nil {
+		return err
+	}
+	if err := engine.RegisterValidation("notblank", validateNotBlank); err != nil {
+		return err
+	}
+	engine.RegisterAlias("content", fmt.Sprintf("notblank,max=%d", cfg.MaxLength))
+	return nil
+}
+
+// Struct validates a value that was not decoded by gin, such as a WebSocket event
+func Struct(v any) error {
+	return binding.Validator.ValidateStruct(v)
+}
+
+// FieldError describes why one input field was rejected, by the name clients use for the field
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+// Fields lists the invalid fields of a binding or validation error. It returns nil when the input
+// could not be decoded at all, such as malformed JSON.
+func Fields(err error) []FieldError {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		fields := make([]FieldError, 0, len(validationErrs))
+		for _, fieldErr := range validationErrs {
+			fields = append(fields, FieldError{Field: fieldErr.Field(), Message: describe(fieldErr)})
+		}
+		return fields
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return []FieldError{{Field: typeErr.Field, Message: "must be " + typeName(typeErr.Type)}}
+	}
+	return nil
+}
+
+func validateUsername(fl validator.FieldLevel) bool {
+	return UsernamePattern.MatchString(fl.Field().String())
+}
+
+func validateNotBlank(fl validator.FieldLevel) bool {
+	return strings.TrimSpace(fl.Field().String()) != ""
+}
+
+// fieldName reports a struct field by the name clients use for it
+func fieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "form", "uri"} {
+		name := strings.SplitN(field.Tag.Get(tag), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+// describe is the client facing message for one failed rule. An alias such as "content" is
+// described by the rule within it that failed.
+func describe(fieldErr validator.FieldError) string {
+	isString := fieldErr.Kind() == reflect.String
+	switch fieldErr.ActualTag() {
+	case "required", "required_without_all":
+		return "is required"
+	case "min":
+		if isString {
+			return fmt.Sprintf("must be at least %s characters", fieldErr.Param())
+		}
+		return "must be at least " + fieldErr.Param()
+	case "max":
+		if isString {
+			return fmt.Sprintf("must be at most %s characters", fieldErr.Param())
+		}
+		return "must be at most " + fieldErr.Param()
+	case "email":
+		return "must be a valid email address"
+	case "url", "http_url":
+		return "must be a valid URL"
+	case "oneof":
+		return "must be one of " + strings.ReplaceAll(fieldErr.Param(), " ", ", ")
+	case "username":
+		return "must be 3 to 32 letters, digits, underscores or dots and start with a letter or digit"
+	case "notblank":
+		return "must not be blank"
+	}
+	return "is invalid"
+}
+
+func typeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "a list"
+	}
+	return "an object"
+}
@@ -0,0 +1,100 @@
+package validation
+
+import (
+	"chat-app-api/internal/config"
+	"chat-app-api/internal/dto"
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testMaxLength = 10
+
+func TestMain(m *testing.M) {
+	if err := Register(config.MessageConfig{MaxLength: testMaxLength}); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// invalidFields decodes body into v the way a handler does and lists the fields it rejects
+func invalidFields(t *testing.T, body string, v any) []FieldError {
+	t.Helper()
+	if err := json.Unmarshal([]byte(body), v); err != nil {
+		return Fields(err)
+	}
+	return Fields(Struct(v))
+}
+
+func TestRequestsReportInvalidFields(t *testing.T) {
+	for name, test := range map[string]struct {
+		body string
+		into any
+		want []FieldError
+	}{
+		"valid signup": {
+			`{"username": "alice.b", "email": "alice@example.com", "password": "secret"}`,
+			&dto.CreateUserRequest{}, nil,
+		},
+		"signup": {
+			`{"username": "_alice", "email": "alice", "profile_image": "not a url", "first_name": "` + strings.Repeat("a", 65) + `"}`,
+			&dto.CreateUserRequest{},
+			[]FieldError{
+				{"username", "must be 3 to 32 letters, digits, underscores or dots and start with a letter or digit"},
+				{"first_name", "must be at most 64 characters"},
+				{"email", "must be a valid email address"},
+				{"profile_image", "must be a valid URL"},
+				{"password", "is required"},
+			},
+		},
+		"login without an identifier": {
+			`{"password": "secret"}`,
+			&dto.LoginRequest{},
+			[]FieldError{{"identifier", "is required"}},
+		},
+		"report": {
+			`{"target_type": "post", "reason": "boring"}`,
+			&dto.CreateReportRequest{},
+			[]FieldError{
+				{"target_type", "must be one of message, user"},
+				{"reason", "must be one of spam, harassment, hate_speech, violence, sexual_content, impersonation, other"},
+			},
+		},
+		"valid message": {
+			`{"receiver_id": 2, "content": "` + strings.Repeat("é", testMaxLength) + `"}`,
+			&dto.SendMessageEvent{}, nil,
+		},
+		"message without a receiver": {
+			`{"receiver_id": 0, "content": "hi"}`,
+			&dto.SendMessageEvent{},
+			[]FieldError{{"receiver_id", "is required"}},
+		},
+		"blank message": {
+			`{"receiver_id": 2, "content": "  \n "}`,
+			&dto.SendMessageEvent{},
+			[]FieldError{{"content", "must not be blank"}},
+		},
+		"long message": {
+			`{"receiver_id": 2, "content": "` + strings.Repeat("a", testMaxLength+1) + `"}`,
+			&dto.SendMessageEvent{},
+			[]FieldError{{"content", "must be at most 10 characters"}},
+		},
+		"wrong type": {
+			`{"receiver_id": "bob", "content": "hi"}`,
+			&dto.SendMessageEvent{},
+			[]FieldError{{"receiver_id", "must be an integer"}},
+		},
+		"malformed": {
+			`{"receiver_id": 2,`,
+			&dto.SendMessageEvent{}, nil,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if got := invalidFields(t, test.body, test.into); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}